@@ -6,10 +6,12 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/sean/janus/internal/api"
+	"github.com/sean/janus/internal/api/handlers"
 	"github.com/sean/janus/internal/config"
 	"github.com/sean/janus/internal/logger"
 	"github.com/sean/janus/internal/session"
@@ -34,10 +36,49 @@ func main() {
 		Str("log_level", cfg.LogLevel).
 		Str("cors_origins", cfg.CORSAllowedOrigins).
 		Str("workspace_dir", cfg.WorkspaceDir).
+		Str("route_prefix", cfg.RoutePrefix).
 		Msg("Configuration loaded")
 
+	// In containers, cursor-agent and model files may show up shortly after startup
+	if cfg.DependencyWaitSeconds > 0 {
+		wait := time.Duration(cfg.DependencyWaitSeconds) * time.Second
+		if err := api.WaitForDependencies(wait, api.DependencyCheck(cfg)); err != nil {
+			if cfg.FailFastOnMissingDeps {
+				log.Fatal().Err(err).Msg("Required dependencies unavailable")
+			}
+			log.Warn().Err(err).Msg("Starting without all dependencies")
+		}
+	}
+
+	// Archive ended sessions in the background so ending one never waits on storage
+	archiver := newArchiver(cfg)
+	var archiveQueue *session.ArchiveQueue
+	if archiver != nil && cfg.ArchiveWorkers > 0 {
+		archiveQueue = session.NewArchiveQueue(archiver, cfg.ArchiveWorkers, cfg.ArchiveQueueSize, cfg.ArchiveQueuePolicy)
+		archiveQueue.Start()
+		archiver = archiveQueue
+	}
+
 	// Create session manager
-	sessionManager := session.NewMemorySessionManager()
+	sessionManager := session.NewMemorySessionManager(
+		session.WithLockMetrics(cfg.LockMetrics),
+		session.WithCursorAgentPath(cfg.CursorAgentPath),
+		session.WithFallbackModels(cfg.CursorAgentFallbackModels),
+		session.WithLongConversationThreshold(cfg.LongConversationThreshold),
+		session.WithMaxCursorOutputBytes(cfg.MaxCursorOutputBytes),
+		session.WithMaxConversationBytes(cfg.MaxConversationBytes),
+		session.WithMaxStoredAnswers(cfg.MaxStoredAnswers),
+		session.WithSerializedAsks(cfg.SerializeAsks, cfg.RejectConcurrentAsks),
+		session.WithMaxConcurrentAsks(cfg.MaxConcurrentAsks),
+		session.WithUsageSummaries(cfg.SessionUsageSummary),
+		session.WithCursorAgentStdin(cfg.CursorAgentUseStdin, cfg.CursorAgentStdinThreshold),
+		session.WithCursorAgentModes(cfg.CursorAgentModes),
+		session.WithArchiver(archiver),
+		session.WithPersistentCursorProcess(cfg.PersistentCursorProcess),
+		session.WithSessionShards(cfg.SessionShards),
+		session.WithInitialSessionCapacity(cfg.InitialSessionCapacity),
+		session.WithPersistence(sessionsFile(cfg), cfg.SessionPersistDebounce),
+	)
 
 	// Start cleanup service for inactive sessions
 	sessionTimeout := time.Duration(cfg.SessionTimeoutMinutes) * time.Minute
@@ -48,26 +89,59 @@ func main() {
 	)
 	cleanupService.Start()
 
+	// Prune expired conversation archives (retention of 0 keeps them forever)
+	var archivePruner *session.ArchivePruner
+	if cfg.ArchiveRetentionDays > 0 {
+		archivePruner = session.NewArchivePruner(
+			cfg.ConversationArchiveDir,
+			time.Duration(cfg.ArchiveRetentionDays)*24*time.Hour,
+			session.DefaultArchivePruneInterval,
+		)
+		archivePruner.Start()
+	}
+
+	// Preload the Whisper model in the background so the first transcription isn't slow
+	go handlers.WarmupWhisper(context.Background(), cfg)
+
 	// Setup router
-	router := api.SetupRouter(cfg, sessionManager)
+	router, reloader := api.NewRouter(cfg, sessionManager, api.WithCleanupMonitor(cleanupService))
 
 	// Create HTTP server
-	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%s", cfg.Port),
-		Handler: router,
+	srv := api.NewServer(cfg, router)
+
+	// Cap open TCP connections so a connection flood can't exhaust the box
+	listener, err := api.Listen(srv.Addr, cfg.MaxConnections)
+	if err != nil {
+		log.Fatal().Err(err).Str("address", srv.Addr).Msg("Failed to listen")
 	}
 
 	// Start server in a goroutine
 	go func() {
 		log.Info().
 			Str("address", fmt.Sprintf("http://localhost:%s", cfg.Port)).
-			Str("health_check", fmt.Sprintf("http://localhost:%s/api/health", cfg.Port)).
+			Str("health_check", fmt.Sprintf("http://localhost:%s%s/health", cfg.Port, cfg.RoutePrefix)).
+			Int("max_connections", cfg.MaxConnections).
 			Msg("Server listening")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
 			log.Fatal().Err(err).Msg("Failed to start server")
 		}
 	}()
 
+	// Reload select configuration on SIGHUP without dropping connections
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			log.Info().Msg("SIGHUP received, reloading configuration")
+			next, err := config.Reload()
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to reload configuration, keeping current settings")
+				continue
+			}
+			reloader.Apply(next)
+		}
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	// kill (no param) default send syscall.SIGTERM
@@ -80,6 +154,9 @@ func main() {
 
 	// Stop cleanup service
 	cleanupService.Stop()
+	if archivePruner != nil {
+		archivePruner.Stop()
+	}
 
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
@@ -90,5 +167,52 @@ func main() {
 		log.Fatal().Err(err).Msg("Server forced to shutdown")
 	}
 
+	// Stop persistent cursor-agents so none outlive the server
+	if closer, ok := sessionManager.(interface{ CloseProcesses() }); ok {
+		closer.CloseProcesses()
+	}
+
+	// Write out session changes still waiting on the persistence debounce
+	if flusher, ok := sessionManager.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			log.Error().Err(err).Msg("Failed to persist sessions on shutdown")
+		}
+	}
+
+	// Let queued archive jobs finish before exiting
+	if archiveQueue != nil {
+		archiveQueue.Stop()
+	}
+
 	log.Info().Msg("Server exited")
 }
+
+// sessionsFile returns where sessions are persisted, or "" when persistence is off
+func sessionsFile(cfg *config.Config) string {
+	if cfg.SessionPersistence != "file" {
+		return ""
+	}
+	return filepath.Join(cfg.ContextDir, session.SessionsFileName)
+}
+
+// newArchiver builds the session archiver selected by ArchiveBackend, or nil when archiving is off
+func newArchiver(cfg *config.Config) session.Archiver {
+	local := session.NewLocalArchiver(cfg.ConversationArchiveDir)
+	s3 := session.NewS3Archiver(session.S3Config{
+		Endpoint:        cfg.S3Endpoint,
+		Bucket:          cfg.S3Bucket,
+		Region:          cfg.S3Region,
+		AccessKeyID:     cfg.S3AccessKeyID,
+		SecretAccessKey: cfg.S3SecretAccessKey,
+	})
+
+	switch cfg.ArchiveBackend {
+	case "local":
+		return local
+	case "s3":
+		return s3
+	case "both":
+		return session.MultiArchiver{local, s3}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/logger"
+)
+
+// dependencyPollInterval is how often WaitForDependencies re-checks missing dependencies
+const dependencyPollInterval = 2 * time.Second
+
+// DependencyCheck returns a check reporting which external dependencies are not
+// yet available: the cursor-agent executable (resolved via PATH) and the
+// configured kokoro model and voices files
+func DependencyCheck(cfg *config.Config) func() []string {
+	return func() []string {
+		var missing []string
+		if _, err := exec.LookPath(cfg.CursorAgentPath); err != nil {
+			missing = append(missing, cfg.CursorAgentPath)
+		}
+		for _, path := range []string{cfg.KokoroTTSModelPath, cfg.KokoroTTSVoicesPath} {
+			if _, err := os.Stat(path); err != nil {
+				missing = append(missing, path)
+			}
+		}
+		return missing
+	}
+}
+
+// WaitForDependencies polls check until it reports nothing missing or timeout
+// passes, logging what is still outstanding. It returns an error naming the
+// dependencies that never appeared.
+func WaitForDependencies(timeout time.Duration, check func() []string) error {
+	return waitForDependencies(timeout, dependencyPollInterval, check)
+}
+
+// waitForDependencies implements WaitForDependencies with a configurable poll interval
+func waitForDependencies(timeout time.Duration, interval time.Duration, check func() []string) error {
+	log := logger.Get()
+	start := time.Now()
+	deadline := start.Add(timeout)
+
+	for {
+		missing := check()
+		if len(missing) == 0 {
+			log.Info().
+				Dur("waited", time.Since(start)).
+				Msg("All dependencies available")
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("dependencies still missing after %s: %s", timeout, strings.Join(missing, ", "))
+		}
+
+		log.Info().
+			Strs("missing", missing).
+			Dur("waited", time.Since(start)).
+			Dur("timeout", timeout).
+			Msg("Waiting for dependencies")
+		time.Sleep(min(interval, time.Until(deadline)))
+	}
+}
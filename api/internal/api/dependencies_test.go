@@ -0,0 +1,61 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sean/janus/internal/config"
+)
+
+func TestWaitForDependencies(t *testing.T) {
+	t.Run("returns once dependencies appear", func(t *testing.T) {
+		checks := 0
+		check := func() []string {
+			checks++
+			if checks < 3 {
+				return []string{"cursor-agent"}
+			}
+			return nil
+		}
+
+		if err := waitForDependencies(time.Second, time.Millisecond, check); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if checks != 3 {
+			t.Errorf("expected 3 checks, got %d", checks)
+		}
+	})
+
+	t.Run("fails naming what is still missing after the timeout", func(t *testing.T) {
+		check := func() []string { return []string{"/models/kokoro.onnx"} }
+
+		err := waitForDependencies(20*time.Millisecond, time.Millisecond, check)
+		if err == nil || !strings.Contains(err.Error(), "/models/kokoro.onnx") {
+			t.Errorf("expected error naming the missing model, got %v", err)
+		}
+	})
+}
+
+func TestDependencyCheck(t *testing.T) {
+	dir := t.TempDir()
+	agent := filepath.Join(dir, "cursor-agent")
+	model := filepath.Join(dir, "model.onnx")
+	voices := filepath.Join(dir, "voices.bin")
+	cfg := &config.Config{CursorAgentPath: agent, KokoroTTSModelPath: model, KokoroTTSVoicesPath: voices}
+	check := DependencyCheck(cfg)
+
+	if missing := check(); len(missing) != 3 {
+		t.Errorf("expected all 3 dependencies missing, got %v", missing)
+	}
+
+	os.WriteFile(agent, []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(model, nil, 0644)
+	os.WriteFile(voices, nil, 0644)
+
+	if missing := check(); len(missing) != 0 {
+		t.Errorf("expected nothing missing, got %v", missing)
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/middleware"
+	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/logger"
+)
+
+// AdminHandler handles operator-only requests
+type AdminHandler struct {
+	config      *config.Config
+	maintenance *middleware.MaintenanceMode
+	drain       *DrainMode
+	// logTail is the source for TailLogs; logger.Tail unless replaced in tests
+	logTail *logger.RingBuffer
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(cfg *config.Config, maintenance *middleware.MaintenanceMode, drain *DrainMode) *AdminHandler {
+	return &AdminHandler{
+		config:      cfg,
+		maintenance: maintenance,
+		drain:       drain,
+		logTail:     logger.Tail,
+	}
+}
+
+// MaintenanceRequest toggles maintenance mode
+type MaintenanceRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Message string `json:"message,omitempty"`
+}
+
+// MaintenanceResponse reports the current maintenance state
+type MaintenanceResponse struct {
+	Maintenance bool   `json:"maintenance"`
+	Message     string `json:"message,omitempty"`
+}
+
+// SetMaintenance handles requests to enable or disable maintenance mode
+func (h *AdminHandler) SetMaintenance(c *gin.Context) {
+	var req MaintenanceRequest
+	if err := bindJSON(h.config, c, &req); err != nil {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, bindErrorDetails(err, "Invalid request body: enabled field is required"))
+		return
+	}
+
+	action := "maintenance_disable"
+	if *req.Enabled {
+		action = "maintenance_enable"
+		h.maintenance.Enable(req.Message)
+	} else {
+		h.maintenance.Disable()
+	}
+	auditAction(h.config, c, action, nil)
+
+	enabled, message := h.maintenance.Status()
+	logger.Get().Info().
+		Bool("maintenance", enabled).
+		Str("message", message).
+		Msg("Maintenance mode updated")
+
+	respondWithData(h.config, c, http.StatusOK, MaintenanceResponse{
+		Maintenance: enabled,
+		Message:     message,
+	})
+}
+
+// DrainRequest toggles draining
+type DrainRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// DrainResponse reports the current drain state
+type DrainResponse struct {
+	Draining bool `json:"draining"`
+	// DrainingSince is when draining began (omitted when not draining)
+	DrainingSince *time.Time `json:"draining_since,omitempty"`
+}
+
+// SetDrain handles requests to start or stop draining. While draining, the
+// readiness check returns 503 but all other routes keep working so existing
+// sessions can finish.
+func (h *AdminHandler) SetDrain(c *gin.Context) {
+	var req DrainRequest
+	if err := bindJSON(h.config, c, &req); err != nil {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, bindErrorDetails(err, "Invalid request body: enabled field is required"))
+		return
+	}
+
+	action := "drain_disable"
+	if *req.Enabled {
+		action = "drain_enable"
+		h.drain.Enable()
+	} else {
+		h.drain.Disable()
+	}
+	auditAction(h.config, c, action, nil)
+
+	draining, since := h.drain.Status()
+	logger.Get().Info().
+		Bool("draining", draining).
+		Msg("Drain mode updated")
+
+	resp := DrainResponse{Draining: draining}
+	if draining {
+		resp.DrainingSince = &since
+	}
+	respondWithData(h.config, c, http.StatusOK, resp)
+}
+
+// TailLogs streams recent server log lines over SSE, followed by new lines as
+// they are logged. Each event's data is one JSON log entry.
+func (h *AdminHandler) TailLogs(c *gin.Context) {
+	auditAction(h.config, c, "logs_tail", nil)
+
+	recent, lines, unsubscribe := h.logTail.Subscribe()
+	defer unsubscribe()
+
+	// Cancelled when the client disconnects or is dropped for falling behind
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	events := make(chan interface{}, len(recent))
+	for _, line := range recent {
+		events <- logTailEvent(line)
+	}
+
+	// Forward live lines until the client disconnects
+	go func() {
+		defer close(events)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case line := <-lines:
+				select {
+				case events <- logTailEvent(line):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	streamSSE(c, events, newSSEOptions(h.config, cancel))
+}
+
+// logTailEvent passes a JSON log line through as-is, falling back to a string
+// for anything that isn't valid JSON
+func logTailEvent(line []byte) interface{} {
+	line = bytes.TrimSpace(line)
+	if json.Valid(line) {
+		return json.RawMessage(line)
+	}
+	return string(line)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/sean/janus/internal/api/middleware"
+	"github.com/sean/janus/internal/logger"
+)
+
+func TestAdminHandler_TailLogs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ring := logger.NewRingBuffer(10)
+	log := zerolog.New(ring)
+	log.Info().Msg("logged before connecting")
+
+	handler := NewAdminHandler(newTestConfig(), middleware.NewMaintenanceMode(), NewDrainMode())
+	handler.logTail = ring
+
+	router := gin.New()
+	router.GET("/api/admin/logs/tail", handler.TailLogs)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/admin/logs/tail", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	waitFor := func(message string) {
+		t.Helper()
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "data: ") && strings.Contains(line, `"message":"`+message+`"`) {
+				return
+			}
+		}
+		t.Fatalf("stream ended before %q appeared: %v", message, scanner.Err())
+	}
+
+	waitFor("logged before connecting")
+
+	log.Info().Str("component", "test").Msg("newly logged line")
+	waitFor("newly logged line")
+}
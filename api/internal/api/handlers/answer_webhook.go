@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/logger"
+)
+
+// answerWebhookBackoff is the wait before the first retry, doubling for each one after
+const answerWebhookBackoff = 500 * time.Millisecond
+
+// AnswerWebhookPayload is POSTed to the answer webhook after each answered question
+type AnswerWebhookPayload struct {
+	SessionID string    `json:"session_id"`
+	TraceID   string    `json:"trace_id,omitempty"`
+	Question  string    `json:"question"`
+	Answer    string    `json:"answer"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// answerWebhook delivers answers to webhook URLs in the background so a slow or
+// failing receiver never delays the ask response
+type answerWebhook struct {
+	client  *http.Client
+	retries int
+	backoff time.Duration
+}
+
+// newAnswerWebhook creates a deliverer using the configured per-attempt timeout and retries
+func newAnswerWebhook(cfg *config.Config) *answerWebhook {
+	return &answerWebhook{
+		client:  &http.Client{Timeout: cfg.AnswerWebhookTimeout},
+		retries: cfg.AnswerWebhookRetries,
+		backoff: answerWebhookBackoff,
+	}
+}
+
+// allowsHost reports whether a client-chosen webhook URL targets one of
+// hosts. Session webhooks are limited to them so clients cannot make the
+// server POST to internal services.
+func allowsHost(hosts []string, raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	for _, host := range hosts {
+		if strings.EqualFold(u.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// send starts delivering payload to url and returns immediately
+func (w *answerWebhook) send(url string, payload AnswerWebhookPayload) {
+	go w.deliver(url, payload)
+}
+
+// deliver POSTs payload to url, retrying network errors, 429s, and 5xx
+// responses with exponential backoff. Other failures are not retried.
+func (w *answerWebhook) deliver(url string, payload AnswerWebhookPayload) {
+	log := logger.Get().With().
+		Str("session_id", payload.SessionID).
+		Str("trace_id", payload.TraceID).
+		Logger()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode answer webhook payload")
+		return
+	}
+
+	backoff := w.backoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := w.post(url, body)
+		if err == nil {
+			log.Debug().Int("attempt", attempt+1).Msg("Delivered answer webhook")
+			return
+		}
+		if !retryable || attempt >= w.retries {
+			log.Warn().Err(err).Int("attempts", attempt+1).Msg("Failed to deliver answer webhook")
+			return
+		}
+
+		log.Debug().Err(err).Int("attempt", attempt+1).Dur("backoff", backoff).Msg("Answer webhook failed, retrying")
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth retrying
+func (w *answerWebhook) post(url string, body []byte) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/session"
+)
+
+// newWebhookServer records each delivered payload, failing the first failures requests with 500
+func newWebhookServer(t *testing.T, failures int32) (*httptest.Server, <-chan AnswerWebhookPayload, *atomic.Int32) {
+	t.Helper()
+	payloads := make(chan AnswerWebhookPayload, 10)
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var payload AnswerWebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("failed to decode webhook payload: %v", err)
+		}
+		payloads <- payload
+	}))
+	t.Cleanup(server.Close)
+	return server, payloads, &attempts
+}
+
+func waitForPayload(t *testing.T, payloads <-chan AnswerWebhookPayload) AnswerWebhookPayload {
+	t.Helper()
+	select {
+	case payload := <-payloads:
+		return payload
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for webhook delivery")
+		return AnswerWebhookPayload{}
+	}
+}
+
+func TestAsk_AnswerWebhook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ask := func(handler *SessionHandler, sessionID string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask?session_id="+sessionID, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+		return w
+	}
+
+	t.Run("delivers the answer to the session webhook", func(t *testing.T) {
+		server, payloads, _ := newWebhookServer(t, 0)
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSessionWithOptions(session.SessionOptions{AnswerWebhookURL: server.URL})
+		cfg := newTestConfig()
+		cfg.AnswerWebhookHosts = []string{"127.0.0.1"}
+		handler := NewSessionHandler(mockManager, cfg)
+
+		w := ask(handler, sess.ID, `{"question":"What changed?","trace_id":"trace-1"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		payload := waitForPayload(t, payloads)
+		if payload.SessionID != sess.ID || payload.TraceID != "trace-1" {
+			t.Errorf("expected session %s and trace-1, got %+v", sess.ID, payload)
+		}
+		if payload.Question != "What changed?" {
+			t.Errorf("expected the question in the payload, got %q", payload.Question)
+		}
+		if payload.Answer != "Mock cursor-agent response to: What changed?" {
+			t.Errorf("expected the answer in the payload, got %q", payload.Answer)
+		}
+	})
+
+	t.Run("falls back to the global webhook", func(t *testing.T) {
+		server, payloads, _ := newWebhookServer(t, 0)
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		cfg := newTestConfig()
+		cfg.AnswerWebhookURL = server.URL
+		handler := NewSessionHandler(mockManager, cfg)
+
+		ask(handler, sess.ID, `{"question":"hello"}`)
+
+		if payload := waitForPayload(t, payloads); payload.Question != "hello" {
+			t.Errorf("expected the question in the payload, got %q", payload.Question)
+		}
+	})
+
+	t.Run("skips ephemeral asks", func(t *testing.T) {
+		server, _, attempts := newWebhookServer(t, 0)
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSessionWithOptions(session.SessionOptions{AnswerWebhookURL: server.URL})
+		cfg := newTestConfig()
+		cfg.AnswerWebhookHosts = []string{"127.0.0.1"}
+		handler := NewSessionHandler(mockManager, cfg)
+
+		ask(handler, sess.ID, `{"question":"private","ephemeral":true}`)
+
+		time.Sleep(100 * time.Millisecond)
+		if n := attempts.Load(); n != 0 {
+			t.Errorf("expected no webhook delivery, got %d", n)
+		}
+	})
+
+	t.Run("ignores a session webhook whose host is no longer allowed", func(t *testing.T) {
+		server, _, attempts := newWebhookServer(t, 0)
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSessionWithOptions(session.SessionOptions{AnswerWebhookURL: server.URL})
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		ask(handler, sess.ID, `{"question":"hello"}`)
+
+		time.Sleep(100 * time.Millisecond)
+		if n := attempts.Load(); n != 0 {
+			t.Errorf("expected no webhook delivery, got %d", n)
+		}
+	})
+}
+
+func TestAnswerWebhook_Retries(t *testing.T) {
+	server, payloads, attempts := newWebhookServer(t, 2)
+	webhook := newAnswerWebhook(newTestConfig())
+	webhook.client = server.Client()
+	webhook.retries = 3
+	webhook.backoff = time.Millisecond
+
+	webhook.send(server.URL, AnswerWebhookPayload{SessionID: "s1", Question: "q", Answer: "a"})
+
+	if payload := waitForPayload(t, payloads); payload.Answer != "a" {
+		t.Errorf("expected the answer in the payload, got %q", payload.Answer)
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestStart_InvalidAnswerWebhookURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := newTestConfig()
+	cfg.AnswerWebhookHosts = []string{"example.com"}
+	handler := NewSessionHandler(NewMockSessionManager(), cfg)
+
+	start := func(body string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/session/start", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Start(c)
+		return w.Code
+	}
+
+	if code := start(`{"answer_webhook_url":"ftp://example.com/hook"}`); code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a non-http URL, got %d", code)
+	}
+	for _, internal := range []string{"http://127.0.0.1:8080/admin", "http://169.254.169.254/latest/meta-data", "http://evil.example.org/hook"} {
+		if code := start(`{"answer_webhook_url":"` + internal + `"}`); code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, whose host is not allowed, got %d", internal, code)
+		}
+	}
+	if code := start(`{"answer_webhook_url":"https://EXAMPLE.com:8443/hook"}`); code != http.StatusOK {
+		t.Errorf("expected an allowed host to be accepted, got %d", code)
+	}
+}
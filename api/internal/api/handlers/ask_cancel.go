@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/logger"
+	"github.com/sean/janus/internal/session"
+)
+
+// StatusQuestionCancelled is the nginx-style "client closed request" status
+// returned to an ask stopped through POST /ask/cancel
+const StatusQuestionCancelled = 499
+
+// CancelAskResponse represents the response from cancelling a session's questions
+type CancelAskResponse struct {
+	SessionID string `json:"session_id"`
+	// Cancelled is false when nothing was running for the session
+	Cancelled bool `json:"cancelled"`
+}
+
+// CancelAsk handles requests to stop a session's in-flight questions. The
+// pending ask responds with StatusQuestionCancelled and the session stays
+// usable. Cancelling when nothing is running succeeds without effect.
+func (h *SessionHandler) CancelAsk(c *gin.Context) {
+	sessionID, ok := h.sessionIDParam(c)
+	if !ok {
+		return
+	}
+
+	canceller, ok := h.sessionManager.(session.CancelManager)
+	if !ok {
+		response.RespondWithError(c, http.StatusNotImplemented, response.ErrInvalidRequest, "Cancelling questions is not supported by this server")
+		return
+	}
+
+	cancelled, err := canceller.CancelQuestion(sessionID)
+	if err != nil {
+		response.RespondWithError(c, http.StatusNotFound, response.ErrSessionNotFound, "The specified session does not exist or has expired")
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).Info().
+		Str("session_id", sessionID).
+		Bool("cancelled", cancelled).
+		Msg("Cancel requested for in-flight questions")
+
+	respondWithData(h.config, c, http.StatusOK, CancelAskResponse{
+		SessionID: h.publicSessionID(sessionID),
+		Cancelled: cancelled,
+	})
+}
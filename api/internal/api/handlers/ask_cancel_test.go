@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/session"
+)
+
+// cancellingMockSessionManager adds CancelQuestion to the mock, reporting running as the result
+type cancellingMockSessionManager struct {
+	*MockSessionManager
+	running bool
+}
+
+func (m *cancellingMockSessionManager) CancelQuestion(id string) (bool, error) {
+	if _, err := m.GetSession(id); err != nil {
+		return false, err
+	}
+	return m.running, nil
+}
+
+func TestCancelAsk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cancel := func(manager session.Manager, sessionID string) *httptest.ResponseRecorder {
+		handler := NewSessionHandler(manager, newTestConfig())
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask/cancel?session_id="+sessionID, nil)
+		handler.CancelAsk(c)
+		return w
+	}
+
+	t.Run("reports whether a question was cancelled", func(t *testing.T) {
+		for _, running := range []bool{true, false} {
+			manager := &cancellingMockSessionManager{MockSessionManager: NewMockSessionManager(), running: running}
+			sess, _ := manager.CreateSession()
+
+			w := cancel(manager, sess.ID)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			var response CancelAskResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if response.SessionID != sess.ID || response.Cancelled != running {
+				t.Errorf("expected cancelled=%v for %s, got %+v", running, sess.ID, response)
+			}
+		}
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		manager := &cancellingMockSessionManager{MockSessionManager: NewMockSessionManager()}
+
+		if w := cancel(manager, "missing"); w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("manager without cancel support", func(t *testing.T) {
+		manager := NewMockSessionManager()
+		sess, _ := manager.CreateSession()
+
+		if w := cancel(manager, sess.ID); w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+	})
+
+	t.Run("cancelled ask responds with 499", func(t *testing.T) {
+		manager := NewMockSessionManager()
+		sess, _ := manager.CreateSession()
+		manager.askQuestionFunc = func(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
+			return "", "", fmt.Errorf("%w: killed", session.ErrQuestionCancelled)
+		}
+		handler := NewSessionHandler(manager, newTestConfig())
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask?session_id="+sess.ID, bytes.NewBufferString(`{"question":"test"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+
+		if w.Code != StatusQuestionCancelled {
+			t.Errorf("expected status 499, got %d", w.Code)
+		}
+	})
+}
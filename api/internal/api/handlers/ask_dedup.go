@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+)
+
+// askResult is the outcome of a cursor-agent ask shared between duplicate requests
+type askResult struct {
+	answer       string
+	cursorChatID string
+	resumed      bool
+	err          error
+}
+
+// askCall is an ask in flight that duplicates can wait on
+type askCall struct {
+	done   chan struct{}
+	result askResult
+	// dups counts requests that attached to this call instead of running their own
+	dups int
+}
+
+// askFlight collapses concurrent identical asks into a single cursor-agent run
+type askFlight struct {
+	mu    sync.Mutex
+	calls map[string]*askCall
+}
+
+// newAskFlight creates an empty askFlight
+func newAskFlight() *askFlight {
+	return &askFlight{calls: make(map[string]*askCall)}
+}
+
+// Do runs fn for key unless an identical ask is already in flight, in which case
+// it waits for and returns that ask's result with shared set. A waiting caller
+// gives up with its own context's error if ctx ends first.
+func (f *askFlight) Do(ctx context.Context, key string, fn func() askResult) (result askResult, shared bool) {
+	f.mu.Lock()
+	if call, ok := f.calls[key]; ok {
+		call.dups++
+		f.mu.Unlock()
+
+		select {
+		case <-call.done:
+			return call.result, true
+		case <-ctx.Done():
+			return askResult{err: ctx.Err()}, true
+		}
+	}
+
+	call := &askCall{done: make(chan struct{})}
+	f.calls[key] = call
+	f.mu.Unlock()
+
+	call.result = fn()
+
+	f.mu.Lock()
+	delete(f.calls, key)
+	f.mu.Unlock()
+	close(call.done)
+
+	return call.result, false
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/session"
+)
+
+func TestAsk_DedupeConcurrentAsks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	callsFile := filepath.Join(dir, "calls")
+	releaseFile := filepath.Join(dir, "release")
+	fake := writeFakeScript(t, "cursor-agent", fmt.Sprintf(`echo call >> %s
+while [ ! -f %s ]; do sleep 0.01; done
+echo '{"type":"result","is_error":false,"result":"shared answer","session_id":"chat-1"}'
+`, callsFile, releaseFile))
+
+	manager := session.NewMemorySessionManager(session.WithCursorAgentPath(fake))
+	sess, _ := manager.CreateSession()
+	cfg := newTestConfig()
+	cfg.WorkspaceDir = t.TempDir()
+	cfg.DedupeConcurrentAsks = true
+	handler := NewSessionHandler(manager, cfg)
+
+	ask := func(question string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body := fmt.Sprintf(`{"question":%q}`, question)
+		c.Request = httptest.NewRequest("POST", "/api/ask?session_id="+sess.ID, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+		return w
+	}
+
+	responses := make(chan *httptest.ResponseRecorder, 2)
+	go func() { responses <- ask("what does main do") }()
+	waitFor(t, func() bool {
+		_, err := os.Stat(callsFile)
+		return err == nil
+	})
+
+	// Differently spaced, the same question attaches to the ask in flight
+	go func() { responses <- ask("  what does   main do ") }()
+	waitFor(t, func() bool {
+		handler.flights.mu.Lock()
+		defer handler.flights.mu.Unlock()
+		for _, call := range handler.flights.calls {
+			if call.dups == 1 {
+				return true
+			}
+		}
+		return false
+	})
+
+	if err := os.WriteFile(releaseFile, nil, 0644); err != nil {
+		t.Fatalf("failed to release fake cursor-agent: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		w := <-responses
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response AskResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Answer != "shared answer" {
+			t.Errorf("expected the shared answer, got %q", response.Answer)
+		}
+	}
+
+	calls, _ := os.ReadFile(callsFile)
+	if n := strings.Count(string(calls), "call"); n != 1 {
+		t.Errorf("expected cursor-agent to run once, ran %d times", n)
+	}
+	updated, _ := manager.GetSession(sess.ID)
+	if len(updated.ConversationLog) != 2 {
+		t.Errorf("expected the exchange to be logged once, got %d messages", len(updated.ConversationLog))
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// idempotencyKeyPattern bounds client-supplied idempotency keys, leaving room
+// for UUIDs and base64-encoded hashes
+var idempotencyKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.:=+/-]{1,255}$`)
+
+// isValidIdempotencyKey reports whether a client-supplied idempotency key is acceptable
+func isValidIdempotencyKey(key string) bool {
+	return idempotencyKeyPattern.MatchString(key)
+}
+
+// errIdempotencyKeyReused is returned by Begin when a key arrives with a
+// different request body than the ask that claimed it
+var errIdempotencyKeyReused = errors.New("idempotency key reused with a different request body")
+
+// askIdempotency remembers ask responses by idempotency key for a TTL so a
+// retried ask returns the original answer instead of running cursor-agent again
+type askIdempotency struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*idempotentAsk
+	now     func() time.Time
+}
+
+// idempotentAsk is an ask for one key, pending until done is closed
+type idempotentAsk struct {
+	done chan struct{}
+	// bodyHash fingerprints the request body that claimed the key
+	bodyHash [sha256.Size]byte
+	// response is nil when the ask failed, leaving the key free for a retry
+	response *AskResponse
+	expires  time.Time
+}
+
+// newAskIdempotency returns a cache holding responses for ttl, or nil when ttl
+// is not positive (idempotency keys ignored)
+func newAskIdempotency(ttl time.Duration) *askIdempotency {
+	if ttl <= 0 {
+		return nil
+	}
+	return &askIdempotency{
+		ttl:     ttl,
+		entries: make(map[string]*idempotentAsk),
+		now:     time.Now,
+	}
+}
+
+// Begin claims key for a new ask, or returns the response of an earlier ask
+// with the same key. A retry that arrives while the first ask is still running
+// waits for it. When Begin returns no response, the caller must report its
+// outcome with finish: a nil response (failure) releases the key so the next
+// retry runs the ask again. A key reused with a different body returns
+// errIdempotencyKeyReused.
+func (ai *askIdempotency) Begin(ctx context.Context, key string, body []byte) (cached *AskResponse, finish func(*AskResponse), err error) {
+	bodyHash := sha256.Sum256(body)
+	for {
+		ai.mu.Lock()
+		now := ai.now()
+		for k, entry := range ai.entries {
+			if !entry.expires.IsZero() && !now.Before(entry.expires) {
+				delete(ai.entries, k)
+			}
+		}
+
+		entry, ok := ai.entries[key]
+		if !ok {
+			entry = &idempotentAsk{done: make(chan struct{}), bodyHash: bodyHash}
+			ai.entries[key] = entry
+			ai.mu.Unlock()
+			return nil, func(response *AskResponse) { ai.finish(key, entry, response) }, nil
+		}
+		ai.mu.Unlock()
+		if entry.bodyHash != bodyHash {
+			return nil, nil, errIdempotencyKeyReused
+		}
+
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			// Run without the key; the ask fails on the finished context anyway
+			return nil, func(*AskResponse) {}, nil
+		}
+		if entry.response != nil {
+			return entry.response, nil, nil
+		}
+	}
+}
+
+// finish records the outcome of the ask that claimed key and wakes any retries
+func (ai *askIdempotency) finish(key string, entry *idempotentAsk, response *AskResponse) {
+	ai.mu.Lock()
+	if response == nil {
+		delete(ai.entries, key)
+	} else {
+		entry.response = response
+		entry.expires = ai.now().Add(ai.ttl)
+	}
+	ai.mu.Unlock()
+	close(entry.done)
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/middleware"
+)
+
+func TestAskIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	setup := func(ttl time.Duration) (*SessionHandler, *MockSessionManager, string, *atomic.Int32) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		runs := &atomic.Int32{}
+		mockManager.askQuestionFunc = func(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
+			n := runs.Add(1)
+			return fmt.Sprintf("answer %d", n), "chat-1", nil
+		}
+
+		cfg := newTestConfig()
+		cfg.AskIdempotencyTTL = ttl
+		return NewSessionHandler(mockManager, cfg), mockManager, sess.ID, runs
+	}
+
+	askBody := func(handler *SessionHandler, sessionID string, key string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest("POST", "/api/ask?session_id="+sessionID, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			c.Request.Header.Set(middleware.IdempotencyKeyHeader, key)
+		}
+		handler.Ask(c)
+		return recorder
+	}
+
+	ask := func(handler *SessionHandler, sessionID string, key string) *httptest.ResponseRecorder {
+		return askBody(handler, sessionID, key, `{"question":"test"}`)
+	}
+
+	answer := func(t *testing.T, recorder *httptest.ResponseRecorder) string {
+		t.Helper()
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", recorder.Code)
+		}
+		var response AskResponse
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return response.Answer
+	}
+
+	t.Run("retry with the same key replays the cached answer", func(t *testing.T) {
+		handler, mockManager, sessionID, runs := setup(time.Minute)
+
+		first := ask(handler, sessionID, "retry-1")
+		retry := ask(handler, sessionID, "retry-1")
+
+		if got := answer(t, retry); got != answer(t, first) {
+			t.Errorf("expected the cached answer, got %q", got)
+		}
+		if runs.Load() != 1 {
+			t.Errorf("expected cursor-agent to run once, ran %d times", runs.Load())
+		}
+		if retry.Header().Get(middleware.IdempotentReplayedHeader) != "true" {
+			t.Error("expected the retry to be marked as replayed")
+		}
+		if first.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+			t.Error("expected the first response not to be marked as replayed")
+		}
+		sess, _ := mockManager.GetSession(sessionID)
+		if len(sess.ConversationLog) != 2 {
+			t.Errorf("expected the exchange to be logged once, got %d messages", len(sess.ConversationLog))
+		}
+	})
+
+	t.Run("different keys and no key run the ask again", func(t *testing.T) {
+		handler, _, sessionID, runs := setup(time.Minute)
+
+		ask(handler, sessionID, "key-a")
+		ask(handler, sessionID, "key-b")
+		ask(handler, sessionID, "")
+		ask(handler, sessionID, "")
+
+		if runs.Load() != 4 {
+			t.Errorf("expected 4 runs, got %d", runs.Load())
+		}
+	})
+
+	t.Run("a retry while the first ask runs waits for its answer", func(t *testing.T) {
+		handler, mockManager, sessionID, runs := setup(time.Minute)
+		started := make(chan struct{})
+		unblock := make(chan struct{})
+		mockManager.askQuestionFunc = func(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
+			runs.Add(1)
+			close(started)
+			<-unblock
+			return "slow answer", "chat-1", nil
+		}
+
+		var wg sync.WaitGroup
+		results := make([]*httptest.ResponseRecorder, 2)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[0] = ask(handler, sessionID, "retry-2")
+		}()
+		<-started
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[1] = ask(handler, sessionID, "retry-2")
+		}()
+		time.Sleep(20 * time.Millisecond)
+		close(unblock)
+		wg.Wait()
+
+		for _, recorder := range results {
+			if got := answer(t, recorder); got != "slow answer" {
+				t.Errorf("expected the shared answer, got %q", got)
+			}
+		}
+		if runs.Load() != 1 {
+			t.Errorf("expected cursor-agent to run once, ran %d times", runs.Load())
+		}
+	})
+
+	t.Run("a failed ask lets the retry run", func(t *testing.T) {
+		handler, mockManager, sessionID, runs := setup(time.Minute)
+		mockManager.askQuestionFunc = func(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
+			if runs.Add(1) == 1 {
+				return "", "", fmt.Errorf("cursor-agent command failed")
+			}
+			return "recovered", "chat-1", nil
+		}
+
+		if failed := ask(handler, sessionID, "retry-3"); failed.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status 500, got %d", failed.Code)
+		}
+		if got := answer(t, ask(handler, sessionID, "retry-3")); got != "recovered" {
+			t.Errorf("expected the retry to run, got %q", got)
+		}
+	})
+
+	t.Run("responses expire after the TTL", func(t *testing.T) {
+		handler, _, sessionID, runs := setup(time.Minute)
+		now := time.Now()
+		handler.idempotency.now = func() time.Time { return now }
+
+		ask(handler, sessionID, "retry-4")
+		now = now.Add(2 * time.Minute)
+		ask(handler, sessionID, "retry-4")
+
+		if runs.Load() != 2 {
+			t.Errorf("expected the expired key to run again, got %d runs", runs.Load())
+		}
+	})
+
+	t.Run("keys are ignored when disabled", func(t *testing.T) {
+		handler, _, sessionID, runs := setup(0)
+
+		ask(handler, sessionID, "retry-5")
+		ask(handler, sessionID, "retry-5")
+
+		if runs.Load() != 2 {
+			t.Errorf("expected 2 runs, got %d", runs.Load())
+		}
+	})
+
+	t.Run("rejects a key reused with a different body", func(t *testing.T) {
+		handler, _, sessionID, runs := setup(time.Minute)
+
+		ask(handler, sessionID, "retry-6")
+		recorder := askBody(handler, sessionID, "retry-6", `{"question":"something else"}`)
+
+		if recorder.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", recorder.Code)
+		}
+		if runs.Load() != 1 {
+			t.Errorf("expected cursor-agent to run once, ran %d times", runs.Load())
+		}
+	})
+
+	t.Run("accepts keys longer than a trace ID", func(t *testing.T) {
+		handler, _, sessionID, runs := setup(time.Minute)
+
+		if recorder := ask(handler, sessionID, strings.Repeat("k", 200)); recorder.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", recorder.Code)
+		}
+		if recorder := ask(handler, sessionID, strings.Repeat("k", 256)); recorder.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 over 255 characters, got %d", recorder.Code)
+		}
+		if runs.Load() != 1 {
+			t.Errorf("expected 1 run, got %d", runs.Load())
+		}
+	})
+
+	t.Run("rejects a malformed key", func(t *testing.T) {
+		handler, _, sessionID, runs := setup(time.Minute)
+
+		if recorder := ask(handler, sessionID, "bad key!"); recorder.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", recorder.Code)
+		}
+		if runs.Load() != 0 {
+			t.Error("expected no ask to run")
+		}
+	})
+}
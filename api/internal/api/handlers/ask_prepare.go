@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/logger"
+	"github.com/sean/janus/internal/session"
+)
+
+// preparedAsk is a validated ask ready to send to cursor-agent, shared by the
+// plain and streamed ask handlers
+type preparedAsk struct {
+	sessionID string
+	req       AskRequest
+	sess      *session.Session
+	profile   clientProfile
+	// prompt is the question with attachments, context, and client preamble applied
+	prompt string
+	// idleWarning is measured before this ask counts as activity
+	idleWarning bool
+	// release frees the workspace and the client's concurrent-ask slot
+	release func()
+}
+
+// prepareAsk validates req for sessionID and builds the prompt: mode,
+// client type, translation, trace ID, deny patterns, and attachments are
+// checked, the client's concurrent-ask slot is taken, and a branch-pinned
+// session's branch is checked out. streaming selects which manager capability
+// the mode must be supported by. On failure it writes the error response itself
+// and returns false; on success the caller must call release.
+func (h *SessionHandler) prepareAsk(c *gin.Context, sessionID string, req AskRequest, streaming bool) (*preparedAsk, bool) {
+	if req.Mode != "" && !h.supportsMode(req.Mode, streaming) {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "unknown mode: "+req.Mode)
+		return nil, false
+	}
+
+	profile, ok := h.clientProfile(req.ClientType)
+	if !ok {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "client_type must be 'voice' or 'text'")
+		return nil, false
+	}
+
+	if req.TranslateTo != "" {
+		if h.config.TranslatePath == "" {
+			response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "translation is not configured on this server")
+			return nil, false
+		}
+		if !isValidLanguage(req.TranslateTo) {
+			response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "translate_to must be a language tag such as 'es' or 'pt-BR'")
+			return nil, false
+		}
+	}
+
+	if req.TraceID != "" && !isValidTraceID(req.TraceID) {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "trace_id must be 1-128 letters, digits, or '_.:-'")
+		return nil, false
+	}
+
+	// The response stays generic so clients can't probe which pattern matched
+	if pattern := h.denylist.match(req.Question); pattern >= 0 {
+		logger.FromContext(c.Request.Context()).Warn().
+			Str("session_id", sessionID).
+			Str("trace_id", req.TraceID).
+			Int("pattern_index", pattern).
+			Msg("Rejected question matching a deny pattern")
+		response.RespondWithError(c, http.StatusUnprocessableEntity, response.ErrQuestionRejected, "This question can't be processed")
+		return nil, false
+	}
+
+	files, err := resolveAttachments(h.config.WorkspaceDir, req.Files)
+	if err != nil {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, err.Error())
+		return nil, false
+	}
+
+	// Verify session exists
+	sess, err := h.sessionManager.GetSession(sessionID)
+	if err != nil {
+		response.RespondWithError(c, http.StatusNotFound, response.ErrSessionNotFound, "The specified session does not exist or has expired")
+		return nil, false
+	}
+
+	ask := &preparedAsk{
+		sessionID:   sessionID,
+		req:         req,
+		sess:        sess,
+		profile:     profile,
+		idleWarning: h.idleWarning(sess),
+	}
+
+	// Each cursor-agent run is expensive, so one client can only hold MaxAsksPerIP at once
+	release, ok := h.asks.Acquire(c.ClientIP())
+	if !ok {
+		logger.Get().Warn().
+			Str("session_id", sessionID).
+			Str("trace_id", req.TraceID).
+			Str("client_ip", c.ClientIP()).
+			Int("max_asks_per_ip", h.config.MaxAsksPerIP).
+			Msg("Too many concurrent asks from client")
+		response.RespondWithError(c, http.StatusTooManyRequests, response.ErrRateLimited, "Too many concurrent asks from this client")
+		return nil, false
+	}
+
+	// Sessions pinned to a branch get the workspace switched to it before asking,
+	// and the workspace stays on it until the ask is done
+	ctx := c.Request.Context()
+	unlock, err := h.workspace.acquire(ctx, sess.Branch, func() error {
+		return ensureBranch(ctx, h.config.GitPath, h.config.WorkspaceDir, sess.Branch)
+	})
+	if err != nil {
+		release()
+		logger.Get().Error().
+			Str("session_id", sessionID).
+			Str("trace_id", req.TraceID).
+			Str("branch", sess.Branch).
+			Err(err).
+			Msg("Failed to switch workspace branch")
+		response.RespondWithError(c, http.StatusInternalServerError, response.ErrInternalServer, "Failed to switch workspace to session branch")
+		return nil, false
+	}
+	ask.release = func() {
+		unlock()
+		release()
+	}
+
+	// The prompt may carry extra context; the conversation log keeps the raw question
+	prompt := withFileReferences(req.Question, files)
+	if req.IncludeContext {
+		gathered, err := h.gatherContext(c.Request.Context())
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Warn().
+				Str("session_id", sessionID).
+				Str("trace_id", req.TraceID).
+				Err(err).
+				Msg("Failed to gather project context, asking without it")
+		} else {
+			prompt = withProjectContext(prompt, gathered)
+		}
+	}
+	ask.prompt = profile.withPreamble(prompt)
+
+	return ask, true
+}
+
+// finishAnswer applies the client type's answer filters and, when requested,
+// translation. A failed translation still answers, just in the original
+// language, so translatedTo is empty then.
+func (h *SessionHandler) finishAnswer(ctx context.Context, ask *preparedAsk, answer string) (finished string, translatedTo string) {
+	answer = ask.profile.filterAnswer(answer)
+	if ask.req.TranslateTo == "" {
+		return answer, ""
+	}
+
+	translated, err := translate(ctx, h.config.TranslatePath, ask.req.TranslateTo, answer)
+	if err != nil {
+		logger.FromContext(ctx).Warn().
+			Str("session_id", ask.sessionID).
+			Str("trace_id", ask.req.TraceID).
+			Str("translate_to", ask.req.TranslateTo).
+			Err(err).
+			Msg("Failed to translate answer, returning it untranslated")
+		return answer, ""
+	}
+	return translated, ask.req.TranslateTo
+}
+
+// recordAnswer saves an answered exchange: the cursor chat ID (when known), the
+// activity timestamp, the conversation log entry, and the answer webhook. A
+// deduplicated ask's exchange is recorded once, by the request that ran it,
+// and ephemeral exchanges are never logged or sent. It returns whether the
+// session's conversation is now long.
+func (h *SessionHandler) recordAnswer(ask *preparedAsk, cursorChatID string, answer string, deduplicated bool) bool {
+	log := logger.Get().With().
+		Str("session_id", ask.sessionID).
+		Str("trace_id", ask.req.TraceID).
+		Logger()
+
+	if cursorChatID != "" {
+		if err := h.sessionManager.UpdateCursorChatID(ask.sessionID, cursorChatID); err != nil {
+			log.Warn().Str("cursor_chat_id", cursorChatID).Err(err).Msg("Failed to update cursor chat ID")
+		}
+	}
+
+	if err := h.sessionManager.UpdateActivity(ask.sessionID); err != nil {
+		log.Warn().Err(err).Msg("Failed to update activity")
+	}
+
+	now := time.Now()
+	ephemeral := ask.req.Ephemeral || ask.sess.Ephemeral
+	if !deduplicated && !ephemeral {
+		messages := []session.Message{
+			{Role: "user", Content: ask.req.Question, Timestamp: now},
+			{Role: "assistant", Content: answer, Timestamp: time.Now()},
+		}
+		if err := h.sessionManager.AddToConversationLog(ask.sessionID, messages); err != nil {
+			// Don't fail the request, just log the warning
+			log.Warn().Err(err).Msg("Failed to add to conversation log")
+		}
+
+		if webhookURL := h.answerWebhookURL(ask.sess); webhookURL != "" {
+			h.webhook.send(webhookURL, AnswerWebhookPayload{
+				SessionID: h.publicSessionID(ask.sessionID),
+				TraceID:   ask.req.TraceID,
+				Question:  ask.req.Question,
+				Answer:    answer,
+				Timestamp: now,
+			})
+		}
+	}
+
+	// Re-read the session so the response reflects the flag set by this exchange
+	if updated, err := h.sessionManager.GetSession(ask.sessionID); err == nil {
+		return updated.LongConversation
+	}
+	return ask.sess.LongConversation
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/logger"
+	"github.com/sean/janus/internal/session"
+)
+
+const (
+	// AskStreamEventDelta carries the next piece of the answer
+	AskStreamEventDelta = "delta"
+	// AskStreamEventDone ends a stream whose answer is complete or was cut off by the deadline
+	AskStreamEventDone = "done"
+	// AskStreamEventError ends a stream that failed
+	AskStreamEventError = "error"
+
+	// askStreamDeadlineMargin is how long before the request deadline cursor-agent
+	// is stopped, capped at a tenth of the time remaining
+	askStreamDeadlineMargin = 2 * time.Second
+)
+
+// AskStreamEvent is one SSE event of a streamed answer
+type AskStreamEvent struct {
+	Type      string `json:"type"`
+	Content   string `json:"content,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	// Truncated is set on done when the request deadline cut the answer off
+	Truncated bool `json:"truncated,omitempty"`
+	// Answer is set on done when client-type filters or translation changed the
+	// streamed text; clients should show it in place of the deltas
+	Answer       string `json:"answer,omitempty"`
+	TranslatedTo string `json:"translated_to,omitempty"`
+	IdleWarning  bool   `json:"idle_warning,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// AskStream handles question requests whose answer is streamed back as
+// Server-Sent Events: a delta event per chunk cursor-agent produces, then done
+// or error. The request is validated and prepared like /ask (attachments,
+// mode, client type, branch checkout), and the exchange is recorded the same
+// way, even if the stream is interrupted. The request timeout still bounds
+// cursor-agent.
+func (h *SessionHandler) AskStream(c *gin.Context) {
+	sessionID, ok := h.sessionIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req AskRequest
+	if err := bindJSON(h.config, c, &req); err != nil {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, bindErrorDetails(err, "Invalid request body: missing or malformed question field"))
+		return
+	}
+
+	streaming, ok := h.sessionManager.(session.StreamingManager)
+	if !ok {
+		response.RespondWithError(c, http.StatusNotImplemented, response.ErrInvalidRequest, "Streaming answers are not supported by this server")
+		return
+	}
+
+	ask, ok := h.prepareAsk(c, sessionID, req, true)
+	if !ok {
+		return
+	}
+	defer ask.release()
+
+	// Cancelled when the client disconnects, is dropped for falling behind, or the
+	// request times out, any of which stops cursor-agent
+	streamCtx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// cursor-agent is cut off a little before the request deadline so the
+	// truncated answer's done event can still reach the client
+	ctx := streamCtx
+	if deadline, ok := streamCtx.Deadline(); ok {
+		margin := min(askStreamDeadlineMargin, time.Until(deadline)/10)
+		var cancelAsk context.CancelFunc
+		ctx, cancelAsk = context.WithDeadline(streamCtx, deadline.Add(-margin))
+		defer cancelAsk()
+	}
+
+	events := make(chan interface{}, sseBufferFrames)
+	publicID := h.publicSessionID(sessionID)
+	emit := func(event AskStreamEvent) {
+		select {
+		case events <- event:
+		case <-streamCtx.Done():
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(events)
+
+		// Tracks what reached the client in case the stream fails without a result
+		var streamed strings.Builder
+		onChunk := func(chunk string) {
+			streamed.WriteString(chunk)
+			emit(AskStreamEvent{Type: AskStreamEventDelta, Content: chunk})
+		}
+		var result *session.StreamResult
+		var err error
+		if req.Mode != "" {
+			result, err = h.sessionManager.(session.ModeStreamingManager).AskQuestionStreamInMode(ctx, sessionID, req.Mode, ask.prompt, h.config.WorkspaceDir, onChunk)
+		} else {
+			result, err = streaming.AskQuestionStream(ctx, sessionID, ask.prompt, h.config.WorkspaceDir, onChunk)
+		}
+
+		log := logger.FromContext(ctx).With().
+			Str("session_id", sessionID).
+			Str("trace_id", req.TraceID).
+			Logger()
+
+		if err == nil {
+			// Post-processing runs on the stream's context, which outlives the ask's
+			answer, translatedTo := h.finishAnswer(streamCtx, ask, result.Answer)
+			h.recordAnswer(ask, result.CursorChatID, answer, false)
+			log.Info().
+				Str("cursor_chat_id", result.CursorChatID).
+				Bool("truncated", result.Truncated).
+				Msg("Streamed question processed successfully")
+
+			event := AskStreamEvent{
+				Type:         AskStreamEventDone,
+				SessionID:    publicID,
+				Truncated:    result.Truncated,
+				TranslatedTo: translatedTo,
+				IdleWarning:  ask.idleWarning,
+			}
+			if answer != streamed.String() {
+				event.Answer = answer
+			}
+			emit(event)
+			return
+		}
+
+		answer := streamed.String()
+		log.Error().Err(err).Int("streamed_length", len(answer)).Msg("Failed to stream answer")
+		if answer != "" {
+			h.recordAnswer(ask, "", answer, false)
+		}
+		emit(AskStreamEvent{Type: AskStreamEventError, Error: "Failed to get response from cursor-agent"})
+	}()
+
+	streamSSE(c, events, newSSEOptions(h.config, cancel))
+
+	// Stop cursor-agent if the client went away, then wait for the exchange to be recorded
+	cancel()
+	<-done
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/session"
+)
+
+// streamingMockSessionManager adds streaming to the mock, emitting chunks and
+// then returning err (or the joined chunks when err is nil)
+type streamingMockSessionManager struct {
+	*MockSessionManager
+	chunks []string
+	err    error
+	// untilDeadline holds the ask open until ctx ends, then returns the chunks as truncated
+	untilDeadline bool
+}
+
+func (m *streamingMockSessionManager) AskQuestionStream(ctx context.Context, id, question, workspaceDir string, onChunk func(chunk string)) (*session.StreamResult, error) {
+	for _, chunk := range m.chunks {
+		onChunk(chunk)
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.untilDeadline {
+		<-ctx.Done()
+		return &session.StreamResult{Answer: strings.Join(m.chunks, ""), Truncated: true}, nil
+	}
+	return &session.StreamResult{Answer: strings.Join(m.chunks, ""), CursorChatID: "chat-stream"}, nil
+}
+
+// parseStreamEvents decodes the data lines of an SSE response body
+func parseStreamEvents(t *testing.T, body string) []AskStreamEvent {
+	t.Helper()
+	var events []AskStreamEvent
+	for _, line := range strings.Split(body, "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var event AskStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("failed to parse event %q: %v", data, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestAskStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	askWithContext := func(ctx context.Context, manager session.Manager, sessionID string) *httptest.ResponseRecorder {
+		handler := NewSessionHandler(manager, newTestConfig())
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body, _ := json.Marshal(AskRequest{Question: "What is this?"})
+		c.Request = httptest.NewRequest("POST", "/api/ask/stream?session_id="+sessionID, bytes.NewReader(body)).WithContext(ctx)
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.AskStream(c)
+		return w
+	}
+	ask := func(manager session.Manager, sessionID string) *httptest.ResponseRecorder {
+		return askWithContext(context.Background(), manager, sessionID)
+	}
+
+	t.Run("streams deltas then done and logs the answer", func(t *testing.T) {
+		manager := &streamingMockSessionManager{MockSessionManager: NewMockSessionManager(), chunks: []string{"Hello", ", world"}}
+		sess, _ := manager.CreateSession()
+
+		w := ask(manager, sess.ID)
+
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+			t.Errorf("expected an event stream, got %q", ct)
+		}
+		events := parseStreamEvents(t, w.Body.String())
+		if len(events) != 3 {
+			t.Fatalf("expected 3 events, got %+v", events)
+		}
+		if events[0].Type != AskStreamEventDelta || events[0].Content != "Hello" || events[1].Content != ", world" {
+			t.Errorf("unexpected deltas: %+v", events[:2])
+		}
+		if events[2].Type != AskStreamEventDone || events[2].SessionID != sess.ID {
+			t.Errorf("expected done for session %s, got %+v", sess.ID, events[2])
+		}
+
+		updated, _ := manager.GetSession(sess.ID)
+		if len(updated.ConversationLog) != 2 || updated.ConversationLog[1].Content != "Hello, world" {
+			t.Errorf("expected the answer in the conversation log, got %+v", updated.ConversationLog)
+		}
+		if updated.CursorChatID != "chat-stream" {
+			t.Errorf("expected cursor chat ID to be updated, got %q", updated.CursorChatID)
+		}
+	})
+
+	t.Run("interrupted stream logs the partial answer", func(t *testing.T) {
+		manager := &streamingMockSessionManager{MockSessionManager: NewMockSessionManager(), chunks: []string{"Partial"}, err: errors.New("cursor-agent exited")}
+		sess, _ := manager.CreateSession()
+
+		w := ask(manager, sess.ID)
+
+		events := parseStreamEvents(t, w.Body.String())
+		if len(events) != 2 || events[1].Type != AskStreamEventError {
+			t.Fatalf("expected a delta then an error, got %+v", events)
+		}
+
+		updated, _ := manager.GetSession(sess.ID)
+		if len(updated.ConversationLog) != 2 || updated.ConversationLog[1].Content != "Partial" {
+			t.Errorf("expected the partial answer in the conversation log, got %+v", updated.ConversationLog)
+		}
+	})
+
+	t.Run("request timeout ends with a truncated done event", func(t *testing.T) {
+		manager := &streamingMockSessionManager{MockSessionManager: NewMockSessionManager(), chunks: []string{"Partial"}, untilDeadline: true}
+		sess, _ := manager.CreateSession()
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+
+		w := askWithContext(ctx, manager, sess.ID)
+
+		events := parseStreamEvents(t, w.Body.String())
+		if len(events) != 2 {
+			t.Fatalf("expected a delta then done, got %+v", events)
+		}
+		if events[1].Type != AskStreamEventDone || !events[1].Truncated {
+			t.Errorf("expected a truncated done event, got %+v", events[1])
+		}
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		manager := &streamingMockSessionManager{MockSessionManager: NewMockSessionManager()}
+
+		if w := ask(manager, "missing"); w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("manager without streaming", func(t *testing.T) {
+		manager := NewMockSessionManager()
+		sess, _ := manager.CreateSession()
+
+		if w := ask(manager, sess.ID); w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+	})
+}
+
+func TestAskStream_SharesAskProcessing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ask := func(handler *SessionHandler, sessionID string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask/stream?session_id="+sessionID, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.AskStream(c)
+		return w
+	}
+
+	t.Run("checks out a branch-pinned session's branch", func(t *testing.T) {
+		logFile := filepath.Join(t.TempDir(), "git-invocations")
+		cfg := newTestConfig()
+		cfg.WorkspaceDir = t.TempDir()
+		cfg.GitPath = writeFakeScript(t, "git", fakeGitScript(logFile, "main", 0))
+		manager := &streamingMockSessionManager{MockSessionManager: NewMockSessionManager(), chunks: []string{"ok"}}
+		sess, _ := manager.CreateSessionWithOptions(session.SessionOptions{Branch: "feature/voice"})
+
+		ask(NewSessionHandler(manager, cfg), sess.ID, `{"question":"test"}`)
+
+		invocations, _ := os.ReadFile(logFile)
+		if !strings.Contains(string(invocations), "checkout feature/voice --") {
+			t.Errorf("expected checkout of feature/voice, got:\n%s", invocations)
+		}
+	})
+
+	t.Run("delivers the streamed answer to the webhook", func(t *testing.T) {
+		server, payloads, _ := newWebhookServer(t, 0)
+		manager := &streamingMockSessionManager{MockSessionManager: NewMockSessionManager(), chunks: []string{"Hello", " there"}}
+		sess, _ := manager.CreateSessionWithOptions(session.SessionOptions{AnswerWebhookURL: server.URL})
+
+		cfg := newTestConfig()
+		cfg.AnswerWebhookHosts = []string{"127.0.0.1"}
+
+		ask(NewSessionHandler(manager, cfg), sess.ID, `{"question":"Hi?"}`)
+
+		if payload := waitForPayload(t, payloads); payload.Answer != "Hello there" {
+			t.Errorf("expected the streamed answer in the webhook, got %+v", payload)
+		}
+	})
+
+	t.Run("client type filters come back on done", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.VoiceClientFilters = []string{config.AnswerFilterStripMarkdown}
+		manager := &streamingMockSessionManager{MockSessionManager: NewMockSessionManager(), chunks: []string{"**Bold**"}}
+		sess, _ := manager.CreateSession()
+
+		events := parseStreamEvents(t, ask(NewSessionHandler(manager, cfg), sess.ID, `{"question":"Hi?","client_type":"voice"}`).Body.String())
+
+		last := events[len(events)-1]
+		if last.Type != AskStreamEventDone || last.Answer != "Bold" {
+			t.Errorf("expected the filtered answer on done, got %+v", last)
+		}
+		updated, _ := manager.GetSession(sess.ID)
+		if updated.ConversationLog[1].Content != "Bold" {
+			t.Errorf("expected the filtered answer to be logged, got %q", updated.ConversationLog[1].Content)
+		}
+	})
+
+	t.Run("rejects attachments outside the workspace", func(t *testing.T) {
+		manager := &streamingMockSessionManager{MockSessionManager: NewMockSessionManager()}
+		sess, _ := manager.CreateSession()
+
+		w := ask(NewSessionHandler(manager, newTestConfig()), sess.ID, `{"question":"Hi?","files":["../../etc/passwd"]}`)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("rejects a mode the manager can't stream", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.CursorAgentModes = map[string][]string{"summarize": {"summarize"}}
+		manager := &streamingMockSessionManager{MockSessionManager: NewMockSessionManager()}
+		sess, _ := manager.CreateSession()
+
+		w := ask(NewSessionHandler(manager, cfg), sess.ID, `{"question":"Hi?","mode":"summarize"}`)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/middleware"
+	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/logger"
+)
+
+// VoiceAskHandler answers a question and replies with the answer as speech
+type VoiceAskHandler struct {
+	sessions *SessionHandler
+	tts      *TTSHandler
+}
+
+// NewVoiceAskHandler creates a voice-ask handler sharing the session and TTS handlers'
+// limits, so voice asks count against the same ask and synthesis slots
+func NewVoiceAskHandler(sessions *SessionHandler, tts *TTSHandler) *VoiceAskHandler {
+	return &VoiceAskHandler{sessions: sessions, tts: tts}
+}
+
+// Handle asks the question like /ask, then synthesizes the answer with the default
+// voice and streams it as WAV. If synthesis fails and AllowBrowserFallback is set,
+// the usual JSON ask response is sent instead with middleware.AudioHeader set to "none".
+func (h *VoiceAskHandler) Handle(c *gin.Context) {
+	answer, ok := h.sessions.processAsk(c)
+	if !ok {
+		return
+	}
+
+	voice, _ := h.tts.resolveVoice("")
+	audioPath, err := h.tts.synthesizeQueued(c.Request.Context(), answer.Answer, voice)
+	if errors.Is(err, errNothingToSpeak) {
+		// An emoji-only answer has nothing to synthesize, so send it as text
+		c.Header(middleware.AudioHeader, "none")
+		respondWithData(h.sessions.config, c, http.StatusOK, answer)
+		return
+	}
+	if err != nil {
+		logger.Get().Error().
+			Err(err).
+			Str("trace_id", answer.TraceID).
+			Bool("fallback", h.tts.config.AllowBrowserFallback).
+			Msg("Failed to synthesize voice-ask answer")
+
+		if h.tts.config.AllowBrowserFallback {
+			c.Header(middleware.AudioHeader, "none")
+			respondWithData(h.sessions.config, c, http.StatusOK, answer)
+			return
+		}
+		if errors.Is(err, errTTSBusy) {
+			response.RespondWithError(c, http.StatusServiceUnavailable, response.ErrServerBusy, "TTS is busy, try again later")
+			return
+		}
+		response.RespondWithError(c, http.StatusInternalServerError, response.ErrInternalServer, "Failed to generate speech for the answer")
+		return
+	}
+
+	c.Header(middleware.AudioHeader, "wav")
+	h.tts.sendAudio(c, audioPath)
+}
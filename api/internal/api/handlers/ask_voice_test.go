@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/middleware"
+	"github.com/sean/janus/internal/config"
+)
+
+func TestVoiceAsk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	voiceAsk := func(cfg *config.Config, synth func(ctx context.Context, text string, voice config.TTSPreset) (string, error)) *httptest.ResponseRecorder {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		tts := NewTTSHandler(cfg)
+		tts.synthesize = synth
+		handler := NewVoiceAskHandler(NewSessionHandler(mockManager, cfg), tts)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask/voice?session_id="+sess.ID, bytes.NewBufferString(`{"question":"hello"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Handle(c)
+		return w
+	}
+
+	failingSynth := func(ctx context.Context, text string, voice config.TTSPreset) (string, error) {
+		return "", errors.New("kokoro-tts failed: CUDA unavailable")
+	}
+
+	t.Run("streams the synthesized answer", func(t *testing.T) {
+		var spoken string
+		w := voiceAsk(newTestConfig(), func(ctx context.Context, text string, voice config.TTSPreset) (string, error) {
+			spoken = text
+			path := filepath.Join(t.TempDir(), "output.wav")
+			return path, os.WriteFile(path, []byte("RIFF"), 0644)
+		})
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "audio/wav" {
+			t.Errorf("expected audio/wav, got %q", ct)
+		}
+		if h := w.Header().Get(middleware.AudioHeader); h != "wav" {
+			t.Errorf("expected %s: wav, got %q", middleware.AudioHeader, h)
+		}
+		if spoken != "Mock cursor-agent response to: hello" {
+			t.Errorf("expected the answer to be synthesized, got %q", spoken)
+		}
+	})
+
+	t.Run("falls back to the text answer when TTS fails", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.AllowBrowserFallback = true
+		w := voiceAsk(cfg, failingSynth)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if h := w.Header().Get(middleware.AudioHeader); h != "none" {
+			t.Errorf("expected %s: none, got %q", middleware.AudioHeader, h)
+		}
+		var response AskResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if response.Answer != "Mock cursor-agent response to: hello" {
+			t.Errorf("expected the text answer, got %q", response.Answer)
+		}
+	})
+
+	t.Run("returns 500 when TTS fails without fallback", func(t *testing.T) {
+		if w := voiceAsk(newTestConfig(), failingSynth); w.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// MaxAskFiles bounds how many file references a single ask may attach
+const MaxAskFiles = 20
+
+// resolveAttachments validates client-supplied file references against the
+// workspace and returns them as cleaned workspace-relative paths. Paths may be
+// relative to the workspace or absolute, but must name an existing file inside
+// it; symlinks are resolved so they cannot be used to escape.
+func resolveAttachments(workspaceDir string, files []string) ([]string, error) {
+	if len(files) > MaxAskFiles {
+		return nil, fmt.Errorf("at most %d files may be attached", MaxAskFiles)
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	root, err := filepath.Abs(workspaceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	resolved := make([]string, 0, len(files))
+	for _, file := range files {
+		if strings.TrimSpace(file) == "" {
+			return nil, fmt.Errorf("file path cannot be empty")
+		}
+
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return nil, fmt.Errorf("file not found in workspace: %s", file)
+		}
+
+		rel, err := filepath.Rel(root, real)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("file is outside the workspace: %s", file)
+		}
+
+		resolved = append(resolved, filepath.ToSlash(rel))
+	}
+
+	return resolved, nil
+}
+
+// withFileReferences appends cursor-agent @-references for the attached files to the question
+func withFileReferences(question string, files []string) string {
+	if len(files) == 0 {
+		return question
+	}
+
+	var b strings.Builder
+	b.WriteString(question)
+	b.WriteString("\n\nReferenced files:")
+	for _, file := range files {
+		b.WriteString("\n@")
+		b.WriteString(file)
+	}
+	return b.String()
+}
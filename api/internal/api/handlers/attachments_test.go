@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResolveAttachments(t *testing.T) {
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, "internal", "api"), 0755)
+	os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main"), 0644)
+	os.WriteFile(filepath.Join(workspace, "internal", "api", "router.go"), []byte("package api"), 0644)
+
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(outside, []byte("secret"), 0644)
+	os.Symlink(outside, filepath.Join(workspace, "escape.txt"))
+
+	t.Run("accepts relative and absolute paths inside the workspace", func(t *testing.T) {
+		files, err := resolveAttachments(workspace, []string{
+			"main.go",
+			"./internal/api/../api/router.go",
+			filepath.Join(workspace, "main.go"),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{"main.go", "internal/api/router.go", "main.go"}
+		if strings.Join(files, ",") != strings.Join(expected, ",") {
+			t.Errorf("expected %v, got %v", expected, files)
+		}
+	})
+
+	rejected := map[string]string{
+		"parent traversal":      "../secret.txt",
+		"nested traversal":      "internal/../../secret.txt",
+		"absolute outside path": outside,
+		"symlink escape":        "escape.txt",
+		"missing file":          "nope.go",
+		"empty path":            " ",
+	}
+	for name, file := range rejected {
+		t.Run("rejects "+name, func(t *testing.T) {
+			if _, err := resolveAttachments(workspace, []string{file}); err == nil {
+				t.Errorf("expected %q to be rejected", file)
+			}
+		})
+	}
+
+	t.Run("rejects too many files", func(t *testing.T) {
+		files := make([]string, MaxAskFiles+1)
+		for i := range files {
+			files[i] = "main.go"
+		}
+		if _, err := resolveAttachments(workspace, files); err == nil {
+			t.Error("expected error for too many files")
+		}
+	})
+}
+
+func TestAsk_Files(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main"), 0644)
+
+	ask := func(t *testing.T, body string) (*httptest.ResponseRecorder, string) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		var sentQuestion string
+		mockManager.askQuestionFunc = func(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
+			sentQuestion = question
+			return "answer", "chat-1", nil
+		}
+		cfg := newTestConfig()
+		cfg.WorkspaceDir = workspace
+		handler := NewSessionHandler(mockManager, cfg)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/ask?session_id=%s", sess.ID), bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+
+		return w, sentQuestion
+	}
+
+	t.Run("passes valid file references to cursor-agent", func(t *testing.T) {
+		w, question := ask(t, `{"question":"What does this do?","files":["main.go"]}`)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if !strings.HasPrefix(question, "What does this do?") || !strings.Contains(question, "\n@main.go") {
+			t.Errorf("expected question with @main.go reference, got %q", question)
+		}
+	})
+
+	t.Run("rejects traversal outside the workspace", func(t *testing.T) {
+		w, question := ask(t, `{"question":"What does this do?","files":["../../etc/passwd"]}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+		if question != "" {
+			t.Error("expected cursor-agent not to be called")
+		}
+	})
+
+	t.Run("sends the question unchanged without files", func(t *testing.T) {
+		_, question := ask(t, `{"question":"What does this do?"}`)
+
+		if question != "What does this do?" {
+			t.Errorf("expected unchanged question, got %q", question)
+		}
+	})
+}
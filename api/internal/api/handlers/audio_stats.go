@@ -0,0 +1,23 @@
+package handlers
+
+import "sync/atomic"
+
+// audioBytes tracks cumulative audio volume across handlers for capacity planning
+var audioBytes struct {
+	transcribed atomic.Int64 // Uploaded audio bytes successfully transcribed
+	synthesized atomic.Int64 // WAV bytes returned by TTS
+}
+
+// AudioStats reports cumulative audio bytes processed since startup
+type AudioStats struct {
+	TranscribedBytes int64 `json:"transcribed_bytes"`
+	SynthesizedBytes int64 `json:"synthesized_bytes"`
+}
+
+// audioStats returns a snapshot of the audio byte counters
+func audioStats() AudioStats {
+	return AudioStats{
+		TranscribedBytes: audioBytes.transcribed.Load(),
+		SynthesizedBytes: audioBytes.synthesized.Load(),
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/logger"
+)
+
+// auditAction records an admin action as a structured audit log entry with the
+// caller's IP, API key identifier (set by auth middleware as "api_key_id"; never
+// the key itself), the action performed, and the sessions it affected.
+// Disabled when AuditLog is false.
+func auditAction(cfg *config.Config, c *gin.Context, action string, sessionIDs []string) {
+	if !cfg.AuditLog {
+		return
+	}
+
+	if sessionIDs == nil {
+		sessionIDs = []string{}
+	}
+
+	logger.Get().Info().
+		Bool("audit", true).
+		Str("action", action).
+		Str("client_ip", c.ClientIP()).
+		Str("api_key_id", c.GetString("api_key_id")).
+		Str("request_id", c.GetString("request_id")).
+		Strs("session_ids", sessionIDs).
+		Msg("Admin action")
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuditAction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newAdminContext := func() *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/api/admin/sessions/clear", nil)
+		c.Request.RemoteAddr = "203.0.113.7:51234"
+		c.Set("api_key_id", "dashboard")
+		c.Set("request_id", "req-1")
+		return c
+	}
+
+	t.Run("logs caller, action, and affected sessions", func(t *testing.T) {
+		logs := captureLogs(t)
+		cfg := newTestConfig()
+		cfg.AuditLog = true
+
+		auditAction(cfg, newAdminContext(), "clear_sessions", []string{"session-a", "session-b"})
+
+		entry := findLogEntry(t, logs, "Admin action")
+		if entry["audit"] != true {
+			t.Errorf("expected audit=true, got %v", entry["audit"])
+		}
+		if entry["action"] != "clear_sessions" {
+			t.Errorf("expected action clear_sessions, got %v", entry["action"])
+		}
+		if entry["client_ip"] != "203.0.113.7" {
+			t.Errorf("expected client_ip 203.0.113.7, got %v", entry["client_ip"])
+		}
+		if entry["api_key_id"] != "dashboard" {
+			t.Errorf("expected api_key_id dashboard, got %v", entry["api_key_id"])
+		}
+		if entry["request_id"] != "req-1" {
+			t.Errorf("expected request_id req-1, got %v", entry["request_id"])
+		}
+		ids, _ := entry["session_ids"].([]interface{})
+		if len(ids) != 2 || ids[0] != "session-a" || ids[1] != "session-b" {
+			t.Errorf("expected affected session IDs, got %v", entry["session_ids"])
+		}
+	})
+
+	t.Run("logs an empty session list for server-wide actions", func(t *testing.T) {
+		logs := captureLogs(t)
+		cfg := newTestConfig()
+		cfg.AuditLog = true
+
+		auditAction(cfg, newAdminContext(), "reload_config", nil)
+
+		entry := findLogEntry(t, logs, "Admin action")
+		if ids, ok := entry["session_ids"].([]interface{}); !ok || len(ids) != 0 {
+			t.Errorf("expected empty session_ids, got %v", entry["session_ids"])
+		}
+	})
+
+	t.Run("skips logging when disabled", func(t *testing.T) {
+		logs := captureLogs(t)
+		cfg := newTestConfig()
+		cfg.AuditLog = false
+
+		auditAction(cfg, newAdminContext(), "clear_sessions", []string{"session-a"})
+
+		if strings.Contains(logs.String(), "Admin action") {
+			t.Errorf("expected no audit entry, got %s", logs.String())
+		}
+	})
+}
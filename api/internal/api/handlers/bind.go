@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/sean/janus/internal/config"
+)
+
+// unknownFieldPrefix starts the error encoding/json returns for undeclared fields
+const unknownFieldPrefix = "json: unknown field "
+
+// bindJSON decodes the JSON request body into obj like ShouldBindJSON. With
+// StrictJSON enabled, fields obj doesn't declare are rejected instead of ignored.
+func bindJSON(cfg *config.Config, c *gin.Context, obj interface{}) error {
+	if !cfg.StrictJSON {
+		return c.ShouldBindJSON(obj)
+	}
+	if c.Request == nil || c.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// bindErrorDetails describes a bindJSON failure for the client, naming the
+// offending field when the body had one the request doesn't accept
+func bindErrorDetails(err error, fallback string) string {
+	if field, ok := strings.CutPrefix(err.Error(), unknownFieldPrefix); ok {
+		return "Invalid request body: unknown field " + field
+	}
+	return fallback
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+)
+
+func TestAsk_StrictJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ask := func(strict bool, body string) *httptest.ResponseRecorder {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		cfg := newTestConfig()
+		cfg.StrictJSON = strict
+		handler := NewSessionHandler(mockManager, cfg)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask?session_id="+sess.ID, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+		return w
+	}
+
+	details := func(t *testing.T, w *httptest.ResponseRecorder) string {
+		t.Helper()
+		var errResp response.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return errResp.Details
+	}
+
+	t.Run("strict mode names an unknown field", func(t *testing.T) {
+		w := ask(true, `{"question":"hi","trace":"abc"}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", w.Code)
+		}
+		if d := details(t, w); !strings.Contains(d, `"trace"`) {
+			t.Errorf("expected details to name the unknown field, got %q", d)
+		}
+	})
+
+	t.Run("strict mode names a misspelled required field", func(t *testing.T) {
+		w := ask(true, `{"quesion":"hi"}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", w.Code)
+		}
+		if d := details(t, w); !strings.Contains(d, `"quesion"`) {
+			t.Errorf("expected details to name the misspelled field, got %q", d)
+		}
+	})
+
+	t.Run("strict mode accepts declared fields", func(t *testing.T) {
+		if w := ask(true, `{"question":"hi","trace_id":"abc"}`); w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+	})
+
+	t.Run("lenient mode ignores unknown fields", func(t *testing.T) {
+		if w := ask(false, `{"question":"hi","trace":"abc"}`); w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+	})
+
+	t.Run("lenient mode reports a misspelled field as missing", func(t *testing.T) {
+		w := ask(false, `{"quesion":"hi"}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", w.Code)
+		}
+		if d := details(t, w); strings.Contains(d, "quesion") || !strings.Contains(d, "question") {
+			t.Errorf("expected the missing question message, got %q", d)
+		}
+	})
+}
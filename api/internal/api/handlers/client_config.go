@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/middleware"
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/session"
+)
+
+// ClientConfigHandler serves server-side defaults clients use to configure themselves
+type ClientConfigHandler struct {
+	config *config.Config
+}
+
+// NewClientConfigHandler creates a new client config handler
+func NewClientConfigHandler(cfg *config.Config) *ClientConfigHandler {
+	return &ClientConfigHandler{
+		config: cfg,
+	}
+}
+
+// ClientConfigResponse represents the recommended client settings. Zero limits mean unlimited.
+type ClientConfigResponse struct {
+	HeartbeatIntervalSeconds int      `json:"heartbeat_interval_seconds"`
+	RequestTimeoutSeconds    int      `json:"request_timeout_seconds"`
+	MaxRequestTimeoutSeconds int      `json:"max_request_timeout_seconds"`
+	MaxUploadBytes           int64    `json:"max_upload_bytes"`
+	AudioFormats             []string `json:"audio_formats"`
+}
+
+// Handle processes client config requests
+func (h *ClientConfigHandler) Handle(c *gin.Context) {
+	response := ClientConfigResponse{
+		HeartbeatIntervalSeconds: int(session.HeartbeatInterval.Seconds()),
+		RequestTimeoutSeconds:    int(middleware.DefaultRequestTimeout.Seconds()),
+		MaxRequestTimeoutSeconds: h.config.MaxRequestTimeoutSeconds,
+		MaxUploadBytes:           h.config.MaxUploadBytes,
+		AudioFormats:             supportedAudioFormats,
+	}
+
+	respondWithData(h.config, c, http.StatusOK, response)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/config"
+)
+
+func TestClientConfigHandler_Handle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewClientConfigHandler(&config.Config{
+		MaxRequestTimeoutSeconds: 300,
+		MaxUploadBytes:           25 << 20,
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/client-config", nil)
+
+	handler.Handle(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	expected := map[string]float64{
+		"heartbeat_interval_seconds":  30,
+		"request_timeout_seconds":     60,
+		"max_request_timeout_seconds": 300,
+		"max_upload_bytes":            25 << 20,
+	}
+	for field, want := range expected {
+		if got, ok := response[field].(float64); !ok || got != want {
+			t.Errorf("expected %s = %v, got %v", field, want, response[field])
+		}
+	}
+
+	formats, ok := response["audio_formats"].([]interface{})
+	if !ok || len(formats) == 0 {
+		t.Fatalf("expected audio_formats list, got %v", response["audio_formats"])
+	}
+	if formats[0] != "webm" {
+		t.Errorf("expected webm (browser recordings) first, got %v", formats[0])
+	}
+}
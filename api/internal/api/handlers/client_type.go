@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sean/janus/internal/config"
+)
+
+const (
+	// ClientTypeVoice marks an ask whose answer will be spoken aloud
+	ClientTypeVoice = "voice"
+	// ClientTypeText marks an ask whose answer will be rendered as text
+	ClientTypeText = "text"
+)
+
+// clientProfile is the prompt preamble and answer filters for a client type
+type clientProfile struct {
+	preamble string
+	filters  []string
+}
+
+// clientProfile returns the configured profile for clientType. An empty client
+// type has an empty profile, leaving the ask unchanged.
+func (h *SessionHandler) clientProfile(clientType string) (clientProfile, bool) {
+	switch clientType {
+	case "":
+		return clientProfile{}, true
+	case ClientTypeVoice:
+		return clientProfile{preamble: h.config.VoiceClientPreamble, filters: h.config.VoiceClientFilters}, true
+	case ClientTypeText:
+		return clientProfile{preamble: h.config.TextClientPreamble, filters: h.config.TextClientFilters}, true
+	}
+	return clientProfile{}, false
+}
+
+// withPreamble appends the profile's answer-format instructions to the prompt
+func (p clientProfile) withPreamble(prompt string) string {
+	if p.preamble == "" {
+		return prompt
+	}
+	return prompt + "\n\n" + p.preamble
+}
+
+// filterAnswer runs the profile's answer filters in order
+func (p clientProfile) filterAnswer(answer string) string {
+	for _, name := range p.filters {
+		if filter, ok := answerFilters[name]; ok {
+			answer = filter(answer)
+		}
+	}
+	return answer
+}
+
+// answerFilters maps config.AnswerFilter* names to their implementations
+var answerFilters = map[string]func(string) string{
+	config.AnswerFilterStripMarkdown:      stripMarkdown,
+	config.AnswerFilterCollapseWhitespace: collapseWhitespace,
+}
+
+var (
+	markdownCodeFence  = regexp.MustCompile("(?m)^[ \\t]*```.*$\\n?")
+	markdownHeading    = regexp.MustCompile(`(?m)^[ \t]{0,3}#{1,6}[ \t]+`)
+	markdownListMarker = regexp.MustCompile(`(?m)^([ \t]*)(?:[-*+]|\d+\.)[ \t]+`)
+	markdownLink       = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	markdownEmphasis   = regexp.MustCompile(`(\*\*|__|\*|_|~~)(\S(?:.*?\S)?)(\*\*|__|\*|_|~~)`)
+)
+
+// stripMarkdown removes markdown syntax so an answer reads naturally as plain text
+func stripMarkdown(answer string) string {
+	answer = markdownCodeFence.ReplaceAllString(answer, "")
+	answer = markdownHeading.ReplaceAllString(answer, "")
+	answer = markdownListMarker.ReplaceAllString(answer, "$1")
+	answer = markdownLink.ReplaceAllString(answer, "$1")
+	answer = markdownEmphasis.ReplaceAllString(answer, "$2")
+	answer = strings.ReplaceAll(answer, "`", "")
+	return strings.TrimSpace(answer)
+}
+
+// collapseWhitespace joins lines and runs of whitespace into single spaces
+func collapseWhitespace(answer string) string {
+	return strings.Join(strings.Fields(answer), " ")
+}
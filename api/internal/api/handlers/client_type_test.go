@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/config"
+)
+
+func TestStripMarkdown(t *testing.T) {
+	answer := "## Setup\n\nRun **go build** in `api/`:\n\n```sh\ngo build ./...\n```\n\n- See [the docs](https://example.com)\n1. Then _test_"
+
+	got := stripMarkdown(answer)
+
+	want := "Setup\n\nRun go build in api/:\n\ngo build ./...\n\nSee the docs\nThen test"
+	if got != want {
+		t.Errorf("stripMarkdown() = %q, want %q", got, want)
+	}
+}
+
+func TestCollapseWhitespace(t *testing.T) {
+	if got := collapseWhitespace("one\n\ntwo   three\n"); got != "one two three" {
+		t.Errorf("collapseWhitespace() = %q", got)
+	}
+}
+
+func TestAsk_ClientType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const answer = "## Answer\n\nUse **NewRouter**."
+
+	newHandler := func() (*SessionHandler, string, *string) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		var prompt string
+		mockManager.askQuestionFunc = func(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
+			prompt = question
+			return answer, "chat-1", nil
+		}
+		cfg := newTestConfig()
+		cfg.VoiceClientPreamble = "Be brief."
+		cfg.VoiceClientFilters = []string{config.AnswerFilterStripMarkdown, config.AnswerFilterCollapseWhitespace}
+		cfg.TextClientPreamble = "Use markdown."
+		return NewSessionHandler(mockManager, cfg), sess.ID, &prompt
+	}
+
+	ask := func(handler *SessionHandler, sessionID string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask?session_id="+sessionID, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+		return w
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantPrompt string
+		wantAnswer string
+	}{
+		{
+			name:       "voice appends the voice preamble and strips markdown",
+			body:       `{"question":"Where is routing?","client_type":"voice"}`,
+			wantPrompt: "Where is routing?\n\nBe brief.",
+			wantAnswer: "Answer Use NewRouter.",
+		},
+		{
+			name:       "text appends the text preamble and keeps markdown",
+			body:       `{"question":"Where is routing?","client_type":"text"}`,
+			wantPrompt: "Where is routing?\n\nUse markdown.",
+			wantAnswer: answer,
+		},
+		{
+			name:       "no client type leaves the ask unchanged",
+			body:       `{"question":"Where is routing?"}`,
+			wantPrompt: "Where is routing?",
+			wantAnswer: answer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, sessionID, prompt := newHandler()
+
+			w := ask(handler, sessionID, tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			var response AskResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if *prompt != tt.wantPrompt {
+				t.Errorf("prompt = %q, want %q", *prompt, tt.wantPrompt)
+			}
+			if response.Answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", response.Answer, tt.wantAnswer)
+			}
+		})
+	}
+
+	t.Run("unknown client type returns 400", func(t *testing.T) {
+		handler, sessionID, prompt := newHandler()
+
+		w := ask(handler, sessionID, `{"question":"Where is routing?","client_type":"watch"}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "client_type") || *prompt != "" {
+			t.Errorf("expected a client_type error and no ask, got %s", w.Body.String())
+		}
+	})
+}
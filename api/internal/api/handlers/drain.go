@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// DrainMode is a runtime toggle that makes the readiness check fail so a load
+// balancer stops routing new traffic, while every other route keeps serving
+// existing sessions until they finish
+type DrainMode struct {
+	mu       sync.RWMutex
+	draining bool
+	since    time.Time
+}
+
+// NewDrainMode creates a drain toggle, initially off
+func NewDrainMode() *DrainMode {
+	return &DrainMode{}
+}
+
+// Enable starts draining, keeping the original start time if already draining
+func (d *DrainMode) Enable() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.draining {
+		d.draining = true
+		d.since = time.Now()
+	}
+}
+
+// Disable stops draining
+func (d *DrainMode) Disable() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.draining = false
+	d.since = time.Time{}
+}
+
+// Status reports whether the server is draining and since when
+func (d *DrainMode) Status() (draining bool, since time.Time) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.draining, d.since
+}
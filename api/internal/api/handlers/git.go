@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sean/janus/internal/logger"
+)
+
+// branchPattern restricts session branches to ordinary git branch names. The
+// leading character must be alphanumeric so the value can never be parsed as a flag.
+var branchPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]{0,199}$`)
+
+// isValidBranch reports whether a client-supplied branch name is safe to pass to git
+func isValidBranch(branch string) bool {
+	if !branchPattern.MatchString(branch) {
+		return false
+	}
+	// Mirror the git check-ref-format rules the pattern can't express
+	return !strings.Contains(branch, "..") &&
+		!strings.Contains(branch, "//") &&
+		!strings.HasSuffix(branch, "/") &&
+		!strings.HasSuffix(branch, ".") &&
+		!strings.HasSuffix(branch, ".lock")
+}
+
+// ensureBranch switches the workspace to branch unless it is already checked out
+func ensureBranch(ctx context.Context, gitPath string, workspaceDir string, branch string) error {
+	current, err := exec.CommandContext(ctx, gitPath, "-C", workspaceDir, "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err == nil && strings.TrimSpace(string(current)) == branch {
+		return nil
+	}
+
+	// The trailing "--" makes git treat the branch strictly as a revision, not a path
+	output, err := exec.CommandContext(ctx, gitPath, "-C", workspaceDir, "checkout", branch, "--").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git checkout %s failed: %w, output: %s", branch, err, strings.TrimSpace(string(output)))
+	}
+
+	logger.FromContext(ctx).Info().
+		Str("branch", branch).
+		Str("workspace_dir", workspaceDir).
+		Msg("Switched workspace to session branch")
+	return nil
+}
+
+// workspaceGate keeps the shared workspace on one branch while asks run in it.
+// Asks on the checked-out branch, and asks of sessions without a branch, run
+// together; an ask needing another branch waits until the workspace is idle
+// before checking it out, so a checkout never happens under a running ask.
+type workspaceGate struct {
+	mu      sync.Mutex
+	branch  string
+	holders int
+	// idle is closed when the last holder releases the workspace
+	idle chan struct{}
+}
+
+// acquire waits until the workspace can be used on branch, running checkout
+// when the branch has to change. An empty branch accepts whatever is checked
+// out. Waiting stops with the context's error if ctx ends first. The returned
+// function releases the workspace.
+func (g *workspaceGate) acquire(ctx context.Context, branch string, checkout func() error) (func(), error) {
+	for {
+		g.mu.Lock()
+		if branch == "" || (g.holders > 0 && g.branch == branch) {
+			g.holders++
+			g.mu.Unlock()
+			return g.release, nil
+		}
+		if g.holders == 0 {
+			// Others wait on mu while the checkout runs, which is what they would wait for anyway
+			err := checkout()
+			if err == nil {
+				g.branch = branch
+				g.holders++
+			}
+			g.mu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			return g.release, nil
+		}
+		if g.idle == nil {
+			g.idle = make(chan struct{})
+		}
+		idle := g.idle
+		g.mu.Unlock()
+
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release drops one holder, waking waiting asks once the workspace is idle
+func (g *workspaceGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.holders--
+	if g.holders == 0 && g.idle != nil {
+		close(g.idle)
+		g.idle = nil
+	}
+}
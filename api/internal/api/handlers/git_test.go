@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/session"
+)
+
+// fakeGitScript records its arguments, reports currentBranch for rev-parse, and exits checkoutExit on checkout
+func fakeGitScript(logFile string, currentBranch string, checkoutExit int) string {
+	return fmt.Sprintf(`echo "$@" >> %s
+case "$*" in
+  *rev-parse*) echo %s ;;
+  *checkout*) exit %d ;;
+esac
+`, logFile, currentBranch, checkoutExit)
+}
+
+func TestIsValidBranch(t *testing.T) {
+	valid := []string{"main", "feature/voice-ask", "release-1.2", "user/sean/fix_123"}
+	for _, branch := range valid {
+		if !isValidBranch(branch) {
+			t.Errorf("expected %q to be valid", branch)
+		}
+	}
+
+	invalid := []string{"", "-b", "--orphan", "../main", "feature..x", "feature//x", "trailing/", "main.lock", "has space", "semi;colon", "$(reboot)"}
+	for _, branch := range invalid {
+		if isValidBranch(branch) {
+			t.Errorf("expected %q to be rejected", branch)
+		}
+	}
+}
+
+func TestSessionBranch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	setup := func(t *testing.T, currentBranch string, checkoutExit int) (*SessionHandler, *MockSessionManager, string) {
+		logFile := filepath.Join(t.TempDir(), "git-invocations")
+		cfg := newTestConfig()
+		cfg.WorkspaceDir = t.TempDir()
+		cfg.GitPath = writeFakeScript(t, "git", fakeGitScript(logFile, currentBranch, checkoutExit))
+		mockManager := NewMockSessionManager()
+		return NewSessionHandler(mockManager, cfg), mockManager, logFile
+	}
+
+	ask := func(handler *SessionHandler, sessionID string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/ask?session_id=%s", sessionID), bytes.NewBufferString(`{"question":"test"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+		return w.Code
+	}
+
+	t.Run("start rejects an invalid branch", func(t *testing.T) {
+		handler, _, _ := setup(t, "main", 0)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/session/start", bytes.NewBufferString(`{"branch":"--orphan"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Start(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("start stores the branch", func(t *testing.T) {
+		handler, mockManager, _ := setup(t, "main", 0)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/session/start", bytes.NewBufferString(`{"branch":"feature/voice"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Start(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		for _, sess := range mockManager.sessions {
+			if sess.Branch != "feature/voice" {
+				t.Errorf("expected stored branch feature/voice, got %q", sess.Branch)
+			}
+		}
+	})
+
+	t.Run("ask checks out the session branch", func(t *testing.T) {
+		handler, mockManager, logFile := setup(t, "main", 0)
+		sess, _ := mockManager.CreateSessionWithOptions(session.SessionOptions{Branch: "feature/voice"})
+
+		if code := ask(handler, sess.ID); code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", code)
+		}
+
+		invocations, _ := os.ReadFile(logFile)
+		if !strings.Contains(string(invocations), "checkout feature/voice --") {
+			t.Errorf("expected checkout of feature/voice, got:\n%s", invocations)
+		}
+	})
+
+	t.Run("ask skips checkout when already on the branch", func(t *testing.T) {
+		handler, mockManager, logFile := setup(t, "feature/voice", 0)
+		sess, _ := mockManager.CreateSessionWithOptions(session.SessionOptions{Branch: "feature/voice"})
+
+		ask(handler, sess.ID)
+
+		invocations, _ := os.ReadFile(logFile)
+		if strings.Contains(string(invocations), " checkout ") {
+			t.Errorf("expected no checkout, got:\n%s", invocations)
+		}
+	})
+
+	t.Run("ask does not run git for sessions without a branch", func(t *testing.T) {
+		handler, mockManager, logFile := setup(t, "main", 0)
+		sess, _ := mockManager.CreateSession()
+
+		ask(handler, sess.ID)
+
+		if _, err := os.Stat(logFile); !os.IsNotExist(err) {
+			t.Error("expected git not to be invoked")
+		}
+	})
+
+	t.Run("ask fails when checkout fails", func(t *testing.T) {
+		handler, mockManager, _ := setup(t, "main", 1)
+		sess, _ := mockManager.CreateSessionWithOptions(session.SessionOptions{Branch: "feature/voice"})
+
+		if code := ask(handler, sess.ID); code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", code)
+		}
+	})
+}
+
+func TestWorkspaceGate(t *testing.T) {
+	var checkouts []string
+	checkout := func(branch string) func() error {
+		return func() error {
+			checkouts = append(checkouts, branch)
+			return nil
+		}
+	}
+
+	t.Run("asks on the same branch share the workspace", func(t *testing.T) {
+		checkouts = nil
+		gate := &workspaceGate{}
+
+		first, err := gate.acquire(context.Background(), "feature/a", checkout("feature/a"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		second, err := gate.acquire(context.Background(), "feature/a", checkout("feature/a"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		unbranched, err := gate.acquire(context.Background(), "", checkout(""))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		first()
+		second()
+		unbranched()
+
+		if len(checkouts) != 1 {
+			t.Errorf("expected one checkout, got %v", checkouts)
+		}
+	})
+
+	t.Run("another branch waits for running asks", func(t *testing.T) {
+		checkouts = nil
+		gate := &workspaceGate{}
+
+		release, _ := gate.acquire(context.Background(), "feature/a", checkout("feature/a"))
+
+		acquired := make(chan func())
+		go func() {
+			next, _ := gate.acquire(context.Background(), "feature/b", checkout("feature/b"))
+			acquired <- next
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("expected feature/b to wait while feature/a is in use")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		release()
+		select {
+		case next := <-acquired:
+			next()
+		case <-time.After(time.Second):
+			t.Fatal("expected feature/b to be checked out once the workspace was idle")
+		}
+		if len(checkouts) != 2 || checkouts[1] != "feature/b" {
+			t.Errorf("expected feature/a then feature/b to be checked out, got %v", checkouts)
+		}
+	})
+
+	t.Run("waiting stops when the context ends", func(t *testing.T) {
+		gate := &workspaceGate{}
+		release, _ := gate.acquire(context.Background(), "feature/a", checkout("feature/a"))
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := gate.acquire(ctx, "feature/b", checkout("feature/b")); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("failed checkout leaves the workspace free", func(t *testing.T) {
+		gate := &workspaceGate{}
+		failure := errors.New("checkout failed")
+
+		if _, err := gate.acquire(context.Background(), "feature/a", func() error { return failure }); !errors.Is(err, failure) {
+			t.Fatalf("expected the checkout error, got %v", err)
+		}
+		release, err := gate.acquire(context.Background(), "feature/b", checkout("feature/b"))
+		if err != nil {
+			t.Fatalf("expected the workspace to be free, got %v", err)
+		}
+		release()
+	})
+}
@@ -3,9 +3,11 @@ package handlers
 import (
 	"net/http"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/logger"
 	"github.com/sean/janus/internal/session"
 )
 
@@ -14,15 +16,66 @@ var startTime = time.Now()
 // HealthHandler handles health check requests
 type HealthHandler struct {
 	sessionManager session.Manager
+
+	// Memory stats are cached for memStatsTTL since ReadMemStats stops the world
+	memStatsTTL  time.Duration
+	readMemStats func(*runtime.MemStats)
+	memMu        sync.Mutex
+	memoryMB     float64
+	memReadAt    time.Time
+
+	// lastActivity remembers the latest session activity seen so idle time
+	// survives the sessions themselves being cleaned up
+	activityMu   sync.Mutex
+	lastActivity time.Time
+
+	// cleanup, when set, is checked by Full to report a stalled cleanup loop
+	cleanup CleanupMonitor
+
+	// drain, when set, makes Ready report not-ready while draining
+	drain *DrainMode
+
+	// locator, when set, re-resolves cursor-agent at most once per rediscoverInterval
+	locator            session.CursorAgentLocator
+	rediscoverInterval time.Duration
+	rediscoverMu       sync.Mutex
+	rediscoveredAt     time.Time
+	cursorAgentPath    string
+}
+
+// CleanupMonitor reports on the session cleanup loop's progress
+type CleanupMonitor interface {
+	LastRun() time.Time
+	Stalled() bool
 }
 
-// NewHealthHandler creates a new health handler
-func NewHealthHandler(sessionManager session.Manager) *HealthHandler {
+// NewHealthHandler creates a new health handler. Memory usage is re-read at
+// most once per memStatsTTL; a TTL of 0 reads it on every request.
+func NewHealthHandler(sessionManager session.Manager, memStatsTTL time.Duration) *HealthHandler {
 	return &HealthHandler{
 		sessionManager: sessionManager,
+		memStatsTTL:    memStatsTTL,
+		readMemStats:   runtime.ReadMemStats,
 	}
 }
 
+// SetCleanupMonitor makes the full health check report on the session cleanup loop
+func (h *HealthHandler) SetCleanupMonitor(monitor CleanupMonitor) {
+	h.cleanup = monitor
+}
+
+// SetDrainMode makes the readiness check fail while drain is enabled
+func (h *HealthHandler) SetDrainMode(drain *DrainMode) {
+	h.drain = drain
+}
+
+// SetCursorAgentLocator makes health checks re-resolve the cursor-agent
+// executable at most once per interval, so asks follow a reinstall
+func (h *HealthHandler) SetCursorAgentLocator(locator session.CursorAgentLocator, interval time.Duration) {
+	h.locator = locator
+	h.rediscoverInterval = interval
+}
+
 // HealthResponse represents the health check response
 type HealthResponse struct {
 	Status         string  `json:"status"`
@@ -32,25 +85,151 @@ type HealthResponse struct {
 	MemoryUsageMB  float64 `json:"memory_usage_mb"`
 }
 
+// FullHealthResponse extends the health response with details for autoscalers
+type FullHealthResponse struct {
+	HealthResponse
+	IdleSeconds int64 `json:"idle_seconds"`
+	// CleanupLastRun is when session cleanup last completed (omitted before the first run)
+	CleanupLastRun *time.Time `json:"cleanup_last_run,omitempty"`
+	// CleanupStalled is set when cleanup hasn't completed within twice its interval
+	CleanupStalled bool `json:"cleanup_stalled"`
+	// CursorAgentPath is where cursor-agent was last found (omitted without rediscovery)
+	CursorAgentPath string `json:"cursor_agent_path,omitempty"`
+}
+
 // Handle processes health check requests
 func (h *HealthHandler) Handle(c *gin.Context) {
-	uptime := time.Since(startTime).Seconds()
+	h.rediscoverCursorAgent()
+	c.JSON(http.StatusOK, h.health(h.sessionManager.GetAllSessionsShallow()))
+}
 
-	// Get active session count
-	activeSessions := len(h.sessionManager.GetAllSessions())
+// Full processes detailed health check requests, including how long the
+// server has been idle so operators can scale to zero and whether session
+// cleanup has stalled
+func (h *HealthHandler) Full(c *gin.Context) {
+	sessions := h.sessionManager.GetAllSessionsShallow()
 
-	// Get memory usage statistics
-	var memStats runtime.MemStats
-	runtime.ReadMemStats(&memStats)
-	memoryMB := float64(memStats.Alloc) / 1024 / 1024
+	resp := FullHealthResponse{
+		HealthResponse:  h.health(sessions),
+		IdleSeconds:     h.idleSeconds(sessions),
+		CursorAgentPath: h.rediscoverCursorAgent(),
+	}
+	if h.cleanup != nil {
+		if lastRun := h.cleanup.LastRun(); !lastRun.IsZero() {
+			resp.CleanupLastRun = &lastRun
+		}
+		if h.cleanup.Stalled() {
+			resp.CleanupStalled = true
+			resp.Status = "degraded"
+		}
+	}
 
-	response := HealthResponse{
+	c.JSON(http.StatusOK, resp)
+}
+
+// ReadyResponse reports whether the server should receive new traffic
+type ReadyResponse struct {
+	Ready    bool `json:"ready"`
+	Draining bool `json:"draining"`
+	// DrainingSince is when draining began (omitted when not draining)
+	DrainingSince *time.Time `json:"draining_since,omitempty"`
+	// ActiveSessions lets operators watch a draining server empty out
+	ActiveSessions int `json:"active_sessions"`
+}
+
+// Ready processes readiness check requests for load balancers. It returns 503
+// while the server is draining so no new traffic arrives, and 200 otherwise.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	h.rediscoverCursorAgent()
+	resp := ReadyResponse{
+		Ready:          true,
+		ActiveSessions: len(h.sessionManager.GetAllSessionsShallow()),
+	}
+	if h.drain != nil {
+		if draining, since := h.drain.Status(); draining {
+			resp.Ready = false
+			resp.Draining = true
+			resp.DrainingSince = &since
+		}
+	}
+
+	status := http.StatusOK
+	if !resp.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, resp)
+}
+
+// rediscoverCursorAgent re-resolves cursor-agent through the locator when the
+// interval has passed since the last lookup, returning the last path found.
+// Lookup failures are logged and the previous path kept.
+func (h *HealthHandler) rediscoverCursorAgent() string {
+	if h.locator == nil || h.rediscoverInterval <= 0 {
+		return ""
+	}
+
+	h.rediscoverMu.Lock()
+	defer h.rediscoverMu.Unlock()
+
+	if !h.rediscoveredAt.IsZero() && time.Since(h.rediscoveredAt) < h.rediscoverInterval {
+		return h.cursorAgentPath
+	}
+	h.rediscoveredAt = time.Now()
+
+	path, err := h.locator.RediscoverCursorAgent()
+	if err != nil {
+		logger.Get().Warn().Err(err).Str("cursor_agent_path", path).Msg("Failed to locate cursor-agent")
+	}
+	h.cursorAgentPath = path
+	return path
+}
+
+// health builds the basic health response for the given sessions
+func (h *HealthHandler) health(sessions []*session.Session) HealthResponse {
+	return HealthResponse{
 		Status:         "ok",
 		Version:        "1.0.0",
-		UptimeSeconds:  int64(uptime),
-		ActiveSessions: activeSessions,
-		MemoryUsageMB:  memoryMB,
+		UptimeSeconds:  int64(time.Since(startTime).Seconds()),
+		ActiveSessions: len(sessions),
+		MemoryUsageMB:  h.MemoryUsageMB(),
 	}
+}
+
+// idleSeconds returns whole seconds since the most recent session activity.
+// Before any activity has been seen, idle time is measured from server start.
+func (h *HealthHandler) idleSeconds(sessions []*session.Session) int64 {
+	h.activityMu.Lock()
+	defer h.activityMu.Unlock()
+
+	for _, sess := range sessions {
+		if sess.LastActivity.After(h.lastActivity) {
+			h.lastActivity = sess.LastActivity
+		}
+	}
+
+	since := startTime
+	if !h.lastActivity.IsZero() {
+		since = h.lastActivity
+	}
+	idle := int64(time.Since(since).Seconds())
+	if idle < 0 {
+		return 0
+	}
+	return idle
+}
+
+// MemoryUsageMB returns allocated heap memory, reusing the last reading within the TTL
+func (h *HealthHandler) MemoryUsageMB() float64 {
+	h.memMu.Lock()
+	defer h.memMu.Unlock()
 
-	c.JSON(http.StatusOK, response)
+	if !h.memReadAt.IsZero() && time.Since(h.memReadAt) < h.memStatsTTL {
+		return h.memoryMB
+	}
+
+	var memStats runtime.MemStats
+	h.readMemStats(&memStats)
+	h.memoryMB = float64(memStats.Alloc) / 1024 / 1024
+	h.memReadAt = time.Now()
+	return h.memoryMB
 }
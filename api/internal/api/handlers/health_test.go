@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"testing"
 	"time"
 
@@ -15,7 +16,7 @@ func TestHealthHandler_Handle(t *testing.T) {
 
 	t.Run("returns health response with all fields", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewHealthHandler(mockManager)
+		handler := NewHealthHandler(mockManager, 0)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -57,7 +58,7 @@ func TestHealthHandler_Handle(t *testing.T) {
 
 	t.Run("returns correct status", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewHealthHandler(mockManager)
+		handler := NewHealthHandler(mockManager, 0)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -75,7 +76,7 @@ func TestHealthHandler_Handle(t *testing.T) {
 
 	t.Run("returns version", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewHealthHandler(mockManager)
+		handler := NewHealthHandler(mockManager, 0)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -98,7 +99,7 @@ func TestHealthHandler_Handle(t *testing.T) {
 
 	t.Run("returns zero active sessions when none exist", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewHealthHandler(mockManager)
+		handler := NewHealthHandler(mockManager, 0)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -122,7 +123,7 @@ func TestHealthHandler_Handle(t *testing.T) {
 		mockManager.CreateSession()
 		mockManager.CreateSession()
 
-		handler := NewHealthHandler(mockManager)
+		handler := NewHealthHandler(mockManager, 0)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -145,7 +146,7 @@ func TestHealthHandler_Handle(t *testing.T) {
 		sess1, _ := mockManager.CreateSession()
 		sess2, _ := mockManager.CreateSession()
 
-		handler := NewHealthHandler(mockManager)
+		handler := NewHealthHandler(mockManager, 0)
 
 		// First call - should have 2 sessions
 		w1 := httptest.NewRecorder()
@@ -195,7 +196,7 @@ func TestHealthHandler_Handle(t *testing.T) {
 
 	t.Run("uptime increases over time", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewHealthHandler(mockManager)
+		handler := NewHealthHandler(mockManager, 0)
 
 		// First call
 		w1 := httptest.NewRecorder()
@@ -232,7 +233,7 @@ func TestHealthHandler_Handle(t *testing.T) {
 
 	t.Run("memory usage is reasonable", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewHealthHandler(mockManager)
+		handler := NewHealthHandler(mockManager, 0)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -255,7 +256,7 @@ func TestHealthHandler_Handle(t *testing.T) {
 
 	t.Run("response format is consistent", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewHealthHandler(mockManager)
+		handler := NewHealthHandler(mockManager, 0)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -279,3 +280,231 @@ func TestHealthHandler_Handle(t *testing.T) {
 		}
 	})
 }
+
+func TestHealthHandler_MemStatsCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// newCountingHandler stubs memory reads to report a distinct allocation each time
+	newCountingHandler := func(ttl time.Duration) (*HealthHandler, *int) {
+		handler := NewHealthHandler(NewMockSessionManager(), ttl)
+		reads := 0
+		handler.readMemStats = func(m *runtime.MemStats) {
+			reads++
+			m.Alloc = uint64(reads) * 1024 * 1024
+		}
+		return handler, &reads
+	}
+
+	check := func(handler *HealthHandler) HealthResponse {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/health", nil)
+		handler.Handle(c)
+
+		var response HealthResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	t.Run("rapid calls within the TTL reuse the cached value", func(t *testing.T) {
+		handler, reads := newCountingHandler(time.Minute)
+
+		first := check(handler)
+		second := check(handler)
+
+		if *reads != 1 {
+			t.Errorf("expected a single memory read, got %d", *reads)
+		}
+		if first.MemoryUsageMB != second.MemoryUsageMB {
+			t.Errorf("expected cached memory value, got %.2f then %.2f", first.MemoryUsageMB, second.MemoryUsageMB)
+		}
+	})
+
+	t.Run("reads again once the TTL has passed", func(t *testing.T) {
+		handler, reads := newCountingHandler(10 * time.Millisecond)
+
+		check(handler)
+		time.Sleep(20 * time.Millisecond)
+		second := check(handler)
+
+		if *reads != 2 {
+			t.Errorf("expected memory to be re-read, got %d reads", *reads)
+		}
+		if second.MemoryUsageMB != 2 {
+			t.Errorf("expected refreshed memory value 2, got %.2f", second.MemoryUsageMB)
+		}
+	})
+
+	t.Run("zero TTL reads on every call", func(t *testing.T) {
+		handler, reads := newCountingHandler(0)
+
+		check(handler)
+		check(handler)
+
+		if *reads != 2 {
+			t.Errorf("expected 2 memory reads, got %d", *reads)
+		}
+	})
+}
+
+func TestHealthHandler_Full(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	check := func(handler *HealthHandler) FullHealthResponse {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/health/full", nil)
+		handler.Full(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var response FullHealthResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("reports zero idle time while a session is active", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		mockManager.CreateSession()
+		handler := NewHealthHandler(mockManager, 0)
+
+		response := check(handler)
+
+		if response.IdleSeconds != 0 {
+			t.Errorf("expected 0 idle seconds, got %d", response.IdleSeconds)
+		}
+		if response.ActiveSessions != 1 || response.Status != "ok" {
+			t.Errorf("expected basic health fields, got %+v", response.HealthResponse)
+		}
+	})
+
+	t.Run("reports time since the most recent session activity", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		older, _ := mockManager.CreateSession()
+		newer, _ := mockManager.CreateSession()
+		older.LastActivity = time.Now().Add(-30 * time.Minute)
+		newer.LastActivity = time.Now().Add(-10 * time.Minute)
+		handler := NewHealthHandler(mockManager, 0)
+
+		response := check(handler)
+
+		if response.IdleSeconds < 600 || response.IdleSeconds > 605 {
+			t.Errorf("expected about 600 idle seconds, got %d", response.IdleSeconds)
+		}
+	})
+
+	t.Run("keeps counting idle time after sessions are removed", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		sess.LastActivity = time.Now().Add(-20 * time.Minute)
+		handler := NewHealthHandler(mockManager, 0)
+
+		check(handler)
+		mockManager.EndSession(sess.ID)
+		response := check(handler)
+
+		if response.ActiveSessions != 0 {
+			t.Errorf("expected 0 active sessions, got %d", response.ActiveSessions)
+		}
+		if response.IdleSeconds < 1200 {
+			t.Errorf("expected at least 1200 idle seconds, got %d", response.IdleSeconds)
+		}
+	})
+	t.Run("reports a stalled cleanup loop as degraded", func(t *testing.T) {
+		lastRun := time.Now().Add(-5 * time.Minute)
+		handler := NewHealthHandler(NewMockSessionManager(), 0)
+		handler.SetCleanupMonitor(fakeCleanupMonitor{lastRun: lastRun, stalled: true})
+
+		response := check(handler)
+
+		if !response.CleanupStalled || response.Status != "degraded" {
+			t.Errorf("expected stalled cleanup and degraded status, got %+v", response)
+		}
+		if response.CleanupLastRun == nil || !response.CleanupLastRun.Equal(lastRun) {
+			t.Errorf("expected cleanup_last_run %v, got %v", lastRun, response.CleanupLastRun)
+		}
+	})
+
+	t.Run("healthy cleanup loop stays ok", func(t *testing.T) {
+		handler := NewHealthHandler(NewMockSessionManager(), 0)
+		handler.SetCleanupMonitor(fakeCleanupMonitor{})
+
+		response := check(handler)
+
+		if response.CleanupStalled || response.Status != "ok" || response.CleanupLastRun != nil {
+			t.Errorf("expected ok status without a last run, got %+v", response)
+		}
+	})
+}
+
+// fakeCleanupMonitor reports fixed cleanup progress
+type fakeCleanupMonitor struct {
+	lastRun time.Time
+	stalled bool
+}
+
+func (m fakeCleanupMonitor) LastRun() time.Time { return m.lastRun }
+func (m fakeCleanupMonitor) Stalled() bool      { return m.stalled }
+
+// countingLocator reports a fixed cursor-agent path and counts lookups
+type countingLocator struct {
+	path    string
+	lookups int
+}
+
+func (l *countingLocator) RediscoverCursorAgent() (string, error) {
+	l.lookups++
+	return l.path, nil
+}
+
+func TestHealthHandler_RediscoversCursorAgent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	full := func(handler *HealthHandler) FullHealthResponse {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/health/full", nil)
+		handler.Full(c)
+
+		var response FullHealthResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	t.Run("looks up at most once per interval", func(t *testing.T) {
+		locator := &countingLocator{path: "/opt/cursor/cursor-agent"}
+		handler := NewHealthHandler(NewMockSessionManager(), 0)
+		handler.SetCursorAgentLocator(locator, time.Hour)
+
+		for i := 0; i < 3; i++ {
+			if got := full(handler).CursorAgentPath; got != locator.path {
+				t.Errorf("expected cursor_agent_path %q, got %q", locator.path, got)
+			}
+		}
+		if locator.lookups != 1 {
+			t.Errorf("expected 1 lookup, got %d", locator.lookups)
+		}
+
+		handler.rediscoveredAt = time.Now().Add(-2 * time.Hour)
+		locator.path = "/usr/local/bin/cursor-agent"
+		if got := full(handler).CursorAgentPath; got != locator.path {
+			t.Errorf("expected the new path after the interval, got %q", got)
+		}
+	})
+
+	t.Run("a zero interval disables rediscovery", func(t *testing.T) {
+		locator := &countingLocator{path: "/opt/cursor/cursor-agent"}
+		handler := NewHealthHandler(NewMockSessionManager(), 0)
+		handler.SetCursorAgentLocator(locator, 0)
+
+		if got := full(handler).CursorAgentPath; got != "" {
+			t.Errorf("expected no cursor_agent_path, got %q", got)
+		}
+		if locator.lookups != 0 {
+			t.Errorf("expected no lookups, got %d", locator.lookups)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/sean/janus/internal/logger"
+	"github.com/sean/janus/internal/session"
+)
+
+// memoryGuard rejects new work while heap usage is above a critical threshold.
+// Entering pressure logs the event and, in the background, ends sessions idle
+// longer than sweepCutoff, a shorter cutoff than the regular cleanup uses so
+// the sweep frees memory the next cleanup run wouldn't; the guard lifts on its own once a later reading drops back under
+// the threshold.
+type memoryGuard struct {
+	criticalMB     float64
+	sweepCutoff    time.Duration
+	sessionManager session.Manager
+	// readMemoryMB returns current heap usage; the router points it at the
+	// health handler's cached reading, and tests replace it
+	readMemoryMB func() float64
+	// sweeping tracks the background sweep so tests can wait for it
+	sweeping sync.WaitGroup
+
+	mu          sync.Mutex
+	underStress bool
+}
+
+// newMemoryGuard creates a guard for criticalMB; zero or less disables it. A
+// sweepCutoff of zero or less falls back to sessionTimeout.
+func newMemoryGuard(criticalMB int, sweepCutoff time.Duration, sessionTimeout time.Duration, sessionManager session.Manager) *memoryGuard {
+	if sweepCutoff <= 0 {
+		sweepCutoff = sessionTimeout
+	}
+	return &memoryGuard{
+		criticalMB:     float64(criticalMB),
+		sweepCutoff:    sweepCutoff,
+		sessionManager: sessionManager,
+		readMemoryMB:   heapAllocMB,
+	}
+}
+
+// Allow reports whether memory is below the critical threshold
+func (g *memoryGuard) Allow() bool {
+	if g.criticalMB <= 0 {
+		return true
+	}
+
+	memoryMB := g.readMemoryMB()
+	critical := memoryMB >= g.criticalMB
+
+	g.mu.Lock()
+	entered := critical && !g.underStress
+	recovered := !critical && g.underStress
+	g.underStress = critical
+	g.mu.Unlock()
+
+	if entered {
+		logger.Get().Warn().
+			Float64("memory_usage_mb", memoryMB).
+			Float64("memory_critical_mb", g.criticalMB).
+			Msg("Memory critically high, rejecting new sessions and sweeping inactive ones")
+		// The sweep takes the session locks, so it stays off the request path
+		g.sweeping.Add(1)
+		go func() {
+			defer g.sweeping.Done()
+			g.sessionManager.CleanupInactiveSessions(g.sweepCutoff)
+		}()
+	}
+	if recovered {
+		logger.Get().Info().
+			Float64("memory_usage_mb", memoryMB).
+			Msg("Memory recovered, accepting new sessions")
+	}
+
+	return !critical
+}
+
+// heapAllocMB reads allocated heap memory in megabytes
+func heapAllocMB() float64 {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return float64(memStats.Alloc) / 1024 / 1024
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"regexp"
+
+	"github.com/sean/janus/internal/logger"
+)
+
+// questionDenylist rejects questions matching any configured pattern before
+// they reach cursor-agent
+type questionDenylist struct {
+	patterns []*regexp.Regexp
+}
+
+// newQuestionDenylist compiles patterns, skipping any that fail to compile.
+// Config validation rejects invalid patterns, so this only matters for
+// hand-built configs.
+func newQuestionDenylist(patterns []string) *questionDenylist {
+	d := &questionDenylist{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.Get().Warn().Err(err).Str("pattern", pattern).Msg("Skipping invalid question deny pattern")
+			continue
+		}
+		d.patterns = append(d.patterns, re)
+	}
+	return d
+}
+
+// match returns the index of the first pattern question matches, or -1
+func (d *questionDenylist) match(question string) int {
+	for i, re := range d.patterns {
+		if re.MatchString(question) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestQuestionDenylist(t *testing.T) {
+	denylist := newQuestionDenylist([]string{`(?i)\bpassword\b`, `rm\s+-rf`, `[invalid`})
+
+	if n := len(denylist.patterns); n != 2 {
+		t.Fatalf("expected the invalid pattern to be skipped, got %d patterns", n)
+	}
+
+	blocked := []string{"What is the admin PASSWORD?", "run rm  -rf / please"}
+	for _, question := range blocked {
+		if denylist.match(question) < 0 {
+			t.Errorf("expected %q to be blocked", question)
+		}
+	}
+
+	allowed := []string{"How do I reset a passwordless login?", "Explain the router", ""}
+	for _, question := range allowed {
+		if i := denylist.match(question); i >= 0 {
+			t.Errorf("expected %q to be allowed, matched pattern %d", question, i)
+		}
+	}
+}
+
+func TestAsk_QuestionDenyPatterns(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockManager := NewMockSessionManager()
+	sess, _ := mockManager.CreateSession()
+	var asked atomic.Int32
+	mockManager.askQuestionFunc = func(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
+		asked.Add(1)
+		return "answer", "chat", nil
+	}
+	cfg := newTestConfig()
+	cfg.QuestionDenyPatterns = []string{`(?i)secret`}
+	handler := NewSessionHandler(mockManager, cfg)
+
+	ask := func(question string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask?session_id="+sess.ID, bytes.NewBufferString(`{"question":"`+question+`"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+		return w
+	}
+
+	t.Run("blocked question returns 422 without asking", func(t *testing.T) {
+		w := ask("Show me the SECRET keys")
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected status 422, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "QUESTION_REJECTED") || strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("expected a generic rejection, got %s", w.Body.String())
+		}
+		if asked.Load() != 0 {
+			t.Error("expected cursor-agent not to be invoked")
+		}
+	})
+
+	t.Run("allowed question is answered", func(t *testing.T) {
+		w := ask("Explain the router")
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if asked.Load() != 1 {
+			t.Errorf("expected one cursor-agent call, got %d", asked.Load())
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// windowLimiter counts events per key over a sliding window and rejects keys
+// that exceed the limit. A limit of 0 or less allows everything.
+type windowLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+// newWindowLimiter creates a limiter allowing limit events per key per window
+func newWindowLimiter(limit int, window time.Duration) *windowLimiter {
+	return &windowLimiter{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// Allow records an event for key and reports whether it is within the limit.
+// When rejected, retryAfter is how long until the oldest counted event expires.
+func (l *windowLimiter) Allow(key string) (allowed bool, retryAfter time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	cutoff := now.Add(-l.window)
+	l.sweep(now, cutoff)
+
+	recent := pruneBefore(l.hits[key], cutoff)
+	if len(recent) >= l.limit {
+		l.hits[key] = recent
+		return false, recent[0].Sub(cutoff)
+	}
+
+	l.hits[key] = append(recent, now)
+	return true, 0
+}
+
+// Forget drops any counts held for key, e.g. when its session ends
+func (l *windowLimiter) Forget(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.hits, key)
+}
+
+// sweep removes keys with no events in the window, at most once per window,
+// so keys that stop sending (expired sessions) don't accumulate
+func (l *windowLimiter) sweep(now, cutoff time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key, times := range l.hits {
+		if recent := pruneBefore(times, cutoff); len(recent) == 0 {
+			delete(l.hits, key)
+		} else {
+			l.hits[key] = recent
+		}
+	}
+}
+
+// pruneBefore drops timestamps at or before cutoff from an ascending slice
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// inflightLimiter caps how many operations per key may run at once. A limit of
+// 0 or less allows everything.
+type inflightLimiter struct {
+	limit int
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// newInflightLimiter creates a limiter allowing limit concurrent operations per key
+func newInflightLimiter(limit int) *inflightLimiter {
+	return &inflightLimiter{
+		limit:  limit,
+		counts: make(map[string]int),
+	}
+}
+
+// Acquire claims a slot for key, returning a release func, or false if key
+// already has limit operations in flight
+func (l *inflightLimiter) Acquire(key string) (release func(), ok bool) {
+	if l.limit <= 0 {
+		return func() {}, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.counts[key] >= l.limit {
+		return nil, false
+	}
+	l.counts[key]++
+
+	var once sync.Once
+	return func() { once.Do(func() { l.release(key) }) }, true
+}
+
+// release frees a slot for key, dropping the key once nothing is in flight
+func (l *inflightLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.counts[key] <= 1 {
+		delete(l.counts, key)
+		return
+	}
+	l.counts[key]--
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWindowLimiter(t *testing.T) {
+	t.Run("rejects events past the limit within the window", func(t *testing.T) {
+		limiter := newWindowLimiter(3, time.Minute)
+		clock := time.Now()
+		limiter.now = func() time.Time { return clock }
+
+		for i := 0; i < 3; i++ {
+			if allowed, _ := limiter.Allow("session"); !allowed {
+				t.Fatalf("expected event %d to be allowed", i+1)
+			}
+		}
+		allowed, retryAfter := limiter.Allow("session")
+		if allowed {
+			t.Error("expected 4th event to be rejected")
+		}
+		if retryAfter <= 0 || retryAfter > time.Minute {
+			t.Errorf("expected retry-after within the window, got %v", retryAfter)
+		}
+
+		// Other keys are counted independently
+		if allowed, _ := limiter.Allow("other"); !allowed {
+			t.Error("expected a different key to be allowed")
+		}
+	})
+
+	t.Run("allows events again once the window slides", func(t *testing.T) {
+		limiter := newWindowLimiter(2, time.Minute)
+		clock := time.Now()
+		limiter.now = func() time.Time { return clock }
+
+		limiter.Allow("session")
+		limiter.Allow("session")
+		clock = clock.Add(61 * time.Second)
+
+		if allowed, _ := limiter.Allow("session"); !allowed {
+			t.Error("expected event to be allowed after the window passed")
+		}
+	})
+
+	t.Run("forget resets a key", func(t *testing.T) {
+		limiter := newWindowLimiter(1, time.Minute)
+		limiter.Allow("session")
+		limiter.Forget("session")
+
+		if allowed, _ := limiter.Allow("session"); !allowed {
+			t.Error("expected event to be allowed after forget")
+		}
+	})
+
+	t.Run("sweeps idle keys", func(t *testing.T) {
+		limiter := newWindowLimiter(5, time.Minute)
+		clock := time.Now()
+		limiter.now = func() time.Time { return clock }
+
+		limiter.Allow("expired-session")
+		clock = clock.Add(2 * time.Minute)
+		limiter.Allow("active-session")
+
+		if _, exists := limiter.hits["expired-session"]; exists {
+			t.Error("expected idle key to be swept")
+		}
+	})
+
+	t.Run("zero limit allows everything", func(t *testing.T) {
+		limiter := newWindowLimiter(0, time.Minute)
+		for i := 0; i < 100; i++ {
+			if allowed, _ := limiter.Allow("session"); !allowed {
+				t.Fatal("expected unlimited limiter to allow events")
+			}
+		}
+	})
+}
+
+func TestHeartbeat_RateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	heartbeat := func(handler *SessionHandler, sessionID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/heartbeat?session_id=%s", sessionID), nil)
+		handler.Heartbeat(c)
+		return w
+	}
+
+	t.Run("rapid heartbeats hit the limit", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		cfg := newTestConfig()
+		cfg.MaxHeartbeatsPerMinute = 5
+		handler := NewSessionHandler(mockManager, cfg)
+
+		for i := 0; i < 5; i++ {
+			if w := heartbeat(handler, sess.ID); w.Code != http.StatusOK {
+				t.Fatalf("expected heartbeat %d to succeed, got %d", i+1, w.Code)
+			}
+		}
+
+		w := heartbeat(handler, sess.ID)
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("expected status 429, got %d", w.Code)
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Error("expected Retry-After header")
+		}
+	})
+
+	t.Run("normal interval heartbeats pass", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		cfg := newTestConfig()
+		cfg.MaxHeartbeatsPerMinute = 5
+		handler := NewSessionHandler(mockManager, cfg)
+
+		// Simulate an hour of heartbeats on the client's 30s interval
+		clock := time.Now()
+		handler.heartbeats.now = func() time.Time { return clock }
+		for i := 0; i < 120; i++ {
+			if w := heartbeat(handler, sess.ID); w.Code != http.StatusOK {
+				t.Fatalf("expected heartbeat %d to succeed, got %d", i+1, w.Code)
+			}
+			clock = clock.Add(30 * time.Second)
+		}
+	})
+}
+
+func TestInflightLimiter(t *testing.T) {
+	limiter := newInflightLimiter(2)
+
+	releaseFirst, ok := limiter.Acquire("client")
+	if !ok {
+		t.Fatal("expected first acquire to succeed")
+	}
+	if _, ok := limiter.Acquire("client"); !ok {
+		t.Fatal("expected second acquire to succeed")
+	}
+	if _, ok := limiter.Acquire("client"); ok {
+		t.Error("expected third acquire to be rejected")
+	}
+
+	releaseFirst()
+	releaseFirst() // Releasing twice frees only one slot
+	if _, ok := limiter.Acquire("client"); !ok {
+		t.Error("expected acquire to succeed after a release")
+	}
+	if _, ok := limiter.Acquire("client"); ok {
+		t.Error("expected double release not to free an extra slot")
+	}
+}
+
+func TestAsk_MaxAsksPerIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockManager := NewMockSessionManager()
+	sess, _ := mockManager.CreateSession()
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	mockManager.askQuestionFunc = func(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
+		if question == "slow" {
+			started <- struct{}{}
+			<-release
+		}
+		return "answer", "chat-1", nil
+	}
+	cfg := newTestConfig()
+	cfg.MaxAsksPerIP = 2
+	handler := NewSessionHandler(mockManager, cfg)
+
+	ask := func(ip string, question string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body := fmt.Sprintf(`{"question":%q}`, question)
+		c.Request = httptest.NewRequest("POST", "/api/ask?session_id="+sess.ID, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.RemoteAddr = ip + ":12345"
+		handler.Ask(c)
+		return w.Code
+	}
+
+	// Two slow asks from the same IP occupy its slots
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() { codes <- ask("192.0.2.1", "slow") }()
+	}
+	<-started
+	<-started
+
+	if code := ask("192.0.2.1", "fast"); code != http.StatusTooManyRequests {
+		t.Errorf("expected third concurrent ask from the same IP to get 429, got %d", code)
+	}
+	if code := ask("192.0.2.2", "fast"); code != http.StatusOK {
+		t.Errorf("expected ask from a different IP to succeed, got %d", code)
+	}
+
+	// Let the slow asks finish one at a time
+	for i := 0; i < 2; i++ {
+		release <- struct{}{}
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("expected slow ask to succeed, got %d", code)
+		}
+	}
+
+	if code := ask("192.0.2.1", "fast"); code != http.StatusOK {
+		t.Errorf("expected ask to succeed once slots are free, got %d", code)
+	}
+}
@@ -1,44 +1,158 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/middleware"
 	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/config"
 	"github.com/sean/janus/internal/logger"
 	"github.com/sean/janus/internal/session"
 )
 
+// cursorChatIDPattern restricts cursor chat IDs to the characters cursor-agent emits
+// (UUIDs and similar tokens). The leading character must be alphanumeric so the
+// value passed to --resume can never be parsed as a flag.
+var cursorChatIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,127}$`)
+
+// traceIDPattern bounds client-supplied trace IDs so they are safe to log
+var traceIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,128}$`)
+
+// externalKeyPattern bounds client-supplied session keys such as device IDs
+var externalKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,128}$`)
+
+// isValidTraceID reports whether a client-supplied trace ID is acceptable
+func isValidTraceID(traceID string) bool {
+	return traceIDPattern.MatchString(traceID)
+}
+
 // SessionHandler handles session-related requests
 type SessionHandler struct {
 	sessionManager session.Manager
-	workspaceDir   string
+	config         *config.Config
+	heartbeats     *windowLimiter
+	asks           *inflightLimiter
+	flights        *askFlight
+	workspace      *workspaceGate
+	memory         *memoryGuard
+	webhook        *answerWebhook
+	denylist       *questionDenylist
+	// idempotency replays responses to retried asks (nil when AskIdempotencyTTL is disabled)
+	idempotency *askIdempotency
 }
 
 // NewSessionHandler creates a new session handler
-func NewSessionHandler(sessionManager session.Manager, workspaceDir string) *SessionHandler {
+func NewSessionHandler(sessionManager session.Manager, cfg *config.Config) *SessionHandler {
 	return &SessionHandler{
 		sessionManager: sessionManager,
-		workspaceDir:   workspaceDir,
+		config:         cfg,
+		heartbeats:     newWindowLimiter(cfg.MaxHeartbeatsPerMinute, time.Minute),
+		asks:           newInflightLimiter(cfg.MaxAsksPerIP),
+		flights:        newAskFlight(),
+		workspace:      &workspaceGate{},
+		memory: newMemoryGuard(
+			cfg.MemoryCriticalMB,
+			cfg.MemoryPressureIdleCutoff,
+			time.Duration(cfg.SessionTimeoutMinutes)*time.Minute,
+			sessionManager,
+		),
+		webhook:     newAnswerWebhook(cfg),
+		denylist:    newQuestionDenylist(cfg.QuestionDenyPatterns),
+		idempotency: newAskIdempotency(cfg.AskIdempotencyTTL),
 	}
 }
 
+// SetMemoryReader makes the memory guard use read for heap usage, such as the
+// health handler's cached reading, instead of reading runtime stats itself
+func (h *SessionHandler) SetMemoryReader(read func() float64) {
+	h.memory.readMemoryMB = read
+}
+
+// StartSessionRequest represents the optional settings for a new session
+type StartSessionRequest struct {
+	// AutoTTS opts the session into receiving TTS info with every answer
+	AutoTTS bool `json:"auto_tts"`
+	// Branch, when set, is checked out in the workspace before each ask
+	Branch string `json:"branch,omitempty"`
+	// TimeoutMinutes overrides the session inactivity timeout, clamped to MaxSessionTimeoutMinutes
+	TimeoutMinutes int `json:"timeout_minutes,omitempty"`
+	// Ephemeral keeps every exchange in the session out of the conversation log and archives
+	Ephemeral bool `json:"ephemeral,omitempty"`
+	// AnswerWebhookURL receives each answer in the session, overriding the global AnswerWebhookURL.
+	// Its host must be one of AnswerWebhookHosts.
+	AnswerWebhookURL string `json:"answer_webhook_url,omitempty"`
+}
+
 // StartSessionResponse represents the response for starting a session
 type StartSessionResponse struct {
+	SessionID      string `json:"session_id"`
+	Message        string `json:"message"`
+	AutoTTS        bool   `json:"auto_tts"`
+	Branch         string `json:"branch,omitempty"`
+	TimeoutMinutes int    `json:"timeout_minutes,omitempty"`
+	Ephemeral      bool   `json:"ephemeral,omitempty"`
+}
+
+// EnsureSessionRequest identifies the client-side key a session is tied to
+type EnsureSessionRequest struct {
+	// ExternalKey is the client's own stable identifier, such as a device ID
+	ExternalKey string `json:"external_key" binding:"required"`
+}
+
+// EnsureSessionResponse represents the response for getting or creating a keyed session
+type EnsureSessionResponse struct {
 	SessionID string `json:"session_id"`
-	Message   string `json:"message"`
+	Created   bool   `json:"created"`
+	AutoTTS   bool   `json:"auto_tts"`
+	Branch    string `json:"branch,omitempty"`
 }
 
 // AskRequest represents a question request
 type AskRequest struct {
 	Question string `json:"question" binding:"required"`
+	// TraceID optionally correlates this ask with a prior transcription
+	TraceID string `json:"trace_id,omitempty"`
+	// Files are workspace paths the question refers to, passed to cursor-agent as @-references
+	Files []string `json:"files,omitempty"`
+	// Mode optionally runs a configured cursor-agent operation (CursorAgentModes) instead of plain chat
+	Mode string `json:"mode,omitempty"`
+	// IncludeContext prepends recently changed files and conversation summaries to the prompt
+	IncludeContext bool `json:"include_context,omitempty"`
+	// ClientType ("voice" or "text") selects answer-format instructions and post-processing
+	ClientType string `json:"client_type,omitempty"`
+	// Ephemeral answers the question without adding the exchange to the conversation log
+	Ephemeral bool `json:"ephemeral,omitempty"`
+	// TranslateTo translates the answer into this language (e.g. "es") with the TranslatePath command
+	TranslateTo string `json:"translate_to,omitempty"`
 }
 
 // AskResponse represents a response to a question
 type AskResponse struct {
-	Answer    string `json:"answer"`
-	SessionID string `json:"session_id"`
+	Answer           string       `json:"answer"`
+	SessionID        string       `json:"session_id"`
+	TraceID          string       `json:"trace_id,omitempty"`
+	Resumed          bool         `json:"resumed"`
+	TTS              *AutoTTSInfo `json:"tts,omitempty"`
+	LongConversation bool         `json:"long_conversation"` // Nudge to suggest starting a fresh session
+	IdleWarning      bool         `json:"idle_warning"`      // Session was idle long enough to be near expiry
+	TranslatedTo     string       `json:"translated_to,omitempty"`
+}
+
+// AutoTTSInfo tells AutoTTS clients how to fetch synthesized audio for the answer
+type AutoTTSInfo struct {
+	Endpoint string `json:"endpoint"`
+	Method   string `json:"method"`
+	Provider string `json:"provider"`
+	Voice    string `json:"voice,omitempty"`
 }
 
 // GenericResponse represents a generic success response
@@ -51,19 +165,75 @@ type GenericResponse struct {
 type EndSessionResponse struct {
 	Message   string `json:"message"`
 	SessionID string `json:"session_id"`
+	// Usage summarizes the session when SessionUsageSummary is enabled
+	Usage *session.UsageSummary `json:"usage,omitempty"`
 }
 
 // HeartbeatResponse represents the response for a heartbeat request
 type HeartbeatResponse struct {
-	Message      string    `json:"message"`
-	SessionID    string    `json:"session_id"`
-	LastActivity time.Time `json:"last_activity"`
+	Message          string    `json:"message"`
+	SessionID        string    `json:"session_id"`
+	LastActivity     time.Time `json:"last_activity"`
+	LongConversation bool      `json:"long_conversation"`
+	IdleWarning      bool      `json:"idle_warning"`
+}
+
+// UpdateCursorChatRequest represents a request to re-point a session's cursor chat
+type UpdateCursorChatRequest struct {
+	CursorChatID string `json:"cursor_chat_id" binding:"required"`
+}
+
+// UpdateCursorChatResponse represents the updated cursor chat mapping
+type UpdateCursorChatResponse struct {
+	Message      string `json:"message"`
+	SessionID    string `json:"session_id"`
+	CursorChatID string `json:"cursor_chat_id"`
 }
 
 // Start handles session start requests
 func (h *SessionHandler) Start(c *gin.Context) {
+	// The body is optional; an empty body starts a session with defaults
+	var req StartSessionRequest
+	if err := bindJSON(h.config, c, &req); err != nil && !errors.Is(err, io.EOF) {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, bindErrorDetails(err, "Invalid request body"))
+		return
+	}
+
+	if req.Branch != "" && !isValidBranch(req.Branch) {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "branch must be a valid git branch name")
+		return
+	}
+
+	if req.AnswerWebhookURL != "" && !config.IsWebhookURL(req.AnswerWebhookURL) {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "answer_webhook_url must be an absolute http or https URL")
+		return
+	}
+
+	if req.AnswerWebhookURL != "" && !allowsHost(h.config.AnswerWebhookHosts, req.AnswerWebhookURL) {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "answer_webhook_url host is not an allowed webhook host")
+		return
+	}
+
+	if req.TimeoutMinutes < 0 {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "timeout_minutes cannot be negative")
+		return
+	}
+	timeoutMinutes := min(req.TimeoutMinutes, h.config.MaxSessionTimeoutMinutes)
+
+	// Shed new sessions while memory is critically high so existing ones keep working
+	if !h.memory.Allow() {
+		response.RespondWithError(c, http.StatusTooManyRequests, response.ErrRateLimited, "Server is under memory pressure, try again later")
+		return
+	}
+
 	// Create session in manager
-	sess, err := h.sessionManager.CreateSession()
+	sess, err := h.sessionManager.CreateSessionWithOptions(session.SessionOptions{
+		AutoTTS:          req.AutoTTS,
+		Branch:           req.Branch,
+		Timeout:          time.Duration(timeoutMinutes) * time.Minute,
+		Ephemeral:        req.Ephemeral,
+		AnswerWebhookURL: req.AnswerWebhookURL,
+	})
 	if err != nil {
 		logger.Get().Error().Err(err).Msg("Failed to create session")
 		response.RespondWithError(c, http.StatusInternalServerError, response.ErrInternalServer, "Failed to create session")
@@ -72,126 +242,327 @@ func (h *SessionHandler) Start(c *gin.Context) {
 
 	logger.Get().Info().
 		Str("session_id", sess.ID).
+		Bool("auto_tts", sess.AutoTTS).
+		Str("branch", sess.Branch).
+		Dur("timeout", sess.Timeout).
+		Bool("ephemeral", sess.Ephemeral).
 		Msg("Session created successfully")
 
 	response := StartSessionResponse{
-		SessionID: sess.ID,
-		Message:   "Session started successfully",
+		SessionID:      h.publicSessionID(sess.ID),
+		Message:        "Session started successfully",
+		AutoTTS:        sess.AutoTTS,
+		Branch:         sess.Branch,
+		TimeoutMinutes: timeoutMinutes,
+		Ephemeral:      sess.Ephemeral,
 	}
 
-	c.JSON(http.StatusOK, response)
+	respondWithData(h.config, c, http.StatusOK, response)
 }
 
-// Ask handles question requests
-func (h *SessionHandler) Ask(c *gin.Context) {
-	sessionID := c.Query("session_id")
-	if sessionID == "" {
-		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "session_id query parameter is required")
+// Ensure handles requests to get the session tied to an external key, creating it if needed
+func (h *SessionHandler) Ensure(c *gin.Context) {
+	var req EnsureSessionRequest
+	if err := bindJSON(h.config, c, &req); err != nil {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, bindErrorDetails(err, "Invalid request body: missing or malformed external_key field"))
 		return
 	}
 
-	// Parse request body
-	var req AskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "Invalid request body: missing or malformed question field")
+	if !externalKeyPattern.MatchString(req.ExternalKey) {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "external_key must be 1-128 letters, digits, or '_.:-'")
 		return
 	}
 
-	// Verify session exists
-	_, err := h.sessionManager.GetSession(sessionID)
+	sess, created, err := h.sessionManager.GetOrCreateByKey(req.ExternalKey)
 	if err != nil {
-		response.RespondWithError(c, http.StatusNotFound, response.ErrSessionNotFound, "The specified session does not exist or has expired")
+		logger.Get().Error().Err(err).Msg("Failed to ensure session")
+		response.RespondWithError(c, http.StatusInternalServerError, response.ErrInternalServer, "Failed to ensure session")
 		return
 	}
 
-	// Ask question using cursor-agent command (with context for timeout)
-	answer, cursorChatID, err := h.sessionManager.AskQuestion(c.Request.Context(), sessionID, req.Question, h.workspaceDir)
-	if err != nil {
-		// Check if the error was due to context timeout
-		if c.Request.Context().Err() != nil {
-			logger.Get().Warn().
-				Str("session_id", sessionID).
-				Err(err).
-				Msg("Request timed out")
-			response.RespondWithError(c, http.StatusRequestTimeout, response.ErrTimeout, "Request to cursor-agent timed out")
-			return
-		}
-		logger.Get().Error().
-			Str("session_id", sessionID).
-			Err(err).
-			Msg("Failed to ask question")
-		response.RespondWithError(c, http.StatusInternalServerError, response.ErrProcessCommunication, "Failed to get response from cursor-agent")
+	logger.Get().Info().
+		Str("session_id", sess.ID).
+		Bool("created", created).
+		Msg("Session ensured for external key")
+
+	respondWithData(h.config, c, http.StatusOK, EnsureSessionResponse{
+		SessionID: h.publicSessionID(sess.ID),
+		Created:   created,
+		AutoTTS:   sess.AutoTTS,
+		Branch:    sess.Branch,
+	})
+}
+
+// supportsMode reports whether mode is in the CursorAgentModes allowlist and the
+// session manager can run it, streaming its answer when streaming is set
+func (h *SessionHandler) supportsMode(mode string, streaming bool) bool {
+	if _, ok := h.config.CursorAgentModes[mode]; !ok {
+		return false
+	}
+	if streaming {
+		_, ok := h.sessionManager.(session.ModeStreamingManager)
+		return ok
+	}
+	_, ok := h.sessionManager.(session.ModeManager)
+	return ok
+}
+
+// askQuestion sends the question to cursor-agent, through the given mode when set
+func (h *SessionHandler) askQuestion(ctx context.Context, sessionID string, mode string, question string) (string, string, bool, error) {
+	if mode == "" {
+		return h.sessionManager.AskQuestion(ctx, sessionID, question, h.config.WorkspaceDir)
+	}
+	modes, ok := h.sessionManager.(session.ModeManager)
+	if !ok {
+		return "", "", false, session.ErrUnknownMode
+	}
+	return modes.AskQuestionInMode(ctx, sessionID, mode, question, h.config.WorkspaceDir)
+}
+
+// Ask handles question requests
+func (h *SessionHandler) Ask(c *gin.Context) {
+	response, ok := h.processAsk(c)
+	if !ok {
 		return
 	}
+	respondWithData(h.config, c, http.StatusOK, response)
+}
 
-	// Update cursor chat ID if this was the first question
-	if err := h.sessionManager.UpdateCursorChatID(sessionID, cursorChatID); err != nil {
-		logger.Get().Warn().
-			Str("session_id", sessionID).
-			Str("cursor_chat_id", cursorChatID).
-			Err(err).
-			Msg("Failed to update cursor chat ID")
+// processAsk runs an ask request through cursor-agent and records the exchange,
+// returning the response to send. On failure it writes the error response itself
+// and returns false.
+func (h *SessionHandler) processAsk(c *gin.Context) (*AskResponse, bool) {
+	sessionID, ok := h.sessionIDParam(c)
+	if !ok {
+		return nil, false
 	}
 
-	// Update activity timestamp
-	if err := h.sessionManager.UpdateActivity(sessionID); err != nil {
-		logger.Get().Warn().
-			Str("session_id", sessionID).
-			Err(err).
-			Msg("Failed to update activity")
+	// A retry carrying an earlier ask's idempotency key gets that ask's response
+	// instead of running cursor-agent again
+	var recorded *AskResponse
+	if key := c.GetHeader(middleware.IdempotencyKeyHeader); key != "" && h.idempotency != nil {
+		if !isValidIdempotencyKey(key) {
+			response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, middleware.IdempotencyKeyHeader+" must be 1-255 letters, digits, or '_.:=+/-'")
+			return nil, false
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "Invalid request body")
+			return nil, false
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		cached, finish, err := h.idempotency.Begin(c.Request.Context(), sessionID+"\x00"+key, body)
+		if err != nil {
+			response.RespondWithError(c, http.StatusUnprocessableEntity, response.ErrInvalidRequest, middleware.IdempotencyKeyHeader+" was already used with a different request body")
+			return nil, false
+		}
+		if cached != nil {
+			logger.Get().Info().
+				Str("session_id", sessionID).
+				Str("idempotency_key", key).
+				Msg("Replaying response for retried ask")
+			c.Header(middleware.IdempotentReplayedHeader, "true")
+			return cached, true
+		}
+		defer func() { finish(recorded) }()
 	}
 
-	// Add to conversation log
-	now := time.Now()
-	messages := []session.Message{
-		{
-			Role:      "user",
-			Content:   req.Question,
-			Timestamp: now,
-		},
-		{
-			Role:      "assistant",
-			Content:   answer,
-			Timestamp: time.Now(),
-		},
+	// Parse request body
+	var req AskRequest
+	if err := bindJSON(h.config, c, &req); err != nil {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, bindErrorDetails(err, "Invalid request body: missing or malformed question field"))
+		return nil, false
 	}
 
-	if err := h.sessionManager.AddToConversationLog(sessionID, messages); err != nil {
-		logger.Get().Warn().
-			Str("session_id", sessionID).
-			Err(err).
-			Msg("Failed to add to conversation log")
-		// Don't fail the request, just log the warning
+	ask, ok := h.prepareAsk(c, sessionID, req, false)
+	if !ok {
+		return nil, false
+	}
+	defer ask.release()
+
+	// Ask question using cursor-agent command (with context for timeout)
+	answer, cursorChatID, resumed, deduplicated, err := h.askOnce(c.Request.Context(), sessionID, req.Mode, ask.prompt)
+	if err != nil {
+		h.respondAskError(c, ask, err)
+		return nil, false
 	}
 
+	answer, translatedTo := h.finishAnswer(c.Request.Context(), ask, answer)
+	longConversation := h.recordAnswer(ask, cursorChatID, answer, deduplicated)
+
 	logger.Get().Info().
 		Str("session_id", sessionID).
+		Str("trace_id", req.TraceID).
 		Str("cursor_chat_id", cursorChatID).
+		Bool("resumed", resumed).
+		Bool("deduplicated", deduplicated).
+		Bool("ephemeral", req.Ephemeral || ask.sess.Ephemeral).
 		Msg("Question processed successfully")
 
 	response := AskResponse{
-		Answer:    answer,
-		SessionID: sessionID,
+		Answer:           answer,
+		SessionID:        h.publicSessionID(sessionID),
+		TraceID:          req.TraceID,
+		Resumed:          resumed,
+		LongConversation: longConversation,
+		IdleWarning:      ask.idleWarning,
+		TranslatedTo:     translatedTo,
+	}
+	if ask.sess.AutoTTS {
+		response.TTS = h.autoTTSInfo()
+	}
+
+	recorded = &response
+	return &response, true
+}
+
+// respondAskError writes the error response for a failed cursor-agent ask
+func (h *SessionHandler) respondAskError(c *gin.Context, ask *preparedAsk, err error) {
+	log := logger.Get().With().
+		Str("session_id", ask.sessionID).
+		Str("trace_id", ask.req.TraceID).
+		Logger()
+
+	if errors.Is(err, session.ErrQuestionCancelled) {
+		log.Info().Msg("Question cancelled")
+		response.RespondWithError(c, StatusQuestionCancelled, response.ErrQuestionCancelled, "The question was cancelled")
+		return
+	}
+	if errors.Is(err, session.ErrSessionBusy) {
+		log.Warn().Msg("Rejected question while session is answering another")
+		response.RespondWithError(c, http.StatusConflict, response.ErrSessionBusy, "The session is already answering a question")
+		return
+	}
+	var agentErr *session.CursorAgentError
+	if errors.As(err, &agentErr) {
+		status := h.cursorErrorStatus(agentErr.Subtype)
+		log.Error().
+			Str("subtype", agentErr.Subtype).
+			Int("status", status).
+			Err(err).
+			Msg("cursor-agent reported an error")
+		response.RespondWithSubtype(c, status, response.ErrCursorAgent, agentErr.Subtype, agentErr.Message)
+		return
+	}
+	// Check if the error was due to context timeout
+	if c.Request.Context().Err() != nil {
+		log.Warn().Err(err).Msg("Request timed out")
+		response.RespondWithError(c, http.StatusRequestTimeout, response.ErrTimeout, "Request to cursor-agent timed out")
+		return
+	}
+	log.Error().Err(err).Msg("Failed to ask question")
+	response.RespondWithError(c, http.StatusInternalServerError, response.ErrProcessCommunication, "Failed to get response from cursor-agent")
+}
+
+// cursorErrorStatus returns the HTTP status for a cursor-agent error subtype
+// from CursorErrorStatuses, or 500 for subtypes it doesn't map
+func (h *SessionHandler) cursorErrorStatus(subtype string) int {
+	if status, ok := h.config.CursorErrorStatuses[subtype]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// answerWebhookURL returns where sess's answers are delivered: its own webhook
+// while its host is allowed, else the global AnswerWebhookURL, else "" for none
+func (h *SessionHandler) answerWebhookURL(sess *session.Session) string {
+	if h.hasSessionWebhook(sess) {
+		return sess.AnswerWebhookURL
+	}
+	return h.config.AnswerWebhookURL
+}
+
+// hasSessionWebhook reports whether sess has its own webhook on an allowed
+// host. The allowlist is checked again here since it may have changed since
+// the session started.
+func (h *SessionHandler) hasSessionWebhook(sess *session.Session) bool {
+	return sess.AnswerWebhookURL != "" && allowsHost(h.config.AnswerWebhookHosts, sess.AnswerWebhookURL)
+}
+
+// idleWarning reports whether sess had been idle for at least IdleWarningThreshold,
+// warning the client that it will soon be reaped without a heartbeat. The
+// threshold is set against SessionTimeoutMinutes, so a session with its own
+// timeout is warned at the same fraction of that timeout instead.
+func (h *SessionHandler) idleWarning(sess *session.Session) bool {
+	threshold := h.config.IdleWarningThreshold
+	serverTimeout := time.Duration(h.config.SessionTimeoutMinutes) * time.Minute
+	if sess.Timeout > 0 && serverTimeout > 0 {
+		threshold = time.Duration(float64(threshold) * float64(sess.Timeout) / float64(serverTimeout))
+	}
+	return threshold > 0 && time.Since(sess.LastActivity) >= threshold
+}
+
+// askOnce asks the question, attaching to an identical ask already in flight on
+// the same session when DedupeConcurrentAsks is enabled. resumed reports whether
+// the run continued the session's cursor chat, and deduplicated whether the
+// answer came from another request's cursor-agent run.
+func (h *SessionHandler) askOnce(ctx context.Context, sessionID string, mode string, question string) (answer string, cursorChatID string, resumed bool, deduplicated bool, err error) {
+	if !h.config.DedupeConcurrentAsks {
+		answer, cursorChatID, resumed, err = h.askQuestion(ctx, sessionID, mode, question)
+		return answer, cursorChatID, resumed, false, err
+	}
+
+	key := sessionID + "\x00" + mode + "\x00" + session.NormalizeQuestion(question, h.config.NormalizeQuestions)
+	result, shared := h.flights.Do(ctx, key, func() askResult {
+		answer, cursorChatID, resumed, err := h.askQuestion(ctx, sessionID, mode, question)
+		return askResult{answer: answer, cursorChatID: cursorChatID, resumed: resumed, err: err}
+	})
+	return result.answer, result.cursorChatID, result.resumed, shared, result.err
+}
+
+// respondWithData sends a successful JSON response, wrapped in the standard
+// envelope when EnvelopeResponses is enabled
+func respondWithData(cfg *config.Config, c *gin.Context, status int, data interface{}) {
+	if cfg.EnvelopeResponses {
+		response.RespondWithData(c, status, data)
+		return
+	}
+	c.JSON(status, data)
+}
+
+// autoTTSInfo describes where to synthesize the answer, or nil when no server TTS is available
+func (h *SessionHandler) autoTTSInfo() *AutoTTSInfo {
+	availability := checkKokoroAvailability(h.config)
+	if !availability.Available {
+		return nil
 	}
 
-	c.JSON(http.StatusOK, response)
+	return &AutoTTSInfo{
+		Endpoint: h.config.RoutePrefix + "/tts",
+		Method:   http.MethodPost,
+		Provider: availability.Provider,
+		Voice:    availability.Voice,
+	}
 }
 
 // Heartbeat handles heartbeat requests
 func (h *SessionHandler) Heartbeat(c *gin.Context) {
-	sessionID := c.Query("session_id")
-	if sessionID == "" {
-		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "session_id query parameter is required")
+	sessionID, ok := h.sessionIDParam(c)
+	if !ok {
 		return
 	}
 
 	// Verify session exists
-	_, err := h.sessionManager.GetSession(sessionID)
+	sess, err := h.sessionManager.GetSession(sessionID)
 	if err != nil {
 		response.RespondWithError(c, http.StatusNotFound, response.ErrSessionNotFound, "The specified session does not exist or has expired")
 		return
 	}
 
+	// Measured before this heartbeat counts as activity
+	idleWarning := h.idleWarning(sess)
+
+	// Clients heartbeat every 30s; anything near the limit indicates a misbehaving client
+	if allowed, retryAfter := h.heartbeats.Allow(sessionID); !allowed {
+		logger.Get().Warn().
+			Str("session_id", sessionID).
+			Int("max_heartbeats_per_minute", h.config.MaxHeartbeatsPerMinute).
+			Msg("Heartbeat rate limit exceeded")
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		response.RespondWithError(c, http.StatusTooManyRequests, response.ErrRateLimited, "Too many heartbeats for this session")
+		return
+	}
+
 	// Update activity timestamp
 	if err := h.sessionManager.UpdateActivity(sessionID); err != nil {
 		response.RespondWithError(c, http.StatusInternalServerError, response.ErrInternalServer, "Failed to update session activity")
@@ -199,7 +570,7 @@ func (h *SessionHandler) Heartbeat(c *gin.Context) {
 	}
 
 	// Get updated session to return new timestamp
-	sess, err := h.sessionManager.GetSession(sessionID)
+	sess, err = h.sessionManager.GetSession(sessionID)
 	if err != nil {
 		// Unlikely since we just updated it, but handle anyway
 		response.RespondWithError(c, http.StatusInternalServerError, response.ErrInternalServer, "Failed to retrieve updated session")
@@ -211,25 +582,37 @@ func (h *SessionHandler) Heartbeat(c *gin.Context) {
 		Msg("Heartbeat received")
 
 	response := HeartbeatResponse{
-		Message:      "Heartbeat received",
-		SessionID:    sessionID,
-		LastActivity: sess.LastActivity,
+		Message:          "Heartbeat received",
+		SessionID:        h.publicSessionID(sessionID),
+		LastActivity:     sess.LastActivity,
+		LongConversation: sess.LongConversation,
+		IdleWarning:      idleWarning,
 	}
 
-	c.JSON(http.StatusOK, response)
+	respondWithData(h.config, c, http.StatusOK, response)
 }
 
 // End handles session end requests
 func (h *SessionHandler) End(c *gin.Context) {
-	sessionID := c.Query("session_id")
-	if sessionID == "" {
-		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "session_id query parameter is required")
+	sessionID, ok := h.sessionIDParam(c)
+	if !ok {
 		return
 	}
 
 	// Verify session exists
-	_, err := h.sessionManager.GetSession(sessionID)
+	sess, err := h.sessionManager.GetSession(sessionID)
 	if err != nil {
+		// Clients that retry end on timeout can opt into treating a missing session as already ended
+		if h.config.IdempotentEnd {
+			logger.Get().Debug().
+				Str("session_id", sessionID).
+				Msg("End requested for nonexistent session, treating as already ended")
+			respondWithData(h.config, c, http.StatusOK, EndSessionResponse{
+				Message:   "Session already ended",
+				SessionID: h.publicSessionID(sessionID),
+			})
+			return
+		}
 		response.RespondWithError(c, http.StatusNotFound, response.ErrSessionNotFound, "The specified session does not exist or has expired")
 		return
 	}
@@ -239,6 +622,7 @@ func (h *SessionHandler) End(c *gin.Context) {
 		response.RespondWithError(c, http.StatusInternalServerError, response.ErrInternalServer, "Failed to end session")
 		return
 	}
+	h.heartbeats.Forget(sessionID)
 
 	logger.Get().Info().
 		Str("session_id", sessionID).
@@ -246,8 +630,57 @@ func (h *SessionHandler) End(c *gin.Context) {
 
 	response := EndSessionResponse{
 		Message:   "Session ended successfully",
-		SessionID: sessionID,
+		SessionID: h.publicSessionID(sessionID),
+	}
+	if h.config.SessionUsageSummary {
+		usage := session.Usage(sess, time.Now(), session.EndReasonEnded)
+		response.Usage = &usage
+	}
+
+	respondWithData(h.config, c, http.StatusOK, response)
+}
+
+// UpdateCursorChat handles requests to re-point a session at a different cursor chat ID
+func (h *SessionHandler) UpdateCursorChat(c *gin.Context) {
+	sessionID, ok := h.sessionIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateCursorChatRequest
+	if err := bindJSON(h.config, c, &req); err != nil {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, bindErrorDetails(err, "Invalid request body: missing or malformed cursor_chat_id field"))
+		return
+	}
+
+	if !cursorChatIDPattern.MatchString(req.CursorChatID) {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "cursor_chat_id must be 1-128 letters, digits, '-' or '_' starting with a letter or digit")
+		return
+	}
+
+	// Verify session exists
+	sess, err := h.sessionManager.GetSession(sessionID)
+	if err != nil {
+		response.RespondWithError(c, http.StatusNotFound, response.ErrSessionNotFound, "The specified session does not exist or has expired")
+		return
+	}
+
+	if err := h.sessionManager.UpdateCursorChatID(sessionID, req.CursorChatID); err != nil {
+		response.RespondWithError(c, http.StatusInternalServerError, response.ErrInternalServer, "Failed to update cursor chat ID")
+		return
+	}
+
+	logger.Get().Info().
+		Str("session_id", sessionID).
+		Str("previous_cursor_chat_id", sess.CursorChatID).
+		Str("cursor_chat_id", req.CursorChatID).
+		Msg("Cursor chat ID updated")
+
+	response := UpdateCursorChatResponse{
+		Message:      "Cursor chat ID updated successfully",
+		SessionID:    h.publicSessionID(sessionID),
+		CursorChatID: req.CursorChatID,
 	}
 
-	c.JSON(http.StatusOK, response)
+	respondWithData(h.config, c, http.StatusOK, response)
 }
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/logger"
+	"github.com/sean/janus/internal/repocontext"
+)
+
+// SessionContextResponse is the project context available to a session's workspace
+type SessionContextResponse struct {
+	SessionID   string                `json:"session_id"`
+	RecentDays  int                   `json:"recent_days"`
+	RecentFiles []string              `json:"recent_files"`
+	Summaries   []repocontext.Summary `json:"summaries"`
+}
+
+// gatherContext collects the workspace's recent git changes (GitRecentDays) and
+// newest conversation summaries from ContextDir (MaxContextSummaries)
+func (h *SessionHandler) gatherContext(ctx context.Context) (*repocontext.Context, error) {
+	return repocontext.Gather(ctx, repocontext.Options{
+		GitPath:      h.config.GitPath,
+		WorkspaceDir: h.config.WorkspaceDir,
+		ContextDir:   h.config.ContextDir,
+		RecentDays:   h.config.GitRecentDays,
+		MaxSummaries: h.config.MaxContextSummaries,
+	})
+}
+
+// withProjectContext prepends the gathered recent files and summaries to the question
+func withProjectContext(question string, gathered *repocontext.Context) string {
+	if len(gathered.RecentFiles) == 0 && len(gathered.Summaries) == 0 {
+		return question
+	}
+
+	var b strings.Builder
+	if len(gathered.RecentFiles) > 0 {
+		b.WriteString("Recently changed files:")
+		for _, file := range gathered.RecentFiles {
+			b.WriteString("\n- ")
+			b.WriteString(file)
+		}
+		b.WriteString("\n\n")
+	}
+	for _, summary := range gathered.Summaries {
+		b.WriteString("Previous conversation summary (")
+		b.WriteString(summary.Name)
+		b.WriteString("):\n")
+		b.WriteString(strings.TrimSpace(summary.Content))
+		b.WriteString("\n\n")
+	}
+	b.WriteString("Question: ")
+	b.WriteString(question)
+	return b.String()
+}
+
+// ProjectContext handles requests for the git and summary context of a session's workspace
+func (h *SessionHandler) ProjectContext(c *gin.Context) {
+	sessionID, ok := h.sessionIDParam(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.sessionManager.GetSession(sessionID); err != nil {
+		response.RespondWithError(c, http.StatusNotFound, response.ErrSessionNotFound, "The specified session does not exist or has expired")
+		return
+	}
+
+	gathered, err := h.gatherContext(c.Request.Context())
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error().
+			Str("session_id", sessionID).
+			Str("workspace_dir", h.config.WorkspaceDir).
+			Err(err).
+			Msg("Failed to gather project context")
+		response.RespondWithError(c, http.StatusInternalServerError, response.ErrInternalServer, "Failed to gather project context")
+		return
+	}
+
+	respondWithData(h.config, c, http.StatusOK, SessionContextResponse{
+		SessionID:   h.publicSessionID(sessionID),
+		RecentDays:  h.config.GitRecentDays,
+		RecentFiles: gathered.RecentFiles,
+		Summaries:   gathered.Summaries,
+	})
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/repocontext"
+)
+
+func TestSessionProjectContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockManager := NewMockSessionManager()
+	sess, _ := mockManager.CreateSession()
+
+	contextDir := t.TempDir()
+	summariesDir := filepath.Join(contextDir, repocontext.SummariesDir)
+	if err := os.MkdirAll(summariesDir, 0755); err != nil {
+		t.Fatalf("failed to create summaries dir: %v", err)
+	}
+	for _, name := range []string{"2025-01-01-09-00.md", "2025-01-02-09-00.md"} {
+		if err := os.WriteFile(filepath.Join(summariesDir, name), []byte("summary"), 0644); err != nil {
+			t.Fatalf("failed to write summary: %v", err)
+		}
+	}
+
+	cfg := newTestConfig()
+	cfg.GitPath = writeFakeScript(t, "git", "printf 'api/main.go\\nREADME.md\\napi/main.go\\n'\n")
+	cfg.ContextDir = contextDir
+	cfg.GitRecentDays = 3
+	cfg.MaxContextSummaries = 1
+	handler := NewSessionHandler(mockManager, cfg)
+
+	get := func(sessionID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/session/context?session_id="+sessionID, nil)
+		handler.ProjectContext(c)
+		return w
+	}
+
+	t.Run("returns recent files and newest summaries", func(t *testing.T) {
+		w := get(sess.ID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response SessionContextResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if len(response.RecentFiles) != 2 || response.RecentFiles[0] != "api/main.go" {
+			t.Errorf("unexpected recent files: %v", response.RecentFiles)
+		}
+		if len(response.Summaries) != 1 || response.Summaries[0].Name != "2025-01-02-09-00.md" {
+			t.Errorf("unexpected summaries: %+v", response.Summaries)
+		}
+		if response.RecentDays != 3 {
+			t.Errorf("expected recent_days 3, got %d", response.RecentDays)
+		}
+	})
+
+	t.Run("unknown session returns 404", func(t *testing.T) {
+		if w := get("missing"); w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("git failure returns 500", func(t *testing.T) {
+		cfg.GitPath = writeFakeScript(t, "git", "exit 128\n")
+		if w := get(sess.ID); w.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", w.Code)
+		}
+	})
+}
+
+func TestAsk_IncludeContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	contextDir := t.TempDir()
+	summariesDir := filepath.Join(contextDir, repocontext.SummariesDir)
+	if err := os.MkdirAll(summariesDir, 0755); err != nil {
+		t.Fatalf("failed to create summaries dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(summariesDir, "2025-01-02-09-00.md"), []byte("Discussed the router"), 0644); err != nil {
+		t.Fatalf("failed to write summary: %v", err)
+	}
+
+	newHandler := func(gitScript string) (*SessionHandler, *MockSessionManager, string, *string) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		var prompt string
+		mockManager.askQuestionFunc = func(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
+			prompt = question
+			return "answer", "chat-1", nil
+		}
+		cfg := newTestConfig()
+		cfg.GitPath = writeFakeScript(t, "git", gitScript)
+		cfg.ContextDir = contextDir
+		cfg.GitRecentDays = 3
+		cfg.MaxContextSummaries = 3
+		return NewSessionHandler(mockManager, cfg), mockManager, sess.ID, &prompt
+	}
+
+	ask := func(handler *SessionHandler, sessionID string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask?session_id="+sessionID, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+		return w
+	}
+
+	t.Run("prepends context and logs the raw question", func(t *testing.T) {
+		handler, mockManager, sessionID, prompt := newHandler("printf 'api/main.go\\n'\n")
+
+		if w := ask(handler, sessionID, `{"question":"What changed?","include_context":true}`); w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		for _, want := range []string{"Recently changed files:\n- api/main.go", "Discussed the router", "Question: What changed?"} {
+			if !strings.Contains(*prompt, want) {
+				t.Errorf("expected prompt to contain %q, got %q", want, *prompt)
+			}
+		}
+		if logged := mockManager.sessions[sessionID].ConversationLog[0].Content; logged != "What changed?" {
+			t.Errorf("expected raw question in the conversation log, got %q", logged)
+		}
+	})
+
+	t.Run("context is not gathered without the flag", func(t *testing.T) {
+		handler, _, sessionID, prompt := newHandler("printf 'api/main.go\\n'\n")
+
+		ask(handler, sessionID, `{"question":"What changed?"}`)
+
+		if *prompt != "What changed?" {
+			t.Errorf("expected the bare question, got %q", *prompt)
+		}
+	})
+
+	t.Run("git failure asks without context", func(t *testing.T) {
+		handler, _, sessionID, prompt := newHandler("exit 128\n")
+
+		if w := ask(handler, sessionID, `{"question":"What changed?","include_context":true}`); w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if *prompt != "What changed?" {
+			t.Errorf("expected the bare question, got %q", *prompt)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/session"
+)
+
+const (
+	// ConversationFormatJSON returns the conversation log as JSON (the default)
+	ConversationFormatJSON = "json"
+	// ConversationFormatMarkdown renders the log with a heading per message
+	ConversationFormatMarkdown = "markdown"
+	// ConversationFormatText renders the log as plain "Role: message" paragraphs
+	ConversationFormatText = "text"
+)
+
+// SessionConversationResponse is a session's full conversation log
+type SessionConversationResponse struct {
+	SessionID string            `json:"session_id"`
+	Messages  []session.Message `json:"messages"`
+}
+
+// Conversation handles requests for a session's conversation log as JSON,
+// markdown, or plain text, selected by the format query parameter
+func (h *SessionHandler) Conversation(c *gin.Context) {
+	sessionID, ok := h.sessionIDParam(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", ConversationFormatJSON)
+	if format != ConversationFormatJSON && format != ConversationFormatMarkdown && format != ConversationFormatText {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "format must be 'json', 'markdown', or 'text'")
+		return
+	}
+
+	sess, err := h.sessionManager.GetSession(sessionID)
+	if err != nil {
+		response.RespondWithError(c, http.StatusNotFound, response.ErrSessionNotFound, "The specified session does not exist or has expired")
+		return
+	}
+
+	switch format {
+	case ConversationFormatMarkdown:
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(renderConversationMarkdown(sess.ConversationLog)))
+	case ConversationFormatText:
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(renderConversationText(sess.ConversationLog)))
+	default:
+		respondWithData(h.config, c, http.StatusOK, SessionConversationResponse{
+			SessionID: h.publicSessionID(sessionID),
+			Messages:  sess.ConversationLog,
+		})
+	}
+}
+
+// roleLabel capitalizes a message role for display
+func roleLabel(role string) string {
+	if role == "" {
+		return role
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+// renderConversationMarkdown renders each message under a "## Role" heading
+// with its timestamp, separated by horizontal rules
+func renderConversationMarkdown(messages []session.Message) string {
+	var b strings.Builder
+	for i, msg := range messages {
+		if i > 0 {
+			b.WriteString("\n---\n\n")
+		}
+		b.WriteString("## ")
+		b.WriteString(roleLabel(msg.Role))
+		b.WriteString("\n\n_")
+		b.WriteString(msg.Timestamp.UTC().Format(time.RFC3339))
+		b.WriteString("_\n\n")
+		b.WriteString(strings.TrimSpace(msg.Content))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// renderConversationText renders each message as a "Role: content" paragraph
+func renderConversationText(messages []session.Message) string {
+	var b strings.Builder
+	for i, msg := range messages {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(roleLabel(msg.Role))
+		b.WriteString(": ")
+		b.WriteString(strings.TrimSpace(msg.Content))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
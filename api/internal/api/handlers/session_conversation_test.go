@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/session"
+)
+
+func TestSessionConversation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockManager := NewMockSessionManager()
+	sess, _ := mockManager.CreateSession()
+	at := time.Date(2025, 1, 2, 9, 30, 0, 0, time.UTC)
+	mockManager.AddToConversationLog(sess.ID, []session.Message{
+		{Role: "user", Content: "Where is the router?", Timestamp: at},
+		{Role: "assistant", Content: "In `internal/api/router.go`.\n", Timestamp: at.Add(time.Second)},
+	})
+	handler := NewSessionHandler(mockManager, newTestConfig())
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/session/conversation?session_id="+sess.ID+query, nil)
+		handler.Conversation(c)
+		return w
+	}
+
+	t.Run("json by default", func(t *testing.T) {
+		w := get("")
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var response SessionConversationResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if len(response.Messages) != 2 {
+			t.Errorf("expected 2 messages, got %d", len(response.Messages))
+		}
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		w := get("&format=markdown")
+
+		if ct := w.Header().Get("Content-Type"); ct != "text/markdown; charset=utf-8" {
+			t.Errorf("unexpected content type: %q", ct)
+		}
+		want := "## User\n\n_2025-01-02T09:30:00Z_\n\nWhere is the router?\n" +
+			"\n---\n\n" +
+			"## Assistant\n\n_2025-01-02T09:30:01Z_\n\nIn `internal/api/router.go`.\n"
+		if w.Body.String() != want {
+			t.Errorf("unexpected markdown:\n%s\nwant:\n%s", w.Body.String(), want)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		w := get("&format=text")
+
+		if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+			t.Errorf("unexpected content type: %q", ct)
+		}
+		want := "User: Where is the router?\n\nAssistant: In `internal/api/router.go`.\n"
+		if w.Body.String() != want {
+			t.Errorf("unexpected text:\n%q\nwant:\n%q", w.Body.String(), want)
+		}
+	})
+
+	t.Run("unknown format returns 400", func(t *testing.T) {
+		if w := get("&format=pdf"); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("unknown session returns 404", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/session/conversation?session_id=missing&format=text", nil)
+		handler.Conversation(c)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+}
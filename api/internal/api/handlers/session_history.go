@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/session"
+)
+
+// History handles requests for a session's conversation log as JSON. The
+// optional since (RFC 3339) keeps only messages after that time, and limit keeps
+// only the last N of those.
+func (h *SessionHandler) History(c *gin.Context) {
+	sessionID, ok := h.sessionIDParam(c)
+	if !ok {
+		return
+	}
+
+	var since time.Time
+	if raw := c.Query("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "since must be an RFC 3339 timestamp")
+			return
+		}
+		since = parsed
+	}
+
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "limit must be a positive integer")
+			return
+		}
+		limit = parsed
+	}
+
+	sess, err := h.sessionManager.GetSession(sessionID)
+	if err != nil {
+		response.RespondWithError(c, http.StatusNotFound, response.ErrSessionNotFound, "The specified session does not exist or has expired")
+		return
+	}
+
+	respondWithData(h.config, c, http.StatusOK, SessionConversationResponse{
+		SessionID: h.publicSessionID(sessionID),
+		Messages:  filterHistory(sess.ConversationLog, since, limit),
+	})
+}
+
+// filterHistory keeps messages after since (when set), then the last limit of
+// them (when positive). The result is never nil so it encodes as an empty list.
+func filterHistory(messages []session.Message, since time.Time, limit int) []session.Message {
+	filtered := make([]session.Message, 0, len(messages))
+	for _, msg := range messages {
+		if since.IsZero() || msg.Timestamp.After(since) {
+			filtered = append(filtered, msg)
+		}
+	}
+
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[len(filtered)-limit:]
+	}
+	return filtered
+}
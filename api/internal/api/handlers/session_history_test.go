@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/session"
+)
+
+func TestSessionHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockManager := NewMockSessionManager()
+	sess, _ := mockManager.CreateSession()
+	at := time.Date(2025, 1, 2, 9, 30, 0, 0, time.UTC)
+	mockManager.AddToConversationLog(sess.ID, []session.Message{
+		{Role: "user", Content: "first question", Timestamp: at},
+		{Role: "assistant", Content: "first answer", Timestamp: at.Add(time.Second)},
+		{Role: "user", Content: "second question", Timestamp: at.Add(time.Minute)},
+		{Role: "assistant", Content: "second answer", Timestamp: at.Add(time.Minute + time.Second)},
+	})
+	handler := NewSessionHandler(mockManager, newTestConfig())
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/session/history"+query, nil)
+		handler.History(c)
+		return w
+	}
+
+	contents := func(t *testing.T, w *httptest.ResponseRecorder) []string {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response SessionConversationResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if response.SessionID != sess.ID {
+			t.Errorf("expected session ID %s, got %s", sess.ID, response.SessionID)
+		}
+		var got []string
+		for _, msg := range response.Messages {
+			got = append(got, msg.Content)
+		}
+		return got
+	}
+
+	t.Run("returns the full log", func(t *testing.T) {
+		got := contents(t, get("?session_id="+sess.ID))
+
+		if len(got) != 4 || got[0] != "first question" || got[3] != "second answer" {
+			t.Errorf("expected all 4 messages in order, got %v", got)
+		}
+	})
+
+	t.Run("limit returns the last N messages", func(t *testing.T) {
+		got := contents(t, get("?session_id="+sess.ID+"&limit=2"))
+
+		if len(got) != 2 || got[0] != "second question" || got[1] != "second answer" {
+			t.Errorf("expected the last 2 messages, got %v", got)
+		}
+	})
+
+	t.Run("since keeps only later messages", func(t *testing.T) {
+		got := contents(t, get("?session_id="+sess.ID+"&since=2025-01-02T09:30:01Z"))
+
+		if len(got) != 2 || got[0] != "second question" {
+			t.Errorf("expected messages after the first exchange, got %v", got)
+		}
+	})
+
+	t.Run("since and limit combine", func(t *testing.T) {
+		got := contents(t, get("?session_id="+sess.ID+"&since=2025-01-02T09:00:00Z&limit=1"))
+
+		if len(got) != 1 || got[0] != "second answer" {
+			t.Errorf("expected only the newest message, got %v", got)
+		}
+	})
+
+	t.Run("missing session_id returns 400", func(t *testing.T) {
+		if w := get(""); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("invalid limit or since returns 400", func(t *testing.T) {
+		for _, query := range []string{"&limit=0", "&limit=abc", "&since=yesterday"} {
+			if w := get("?session_id=" + sess.ID + query); w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400 for %q, got %d", query, w.Code)
+			}
+		}
+	})
+
+	t.Run("unknown session returns 404", func(t *testing.T) {
+		if w := get("?session_id=missing"); w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+)
+
+const (
+	// sessionIDSignatureSeparator joins a session ID to its signature
+	sessionIDSignatureSeparator = "."
+	// sessionIDSignatureBytes is how much of the HMAC is kept in a signed ID
+	sessionIDSignatureBytes = 16
+)
+
+// signSessionID appends a truncated HMAC-SHA256 of id, keyed by secret
+func signSessionID(secret string, id string) string {
+	return id + sessionIDSignatureSeparator + sessionIDSignature(secret, id)
+}
+
+// verifySessionID checks a signed ID and returns the bare session ID it carries
+func verifySessionID(secret string, signed string) (string, bool) {
+	id, signature, found := strings.Cut(signed, sessionIDSignatureSeparator)
+	if !found || id == "" {
+		return "", false
+	}
+	if !hmac.Equal([]byte(signature), []byte(sessionIDSignature(secret, id))) {
+		return "", false
+	}
+	return id, true
+}
+
+// sessionIDSignature returns the hex-encoded signature for id
+func sessionIDSignature(secret string, id string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil)[:sessionIDSignatureBytes])
+}
+
+// publicSessionID returns the session ID as clients see it, signed when SignSessionIDs is enabled
+func (h *SessionHandler) publicSessionID(id string) string {
+	if !h.config.SignSessionIDs {
+		return id
+	}
+	return signSessionID(h.config.SessionIDSecret, id)
+}
+
+// sessionIDParam reads the required session_id query parameter and, with
+// SignSessionIDs enabled, verifies its signature. It returns the bare session ID
+// for lookups, or writes a 400 and returns false.
+func (h *SessionHandler) sessionIDParam(c *gin.Context) (string, bool) {
+	sessionID := c.Query("session_id")
+	if sessionID == "" {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "session_id query parameter is required")
+		return "", false
+	}
+
+	if !h.config.SignSessionIDs {
+		return sessionID, true
+	}
+
+	id, ok := verifySessionID(h.config.SessionIDSecret, sessionID)
+	if !ok {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidSessionID, "session_id signature is missing or invalid")
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+)
+
+// Sources of a session's answer webhook in SessionInfoResponse
+const (
+	AnswerWebhookSession = "session"
+	AnswerWebhookServer  = "server"
+)
+
+// DefaultModelName reports that cursor-agent picks its own default model
+const DefaultModelName = "default"
+
+// SessionInfoResponse is a session's effective configuration: its own
+// overrides merged with the server defaults for everything it didn't set
+type SessionInfoResponse struct {
+	SessionID      string   `json:"session_id"`
+	CursorChatID   string   `json:"cursor_chat_id,omitempty"`
+	WorkspaceDir   string   `json:"workspace_dir"`
+	Branch         string   `json:"branch,omitempty"`
+	TimeoutMinutes int      `json:"timeout_minutes"`
+	Voice          string   `json:"voice"`
+	Model          string   `json:"model"`
+	FallbackModels []string `json:"fallback_models,omitempty"`
+	AutoTTS        bool     `json:"auto_tts"`
+	Ephemeral      bool     `json:"ephemeral"`
+	// AnswerWebhook says where answers are posted from: "session", "server", or omitted for nowhere
+	AnswerWebhook string `json:"answer_webhook,omitempty"`
+	// Overrides names the settings the session set itself rather than inheriting
+	Overrides []string `json:"overrides"`
+}
+
+// Info handles requests for a session's effective configuration, to help
+// diagnose why one session behaves differently from another
+func (h *SessionHandler) Info(c *gin.Context) {
+	sessionID, ok := h.sessionIDParam(c)
+	if !ok {
+		return
+	}
+
+	sess, err := h.sessionManager.GetSession(sessionID)
+	if err != nil {
+		response.RespondWithError(c, http.StatusNotFound, response.ErrSessionNotFound, "The specified session does not exist or has expired")
+		return
+	}
+
+	info := SessionInfoResponse{
+		SessionID:      h.publicSessionID(sessionID),
+		CursorChatID:   sess.CursorChatID,
+		WorkspaceDir:   h.config.WorkspaceDir,
+		Branch:         sess.Branch,
+		TimeoutMinutes: h.config.SessionTimeoutMinutes,
+		Voice:          h.config.KokoroTTSVoice,
+		Model:          DefaultModelName,
+		FallbackModels: h.config.CursorAgentFallbackModels,
+		AutoTTS:        sess.AutoTTS,
+		Ephemeral:      sess.Ephemeral,
+		Overrides:      make([]string, 0),
+	}
+
+	if sess.Branch != "" {
+		info.Overrides = append(info.Overrides, "branch")
+	}
+	if sess.Timeout > 0 {
+		info.TimeoutMinutes = int(sess.Timeout.Minutes())
+		info.Overrides = append(info.Overrides, "timeout_minutes")
+	}
+	if sess.AutoTTS {
+		info.Overrides = append(info.Overrides, "auto_tts")
+	}
+	if sess.Ephemeral {
+		info.Overrides = append(info.Overrides, "ephemeral")
+	}
+	switch {
+	case h.hasSessionWebhook(sess):
+		info.AnswerWebhook = AnswerWebhookSession
+		info.Overrides = append(info.Overrides, "answer_webhook")
+	case h.config.AnswerWebhookURL != "":
+		info.AnswerWebhook = AnswerWebhookServer
+	}
+
+	respondWithData(h.config, c, http.StatusOK, info)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/session"
+)
+
+func TestSessionInfo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := newTestConfig()
+	cfg.SessionTimeoutMinutes = 10
+	cfg.KokoroTTSVoice = "af_sky"
+	cfg.CursorAgentFallbackModels = []string{"gpt-5"}
+	cfg.AnswerWebhookURL = "https://hooks.example.com/global"
+	cfg.AnswerWebhookHosts = []string{"hooks.example.com"}
+
+	info := func(t *testing.T, manager *MockSessionManager, sessionID string) SessionInfoResponse {
+		t.Helper()
+		handler := NewSessionHandler(manager, cfg)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/session/info?session_id="+sessionID, nil)
+		handler.Info(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response SessionInfoResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("defaults fill a session without overrides", func(t *testing.T) {
+		manager := NewMockSessionManager()
+		sess, _ := manager.CreateSession()
+
+		got := info(t, manager, sess.ID)
+
+		if got.SessionID != sess.ID || got.WorkspaceDir != cfg.WorkspaceDir {
+			t.Errorf("unexpected identity or workspace: %+v", got)
+		}
+		if got.TimeoutMinutes != 10 || got.Voice != "af_sky" || got.Model != DefaultModelName {
+			t.Errorf("expected server defaults, got %+v", got)
+		}
+		if len(got.FallbackModels) != 1 || got.FallbackModels[0] != "gpt-5" {
+			t.Errorf("expected fallback models from config, got %v", got.FallbackModels)
+		}
+		if got.AnswerWebhook != AnswerWebhookServer {
+			t.Errorf("expected the server webhook, got %q", got.AnswerWebhook)
+		}
+		if len(got.Overrides) != 0 {
+			t.Errorf("expected no overrides, got %v", got.Overrides)
+		}
+	})
+
+	t.Run("overrides are reflected", func(t *testing.T) {
+		manager := NewMockSessionManager()
+		sess, _ := manager.CreateSessionWithOptions(session.SessionOptions{
+			AutoTTS:          true,
+			Branch:           "feature/x",
+			Timeout:          45 * time.Minute,
+			AnswerWebhookURL: "https://hooks.example.com/mine",
+		})
+
+		got := info(t, manager, sess.ID)
+
+		if got.Branch != "feature/x" || got.TimeoutMinutes != 45 || !got.AutoTTS {
+			t.Errorf("expected session overrides, got %+v", got)
+		}
+		if got.AnswerWebhook != AnswerWebhookSession {
+			t.Errorf("expected the session webhook, got %q", got.AnswerWebhook)
+		}
+		if got.Voice != "af_sky" {
+			t.Errorf("expected the default voice to fill in, got %q", got.Voice)
+		}
+		want := []string{"branch", "timeout_minutes", "auto_tts", "answer_webhook"}
+		if len(got.Overrides) != len(want) {
+			t.Fatalf("expected overrides %v, got %v", want, got.Overrides)
+		}
+		for i := range want {
+			if got.Overrides[i] != want[i] {
+				t.Errorf("expected overrides %v, got %v", want, got.Overrides)
+				break
+			}
+		}
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		handler := NewSessionHandler(NewMockSessionManager(), cfg)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/session/info?session_id=missing", nil)
+		handler.Info(c)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+)
+
+// SearchMatch is a conversation message matching a transcript search
+type SearchMatch struct {
+	// Index is the message's position in the session's conversation log
+	Index     int       `json:"index"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SessionSearchResponse lists the messages in a session matching a query
+type SessionSearchResponse struct {
+	SessionID string        `json:"session_id"`
+	Query     string        `json:"query"`
+	Matches   []SearchMatch `json:"matches"`
+}
+
+// Search handles requests to find messages in a session's conversation log
+// containing q (case-insensitive), optionally limited to one role
+func (h *SessionHandler) Search(c *gin.Context) {
+	sessionID, ok := h.sessionIDParam(c)
+	if !ok {
+		return
+	}
+
+	query := c.Query("q")
+	if strings.TrimSpace(query) == "" {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "q query parameter is required")
+		return
+	}
+
+	role := c.Query("role")
+	if role != "" && role != "user" && role != "assistant" {
+		response.RespondWithError(c, http.StatusBadRequest, response.ErrInvalidRequest, "role must be 'user' or 'assistant'")
+		return
+	}
+
+	sess, err := h.sessionManager.GetSession(sessionID)
+	if err != nil {
+		response.RespondWithError(c, http.StatusNotFound, response.ErrSessionNotFound, "The specified session does not exist or has expired")
+		return
+	}
+
+	needle := strings.ToLower(query)
+	matches := make([]SearchMatch, 0)
+	for i, msg := range sess.ConversationLog {
+		if role != "" && msg.Role != role {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(msg.Content), needle) {
+			continue
+		}
+		matches = append(matches, SearchMatch{
+			Index:     i,
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Timestamp: msg.Timestamp,
+		})
+	}
+
+	respondWithData(h.config, c, http.StatusOK, SessionSearchResponse{
+		SessionID: h.publicSessionID(sessionID),
+		Query:     query,
+		Matches:   matches,
+	})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/session"
+)
+
+func TestSessionSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockManager := NewMockSessionManager()
+	sess, _ := mockManager.CreateSession()
+	now := time.Now()
+	mockManager.AddToConversationLog(sess.ID, []session.Message{
+		{Role: "user", Content: "Where is the Config loaded?", Timestamp: now},
+		{Role: "assistant", Content: "config.Load reads the environment", Timestamp: now},
+		{Role: "user", Content: "What about the router?", Timestamp: now},
+		{Role: "assistant", Content: "NewRouter wires middleware", Timestamp: now},
+	})
+	handler := NewSessionHandler(mockManager, newTestConfig())
+
+	search := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", fmt.Sprintf("/api/session/search?session_id=%s&%s", sess.ID, query), nil)
+		handler.Search(c)
+		return w
+	}
+
+	results := func(t *testing.T, w *httptest.ResponseRecorder) SessionSearchResponse {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response SessionSearchResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("matches case-insensitively with indices", func(t *testing.T) {
+		response := results(t, search("q=CONFIG"))
+
+		if len(response.Matches) != 2 {
+			t.Fatalf("expected 2 matches, got %+v", response.Matches)
+		}
+		if response.Matches[0].Index != 0 || response.Matches[1].Index != 1 {
+			t.Errorf("expected indices 0 and 1, got %d and %d", response.Matches[0].Index, response.Matches[1].Index)
+		}
+		if response.Query != "CONFIG" {
+			t.Errorf("expected query echoed back, got %q", response.Query)
+		}
+	})
+
+	t.Run("returns an empty list when nothing matches", func(t *testing.T) {
+		response := results(t, search("q="+url.QueryEscape("database schema")))
+
+		if response.Matches == nil || len(response.Matches) != 0 {
+			t.Errorf("expected empty matches, got %+v", response.Matches)
+		}
+	})
+
+	t.Run("filters by role", func(t *testing.T) {
+		response := results(t, search("q=the&role=assistant"))
+
+		if len(response.Matches) != 1 || response.Matches[0].Index != 1 || response.Matches[0].Role != "assistant" {
+			t.Errorf("expected only the assistant match at index 1, got %+v", response.Matches)
+		}
+	})
+
+	t.Run("rejects a missing query or unknown role", func(t *testing.T) {
+		if w := search("q="); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for a missing query, got %d", w.Code)
+		}
+		if w := search("q=config&role=system"); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for an unknown role, got %d", w.Code)
+		}
+	})
+
+	t.Run("returns 404 for an unknown session", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/session/search?session_id=missing&q=config", nil)
+		handler.Search(c)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+}
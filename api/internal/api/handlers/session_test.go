@@ -7,13 +7,48 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/logger"
 	"github.com/sean/janus/internal/session"
 )
 
+// captureLogs redirects the global logger to a JSON buffer for the duration of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	previous := logger.Logger
+	var buf bytes.Buffer
+	logger.InitJSON("debug", &buf)
+	t.Cleanup(func() {
+		logger.Logger = previous
+	})
+	return &buf
+}
+
+// findLogEntry returns the first JSON log entry with the given message
+func findLogEntry(t *testing.T, logs *bytes.Buffer, message string) map[string]interface{} {
+	t.Helper()
+	for _, line := range bytes.Split(logs.Bytes(), []byte("\n")) {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+		if entry["message"] == message {
+			return entry
+		}
+	}
+	t.Fatalf("no log entry with message %q in:\n%s", message, logs.String())
+	return nil
+}
+
 // MockSessionManager implements session.Manager for testing
 type MockSessionManager struct {
 	sessions                map[string]*session.Session
@@ -22,10 +57,19 @@ type MockSessionManager struct {
 	updateActivityError     error
 	updateCursorChatIDError error
 	askQuestionFunc         func(ctx context.Context, id string, question string, workspaceDir string) (string, string, error)
+	lastAskMode             string
 	addToLogError           error
 	endSessionError         error
 }
 
+// newTestConfig returns a minimal handler config for tests
+func newTestConfig() *config.Config {
+	return &config.Config{
+		WorkspaceDir: "/tmp/test-workspace",
+		RoutePrefix:  config.DefaultRoutePrefix,
+	}
+}
+
 func NewMockSessionManager() *MockSessionManager {
 	return &MockSessionManager{
 		sessions: make(map[string]*session.Session),
@@ -33,14 +77,23 @@ func NewMockSessionManager() *MockSessionManager {
 }
 
 func (m *MockSessionManager) CreateSession() (*session.Session, error) {
+	return m.CreateSessionWithOptions(session.SessionOptions{})
+}
+
+func (m *MockSessionManager) CreateSessionWithOptions(opts session.SessionOptions) (*session.Session, error) {
 	if m.createSessionError != nil {
 		return nil, m.createSessionError
 	}
 	sess := &session.Session{
-		ID:              fmt.Sprintf("test-session-%d", len(m.sessions)+1),
-		CreatedAt:       time.Now(),
-		LastActivity:    time.Now(),
-		ConversationLog: make([]session.Message, 0),
+		ID:               fmt.Sprintf("test-session-%d", len(m.sessions)+1),
+		CreatedAt:        time.Now(),
+		LastActivity:     time.Now(),
+		ConversationLog:  make([]session.Message, 0),
+		AutoTTS:          opts.AutoTTS,
+		Branch:           opts.Branch,
+		Timeout:          opts.Timeout,
+		Ephemeral:        opts.Ephemeral,
+		AnswerWebhookURL: opts.AnswerWebhookURL,
 	}
 	m.sessions[sess.ID] = sess
 	return sess, nil
@@ -81,20 +134,27 @@ func (m *MockSessionManager) UpdateCursorChatID(id string, cursorChatID string)
 	return nil
 }
 
-func (m *MockSessionManager) AskQuestion(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
+func (m *MockSessionManager) AskQuestion(ctx context.Context, id string, question string, workspaceDir string) (string, string, bool, error) {
+	sess, exists := m.sessions[id]
+	resumed := exists && sess.CursorChatID != ""
 	if m.askQuestionFunc != nil {
-		return m.askQuestionFunc(ctx, id, question, workspaceDir)
+		answer, cursorChatID, err := m.askQuestionFunc(ctx, id, question, workspaceDir)
+		return answer, cursorChatID, resumed, err
 	}
-	sess, exists := m.sessions[id]
 	if !exists {
-		return "", "", fmt.Errorf("session not found: %s", id)
+		return "", "", false, fmt.Errorf("session not found: %s", id)
 	}
 	// Default mock answer - use existing cursor chat ID or generate one
 	cursorChatID := sess.CursorChatID
 	if cursorChatID == "" {
 		cursorChatID = "mock-cursor-chat-" + id
 	}
-	return "Mock cursor-agent response to: " + question, cursorChatID, nil
+	return "Mock cursor-agent response to: " + question, cursorChatID, resumed, nil
+}
+
+func (m *MockSessionManager) AskQuestionInMode(ctx context.Context, id string, mode string, question string, workspaceDir string) (string, string, bool, error) {
+	m.lastAskMode = mode
+	return m.AskQuestion(ctx, id, question, workspaceDir)
 }
 
 func (m *MockSessionManager) AddToConversationLog(id string, messages []session.Message) error {
@@ -109,6 +169,20 @@ func (m *MockSessionManager) AddToConversationLog(id string, messages []session.
 	return nil
 }
 
+func (m *MockSessionManager) GetOrCreateByKey(externalKey string) (*session.Session, bool, error) {
+	for _, sess := range m.sessions {
+		if sess.ExternalKey == externalKey {
+			return sess, false, nil
+		}
+	}
+	sess, err := m.CreateSession()
+	if err != nil {
+		return nil, false, err
+	}
+	sess.ExternalKey = externalKey
+	return sess, true, nil
+}
+
 func (m *MockSessionManager) EndSession(id string) error {
 	if m.endSessionError != nil {
 		return m.endSessionError
@@ -128,6 +202,14 @@ func (m *MockSessionManager) GetAllSessions() []*session.Session {
 	return sessions
 }
 
+func (m *MockSessionManager) GetAllSessionsShallow() []*session.Session {
+	sessions := make([]*session.Session, 0, len(m.sessions))
+	for _, sess := range m.sessions {
+		sessions = append(sessions, sess.CloneShallow())
+	}
+	return sessions
+}
+
 func (m *MockSessionManager) CleanupInactiveSessions(timeout time.Duration) {
 	now := time.Now()
 	for id, sess := range m.sessions {
@@ -142,7 +224,7 @@ func TestStartSession(t *testing.T) {
 
 	t.Run("creates session successfully", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -168,10 +250,93 @@ func TestStartSession(t *testing.T) {
 		}
 	})
 
+	t.Run("stores auto_tts from request body", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/session/start", bytes.NewBufferString(`{"auto_tts":true}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Start(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var response StartSessionResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if !response.AutoTTS {
+			t.Error("expected auto_tts in response")
+		}
+		sess, _ := mockManager.GetSession(response.SessionID)
+		if !sess.AutoTTS {
+			t.Error("expected session to be created with AutoTTS")
+		}
+	})
+
+	t.Run("stores timeout_minutes clamped to the maximum", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.MaxSessionTimeoutMinutes = 120
+
+		for body, want := range map[string]int{
+			`{"timeout_minutes":2}`:   2,
+			`{"timeout_minutes":600}`: 120,
+			`{}`:                      0,
+		} {
+			mockManager := NewMockSessionManager()
+			handler := NewSessionHandler(mockManager, cfg)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/api/session/start", bytes.NewBufferString(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.Start(c)
+
+			var response StartSessionResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			sess, _ := mockManager.GetSession(response.SessionID)
+			if response.TimeoutMinutes != want || sess.Timeout != time.Duration(want)*time.Minute {
+				t.Errorf("%s: expected timeout of %d minutes, got response %d and session %v", body, want, response.TimeoutMinutes, sess.Timeout)
+			}
+		}
+	})
+
+	t.Run("returns 400 for negative timeout_minutes", func(t *testing.T) {
+		handler := NewSessionHandler(NewMockSessionManager(), newTestConfig())
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/session/start", bytes.NewBufferString(`{"timeout_minutes":-5}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Start(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("returns 400 for malformed body", func(t *testing.T) {
+		handler := NewSessionHandler(NewMockSessionManager(), newTestConfig())
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/session/start", bytes.NewBufferString(`{"auto_tts":`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Start(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
 	t.Run("returns error when session creation fails", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
 		mockManager.createSessionError = fmt.Errorf("database connection failed")
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -203,7 +368,7 @@ func TestAsk(t *testing.T) {
 
 	t.Run("returns 400 when session_id is missing", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -219,7 +384,7 @@ func TestAsk(t *testing.T) {
 	t.Run("returns 400 when request body is invalid", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
 		sess, _ := mockManager.CreateSession()
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		body := bytes.NewBufferString(`{"invalid":"json"}`)
 		w := httptest.NewRecorder()
@@ -236,7 +401,7 @@ func TestAsk(t *testing.T) {
 
 	t.Run("returns 404 when session not found", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		body := bytes.NewBufferString(`{"question":"test"}`)
 		w := httptest.NewRecorder()
@@ -255,7 +420,7 @@ func TestAsk(t *testing.T) {
 		mockManager := NewMockSessionManager()
 		sess, _ := mockManager.CreateSession()
 
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		body := bytes.NewBufferString(`{"question":"What is this codebase?"}`)
 		recorder := httptest.NewRecorder()
@@ -283,6 +448,53 @@ func TestAsk(t *testing.T) {
 		}
 	})
 
+	t.Run("includes trace_id in logs and response", func(t *testing.T) {
+		logs := captureLogs(t)
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		body := bytes.NewBufferString(`{"question":"test","trace_id":"trace-abc-123"}`)
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/ask?session_id=%s", sess.ID), body)
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Ask(c)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", recorder.Code)
+		}
+		var response AskResponse
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		if response.TraceID != "trace-abc-123" {
+			t.Errorf("expected trace_id in response, got %q", response.TraceID)
+		}
+
+		entry := findLogEntry(t, logs, "Question processed successfully")
+		if entry["trace_id"] != "trace-abc-123" {
+			t.Errorf("expected trace_id in log entry, got %v", entry["trace_id"])
+		}
+	})
+
+	t.Run("rejects invalid trace_id", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		body := bytes.NewBufferString(`{"question":"test","trace_id":"bad trace"}`)
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/ask?session_id=%s", sess.ID), body)
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Ask(c)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", recorder.Code)
+		}
+	})
+
 	t.Run("handles cursor-agent error", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
 		sess, _ := mockManager.CreateSession()
@@ -292,7 +504,7 @@ func TestAsk(t *testing.T) {
 			return "", "", fmt.Errorf("cursor-agent command failed")
 		}
 
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		body := bytes.NewBufferString(`{"question":"test"}`)
 		recorder := httptest.NewRecorder()
@@ -306,6 +518,28 @@ func TestAsk(t *testing.T) {
 			t.Errorf("expected status 500, got %d", recorder.Code)
 		}
 	})
+
+	t.Run("returns 409 when the session is busy", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		mockManager.askQuestionFunc = func(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
+			return "", "", session.ErrSessionBusy
+		}
+
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		body := bytes.NewBufferString(`{"question":"test"}`)
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/ask?session_id=%s", sess.ID), body)
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Ask(c)
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("expected status 409, got %d", recorder.Code)
+		}
+	})
 }
 
 func TestHeartbeat(t *testing.T) {
@@ -313,7 +547,7 @@ func TestHeartbeat(t *testing.T) {
 
 	t.Run("returns 400 when session_id is missing", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -328,7 +562,7 @@ func TestHeartbeat(t *testing.T) {
 
 	t.Run("returns 404 when session not found", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -344,7 +578,7 @@ func TestHeartbeat(t *testing.T) {
 	t.Run("updates activity for valid session", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
 		sess, _ := mockManager.CreateSession()
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -378,7 +612,7 @@ func TestHeartbeat(t *testing.T) {
 	t.Run("timestamp updates on subsequent heartbeats", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
 		sess, _ := mockManager.CreateSession()
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		// First heartbeat
 		w1 := httptest.NewRecorder()
@@ -414,7 +648,7 @@ func TestEnd(t *testing.T) {
 
 	t.Run("returns 400 when session_id is missing", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -429,7 +663,7 @@ func TestEnd(t *testing.T) {
 
 	t.Run("returns 404 when session not found", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -445,7 +679,7 @@ func TestEnd(t *testing.T) {
 	t.Run("ends session successfully", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
 		sess, _ := mockManager.CreateSession()
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -481,7 +715,7 @@ func TestEnd(t *testing.T) {
 	t.Run("ending session twice returns 404 second time", func(t *testing.T) {
 		mockManager := NewMockSessionManager()
 		sess, _ := mockManager.CreateSession()
-		handler := NewSessionHandler(mockManager, "/tmp/test-workspace")
+		handler := NewSessionHandler(mockManager, newTestConfig())
 
 		// End session first time
 		w1 := httptest.NewRecorder()
@@ -518,3 +752,887 @@ func TestEnd(t *testing.T) {
 		}
 	})
 }
+
+func TestUpdateCursorChat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRequest := func(url string, body string) (*httptest.ResponseRecorder, *gin.Context) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", url, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		return w, c
+	}
+
+	t.Run("updates cursor chat ID and returns new state", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		w, c := newRequest(fmt.Sprintf("/api/session/cursor-chat?session_id=%s", sess.ID), `{"cursor_chat_id":"0f8c2b8e-1d2a-4c7e-9f00-1234567890ab"}`)
+		handler.UpdateCursorChat(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+
+		var response UpdateCursorChatResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if response.SessionID != sess.ID {
+			t.Errorf("expected session_id %s, got %s", sess.ID, response.SessionID)
+		}
+		if response.CursorChatID != "0f8c2b8e-1d2a-4c7e-9f00-1234567890ab" {
+			t.Errorf("unexpected cursor_chat_id: %s", response.CursorChatID)
+		}
+
+		updated, _ := mockManager.GetSession(sess.ID)
+		if updated.CursorChatID != "0f8c2b8e-1d2a-4c7e-9f00-1234567890ab" {
+			t.Errorf("expected manager to store new cursor chat ID, got %s", updated.CursorChatID)
+		}
+	})
+
+	t.Run("returns 400 when session_id is missing", func(t *testing.T) {
+		handler := NewSessionHandler(NewMockSessionManager(), newTestConfig())
+
+		w, c := newRequest("/api/session/cursor-chat", `{"cursor_chat_id":"abc"}`)
+		handler.UpdateCursorChat(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("returns 404 when session not found", func(t *testing.T) {
+		handler := NewSessionHandler(NewMockSessionManager(), newTestConfig())
+
+		w, c := newRequest("/api/session/cursor-chat?session_id=non-existent", `{"cursor_chat_id":"abc"}`)
+		handler.UpdateCursorChat(c)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("rejects malformed cursor chat IDs", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		for _, body := range []string{`{}`, `{"cursor_chat_id":"--help"}`, `{"cursor_chat_id":"a b"}`, `{"cursor_chat_id":"../etc"}`} {
+			w, c := newRequest(fmt.Sprintf("/api/session/cursor-chat?session_id=%s", sess.ID), body)
+			handler.UpdateCursorChat(c)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("body %s: expected status 400, got %d", body, w.Code)
+			}
+		}
+
+		unchanged, _ := mockManager.GetSession(sess.ID)
+		if unchanged.CursorChatID != "" {
+			t.Errorf("invalid cursor chat ID was stored: %s", unchanged.CursorChatID)
+		}
+	})
+}
+
+func TestAsk_AutoTTS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// kokoroConfig returns a config whose kokoro paths point at real files so TTS reports available
+	kokoroConfig := func(t *testing.T) *config.Config {
+		dir := t.TempDir()
+		cfg := newTestConfig()
+		cfg.KokoroTTSPath = filepath.Join(dir, "kokoro-tts")
+		cfg.KokoroTTSModelPath = filepath.Join(dir, "model.onnx")
+		cfg.KokoroTTSVoicesPath = filepath.Join(dir, "voices.bin")
+		cfg.KokoroTTSVoice = "af_sarah"
+		for _, path := range []string{cfg.KokoroTTSPath, cfg.KokoroTTSModelPath, cfg.KokoroTTSVoicesPath} {
+			os.WriteFile(path, []byte("stub"), 0755)
+		}
+		return cfg
+	}
+
+	ask := func(t *testing.T, handler *SessionHandler, sessionID string) AskResponse {
+		body := bytes.NewBufferString(`{"question":"test"}`)
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/ask?session_id=%s", sessionID), body)
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Ask(c)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", recorder.Code)
+		}
+		var response AskResponse
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return response
+	}
+
+	t.Run("includes TTS info when AutoTTS is set", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSessionWithOptions(session.SessionOptions{AutoTTS: true})
+		handler := NewSessionHandler(mockManager, kokoroConfig(t))
+
+		response := ask(t, handler, sess.ID)
+
+		if response.TTS == nil {
+			t.Fatal("expected tts info in response")
+		}
+		if response.TTS.Endpoint != "/api/tts" || response.TTS.Method != "POST" {
+			t.Errorf("unexpected tts endpoint: %+v", response.TTS)
+		}
+		if response.TTS.Voice != "af_sarah" {
+			t.Errorf("expected voice af_sarah, got %q", response.TTS.Voice)
+		}
+	})
+
+	t.Run("omits TTS info when AutoTTS is not set", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		handler := NewSessionHandler(mockManager, kokoroConfig(t))
+
+		if response := ask(t, handler, sess.ID); response.TTS != nil {
+			t.Errorf("expected no tts info, got %+v", response.TTS)
+		}
+	})
+
+	t.Run("omits TTS info when no provider is available", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSessionWithOptions(session.SessionOptions{AutoTTS: true})
+		cfg := newTestConfig()
+		cfg.KokoroTTSPath = filepath.Join(t.TempDir(), "missing-kokoro")
+		handler := NewSessionHandler(mockManager, cfg)
+
+		if response := ask(t, handler, sess.ID); response.TTS != nil {
+			t.Errorf("expected no tts info, got %+v", response.TTS)
+		}
+	})
+}
+
+func TestAsk_Resumed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ask := func(t *testing.T, handler *SessionHandler, sessionID string) AskResponse {
+		body := bytes.NewBufferString(`{"question":"test"}`)
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/ask?session_id=%s", sessionID), body)
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Ask(c)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", recorder.Code)
+		}
+		var response AskResponse
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return response
+	}
+
+	t.Run("first question starts a new chat", func(t *testing.T) {
+		logs := captureLogs(t)
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		if response := ask(t, handler, sess.ID); response.Resumed {
+			t.Error("expected resumed to be false for the first question")
+		}
+
+		entry := findLogEntry(t, logs, "Question processed successfully")
+		if entry["resumed"] != false {
+			t.Errorf("expected resumed=false in log, got %v", entry["resumed"])
+		}
+	})
+
+	t.Run("follow-up question resumes the chat", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		ask(t, handler, sess.ID)
+
+		logs := captureLogs(t)
+		if response := ask(t, handler, sess.ID); !response.Resumed {
+			t.Error("expected resumed to be true for a follow-up question")
+		}
+
+		entry := findLogEntry(t, logs, "Question processed successfully")
+		if entry["resumed"] != true {
+			t.Errorf("expected resumed=true in log, got %v", entry["resumed"])
+		}
+	})
+}
+
+func TestLongConversationFlag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("ask response reports a long conversation", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		mockManager.sessions[sess.ID].LongConversation = true
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		body := bytes.NewBufferString(`{"question":"test"}`)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/ask?session_id=%s", sess.ID), body)
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+
+		var response AskResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if !response.LongConversation {
+			t.Error("expected long_conversation to be true")
+		}
+	})
+
+	t.Run("heartbeat response reflects the flag", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		heartbeat := func() HeartbeatResponse {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/heartbeat?session_id=%s", sess.ID), nil)
+			handler.Heartbeat(c)
+
+			var response HeartbeatResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			return response
+		}
+
+		if heartbeat().LongConversation {
+			t.Error("expected long_conversation to be false for a new session")
+		}
+
+		mockManager.sessions[sess.ID].LongConversation = true
+		if !heartbeat().LongConversation {
+			t.Error("expected long_conversation to be true once flagged")
+		}
+	})
+}
+
+func TestEnd_UsageSummary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	end := func(t *testing.T, enabled bool) EndSessionResponse {
+		t.Helper()
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		mockManager.AddToConversationLog(sess.ID, []session.Message{
+			{Role: "user", Content: "first?", Timestamp: time.Now()},
+			{Role: "assistant", Content: "one", Timestamp: time.Now()},
+			{Role: "user", Content: "second?", Timestamp: time.Now()},
+			{Role: "assistant", Content: "three", Timestamp: time.Now()},
+		})
+		cfg := newTestConfig()
+		cfg.SessionUsageSummary = enabled
+		handler := NewSessionHandler(mockManager, cfg)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/session/end?session_id="+sess.ID, nil)
+		handler.End(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var response EndSessionResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	t.Run("includes the summary when enabled", func(t *testing.T) {
+		usage := end(t, true).Usage
+		if usage == nil {
+			t.Fatal("expected a usage summary")
+		}
+		if usage.Questions != 2 || usage.AnswerCharacters != 8 || usage.EndReason != session.EndReasonEnded {
+			t.Errorf("unexpected usage summary: %+v", usage)
+		}
+		if usage.DurationSeconds < 0 {
+			t.Errorf("expected a non-negative duration, got %v", usage.DurationSeconds)
+		}
+	})
+
+	t.Run("omitted by default", func(t *testing.T) {
+		if usage := end(t, false).Usage; usage != nil {
+			t.Errorf("expected no usage summary, got %+v", usage)
+		}
+	})
+}
+
+func TestEnd_IdempotentEnd(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	endTwice := func(t *testing.T, idempotent bool) (first, second *httptest.ResponseRecorder) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		cfg := newTestConfig()
+		cfg.IdempotentEnd = idempotent
+		handler := NewSessionHandler(mockManager, cfg)
+
+		end := func() *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/session/end?session_id=%s", sess.ID), nil)
+			handler.End(c)
+			return w
+		}
+		return end(), end()
+	}
+
+	t.Run("repeat end returns 404 by default", func(t *testing.T) {
+		first, second := endTwice(t, false)
+		if first.Code != http.StatusOK {
+			t.Errorf("expected first end to return 200, got %d", first.Code)
+		}
+		if second.Code != http.StatusNotFound {
+			t.Errorf("expected repeat end to return 404, got %d", second.Code)
+		}
+	})
+
+	t.Run("repeat end returns 200 when idempotent", func(t *testing.T) {
+		first, second := endTwice(t, true)
+		if first.Code != http.StatusOK {
+			t.Errorf("expected first end to return 200, got %d", first.Code)
+		}
+		if second.Code != http.StatusOK {
+			t.Fatalf("expected repeat end to return 200, got %d", second.Code)
+		}
+
+		var response EndSessionResponse
+		json.Unmarshal(second.Body.Bytes(), &response)
+		if response.Message != "Session already ended" {
+			t.Errorf("unexpected message: %q", response.Message)
+		}
+	})
+}
+
+func TestAsk_EnvelopeResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ask := func(t *testing.T, envelope bool) map[string]interface{} {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		cfg := newTestConfig()
+		cfg.EnvelopeResponses = envelope
+		handler := NewSessionHandler(mockManager, cfg)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/ask?session_id=%s", sess.ID), bytes.NewBufferString(`{"question":"test"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("request_id", "req-123")
+		handler.Ask(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return body
+	}
+
+	t.Run("unwrapped by default", func(t *testing.T) {
+		body := ask(t, false)
+
+		if _, wrapped := body["data"]; wrapped {
+			t.Error("expected no envelope")
+		}
+		if body["answer"] == nil || body["session_id"] == nil {
+			t.Errorf("expected ask fields at top level, got %v", body)
+		}
+	})
+
+	t.Run("wrapped in envelope when enabled", func(t *testing.T) {
+		body := ask(t, true)
+
+		data, ok := body["data"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("expected data object, got %v", body)
+		}
+		if data["answer"] == nil || data["session_id"] == nil {
+			t.Errorf("expected ask fields inside data, got %v", data)
+		}
+		if body["request_id"] != "req-123" {
+			t.Errorf("expected request_id req-123, got %v", body["request_id"])
+		}
+		if _, err := time.Parse(time.RFC3339, fmt.Sprint(body["timestamp"])); err != nil {
+			t.Errorf("expected RFC3339 timestamp, got %v", body["timestamp"])
+		}
+		if _, leaked := body["answer"]; leaked {
+			t.Error("expected ask fields only inside data")
+		}
+	})
+}
+
+func TestSignedSessionIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newSigningHandler := func(sign bool) (*SessionHandler, *MockSessionManager) {
+		mockManager := NewMockSessionManager()
+		cfg := newTestConfig()
+		cfg.SignSessionIDs = sign
+		cfg.SessionIDSecret = "test-secret"
+		return NewSessionHandler(mockManager, cfg), mockManager
+	}
+
+	heartbeat := func(handler *SessionHandler, sessionID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/heartbeat?session_id="+url.QueryEscape(sessionID), nil)
+		handler.Heartbeat(c)
+		return w
+	}
+
+	t.Run("start issues a signed ID that is accepted", func(t *testing.T) {
+		handler, mockManager := newSigningHandler(true)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/session/start", nil)
+		handler.Start(c)
+
+		var started StartSessionResponse
+		json.Unmarshal(w.Body.Bytes(), &started)
+		sessions := mockManager.GetAllSessions()
+		if len(sessions) != 1 {
+			t.Fatalf("expected 1 session, got %d", len(sessions))
+		}
+		if started.SessionID == sessions[0].ID || !strings.HasPrefix(started.SessionID, sessions[0].ID+".") {
+			t.Fatalf("expected signed session ID, got %q", started.SessionID)
+		}
+
+		hb := heartbeat(handler, started.SessionID)
+		if hb.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", hb.Code, hb.Body.String())
+		}
+		var response HeartbeatResponse
+		json.Unmarshal(hb.Body.Bytes(), &response)
+		if response.SessionID != started.SessionID {
+			t.Errorf("expected signed ID echoed back, got %q", response.SessionID)
+		}
+	})
+
+	t.Run("rejects a tampered ID with 400", func(t *testing.T) {
+		handler, mockManager := newSigningHandler(true)
+		sess, _ := mockManager.CreateSession()
+		other, _ := mockManager.CreateSession()
+
+		// Another session's signature must not validate this session's ID
+		signed := signSessionID("test-secret", other.ID)
+		tampered := sess.ID + signed[strings.Index(signed, "."):]
+
+		if w := heartbeat(handler, tampered); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+		if w := heartbeat(handler, signSessionID("wrong-secret", sess.ID)); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for foreign signature, got %d", w.Code)
+		}
+	})
+
+	t.Run("rejects an unsigned ID when signing is enabled", func(t *testing.T) {
+		handler, mockManager := newSigningHandler(true)
+		sess, _ := mockManager.CreateSession()
+
+		if w := heartbeat(handler, sess.ID); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("accepts an unsigned ID when signing is disabled", func(t *testing.T) {
+		handler, mockManager := newSigningHandler(false)
+		sess, _ := mockManager.CreateSession()
+
+		w := heartbeat(handler, sess.ID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var response HeartbeatResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.SessionID != sess.ID {
+			t.Errorf("expected unsigned ID echoed back, got %q", response.SessionID)
+		}
+	})
+}
+
+func TestStartSession_MemoryCritical(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	start := func(handler *SessionHandler) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/session/start", nil)
+		handler.Start(c)
+		return w
+	}
+
+	t.Run("rejects new sessions and sweeps inactive ones under pressure, then recovers", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		stale, _ := mockManager.CreateSession()
+		stale.LastActivity = time.Now().Add(-20 * time.Minute)
+		fresh, _ := mockManager.CreateSession()
+
+		cfg := newTestConfig()
+		cfg.SessionTimeoutMinutes = 10
+		cfg.MemoryCriticalMB = 512
+		handler := NewSessionHandler(mockManager, cfg)
+		memoryMB := 600.0
+		handler.memory.readMemoryMB = func() float64 { return memoryMB }
+
+		if w := start(handler); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status 429 under memory pressure, got %d", w.Code)
+		}
+		handler.memory.sweeping.Wait()
+		if _, err := mockManager.GetSession(stale.ID); err == nil {
+			t.Error("expected inactive session to be swept")
+		}
+		if _, err := mockManager.GetSession(fresh.ID); err != nil {
+			t.Error("expected active session to survive the sweep")
+		}
+
+		memoryMB = 100
+		if w := start(handler); w.Code != http.StatusOK {
+			t.Errorf("expected status 200 after memory recovers, got %d", w.Code)
+		}
+	})
+
+	t.Run("sweeps sessions the session timeout would still keep", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		idle, _ := mockManager.CreateSession()
+		idle.LastActivity = time.Now().Add(-5 * time.Minute)
+		fresh, _ := mockManager.CreateSession()
+
+		cfg := newTestConfig()
+		cfg.SessionTimeoutMinutes = 10
+		cfg.MemoryCriticalMB = 512
+		cfg.MemoryPressureIdleCutoff = 2 * time.Minute
+		handler := NewSessionHandler(mockManager, cfg)
+		handler.memory.readMemoryMB = func() float64 { return 600 }
+
+		start(handler)
+		handler.memory.sweeping.Wait()
+		if _, err := mockManager.GetSession(idle.ID); err == nil {
+			t.Error("expected a session idle past the pressure cutoff to be swept")
+		}
+		if _, err := mockManager.GetSession(fresh.ID); err != nil {
+			t.Error("expected active session to survive the sweep")
+		}
+	})
+
+	t.Run("disabled when MemoryCriticalMB is zero", func(t *testing.T) {
+		handler := NewSessionHandler(NewMockSessionManager(), newTestConfig())
+		handler.memory.readMemoryMB = func() float64 { return 1 << 20 }
+
+		if w := start(handler); w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+	})
+}
+
+func TestEnsureSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ensure := func(handler *SessionHandler, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/session/ensure", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ensure(c)
+		return w
+	}
+
+	t.Run("creates then returns the same session for a key", func(t *testing.T) {
+		handler := NewSessionHandler(NewMockSessionManager(), newTestConfig())
+
+		var first, second EnsureSessionResponse
+		w := ensure(handler, `{"external_key":"device-abc"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		json.Unmarshal(w.Body.Bytes(), &first)
+		json.Unmarshal(ensure(handler, `{"external_key":"device-abc"}`).Body.Bytes(), &second)
+
+		if !first.Created {
+			t.Error("expected first ensure to report created")
+		}
+		if second.Created {
+			t.Error("expected second ensure to report existing session")
+		}
+		if first.SessionID == "" || first.SessionID != second.SessionID {
+			t.Errorf("expected the same session, got %q and %q", first.SessionID, second.SessionID)
+		}
+	})
+
+	t.Run("returns 400 for a missing or invalid key", func(t *testing.T) {
+		handler := NewSessionHandler(NewMockSessionManager(), newTestConfig())
+
+		if w := ensure(handler, `{}`); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for missing key, got %d", w.Code)
+		}
+		if w := ensure(handler, `{"external_key":"has spaces"}`); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for invalid key, got %d", w.Code)
+		}
+	})
+}
+
+func TestAsk_Mode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ask := func(handler *SessionHandler, sessionID string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/ask?session_id=%s", sessionID), bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+		return recorder
+	}
+
+	newHandler := func() (*SessionHandler, *MockSessionManager, string) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		cfg := newTestConfig()
+		cfg.CursorAgentModes = map[string][]string{"summarize": {"summarize", "--brief"}}
+		return NewSessionHandler(mockManager, cfg), mockManager, sess.ID
+	}
+
+	t.Run("configured mode is passed to the manager", func(t *testing.T) {
+		handler, mockManager, sessionID := newHandler()
+
+		recorder := ask(handler, sessionID, `{"question":"the last hour","mode":"summarize"}`)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", recorder.Code)
+		}
+		if mockManager.lastAskMode != "summarize" {
+			t.Errorf("expected summarize mode, got %q", mockManager.lastAskMode)
+		}
+	})
+
+	t.Run("unknown mode returns 400", func(t *testing.T) {
+		handler, mockManager, sessionID := newHandler()
+
+		recorder := ask(handler, sessionID, `{"question":"the last hour","mode":"deploy"}`)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", recorder.Code)
+		}
+		if mockManager.lastAskMode != "" {
+			t.Errorf("expected no ask for an unknown mode, got %q", mockManager.lastAskMode)
+		}
+	})
+}
+
+func TestIdleWarning(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	heartbeat := func(handler *SessionHandler, sessionID string) HeartbeatResponse {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/heartbeat?session_id=%s", sessionID), nil)
+		handler.Heartbeat(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var response HeartbeatResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	ask := func(handler *SessionHandler, sessionID string) AskResponse {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/ask?session_id=%s", sessionID), bytes.NewBufferString(`{"question":"test"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var response AskResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	newHandler := func() (*SessionHandler, *session.Session) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		cfg := newTestConfig()
+		cfg.IdleWarningThreshold = time.Minute
+		return NewSessionHandler(mockManager, cfg), sess
+	}
+
+	t.Run("heartbeat flags idle time past the threshold and clears after activity", func(t *testing.T) {
+		handler, sess := newHandler()
+
+		if heartbeat(handler, sess.ID).IdleWarning {
+			t.Error("expected no warning for a recently active session")
+		}
+
+		sess.LastActivity = time.Now().Add(-2 * time.Minute)
+		if !heartbeat(handler, sess.ID).IdleWarning {
+			t.Error("expected a warning once idle time crossed the threshold")
+		}
+
+		if heartbeat(handler, sess.ID).IdleWarning {
+			t.Error("expected the warning to clear after activity")
+		}
+	})
+
+	t.Run("ask flags idle time past the threshold and clears after activity", func(t *testing.T) {
+		handler, sess := newHandler()
+
+		sess.LastActivity = time.Now().Add(-2 * time.Minute)
+		if !ask(handler, sess.ID).IdleWarning {
+			t.Error("expected a warning once idle time crossed the threshold")
+		}
+
+		if ask(handler, sess.ID).IdleWarning {
+			t.Error("expected the warning to clear after activity")
+		}
+	})
+
+	t.Run("threshold scales with a session's own timeout", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		cfg := newTestConfig()
+		cfg.SessionTimeoutMinutes = 30
+		cfg.IdleWarningThreshold = 25 * time.Minute
+		handler := NewSessionHandler(mockManager, cfg)
+
+		// 25 of 30 minutes becomes 5 of 6, so the warning comes before expiry
+		short, _ := mockManager.CreateSessionWithOptions(session.SessionOptions{Timeout: 6 * time.Minute})
+		short.LastActivity = time.Now().Add(-5*time.Minute - time.Second)
+		if !heartbeat(handler, short.ID).IdleWarning {
+			t.Error("expected a warning before a short session expires")
+		}
+
+		// 25 of 30 minutes becomes 100 of 120, so an hour idle is not yet near expiry
+		long, _ := mockManager.CreateSessionWithOptions(session.SessionOptions{Timeout: 2 * time.Hour})
+		long.LastActivity = time.Now().Add(-time.Hour)
+		if heartbeat(handler, long.ID).IdleWarning {
+			t.Error("expected no warning far from a long session's expiry")
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		sess.LastActivity = time.Now().Add(-time.Hour)
+		if heartbeat(handler, sess.ID).IdleWarning {
+			t.Error("expected no warning without a threshold")
+		}
+	})
+}
+
+func TestAsk_Ephemeral(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ask := func(handler *SessionHandler, sessionID string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask?session_id="+sessionID, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+		return w
+	}
+
+	assertAnsweredButNotLogged := func(t *testing.T, mockManager *MockSessionManager, sessionID string, w *httptest.ResponseRecorder) {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response AskResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Answer == "" {
+			t.Error("expected an answer for an ephemeral ask")
+		}
+		if log := mockManager.sessions[sessionID].ConversationLog; len(log) != 0 {
+			t.Errorf("expected an empty conversation log, got %+v", log)
+		}
+	}
+
+	t.Run("ephemeral ask is answered but not logged", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		w := ask(handler, sess.ID, `{"question":"private question","ephemeral":true}`)
+
+		assertAnsweredButNotLogged(t, mockManager, sess.ID, w)
+	})
+
+	t.Run("every ask in an ephemeral session is unlogged", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSessionWithOptions(session.SessionOptions{Ephemeral: true})
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		w := ask(handler, sess.ID, `{"question":"private question"}`)
+
+		assertAnsweredButNotLogged(t, mockManager, sess.ID, w)
+	})
+
+	t.Run("regular asks are still logged", func(t *testing.T) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		handler := NewSessionHandler(mockManager, newTestConfig())
+
+		ask(handler, sess.ID, `{"question":"public question"}`)
+
+		if len(mockManager.sessions[sess.ID].ConversationLog) != 2 {
+			t.Error("expected the exchange to be logged")
+		}
+	})
+}
+
+func TestAsk_CursorAgentErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := newTestConfig()
+	cfg.CursorErrorStatuses = map[string]int{"rate_limit": 429, "auth": 401, "quota": 402}
+
+	cases := []struct {
+		subtype string
+		status  int
+	}{
+		{"rate_limit", http.StatusTooManyRequests},
+		{"auth", http.StatusUnauthorized},
+		{"quota", http.StatusPaymentRequired},
+		{"error_during_execution", http.StatusInternalServerError},
+		{"", http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run("subtype "+tc.subtype, func(t *testing.T) {
+			mockManager := NewMockSessionManager()
+			sess, _ := mockManager.CreateSession()
+			mockManager.askQuestionFunc = func(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
+				return "", "", &session.CursorAgentError{Subtype: tc.subtype, Message: "cursor-agent failed"}
+			}
+			handler := NewSessionHandler(mockManager, cfg)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest("POST", fmt.Sprintf("/api/ask?session_id=%s", sess.ID), bytes.NewBufferString(`{"question":"test"}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.Ask(c)
+
+			if recorder.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, recorder.Code)
+			}
+			var resp response.ErrorResponse
+			json.Unmarshal(recorder.Body.Bytes(), &resp)
+			if resp.Error != response.ErrCursorAgent || resp.Subtype != tc.subtype || resp.Details != "cursor-agent failed" {
+				t.Errorf("expected a structured cursor-agent error, got %+v", resp)
+			}
+		})
+	}
+}
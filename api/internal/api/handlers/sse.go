@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/logger"
+)
+
+const (
+	// sseKeepaliveComment is an SSE comment line ignored by clients but seen by proxies
+	sseKeepaliveComment = ": keepalive\n\n"
+	// sseBufferFrames is how many frames may wait for a slow client before it is dropped
+	sseBufferFrames = 32
+)
+
+// errSSESlowClient is returned when a client can't keep up with the stream
+var errSSESlowClient = errors.New("SSE client too slow")
+
+// sseOptions tunes how a stream is served
+type sseOptions struct {
+	// keepaliveInterval is how long the stream may idle before a keepalive (0 disables)
+	keepaliveInterval time.Duration
+	// slowClientTimeout is how long a full buffer may wait on the client before
+	// the connection is dropped (0 waits indefinitely)
+	slowClientTimeout time.Duration
+	// cancel stops the upstream producer when a slow client is dropped
+	cancel context.CancelFunc
+}
+
+// newSSEOptions builds stream options from configuration
+func newSSEOptions(cfg *config.Config, cancel context.CancelFunc) sseOptions {
+	return sseOptions{
+		keepaliveInterval: cfg.SSEKeepaliveInterval,
+		slowClientTimeout: cfg.SSESlowClientTimeout,
+		cancel:            cancel,
+	}
+}
+
+// writeSSEHeaders prepares the response for a Server-Sent Events stream
+func writeSSEHeaders(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Disable response buffering in nginx-style reverse proxies
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+}
+
+// encodeSSEEvent encodes a single JSON data event
+func encodeSSEEvent(payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SSE event: %w", err)
+	}
+	return []byte(fmt.Sprintf("data: %s\n\n", data)), nil
+}
+
+// sseWriter writes frames to the client from its own goroutine through a
+// bounded buffer, so a slow client fills the buffer instead of blocking the producer
+type sseWriter struct {
+	c      *gin.Context
+	frames chan []byte
+	done   chan struct{}
+	// err is the write error that stopped the writer, set before done is closed
+	err error
+}
+
+// newSSEWriter starts a writer for the response with room for size pending frames
+func newSSEWriter(c *gin.Context, size int) *sseWriter {
+	w := &sseWriter{
+		c:      c,
+		frames: make(chan []byte, size),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// run writes and flushes queued frames until the queue is closed or a write fails
+func (w *sseWriter) run() {
+	defer close(w.done)
+	for frame := range w.frames {
+		if _, err := w.c.Writer.Write(frame); err != nil {
+			w.err = err
+			return
+		}
+		w.c.Writer.Flush()
+	}
+}
+
+// send queues a frame, waiting up to timeout for buffer space (forever when
+// timeout is 0). It returns errSSESlowClient if the buffer stays full.
+func (w *sseWriter) send(ctx context.Context, frame []byte, timeout time.Duration) error {
+	select {
+	case w.frames <- frame:
+		return nil
+	case <-w.done:
+		return w.err
+	default:
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case w.frames <- frame:
+		return nil
+	case <-w.done:
+		return w.err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-expired:
+		return errSSESlowClient
+	}
+}
+
+// abort fails any write blocked on the client so the writer can exit and the
+// connection is closed once the handler returns
+func (w *sseWriter) abort() {
+	http.NewResponseController(w.c.Writer).SetWriteDeadline(time.Now())
+}
+
+// close stops accepting frames and waits for queued ones to be written
+func (w *sseWriter) close() {
+	close(w.frames)
+	<-w.done
+}
+
+// streamSSE forwards events from the channel to the client until it is closed
+// or the request context ends. While the stream is idle (e.g. cursor-agent is
+// still thinking before the first token) a keepalive comment is written every
+// keepaliveInterval so proxies don't close the connection. If the client lets
+// the per-connection buffer stay full for slowClientTimeout, the upstream is
+// cancelled, the connection is dropped, and errSSESlowClient is returned.
+func streamSSE(c *gin.Context, events <-chan interface{}, opts sseOptions) error {
+	ctx := c.Request.Context()
+
+	// Streams outlive the server's WriteTimeout; slow clients are handled below instead
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	writeSSEHeaders(c)
+
+	out := newSSEWriter(c, sseBufferFrames)
+	defer out.close()
+
+	var keepalive <-chan time.Time
+	var timer *time.Timer
+	if opts.keepaliveInterval > 0 {
+		timer = time.NewTimer(opts.keepaliveInterval)
+		defer timer.Stop()
+		keepalive = timer.C
+	}
+
+	for {
+		var frame []byte
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-out.done:
+			return out.err
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			var err error
+			if frame, err = encodeSSEEvent(event); err != nil {
+				return err
+			}
+		case <-keepalive:
+			frame = []byte(sseKeepaliveComment)
+		}
+
+		if err := out.send(ctx, frame, opts.slowClientTimeout); err != nil {
+			if errors.Is(err, errSSESlowClient) {
+				logger.FromContext(ctx).Warn().
+					Dur("slow_client_timeout", opts.slowClientTimeout).
+					Int("buffered_frames", sseBufferFrames).
+					Msg("SSE client not keeping up, dropping connection")
+				if opts.cancel != nil {
+					opts.cancel()
+				}
+				out.abort()
+			}
+			return err
+		}
+
+		// Any write resets the idle window
+		if timer != nil {
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(opts.keepaliveInterval)
+		}
+	}
+}
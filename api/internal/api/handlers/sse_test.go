@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamSSE(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("sends keepalives during a stall before real data", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask/stream", nil)
+
+		events := make(chan interface{})
+		go func() {
+			// Simulate cursor-agent thinking before the first token
+			time.Sleep(120 * time.Millisecond)
+			events <- map[string]string{"type": "delta", "content": "hello"}
+			close(events)
+		}()
+
+		if err := streamSSE(c, events, sseOptions{keepaliveInterval: 20 * time.Millisecond}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		body := w.Body.String()
+		firstData := strings.Index(body, "data: ")
+		if firstData < 0 {
+			t.Fatalf("expected a data event, got %q", body)
+		}
+
+		keepalives := strings.Count(body[:firstData], sseKeepaliveComment)
+		if keepalives < 2 {
+			t.Errorf("expected at least 2 keepalives before data, got %d in %q", keepalives, body)
+		}
+		if !strings.Contains(body, `data: {"content":"hello","type":"delta"}`) {
+			t.Errorf("expected delta event in body, got %q", body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("expected text/event-stream content type, got %q", ct)
+		}
+	})
+
+	t.Run("no keepalives when disabled", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask/stream", nil)
+
+		events := make(chan interface{})
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			events <- "done"
+			close(events)
+		}()
+
+		if err := streamSSE(c, events, sseOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if strings.Contains(w.Body.String(), sseKeepaliveComment) {
+			t.Errorf("expected no keepalives, got %q", w.Body.String())
+		}
+	})
+}
+
+func TestStreamSSE_SlowClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	result := make(chan error, 1)
+	upstreamCancelled := make(chan struct{})
+
+	router := gin.New()
+	router.GET("/stream", func(c *gin.Context) {
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+
+		// Stand-in for cursor-agent: produce large chunks until cancelled
+		events := make(chan interface{})
+		chunk := strings.Repeat("x", 64<<10)
+		go func() {
+			for {
+				select {
+				case events <- chunk:
+				case <-ctx.Done():
+					close(upstreamCancelled)
+					return
+				}
+			}
+		}()
+
+		result <- streamSSE(c, events, sseOptions{slowClientTimeout: 100 * time.Millisecond, cancel: cancel})
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// A client that sends the request and never reads the response
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /stream HTTP/1.1\r\nHost: janus\r\n\r\n")
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, errSSESlowClient) {
+			t.Errorf("expected errSSESlowClient, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("stream was never terminated for the slow client")
+	}
+
+	select {
+	case <-upstreamCancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the upstream command to be cancelled")
+	}
+}
+
+func TestStreamSSE_OutlivesWriteTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/stream", func(c *gin.Context) {
+		events := make(chan interface{})
+		go func() {
+			defer close(events)
+			for i := 0; i < 3; i++ {
+				time.Sleep(50 * time.Millisecond)
+				events <- i
+			}
+		}()
+		streamSSE(c, events, sseOptions{})
+	})
+	server := httptest.NewUnstartedServer(router)
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("stream cut off: %v", err)
+	}
+
+	if strings.Count(string(body), "data: ") != 3 {
+		t.Errorf("expected all 3 events despite the write timeout, got %q", body)
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/session"
+)
+
+// StatsHandler handles runtime statistics requests
+type StatsHandler struct {
+	sessionManager session.Manager
+}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler(sessionManager session.Manager) *StatsHandler {
+	return &StatsHandler{
+		sessionManager: sessionManager,
+	}
+}
+
+// StatsResponse represents the runtime statistics response
+type StatsResponse struct {
+	ActiveSessions int                `json:"active_sessions"`
+	Audio          AudioStats         `json:"audio"`
+	LockMetrics    *session.LockStats `json:"lock_metrics,omitempty"`
+}
+
+// Handle processes stats requests
+func (h *StatsHandler) Handle(c *gin.Context) {
+	response := StatsResponse{
+		ActiveSessions: len(h.sessionManager.GetAllSessionsShallow()),
+		Audio:          audioStats(),
+	}
+
+	// Lock metrics are only reported by managers that support instrumentation
+	if provider, ok := h.sessionManager.(session.LockStatsProvider); ok {
+		stats := provider.LockStats()
+		if stats.Enabled {
+			response.LockMetrics = &stats
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/sean/janus/internal/config"
+)
+
+// removeTempFile deletes a per-request temp file. With KeepTempFiles enabled the
+// file is left in place and its path logged so it can be inspected later.
+func removeTempFile(cfg *config.Config, path string, log *zerolog.Logger) {
+	if cfg.KeepTempFiles {
+		log.Info().Str("file", path).Msg("Keeping temp file for debugging")
+		return
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Warn().
+			Err(err).
+			Str("file", path).
+			Msg("Failed to remove temp file")
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/config"
+)
+
+// fakeKokoroScript mimics kokoro-tts by writing a stub WAV to the output path
+const fakeKokoroScript = `echo "RIFF" > "$2"
+`
+
+func TestKeepTempFiles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	transcribe := func(t *testing.T, keep bool) []string {
+		tempDir := t.TempDir()
+		t.Setenv("TMPDIR", tempDir)
+		handler := NewTranscribeHandler(&config.Config{
+			WhisperPath:   writeFakeScript(t, "whisper", fakeWhisperScript),
+			WhisperModel:  "base",
+			KeepTempFiles: keep,
+		})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newAudioUploadRequest(t, "/api/transcribe", []byte("fake audio"))
+		handler.Transcribe(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		files, _ := filepath.Glob(filepath.Join(tempDir, "janus-transcribe", "audio_*"))
+		return files
+	}
+
+	tts := func(t *testing.T, keep bool) []string {
+		tempDir := t.TempDir()
+		t.Setenv("TMPDIR", tempDir)
+		handler := NewTTSHandler(&config.Config{
+			KokoroTTSPath: writeFakeScript(t, "kokoro-tts", fakeKokoroScript),
+			KeepTempFiles: keep,
+		})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/tts", bytes.NewBufferString(`{"text":"hello"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Generate(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		files, _ := filepath.Glob(filepath.Join(tempDir, "janus-tts", "*"))
+		return files
+	}
+
+	t.Run("transcribe retains audio and transcript when enabled", func(t *testing.T) {
+		// The uploaded audio and Whisper's .txt output share the audio_ prefix
+		if files := transcribe(t, true); len(files) != 2 {
+			t.Errorf("expected audio and transcript to be kept, found %v", files)
+		}
+	})
+
+	t.Run("transcribe removes temp files when disabled", func(t *testing.T) {
+		if files := transcribe(t, false); len(files) != 0 {
+			t.Errorf("expected temp files to be removed, found %v", files)
+		}
+	})
+
+	t.Run("tts retains input and output when enabled", func(t *testing.T) {
+		if files := tts(t, true); len(files) != 2 {
+			t.Errorf("expected input and output to be kept, found %v", files)
+		}
+	})
+
+	t.Run("tts removes temp files when disabled", func(t *testing.T) {
+		if files := tts(t, false); len(files) != 0 {
+			t.Errorf("expected temp files to be removed, found %v", files)
+		}
+	})
+}
@@ -1,54 +1,173 @@
 package handlers
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
 	"github.com/sean/janus/internal/config"
 	"github.com/sean/janus/internal/logger"
+	"github.com/sean/janus/internal/metrics"
 )
 
+const (
+	// multipartOverheadBytes allows for form boundaries and fields around the audio part
+	multipartOverheadBytes = 1 << 20
+	// uploadProgressInterval is how often (in bytes) progress is logged while saving an upload
+	uploadProgressInterval = 5 << 20
+	// maxSegmentCandidates caps how many alternatives are returned per segment
+	maxSegmentCandidates = 5
+)
+
+// supportedAudioFormats lists upload extensions Whisper (via ffmpeg) can decode
+var supportedAudioFormats = []string{"webm", "wav", "mp3", "m4a", "mp4", "ogg", "flac"}
+
+// errUploadTooLarge is returned when an upload exceeds MaxUploadBytes
+var errUploadTooLarge = errors.New("upload exceeds maximum size")
+
+// errUnsupportedEncoding is returned for a Content-Encoding other than gzip or identity
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
 // TranscribeHandler handles audio transcription requests
 type TranscribeHandler struct {
 	config *config.Config
+	// cache holds recent results by audio hash (nil when TranscribeCacheTTL is disabled)
+	cache *transcribeCache
 }
 
 // NewTranscribeHandler creates a new transcribe handler
 func NewTranscribeHandler(cfg *config.Config) *TranscribeHandler {
 	return &TranscribeHandler{
 		config: cfg,
+		cache:  newTranscribeCache(cfg.TranscribeCacheTTL),
 	}
 }
 
 // TranscribeResponse represents the transcription response
 type TranscribeResponse struct {
-	Text string `json:"text"`
+	Text       string              `json:"text"`
+	Words      []WordTimestamp     `json:"words,omitempty"`
+	Candidates []SegmentCandidates `json:"candidates,omitempty"`
+	TraceID    string              `json:"trace_id"`
+}
+
+// WordTimestamp is a single transcribed word with its position in the audio (seconds)
+type WordTimestamp struct {
+	Word        string  `json:"word"`
+	Start       float64 `json:"start"`
+	End         float64 `json:"end"`
+	Probability float64 `json:"probability,omitempty"`
+}
+
+// SegmentCandidates lists alternative transcriptions for one segment, best first
+type SegmentCandidates struct {
+	Start        float64     `json:"start"`
+	End          float64     `json:"end"`
+	Alternatives []Candidate `json:"alternatives"`
+}
+
+// Candidate is one possible transcription of a segment. Confidence (0-1) is
+// omitted when Whisper reported nothing to derive it from.
+type Candidate struct {
+	Text       string   `json:"text"`
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// whisperJSONOutput mirrors the fields we use from Whisper's --output_format json file
+type whisperJSONOutput struct {
+	Text     string `json:"text"`
+	Segments []struct {
+		Start      float64         `json:"start"`
+		End        float64         `json:"end"`
+		Text       string          `json:"text"`
+		AvgLogprob *float64        `json:"avg_logprob"`
+		Words      []WordTimestamp `json:"words"`
+		// Alternatives are only present from decoders that keep more than the best beam
+		Alternatives []struct {
+			Text       string   `json:"text"`
+			Confidence *float64 `json:"confidence"`
+			AvgLogprob *float64 `json:"avg_logprob"`
+		} `json:"alternatives"`
+	} `json:"segments"`
 }
 
 // Transcribe processes audio transcription requests
 func (h *TranscribeHandler) Transcribe(c *gin.Context) {
-	log := logger.Get()
+	// Limit and decode the body before anything (e.g. the trace_id form lookup) parses it
+	maxUpload := h.config.MaxUploadBytes
+	if err := prepareUploadBody(c, maxUpload); err != nil {
+		if errors.Is(err, errUnsupportedEncoding) {
+			logger.Get().Warn().Err(err).Msg("Unsupported audio upload encoding")
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported Content-Encoding"})
+			return
+		}
+		logger.Get().Warn().Err(err).Msg("Invalid gzip audio upload")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gzip body"})
+		return
+	}
+
+	// Reuse the client's trace ID (or start a new trace) so the follow-up ask can be correlated
+	traceID := c.Query("trace_id")
+	if traceID == "" {
+		traceID = c.PostForm("trace_id")
+	}
+	if traceID == "" {
+		traceID = uuid.New().String()
+	} else if !isValidTraceID(traceID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trace_id"})
+		return
+	}
+	log := logger.FromContext(c.Request.Context()).With().Str("trace_id", traceID).Logger()
 
 	// Get the uploaded audio file
 	file, header, err := c.Request.FormFile("audio")
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			log.Warn().Int64("max_upload_bytes", maxUpload).Msg("Audio upload too large")
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Audio file too large"})
+			return
+		}
 		log.Error().Err(err).Msg("Failed to get audio file from request")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No audio file provided"})
 		return
 	}
 	defer file.Close()
 
+	// Near-empty recordings waste a Whisper run and transcribe to garbage
+	if minAudio := h.config.MinAudioBytes; minAudio > 0 && header.Size < minAudio {
+		log.Warn().
+			Int64("size", header.Size).
+			Int64("min_audio_bytes", minAudio).
+			Msg("Audio upload too small")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audio file too small"})
+		return
+	}
+
+	wordTimestamps := boolParam(c, "word_timestamps")
+	candidates := boolParam(c, "candidates")
+
 	log.Info().
 		Str("filename", header.Filename).
 		Int64("size", header.Size).
+		Bool("word_timestamps", wordTimestamps).
+		Bool("candidates", candidates).
 		Msg("Received audio file for transcription")
 
 	// Create temp directory for audio processing
@@ -67,109 +186,310 @@ func (h *TranscribeHandler) Transcribe(c *gin.Context) {
 	}
 	audioPath := filepath.Join(tempDir, fmt.Sprintf("audio_%d%s", timestamp, audioExt))
 
-	// Save uploaded file
-	audioFile, err := os.Create(audioPath)
+	// Save uploaded file, enforcing the size limit as it streams to disk and
+	// hashing it so identical re-uploads can be answered from the cache
+	hasher := sha256.New()
+	audioSize, err := saveUpload(audioPath, io.TeeReader(file, hasher), maxUpload, log)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to create audio file")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-		return
-	}
-
-	if _, err := io.Copy(audioFile, file); err != nil {
-		audioFile.Close()
-		os.Remove(audioPath)
+		if errors.Is(err, errUploadTooLarge) {
+			log.Warn().Int64("max_upload_bytes", maxUpload).Msg("Audio upload too large")
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Audio file too large"})
+			return
+		}
 		log.Error().Err(err).Msg("Failed to save audio file")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
-	audioFile.Close()
 
 	// Clean up audio file after processing
-	defer os.Remove(audioPath)
+	defer removeTempFile(h.config, audioPath, &log)
+
+	cacheKey := transcribeCacheKey(hex.EncodeToString(hasher.Sum(nil)), wordTimestamps, candidates)
+	if h.cache != nil {
+		if cached, ok := h.cache.Get(cacheKey); ok {
+			log.Info().
+				Int64("size", audioSize).
+				Msg("Returning cached transcription for duplicate audio")
+			cached.TraceID = traceID
+			respondWithData(h.config, c, http.StatusOK, cached)
+			return
+		}
+	}
 
 	// Run Whisper transcription with timeout
-	text, err := h.runWhisper(c, audioPath)
+	result, err := h.runWhisper(c, audioPath, wordTimestamps, candidates)
 	if err != nil {
 		log.Error().Err(err).Msg("Whisper transcription failed")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transcription failed"})
 		return
 	}
 
+	if h.cache != nil {
+		h.cache.Put(cacheKey, *result)
+	}
+
+	audioBytes.transcribed.Add(audioSize)
+
 	// Log success at Info level (without PII), transcription text at Debug level only
-	log.Info().Msg("Transcription successful")
+	log.Info().
+		Int("word_count", len(result.Words)).
+		Msg("Transcription successful")
 	log.Debug().
-		Str("text", text).
+		Str("text", result.Text).
 		Msg("Transcription text")
 
-	c.JSON(http.StatusOK, TranscribeResponse{
-		Text: text,
-	})
+	result.TraceID = traceID
+	respondWithData(h.config, c, http.StatusOK, result)
 }
 
-// runWhisper executes the Whisper command and returns the transcribed text
-func (h *TranscribeHandler) runWhisper(c *gin.Context, audioPath string) (string, error) {
-	log := logger.Get()
+// prepareUploadBody caps the request body at maxUpload plus multipart overhead and,
+// for Content-Encoding: gzip, swaps in a decompressing reader. The decompressed
+// stream is capped as well so a small body cannot expand without bound.
+func prepareUploadBody(c *gin.Context, maxUpload int64) error {
+	if maxUpload > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUpload+multipartOverheadBytes)
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		return nil
+	case "gzip":
+	default:
+		return fmt.Errorf("%w: %s", errUnsupportedEncoding, encoding)
+	}
+
+	gz, err := gzip.NewReader(c.Request.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip header: %w", err)
+	}
+	c.Request.Body = gz
+	if maxUpload > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, gz, maxUpload+multipartOverheadBytes)
+	}
+	c.Request.Header.Del("Content-Encoding")
+	c.Request.ContentLength = -1
+	return nil
+}
+
+// saveUpload streams src into a new file at path. When limit is positive and
+// the stream exceeds it, the copy is aborted, the partial file is removed, and
+// errUploadTooLarge is returned. Progress is logged periodically for large files.
+func saveUpload(path string, src io.Reader, limit int64, log zerolog.Logger) (int64, error) {
+	dst, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	written, err := copyWithLimit(dst, src, limit, log)
+	if closeErr := dst.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close file: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(path)
+		return written, err
+	}
+
+	return written, nil
+}
+
+// copyWithLimit copies src to dst in chunks, stopping once more than limit bytes are read
+func copyWithLimit(dst io.Writer, src io.Reader, limit int64, log zerolog.Logger) (int64, error) {
+	buf := make([]byte, 32*1024)
+	var written int64
+	nextProgress := int64(uploadProgressInterval)
+
+	for {
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			if limit > 0 && written+int64(n) > limit {
+				return written, errUploadTooLarge
+			}
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return written, fmt.Errorf("failed to write file: %w", err)
+			}
+			written += int64(n)
+
+			if written >= nextProgress {
+				log.Debug().Int64("bytes_written", written).Msg("Saving audio upload")
+				nextProgress += uploadProgressInterval
+			}
+		}
+		if readErr == io.EOF {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, fmt.Errorf("failed to read upload: %w", readErr)
+		}
+	}
+}
+
+// boolParam reads a boolean option from the query string or multipart form
+func boolParam(c *gin.Context, name string) bool {
+	value := c.Query(name)
+	if value == "" {
+		value = c.PostForm(name)
+	}
+	enabled, _ := strconv.ParseBool(value)
+	return enabled
+}
+
+// runWhisper executes the Whisper command and returns the transcription.
+// With wordTimestamps or candidates, Whisper writes JSON output which is parsed
+// for per-word times and per-segment alternatives respectively.
+func (h *TranscribeHandler) runWhisper(c *gin.Context, audioPath string, wordTimestamps bool, candidates bool) (*TranscribeResponse, error) {
+	log := logger.FromContext(c.Request.Context())
 
 	// Build whisper command
 	// whisper audio.webm --model base --output_format txt --output_dir /tmp
 	outputDir := filepath.Dir(audioPath)
+	outputFormat := "txt"
+	if wordTimestamps || candidates {
+		outputFormat = "json"
+	}
 
 	// Create context with timeout (2 minutes should be enough for most audio clips)
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
 	defer cancel()
 
-	cmd := exec.CommandContext(
-		ctx,
-		h.config.WhisperPath,
+	args := []string{
 		audioPath,
 		"--model", h.config.WhisperModel,
-		"--output_format", "txt",
+		"--output_format", outputFormat,
 		"--output_dir", outputDir,
-	)
+	}
+	if wordTimestamps {
+		args = append(args, "--word_timestamps", "True")
+	}
+
+	cmd := exec.CommandContext(ctx, h.config.WhisperPath, args...)
 
 	log.Debug().
 		Str("whisper_path", h.config.WhisperPath).
 		Str("audio_path", audioPath).
 		Str("model", h.config.WhisperModel).
+		Str("output_format", outputFormat).
 		Str("output_dir", outputDir).
 		Msg("Executing whisper command")
 
 	// Capture output
 	output, err := cmd.CombinedOutput()
+	metrics.RecordProcess(metrics.ProcessWhisper, err)
 	if err != nil {
 		// Check if timeout occurred
 		if ctx.Err() == context.DeadlineExceeded {
 			log.Error().
 				Str("output", string(output)).
 				Msg("Whisper command timed out after 2 minutes")
-			return "", fmt.Errorf("whisper command timed out: %w", ctx.Err())
+			return nil, fmt.Errorf("whisper command timed out: %w", ctx.Err())
 		}
 
 		log.Error().
 			Err(err).
 			Str("output", string(output)).
 			Msg("Whisper command failed")
-		return "", fmt.Errorf("whisper command failed: %w", err)
+		return nil, fmt.Errorf("whisper command failed: %w", err)
 	}
 
 	log.Debug().
 		Str("output", string(output)).
 		Msg("Whisper command succeeded")
 
-	// Read the generated .txt file
+	// Read the generated output file (named after the audio file)
 	baseName := strings.TrimSuffix(filepath.Base(audioPath), filepath.Ext(audioPath))
-	txtPath := filepath.Join(outputDir, baseName+".txt")
-	defer os.Remove(txtPath) // Clean up the .txt file
+	outputPath := filepath.Join(outputDir, baseName+"."+outputFormat)
+	defer removeTempFile(h.config, outputPath, log) // Clean up the output file
 
-	textBytes, err := os.ReadFile(txtPath)
+	outputBytes, err := os.ReadFile(outputPath)
 	if err != nil {
 		log.Error().
 			Err(err).
-			Str("txt_path", txtPath).
+			Str("output_path", outputPath).
 			Msg("Failed to read transcription file")
-		return "", fmt.Errorf("failed to read transcription: %w", err)
+		return nil, fmt.Errorf("failed to read transcription: %w", err)
 	}
 
-	text := strings.TrimSpace(string(textBytes))
-	return text, nil
+	if outputFormat == "json" {
+		result, err := parseWhisperJSON(outputBytes)
+		if err != nil {
+			return nil, err
+		}
+		if candidates {
+			if result.Candidates, err = parseWhisperCandidates(outputBytes); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+
+	return &TranscribeResponse{Text: strings.TrimSpace(string(outputBytes))}, nil
+}
+
+// parseWhisperJSON converts Whisper's JSON output into a response with word timestamps
+func parseWhisperJSON(data []byte) (*TranscribeResponse, error) {
+	var output whisperJSONOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("failed to parse whisper JSON output: %w", err)
+	}
+
+	result := &TranscribeResponse{
+		Text:  strings.TrimSpace(output.Text),
+		Words: make([]WordTimestamp, 0),
+	}
+	for _, segment := range output.Segments {
+		for _, word := range segment.Words {
+			// Whisper prefixes words with the separating space
+			word.Word = strings.TrimSpace(word.Word)
+			result.Words = append(result.Words, word)
+		}
+	}
+
+	return result, nil
+}
+
+// parseWhisperCandidates extracts up to maxSegmentCandidates alternatives per
+// segment from Whisper's JSON output. The decoded text always comes first; any
+// alternatives Whisper kept follow in the order it reported them.
+func parseWhisperCandidates(data []byte) ([]SegmentCandidates, error) {
+	var output whisperJSONOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("failed to parse whisper JSON output: %w", err)
+	}
+
+	segments := make([]SegmentCandidates, 0, len(output.Segments))
+	for _, segment := range output.Segments {
+		candidates := SegmentCandidates{
+			Start: segment.Start,
+			End:   segment.End,
+			Alternatives: []Candidate{{
+				Text:       strings.TrimSpace(segment.Text),
+				Confidence: logprobConfidence(segment.AvgLogprob),
+			}},
+		}
+		for _, alt := range segment.Alternatives {
+			if len(candidates.Alternatives) == maxSegmentCandidates {
+				break
+			}
+			confidence := alt.Confidence
+			if confidence == nil {
+				confidence = logprobConfidence(alt.AvgLogprob)
+			}
+			candidates.Alternatives = append(candidates.Alternatives, Candidate{
+				Text:       strings.TrimSpace(alt.Text),
+				Confidence: confidence,
+			})
+		}
+		segments = append(segments, candidates)
+	}
+
+	return segments, nil
+}
+
+// logprobConfidence converts an average token log probability to a 0-1 confidence
+func logprobConfidence(avgLogprob *float64) *float64 {
+	if avgLogprob == nil {
+		return nil
+	}
+	confidence := math.Exp(*avgLogprob)
+	return &confidence
 }
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// transcribeCache remembers transcriptions by audio hash for a TTL so retried
+// uploads of identical audio skip Whisper
+type transcribeCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]transcribeCacheEntry
+	now     func() time.Time
+}
+
+type transcribeCacheEntry struct {
+	result  TranscribeResponse
+	expires time.Time
+}
+
+// newTranscribeCache returns a cache holding results for ttl, or nil when ttl
+// is not positive (caching disabled)
+func newTranscribeCache(ttl time.Duration) *transcribeCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &transcribeCache{
+		ttl:     ttl,
+		entries: make(map[string]transcribeCacheEntry),
+		now:     time.Now,
+	}
+}
+
+// transcribeCacheKey combines the audio hash with the options that shape the result
+func transcribeCacheKey(audioHash string, wordTimestamps bool, candidates bool) string {
+	return audioHash + ":" + strconv.FormatBool(wordTimestamps) + ":" + strconv.FormatBool(candidates)
+}
+
+// Get returns an unexpired result for key
+func (tc *transcribeCache) Get(key string) (TranscribeResponse, bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	entry, ok := tc.entries[key]
+	if !ok || !tc.now().Before(entry.expires) {
+		return TranscribeResponse{}, false
+	}
+	return entry.result, true
+}
+
+// Put stores result under key, dropping any entries that have expired
+func (tc *transcribeCache) Put(key string, result TranscribeResponse) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	now := tc.now()
+	for k, entry := range tc.entries {
+		if !now.Before(entry.expires) {
+			delete(tc.entries, k)
+		}
+	}
+	result.TraceID = ""
+	tc.entries[key] = transcribeCacheEntry{result: result, expires: now.Add(tc.ttl)}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/config"
+)
+
+func TestTranscribeCache_Expiry(t *testing.T) {
+	cache := newTranscribeCache(time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.Put("key", TranscribeResponse{Text: "hello", TraceID: "trace-1"})
+
+	cached, ok := cache.Get("key")
+	if !ok || cached.Text != "hello" {
+		t.Fatalf("expected cached result, got %+v, %v", cached, ok)
+	}
+	if cached.TraceID != "" {
+		t.Errorf("expected trace ID not to be cached, got %q", cached.TraceID)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.Get("key"); ok {
+		t.Error("expected result to expire after the TTL")
+	}
+}
+
+func TestNewTranscribeCache_Disabled(t *testing.T) {
+	if newTranscribeCache(0) != nil {
+		t.Error("expected no cache for a zero TTL")
+	}
+}
+
+func TestTranscribe_CachesDuplicateAudio(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	runs := filepath.Join(t.TempDir(), "runs")
+	handler := NewTranscribeHandler(&config.Config{
+		WhisperPath:        writeFakeScript(t, "whisper", "echo run >> "+runs+"\n"+fakeWhisperScript),
+		WhisperModel:       "base",
+		TranscribeCacheTTL: time.Minute,
+	})
+
+	transcribe := func(url string, audio []byte) TranscribeResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newAudioUploadRequest(t, url, audio)
+		handler.Transcribe(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response TranscribeResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+	whisperRuns := func() int {
+		data, _ := os.ReadFile(runs)
+		return bytes.Count(data, []byte("run"))
+	}
+
+	audio := bytes.Repeat([]byte("a"), 3000)
+	first := transcribe("/api/transcribe?trace_id=first", audio)
+	second := transcribe("/api/transcribe?trace_id=second", audio)
+
+	if whisperRuns() != 1 {
+		t.Errorf("expected identical audio to invoke Whisper once, got %d runs", whisperRuns())
+	}
+	if second.Text != first.Text || second.TraceID != "second" {
+		t.Errorf("expected cached text with the new trace ID, got %+v", second)
+	}
+
+	transcribe("/api/transcribe", bytes.Repeat([]byte("b"), 3000))
+	transcribe("/api/transcribe?word_timestamps=true", audio)
+	if whisperRuns() != 3 {
+		t.Errorf("expected different audio and options to miss the cache, got %d runs", whisperRuns())
+	}
+}
@@ -0,0 +1,555 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/logger"
+)
+
+// fakeWhisperJSON is a trimmed sample of Whisper's --output_format json file
+const fakeWhisperJSON = `{
+  "text": " Hello world.",
+  "segments": [
+    {"id": 0, "start": 0.0, "end": 1.2, "text": " Hello world.", "words": [
+      {"word": " Hello", "start": 0.0, "end": 0.48, "probability": 0.91},
+      {"word": " world.", "start": 0.48, "end": 1.2, "probability": 0.87}
+    ]}
+  ],
+  "language": "en"
+}`
+
+// fakeWhisperScript mimics the whisper CLI: it writes <audio basename>.<format>
+// into --output_dir with canned content
+const fakeWhisperScript = `audio="$1"; shift
+fmt=txt; dir=.
+while [ $# -gt 0 ]; do
+  case "$1" in
+    --output_format) fmt="$2" ;;
+    --output_dir) dir="$2" ;;
+  esac
+  shift
+done
+base=$(basename "$audio"); base="${base%.*}"
+if [ "$fmt" = "json" ]; then
+  cat > "$dir/$base.json" <<'JSON'
+` + fakeWhisperJSON + `
+JSON
+else
+  echo "hello world" > "$dir/$base.txt"
+fi
+`
+
+// writeFakeScript writes an executable shell script standing in for an external CLI
+func writeFakeScript(t *testing.T, name string, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("failed to write fake %s: %v", name, err)
+	}
+	return path
+}
+
+// newAudioUploadRequest builds a multipart transcribe request carrying audio
+func newAudioUploadRequest(t *testing.T, url string, audio []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("audio", "clip.webm")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	part.Write(audio)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", url, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// gzipRequestBody compresses req's body and marks it with Content-Encoding: gzip
+func gzipRequestBody(t *testing.T, req *http.Request) *http.Request {
+	t.Helper()
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := io.Copy(gz, req.Body); err != nil {
+		t.Fatalf("failed to compress body: %v", err)
+	}
+	gz.Close()
+
+	gzipped := httptest.NewRequest("POST", req.URL.String(), &compressed)
+	gzipped.Header = req.Header.Clone()
+	gzipped.Header.Set("Content-Encoding", "gzip")
+	return gzipped
+}
+
+func TestParseWhisperJSON(t *testing.T) {
+	t.Run("parses words with timestamps", func(t *testing.T) {
+		result, err := parseWhisperJSON([]byte(fakeWhisperJSON))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if result.Text != "Hello world." {
+			t.Errorf("unexpected text: %q", result.Text)
+		}
+		if len(result.Words) != 2 {
+			t.Fatalf("expected 2 words, got %d", len(result.Words))
+		}
+		if result.Words[0].Word != "Hello" || result.Words[0].Start != 0.0 || result.Words[0].End != 0.48 {
+			t.Errorf("unexpected first word: %+v", result.Words[0])
+		}
+		if result.Words[1].Word != "world." || result.Words[1].Probability != 0.87 {
+			t.Errorf("unexpected second word: %+v", result.Words[1])
+		}
+	})
+
+	t.Run("returns error for malformed output", func(t *testing.T) {
+		if _, err := parseWhisperJSON([]byte("not json")); err == nil {
+			t.Error("expected error for malformed JSON")
+		}
+	})
+}
+
+// fakeWhisperCandidatesJSON is Whisper JSON output carrying per-segment alternatives
+const fakeWhisperCandidatesJSON = `{
+  "text": " Write a test. Ship it.",
+  "segments": [
+    {"id": 0, "start": 0.0, "end": 1.5, "text": " Write a test.", "avg_logprob": -0.1,
+     "alternatives": [
+       {"text": " Right a test.", "confidence": 0.31},
+       {"text": " Write a text.", "avg_logprob": -1.5}
+     ]},
+    {"id": 1, "start": 1.5, "end": 2.4, "text": " Ship it."}
+  ]
+}`
+
+func TestParseWhisperCandidates(t *testing.T) {
+	t.Run("returns the best text followed by alternatives with confidence", func(t *testing.T) {
+		segments, err := parseWhisperCandidates([]byte(fakeWhisperCandidatesJSON))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(segments) != 2 {
+			t.Fatalf("expected 2 segments, got %d", len(segments))
+		}
+
+		first := segments[0]
+		if first.Start != 0.0 || first.End != 1.5 {
+			t.Errorf("unexpected segment times: %+v", first)
+		}
+		if len(first.Alternatives) != 3 {
+			t.Fatalf("expected 3 alternatives, got %+v", first.Alternatives)
+		}
+		wantTexts := []string{"Write a test.", "Right a test.", "Write a text."}
+		for i, want := range wantTexts {
+			if first.Alternatives[i].Text != want {
+				t.Errorf("alternative %d: expected %q, got %q", i, want, first.Alternatives[i].Text)
+			}
+		}
+		if c := first.Alternatives[0].Confidence; c == nil || *c < 0.9 || *c > 0.91 {
+			t.Errorf("expected best confidence derived from avg_logprob, got %v", c)
+		}
+		if c := first.Alternatives[1].Confidence; c == nil || *c != 0.31 {
+			t.Errorf("expected reported confidence 0.31, got %v", c)
+		}
+		if c := first.Alternatives[2].Confidence; c == nil || *c > 0.23 {
+			t.Errorf("expected low confidence derived from avg_logprob, got %v", c)
+		}
+
+		second := segments[1]
+		if len(second.Alternatives) != 1 || second.Alternatives[0].Text != "Ship it." {
+			t.Errorf("expected only the best text for segment without alternatives, got %+v", second.Alternatives)
+		}
+		if second.Alternatives[0].Confidence != nil {
+			t.Errorf("expected no confidence without avg_logprob, got %v", *second.Alternatives[0].Confidence)
+		}
+	})
+
+	t.Run("caps alternatives per segment", func(t *testing.T) {
+		data := `{"segments": [{"text": "best", "alternatives": [
+			{"text": "a"}, {"text": "b"}, {"text": "c"}, {"text": "d"}, {"text": "e"}, {"text": "f"}
+		]}]}`
+		segments, err := parseWhisperCandidates([]byte(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := len(segments[0].Alternatives); got != maxSegmentCandidates {
+			t.Errorf("expected %d alternatives, got %d", maxSegmentCandidates, got)
+		}
+	})
+
+	t.Run("returns error for malformed output", func(t *testing.T) {
+		if _, err := parseWhisperCandidates([]byte("not json")); err == nil {
+			t.Error("expected error for malformed JSON")
+		}
+	})
+}
+
+func TestTranscribe_WordTimestamps(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	handler := NewTranscribeHandler(&config.Config{
+		WhisperPath:  writeFakeScript(t, "whisper", fakeWhisperScript),
+		WhisperModel: "base",
+	})
+
+	t.Run("plain text by default", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newAudioUploadRequest(t, "/api/transcribe", []byte("fake audio"))
+
+		handler.Transcribe(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response TranscribeResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Text != "hello world" {
+			t.Errorf("unexpected text: %q", response.Text)
+		}
+		if response.Words != nil {
+			t.Errorf("expected no words in plain response, got %+v", response.Words)
+		}
+	})
+
+	t.Run("structured words when requested", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newAudioUploadRequest(t, "/api/transcribe?word_timestamps=true", []byte("fake audio"))
+
+		handler.Transcribe(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response TranscribeResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Text != "Hello world." {
+			t.Errorf("unexpected text: %q", response.Text)
+		}
+		if len(response.Words) != 2 || response.Words[1].End != 1.2 {
+			t.Errorf("unexpected words: %+v", response.Words)
+		}
+	})
+
+	t.Run("candidates when requested", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newAudioUploadRequest(t, "/api/transcribe?candidates=true", []byte("fake audio"))
+
+		handler.Transcribe(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response TranscribeResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Text != "Hello world." {
+			t.Errorf("unexpected text: %q", response.Text)
+		}
+		if len(response.Candidates) != 1 || response.Candidates[0].Alternatives[0].Text != "Hello world." {
+			t.Errorf("unexpected candidates: %+v", response.Candidates)
+		}
+	})
+}
+
+func TestTranscribe_TraceID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	handler := NewTranscribeHandler(&config.Config{
+		WhisperPath:  writeFakeScript(t, "whisper", fakeWhisperScript),
+		WhisperModel: "base",
+	})
+
+	t.Run("echoes client trace_id in response and logs", func(t *testing.T) {
+		logs := captureLogs(t)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newAudioUploadRequest(t, "/api/transcribe?trace_id=trace-abc-123", []byte("fake audio"))
+
+		handler.Transcribe(c)
+
+		var response TranscribeResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.TraceID != "trace-abc-123" {
+			t.Errorf("expected trace_id in response, got %q", response.TraceID)
+		}
+
+		entry := findLogEntry(t, logs, "Transcription successful")
+		if entry["trace_id"] != "trace-abc-123" {
+			t.Errorf("expected trace_id in log entry, got %v", entry["trace_id"])
+		}
+	})
+
+	t.Run("generates trace_id when absent", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newAudioUploadRequest(t, "/api/transcribe", []byte("fake audio"))
+
+		handler.Transcribe(c)
+
+		var response TranscribeResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if len(response.TraceID) != 36 {
+			t.Errorf("expected generated UUID trace_id, got %q", response.TraceID)
+		}
+	})
+}
+
+func TestTranscribe_LogsSubprocessOutputWithRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	handler := NewTranscribeHandler(&config.Config{
+		WhisperPath:  writeFakeScript(t, "whisper", "echo 'CUDA out of memory'\nexit 1\n"),
+		WhisperModel: "base",
+	})
+
+	logs := captureLogs(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	req := newAudioUploadRequest(t, "/api/transcribe", []byte("fake audio"))
+	c.Request = req.WithContext(logger.WithRequestID(req.Context(), "req-abc-123"))
+
+	handler.Transcribe(c)
+
+	entry := findLogEntry(t, logs, "Whisper command failed")
+	if entry["request_id"] != "req-abc-123" {
+		t.Errorf("expected request_id in subprocess log entry, got %v", entry["request_id"])
+	}
+	if output, _ := entry["output"].(string); !strings.Contains(output, "CUDA out of memory") {
+		t.Errorf("expected subprocess output in log entry, got %q", output)
+	}
+}
+
+func TestSaveUpload(t *testing.T) {
+	t.Run("aborts a stream exceeding the limit and removes the partial file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "audio.webm")
+		// Larger than one copy chunk so some bytes hit disk before the limit trips
+		src := bytes.NewReader(make([]byte, 100*1024))
+
+		written, err := saveUpload(path, src, 50*1024, zerolog.Nop())
+
+		if !errors.Is(err, errUploadTooLarge) {
+			t.Fatalf("expected errUploadTooLarge, got %v", err)
+		}
+		if written == 0 {
+			t.Error("expected a partial write before aborting")
+		}
+		if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+			t.Errorf("expected partial file to be removed, stat err: %v", statErr)
+		}
+	})
+
+	t.Run("saves a stream within the limit", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "audio.webm")
+		audio := bytes.Repeat([]byte("a"), 1024)
+
+		written, err := saveUpload(path, bytes.NewReader(audio), 1024, zerolog.Nop())
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if written != int64(len(audio)) {
+			t.Errorf("expected %d bytes written, got %d", len(audio), written)
+		}
+		saved, _ := os.ReadFile(path)
+		if !bytes.Equal(saved, audio) {
+			t.Error("saved file does not match upload")
+		}
+	})
+}
+
+func TestTranscribe_MaxUploadBytes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	handler := NewTranscribeHandler(&config.Config{
+		WhisperPath:    writeFakeScript(t, "whisper", fakeWhisperScript),
+		WhisperModel:   "base",
+		MaxUploadBytes: 1024,
+	})
+
+	t.Run("rejects audio over the limit with 413", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newAudioUploadRequest(t, "/api/transcribe", make([]byte, 4096))
+
+		handler.Transcribe(c)
+
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status 413, got %d", w.Code)
+		}
+		leftovers, _ := filepath.Glob(filepath.Join(tempDir, "janus-transcribe", "audio_*"))
+		if len(leftovers) != 0 {
+			t.Errorf("expected no audio files left behind, found %v", leftovers)
+		}
+	})
+
+	t.Run("accepts audio within the limit", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newAudioUploadRequest(t, "/api/transcribe", []byte("fake audio"))
+
+		handler.Transcribe(c)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestTranscribe_GzipUpload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	handler := NewTranscribeHandler(&config.Config{
+		WhisperPath:    writeFakeScript(t, "whisper", fakeWhisperScript),
+		WhisperModel:   "base",
+		MaxUploadBytes: 64 * 1024,
+	})
+
+	t.Run("decompresses gzipped audio before transcribing", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = gzipRequestBody(t, newAudioUploadRequest(t, "/api/transcribe", []byte("fake audio")))
+
+		handler.Transcribe(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp TranscribeResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if resp.Text != "hello world" {
+			t.Errorf("unexpected text: %q", resp.Text)
+		}
+	})
+
+	t.Run("rejects a decompression bomb with 413", func(t *testing.T) {
+		// Highly compressible audio that expands well past the limit (and the multipart overhead)
+		req := gzipRequestBody(t, newAudioUploadRequest(t, "/api/transcribe", make([]byte, 8<<20)))
+		if req.ContentLength > 64*1024 {
+			t.Fatalf("expected compressed body under the limit, got %d bytes", req.ContentLength)
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		handler.Transcribe(c)
+
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status 413, got %d", w.Code)
+		}
+		leftovers, _ := filepath.Glob(filepath.Join(tempDir, "janus-transcribe", "audio_*"))
+		if len(leftovers) != 0 {
+			t.Errorf("expected no audio files left behind, found %v", leftovers)
+		}
+	})
+
+	t.Run("rejects a malformed gzip body", func(t *testing.T) {
+		req := newAudioUploadRequest(t, "/api/transcribe", []byte("fake audio"))
+		req.Header.Set("Content-Encoding", "gzip")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		handler.Transcribe(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestTranscribe_CountsTranscribedBytes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	handler := NewTranscribeHandler(&config.Config{
+		WhisperPath:  writeFakeScript(t, "whisper", fakeWhisperScript),
+		WhisperModel: "base",
+	})
+	audio := bytes.Repeat([]byte("a"), 3000)
+
+	before := fetchAudioStats(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newAudioUploadRequest(t, "/api/transcribe", audio)
+	handler.Transcribe(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	after := fetchAudioStats(t)
+	if got := after.TranscribedBytes - before.TranscribedBytes; got != int64(len(audio)) {
+		t.Errorf("expected transcribed bytes to grow by %d, got %d", len(audio), got)
+	}
+	if after.SynthesizedBytes != before.SynthesizedBytes {
+		t.Error("expected synthesized bytes to be unchanged")
+	}
+}
+
+func TestTranscribe_MinAudioBytes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	handler := NewTranscribeHandler(&config.Config{
+		WhisperPath:   writeFakeScript(t, "whisper", fakeWhisperScript),
+		WhisperModel:  "base",
+		MinAudioBytes: 256,
+	})
+
+	t.Run("rejects audio under the minimum with 400", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newAudioUploadRequest(t, "/api/transcribe", []byte("tiny"))
+
+		handler.Transcribe(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+		leftovers, _ := filepath.Glob(filepath.Join(tempDir, "janus-transcribe", "audio_*"))
+		if len(leftovers) != 0 {
+			t.Errorf("expected no audio files written, found %v", leftovers)
+		}
+	})
+
+	t.Run("accepts audio at the minimum", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newAudioUploadRequest(t, "/api/transcribe", make([]byte, 256))
+
+		handler.Transcribe(c)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
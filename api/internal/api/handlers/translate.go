@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// languagePattern accepts BCP 47 style language tags such as "es", "pt-BR", or
+// "zh-Hant". The leading letters mean the value can never be parsed as a flag.
+var languagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8}){0,3}$`)
+
+// isValidLanguage reports whether a client-supplied target language is safe to pass to the translator
+func isValidLanguage(language string) bool {
+	return languagePattern.MatchString(language)
+}
+
+// translate runs the translator as "<translatePath> --to <language>" with text on
+// stdin and returns its stdout, trimmed, as the translation
+func translate(ctx context.Context, translatePath string, language string, text string) (string, error) {
+	cmd := exec.CommandContext(ctx, translatePath, "--to", language)
+	cmd.Stdin = strings.NewReader(text)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("translate to %s failed: %w, stderr: %s", language, err, strings.TrimSpace(stderr.String()))
+	}
+
+	translated := strings.TrimSpace(stdout.String())
+	if translated == "" {
+		return "", fmt.Errorf("translate to %s produced no output", language)
+	}
+	return translated, nil
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeTranslator echoes stdin prefixed with the --to language
+const fakeTranslator = `[ "$1" = "--to" ] || exit 2
+printf '[%s] ' "$2"
+cat
+`
+
+func TestAsk_TranslateTo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ask := func(t *testing.T, translatePath string, body string) (*httptest.ResponseRecorder, *MockSessionManager, string) {
+		mockManager := NewMockSessionManager()
+		sess, _ := mockManager.CreateSession()
+		cfg := newTestConfig()
+		cfg.TranslatePath = translatePath
+		handler := NewSessionHandler(mockManager, cfg)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/ask?session_id="+sess.ID, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Ask(c)
+		return w, mockManager, sess.ID
+	}
+
+	t.Run("answer is passed through translation", func(t *testing.T) {
+		path := writeFakeScript(t, "translate", fakeTranslator)
+
+		w, mockManager, sessionID := ask(t, path, `{"question":"hello","translate_to":"es"}`)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response AskResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Answer != "[es] Mock cursor-agent response to: hello" {
+			t.Errorf("expected translated answer, got %q", response.Answer)
+		}
+		if response.TranslatedTo != "es" {
+			t.Errorf("expected translated_to es, got %q", response.TranslatedTo)
+		}
+		if log := mockManager.sessions[sessionID].ConversationLog; len(log) != 2 || log[1].Content != response.Answer {
+			t.Errorf("expected the translated answer in the conversation log, got %+v", log)
+		}
+	})
+
+	t.Run("unset translate_to skips translation", func(t *testing.T) {
+		path := writeFakeScript(t, "translate", fakeTranslator)
+
+		w, _, _ := ask(t, path, `{"question":"hello"}`)
+
+		var response AskResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Answer != "Mock cursor-agent response to: hello" || response.TranslatedTo != "" {
+			t.Errorf("expected untranslated answer, got %+v", response)
+		}
+	})
+
+	t.Run("invalid language returns 400", func(t *testing.T) {
+		path := writeFakeScript(t, "translate", fakeTranslator)
+
+		w, _, _ := ask(t, path, `{"question":"hello","translate_to":"--help"}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("unconfigured translator returns 400", func(t *testing.T) {
+		w, _, _ := ask(t, "", `{"question":"hello","translate_to":"es"}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("failed translation returns the original answer", func(t *testing.T) {
+		path := writeFakeScript(t, "translate", "exit 1\n")
+
+		w, _, _ := ask(t, path, `{"question":"hello","translate_to":"fr"}`)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var response AskResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Answer != "Mock cursor-agent response to: hello" || response.TranslatedTo != "" {
+			t.Errorf("expected untranslated answer, got %+v", response)
+		}
+	})
+}
+
+func TestIsValidLanguage(t *testing.T) {
+	for _, language := range []string{"es", "pt-BR", "zh-Hant", "fil"} {
+		if !isValidLanguage(language) {
+			t.Errorf("expected %q to be valid", language)
+		}
+	}
+	for _, language := range []string{"", "e", "-es", "--to", "es_ES", "english language", "es;rm"} {
+		if isValidLanguage(language) {
+			t.Errorf("expected %q to be invalid", language)
+		}
+	}
+}
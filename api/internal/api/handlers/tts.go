@@ -2,17 +2,22 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sean/janus/internal/api/middleware"
 	"github.com/sean/janus/internal/config"
 	"github.com/sean/janus/internal/logger"
+	"github.com/sean/janus/internal/metrics"
 )
 
 const (
@@ -21,26 +26,125 @@ const (
 	TempFileCleanupAge = middleware.DefaultRequestTimeout + 1*time.Hour
 	// TempFileCleanupBuffer adds extra safety margin beyond request timeout
 	TempFileCleanupBuffer = 1 * time.Hour
+
+	// MinTTSSpeed and MaxTTSSpeed bound the speed a TTS request may ask for
+	MinTTSSpeed = 0.5
+	MaxTTSSpeed = 2.0
 )
 
+// errTTSBusy is returned when every synthesis slot stayed taken past TTSQueueTimeout
+var errTTSBusy = errors.New("TTS is busy")
+
+// errNothingToSpeak is returned when TTSStripEmoji leaves no speakable text
+var errNothingToSpeak = errors.New("no speakable text after stripping")
+
 // TTSHandler handles text-to-speech generation requests
 type TTSHandler struct {
 	config *config.Config
+	// slots bounds concurrent syntheses; nil when MaxConcurrentTTS is 0 (unlimited)
+	slots chan struct{}
+	// synthesize produces a WAV file for text; GenerateSpeech unless replaced in tests
+	synthesize func(ctx context.Context, text string, voice config.TTSPreset) (string, error)
+	// cache reuses audio for repeated text (nil when TTSCacheMaxMB is 0)
+	cache *ttsCache
 }
 
 // NewTTSHandler creates a new TTS handler
 func NewTTSHandler(cfg *config.Config) *TTSHandler {
-	return &TTSHandler{config: cfg}
+	h := &TTSHandler{
+		config: cfg,
+		cache:  newTTSCache(filepath.Join(os.TempDir(), "janus-tts", ttsCacheDirName), cfg.TTSCacheMaxMB),
+	}
+	if cfg.MaxConcurrentTTS > 0 {
+		h.slots = make(chan struct{}, cfg.MaxConcurrentTTS)
+	}
+	h.synthesize = h.GenerateSpeech
+	return h
+}
+
+// acquireSlot waits for a synthesis slot for up to TTSQueueTimeout, returning a
+// release func, or false if the queue timeout or request context expired first
+func (h *TTSHandler) acquireSlot(ctx context.Context) (func(), bool) {
+	if h.slots == nil {
+		return func() {}, true
+	}
+
+	timer := time.NewTimer(h.config.TTSQueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case h.slots <- struct{}{}:
+		return func() { <-h.slots }, true
+	case <-timer.C:
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
 }
 
 // TTSRequest represents the request body for TTS generation
 type TTSRequest struct {
 	Text string `json:"text" binding:"required"`
+	// Preset optionally names a configured voice/speed combination (TTSPresets)
+	Preset string `json:"preset,omitempty"`
+	// Voice and Speed optionally override the preset's or configured default's values
+	Voice string   `json:"voice,omitempty"`
+	Speed *float64 `json:"speed,omitempty"`
+}
+
+// TTSFallbackResponse tells the client to synthesize the text itself after server TTS failed
+type TTSFallbackResponse struct {
+	Fallback string `json:"fallback"`
+	Text     string `json:"text"`
+	Message  string `json:"message"`
+}
+
+// resolveVoice returns the voice and speed for a preset name, or the configured
+// defaults when none is given. It returns false for an unknown preset.
+func (h *TTSHandler) resolveVoice(preset string) (config.TTSPreset, bool) {
+	if preset == "" {
+		return config.TTSPreset{Voice: h.config.KokoroTTSVoice, Speed: h.config.KokoroTTSSpeed}, true
+	}
+	voice, ok := h.config.TTSPresets[preset]
+	return voice, ok
+}
+
+// overrideVoice applies a request's voice and speed on top of a resolved
+// preset. The voice must be one of TTSVoices, the configured default, or a
+// preset's voice, and the speed within MinTTSSpeed-MaxTTSSpeed; anything else
+// is an error rather than being clamped.
+func (h *TTSHandler) overrideVoice(voice config.TTSPreset, name string, speed *float64) (config.TTSPreset, error) {
+	if name != "" {
+		if !h.knownVoice(name) {
+			return voice, fmt.Errorf("unknown voice %q", name)
+		}
+		voice.Voice = name
+	}
+	if speed != nil {
+		if *speed < MinTTSSpeed || *speed > MaxTTSSpeed {
+			return voice, fmt.Errorf("speed must be between %g and %g", MinTTSSpeed, MaxTTSSpeed)
+		}
+		voice.Speed = *speed
+	}
+	return voice, nil
+}
+
+// knownVoice reports whether name is a voice TTS requests may select
+func (h *TTSHandler) knownVoice(name string) bool {
+	if name == h.config.KokoroTTSVoice || slices.Contains(h.config.TTSVoices, name) {
+		return true
+	}
+	for _, preset := range h.config.TTSPresets {
+		if preset.Voice == name {
+			return true
+		}
+	}
+	return false
 }
 
 // GenerateSpeech generates speech audio from text using kokoro-tts CLI
-func (h *TTSHandler) GenerateSpeech(ctx context.Context, text string) (string, error) {
-	log := logger.Get()
+func (h *TTSHandler) GenerateSpeech(ctx context.Context, text string, voice config.TTSPreset) (string, error) {
+	log := logger.FromContext(ctx)
 
 	// Create temp directory for TTS files if it doesn't exist
 	tempDir := filepath.Join(os.TempDir(), "janus-tts")
@@ -60,7 +164,7 @@ func (h *TTSHandler) GenerateSpeech(ctx context.Context, text string) (string, e
 	if err := os.WriteFile(inputFile, []byte(text), 0644); err != nil {
 		return "", fmt.Errorf("failed to write input file: %w", err)
 	}
-	defer os.Remove(inputFile) // Clean up input file after generation
+	defer removeTempFile(h.config, inputFile, log) // Clean up input file after generation
 
 	// Execute kokoro-tts CLI (native WSL executable) with timeout from context
 	cmd := exec.CommandContext(
@@ -70,9 +174,9 @@ func (h *TTSHandler) GenerateSpeech(ctx context.Context, text string) (string, e
 		outputFile,
 		"--model", h.config.KokoroTTSModelPath,
 		"--voices", h.config.KokoroTTSVoicesPath,
-		"--speed", fmt.Sprintf("%.1f", h.config.KokoroTTSSpeed),
+		"--speed", strconv.FormatFloat(voice.Speed, 'f', -1, 64),
 		"--lang", "en-us",
-		"--voice", h.config.KokoroTTSVoice,
+		"--voice", voice.Voice,
 	)
 
 	// Set environment variable for GPU acceleration
@@ -82,14 +186,15 @@ func (h *TTSHandler) GenerateSpeech(ctx context.Context, text string) (string, e
 		Str("kokoro_path", h.config.KokoroTTSPath).
 		Str("model_path", h.config.KokoroTTSModelPath).
 		Str("voices_path", h.config.KokoroTTSVoicesPath).
-		Str("voice", h.config.KokoroTTSVoice).
-		Float64("speed", h.config.KokoroTTSSpeed).
+		Str("voice", voice.Voice).
+		Float64("speed", voice.Speed).
 		Str("input_file", inputFile).
 		Str("output_file", outputFile).
 		Str("onnx_provider", "CUDAExecutionProvider").
 		Msg("Executing kokoro-tts command")
 
 	output, err := cmd.CombinedOutput()
+	metrics.RecordProcess(metrics.ProcessKokoroTTS, err)
 	if err != nil {
 		// Check if error was due to context cancellation (timeout)
 		if ctx.Err() == context.DeadlineExceeded {
@@ -110,9 +215,36 @@ func (h *TTSHandler) GenerateSpeech(ctx context.Context, text string) (string, e
 		Str("output", string(output)).
 		Msg("kokoro-tts command succeeded")
 
+	if h.cache != nil {
+		h.cache.Put(ttsCacheKey(text, voice), outputFile)
+	}
+
 	return outputFile, nil
 }
 
+// cachedSpeech copies audio cached for text and voice to a new temp file,
+// reporting false when nothing is cached
+func (h *TTSHandler) cachedSpeech(ctx context.Context, text string, voice config.TTSPreset) (string, bool) {
+	if h.cache == nil {
+		return "", false
+	}
+
+	tempDir := filepath.Join(os.TempDir(), "janus-tts")
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return "", false
+	}
+	outputFile := filepath.Join(tempDir, fmt.Sprintf("output_%d.wav", time.Now().UnixNano()))
+	if !h.cache.Get(ttsCacheKey(text, voice), outputFile) {
+		return "", false
+	}
+
+	logger.FromContext(ctx).Debug().
+		Str("voice", voice.Voice).
+		Float64("speed", voice.Speed).
+		Msg("Serving cached TTS audio")
+	return outputFile, true
+}
+
 // cleanupOldTempFiles removes temp files older than the specified age threshold
 // The threshold should be large enough to avoid deleting files from concurrent requests
 func (h *TTSHandler) cleanupOldTempFiles(tempDir string, ageThreshold time.Duration) {
@@ -164,9 +296,9 @@ func (h *TTSHandler) Generate(c *gin.Context) {
 	log := logger.Get()
 
 	var req TTSRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(h.config, c, &req); err != nil {
 		log.Warn().Err(err).Msg("Invalid TTS request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorDetails(err, "Invalid request body")})
 		return
 	}
 
@@ -175,8 +307,25 @@ func (h *TTSHandler) Generate(c *gin.Context) {
 		return
 	}
 
+	voice, ok := h.resolveVoice(req.Preset)
+	if !ok {
+		log.Warn().Str("preset", req.Preset).Msg("Unknown TTS preset")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown TTS preset"})
+		return
+	}
+
+	voice, err := h.overrideVoice(voice, req.Voice, req.Speed)
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid TTS voice override")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	log.Info().
 		Int("text_length", len(req.Text)).
+		Str("preset", req.Preset).
+		Str("voice", voice.Voice).
+		Float64("speed", voice.Speed).
 		Msg("Generating TTS audio")
 
 	// Perform background cleanup of old temp files (safe from race conditions)
@@ -184,26 +333,89 @@ func (h *TTSHandler) Generate(c *gin.Context) {
 	go h.cleanupOldTempFiles(tempDir, TempFileCleanupAge)
 
 	// Generate speech audio with context (includes timeout from middleware)
-	audioPath, err := h.GenerateSpeech(c.Request.Context(), req.Text)
+	audioPath, err := h.synthesizeQueued(c.Request.Context(), req.Text, voice)
+	if errors.Is(err, errTTSBusy) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "TTS is busy, try again later"})
+		return
+	}
+	if errors.Is(err, errNothingToSpeak) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Text has no speakable characters"})
+		return
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to generate speech")
+		if h.config.AllowBrowserFallback {
+			c.JSON(http.StatusOK, TTSFallbackResponse{
+				Fallback: "browser",
+				Text:     req.Text,
+				Message:  "Server TTS failed, synthesize locally",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate speech"})
 		return
 	}
 
-	// Ensure the audio file is cleaned up after sending
-	defer func() {
-		if err := os.Remove(audioPath); err != nil {
-			log.Warn().
-				Err(err).
-				Str("file", audioPath).
-				Msg("Failed to remove audio file after sending")
+	h.sendAudio(c, audioPath)
+
+	log.Info().Msg("TTS audio sent successfully")
+}
+
+// synthesizeQueued waits for a synthesis slot, since kokoro is heavy and only
+// MaxConcurrentTTS run at once, then synthesizes text. Cached audio is returned
+// without taking a slot. It returns errTTSBusy if
+// no slot frees up within TTSQueueTimeout, and errNothingToSpeak if TTSStripEmoji
+// leaves nothing to say.
+func (h *TTSHandler) synthesizeQueued(ctx context.Context, text string, voice config.TTSPreset) (string, error) {
+	if h.config.TTSStripEmoji {
+		cleaned, stripped := stripUnspeakable(text)
+		if len(stripped) > 0 {
+			logger.FromContext(ctx).Info().
+				Str("stripped", strconv.QuoteToASCII(string(stripped))).
+				Int("original_length", len(text)).
+				Int("cleaned_length", len(cleaned)).
+				Msg("Stripped unspeakable characters from TTS text")
+		}
+		if strings.TrimSpace(cleaned) == "" {
+			return "", errNothingToSpeak
 		}
-	}()
+		text = cleaned
+	}
+
+	// Repeated phrases are served from the cache without waiting for a slot
+	if audioPath, ok := h.cachedSpeech(ctx, text, voice); ok {
+		return audioPath, nil
+	}
+
+	release, ok := h.acquireSlot(ctx)
+	if !ok {
+		logger.Get().Warn().
+			Int("max_concurrent_tts", h.config.MaxConcurrentTTS).
+			Dur("queue_timeout", h.config.TTSQueueTimeout).
+			Msg("TTS saturated, rejecting request")
+		return "", errTTSBusy
+	}
+	defer release()
+
+	return h.synthesize(ctx, text, voice)
+}
+
+// sendAudio streams a synthesized WAV file as the response and removes it afterwards
+func (h *TTSHandler) sendAudio(c *gin.Context, audioPath string) {
+	// Ensure the audio file is cleaned up after sending
+	defer removeTempFile(h.config, audioPath, logger.Get())
+
+	if info, err := os.Stat(audioPath); err == nil {
+		audioBytes.synthesized.Add(info.Size())
+	}
+
+	if duration, err := wavDuration(audioPath); err == nil {
+		c.Header(middleware.AudioDurationHeader, strconv.FormatInt(duration.Milliseconds(), 10))
+	} else {
+		logger.Get().Debug().Err(err).Str("path", audioPath).Msg("Could not determine audio duration")
+	}
 
 	// Stream the WAV file as response
 	c.Header("Content-Type", "audio/wav")
 	c.File(audioPath)
-
-	log.Info().Msg("TTS audio sent successfully")
 }
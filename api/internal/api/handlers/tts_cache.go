@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/logger"
+)
+
+// ttsCacheDirName is the subdirectory of the TTS temp dir holding cached audio.
+// cleanupOldTempFiles skips directories, so the age-based sweep leaves it alone.
+const ttsCacheDirName = "cache"
+
+// ttsCache keeps synthesized WAVs on disk keyed by text, voice, and speed so
+// repeated phrases skip kokoro-tts. It evicts least recently used audio once
+// the cache grows past maxBytes.
+type ttsCache struct {
+	dir      string
+	maxBytes int64
+
+	mu sync.Mutex
+	// order holds *ttsCacheEntry, most recently used first
+	order   *list.List
+	entries map[string]*list.Element
+	size    int64
+}
+
+type ttsCacheEntry struct {
+	key  string
+	size int64
+}
+
+// newTTSCache returns a cache in dir holding up to maxMB megabytes, or nil when
+// maxMB is not positive (caching disabled). Audio already in dir from an
+// earlier run is kept, ordered by when it was last used.
+func newTTSCache(dir string, maxMB int) *ttsCache {
+	if maxMB <= 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logger.Get().Warn().Err(err).Str("dir", dir).Msg("Failed to create TTS cache directory, caching disabled")
+		return nil
+	}
+
+	tc := &ttsCache{
+		dir:      dir,
+		maxBytes: int64(maxMB) * 1024 * 1024,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+	tc.load()
+	return tc
+}
+
+// ttsCacheKey hashes whitespace-normalized text with the voice and speed that shape the audio
+func ttsCacheKey(text string, voice config.TTSPreset) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", strings.Join(strings.Fields(text), " "), voice.Voice, strconv.FormatFloat(voice.Speed, 'f', -1, 64))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path returns where the audio for key is stored
+func (tc *ttsCache) path(key string) string {
+	return filepath.Join(tc.dir, key+".wav")
+}
+
+// load indexes audio left in the cache directory, oldest use last
+func (tc *ttsCache) load() {
+	entries, err := os.ReadDir(tc.dir)
+	if err != nil {
+		logger.Get().Warn().Err(err).Str("dir", tc.dir).Msg("Failed to read TTS cache directory")
+		return
+	}
+
+	type cached struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var found []cached
+	for _, entry := range entries {
+		key, ok := strings.CutSuffix(entry.Name(), ".wav")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			found = append(found, cached{key: key, size: info.Size(), modTime: info.ModTime()})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime.After(found[j].modTime) })
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	for _, f := range found {
+		tc.entries[f.key] = tc.order.PushBack(&ttsCacheEntry{key: f.key, size: f.size})
+		tc.size += f.size
+	}
+	tc.evictLocked()
+}
+
+// Get copies the cached audio for key to dest, returning false on a miss. The
+// copy is the caller's to delete, so eviction can't remove audio mid-response.
+func (tc *ttsCache) Get(key string, dest string) bool {
+	tc.mu.Lock()
+	elem, ok := tc.entries[key]
+	if ok {
+		tc.order.MoveToFront(elem)
+	}
+	tc.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	path := tc.path(key)
+	if err := linkOrCopy(path, dest); err != nil {
+		// The file went missing underneath the index; forget it
+		tc.mu.Lock()
+		if current, ok := tc.entries[key]; ok && current == elem {
+			tc.removeLocked(elem)
+		}
+		tc.mu.Unlock()
+		return false
+	}
+
+	// Persist recency so a restart keeps the LRU order
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return true
+}
+
+// Put adds the audio at src to the cache under key, evicting the least
+// recently used audio to stay within maxBytes. Audio larger than the whole
+// cache isn't stored.
+func (tc *ttsCache) Put(key string, src string) {
+	info, err := os.Stat(src)
+	if err != nil || info.Size() > tc.maxBytes {
+		return
+	}
+
+	tc.mu.Lock()
+	_, exists := tc.entries[key]
+	tc.mu.Unlock()
+	if exists {
+		return
+	}
+
+	if err := linkOrCopy(src, tc.path(key)); err != nil {
+		if !os.IsExist(err) {
+			logger.Get().Warn().Err(err).Str("key", key).Msg("Failed to cache TTS audio")
+		}
+		return
+	}
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if _, exists := tc.entries[key]; exists {
+		return
+	}
+	tc.entries[key] = tc.order.PushFront(&ttsCacheEntry{key: key, size: info.Size()})
+	tc.size += info.Size()
+	tc.evictLocked()
+}
+
+// evictLocked removes least recently used audio until the cache fits maxBytes
+func (tc *ttsCache) evictLocked() {
+	for tc.size > tc.maxBytes {
+		oldest := tc.order.Back()
+		if oldest == nil {
+			return
+		}
+		entry := tc.removeLocked(oldest)
+		if err := os.Remove(tc.path(entry.key)); err != nil && !os.IsNotExist(err) {
+			logger.Get().Warn().Err(err).Str("key", entry.key).Msg("Failed to evict cached TTS audio")
+		}
+	}
+}
+
+// removeLocked drops elem from the index
+func (tc *ttsCache) removeLocked(elem *list.Element) *ttsCacheEntry {
+	entry := tc.order.Remove(elem).(*ttsCacheEntry)
+	delete(tc.entries, entry.key)
+	tc.size -= entry.size
+	return entry
+}
+
+// linkOrCopy hard-links src to dest, copying when a link isn't possible
+func linkOrCopy(src string, dest string) error {
+	err := os.Link(src, dest)
+	if err == nil || os.IsExist(err) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/config"
+)
+
+func TestTTSCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("identical text is served without running kokoro-tts again", func(t *testing.T) {
+		t.Setenv("TMPDIR", t.TempDir())
+		runs := filepath.Join(t.TempDir(), "runs")
+		handler := NewTTSHandler(&config.Config{
+			KokoroTTSPath:  writeFakeScript(t, "kokoro-tts", `echo run >> "`+runs+`"`+"\n"+`echo "RIFF $*" > "$2"`),
+			KokoroTTSVoice: "af_sarah",
+			KokoroTTSSpeed: 1,
+			TTSCacheMaxMB:  1,
+		})
+
+		generate := func(body string) string {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/api/tts", bytes.NewBufferString(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			handler.Generate(c)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			return w.Body.String()
+		}
+		execs := func() int {
+			data, _ := os.ReadFile(runs)
+			return strings.Count(string(data), "run")
+		}
+
+		first := generate(`{"text":"Got it"}`)
+		second := generate(`{"text":"  Got   it "}`)
+
+		if execs() != 1 {
+			t.Errorf("expected kokoro-tts to run once, ran %d times", execs())
+		}
+		if second != first {
+			t.Errorf("expected the cached audio, got %q", second)
+		}
+
+		generate(`{"text":"Got it","speed":1.5}`)
+		if execs() != 2 {
+			t.Errorf("expected a different speed to run kokoro-tts, ran %d times", execs())
+		}
+	})
+
+	t.Run("cached audio is served while every synthesis slot is busy", func(t *testing.T) {
+		t.Setenv("TMPDIR", t.TempDir())
+		handler := NewTTSHandler(&config.Config{
+			KokoroTTSPath:    writeFakeScript(t, "kokoro-tts", `echo "RIFF $*" > "$2"`),
+			KokoroTTSVoice:   "af_sarah",
+			KokoroTTSSpeed:   1,
+			TTSCacheMaxMB:    1,
+			MaxConcurrentTTS: 1,
+			TTSQueueTimeout:  50 * time.Millisecond,
+		})
+		voice := config.TTSPreset{Voice: "af_sarah", Speed: 1}
+
+		if _, err := handler.synthesizeQueued(context.Background(), "Got it", voice); err != nil {
+			t.Fatalf("expected the first synthesis to succeed, got %v", err)
+		}
+		release, _ := handler.acquireSlot(context.Background())
+		defer release()
+
+		if _, err := handler.synthesizeQueued(context.Background(), "Got it", voice); err != nil {
+			t.Errorf("expected the cached audio without a free slot, got %v", err)
+		}
+		if _, err := handler.synthesizeQueued(context.Background(), "Something new", voice); !errors.Is(err, errTTSBusy) {
+			t.Errorf("expected uncached text to wait for a slot, got %v", err)
+		}
+	})
+
+	put := func(t *testing.T, tc *ttsCache, key string, size int) {
+		t.Helper()
+		src := filepath.Join(t.TempDir(), key+".src")
+		os.WriteFile(src, bytes.Repeat([]byte("x"), size), 0644)
+		tc.Put(key, src)
+	}
+	has := func(tc *ttsCache, key string) bool {
+		_, err := os.Stat(tc.path(key))
+		return err == nil
+	}
+
+	t.Run("evicts the least recently used audio", func(t *testing.T) {
+		tc := newTTSCache(t.TempDir(), 1)
+		tc.maxBytes = 10
+
+		put(t, tc, "a", 4)
+		put(t, tc, "b", 4)
+		if !tc.Get("a", filepath.Join(t.TempDir(), "out.wav")) {
+			t.Fatal("expected a cache hit for a")
+		}
+		put(t, tc, "c", 4)
+
+		if !has(tc, "a") || has(tc, "b") || !has(tc, "c") {
+			t.Errorf("expected b to be evicted, have a=%v b=%v c=%v", has(tc, "a"), has(tc, "b"), has(tc, "c"))
+		}
+		if tc.size != 8 {
+			t.Errorf("expected 8 cached bytes, got %d", tc.size)
+		}
+	})
+
+	t.Run("audio larger than the cache is not stored", func(t *testing.T) {
+		tc := newTTSCache(t.TempDir(), 1)
+		tc.maxBytes = 10
+
+		put(t, tc, "big", 11)
+		if has(tc, "big") {
+			t.Error("expected oversized audio to be skipped")
+		}
+	})
+
+	t.Run("restores cached audio from disk", func(t *testing.T) {
+		dir := t.TempDir()
+		put(t, newTTSCache(dir, 1), "kept", 4)
+
+		restored := newTTSCache(dir, 1)
+		if !restored.Get("kept", filepath.Join(t.TempDir(), "out.wav")) {
+			t.Error("expected audio cached by an earlier run to be served")
+		}
+	})
+
+	t.Run("the temp file sweep leaves cached audio alone", func(t *testing.T) {
+		tempDir := t.TempDir()
+		tc := newTTSCache(filepath.Join(tempDir, ttsCacheDirName), 1)
+		put(t, tc, "phrase", 4)
+		old := time.Now().Add(-48 * time.Hour)
+		os.Chtimes(tc.path("phrase"), old, old)
+
+		NewTTSHandler(&config.Config{}).cleanupOldTempFiles(tempDir, time.Hour)
+
+		if !has(tc, "phrase") {
+			t.Error("expected cached audio to survive the sweep")
+		}
+	})
+}
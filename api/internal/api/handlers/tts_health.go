@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/config"
 	"github.com/sean/janus/internal/logger"
 )
 
@@ -19,98 +20,97 @@ type TTSHealthResponse struct {
 
 // HealthCheck checks if Kokoro TTS is properly configured and available
 func (h *TTSHandler) HealthCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, checkKokoroAvailability(h.config))
+}
+
+// checkKokoroAvailability verifies the kokoro-tts executable, model, and voices files exist
+func checkKokoroAvailability(cfg *config.Config) TTSHealthResponse {
 	log := logger.Get()
 
 	// Check if kokoro-tts executable exists
-	if _, err := os.Stat(h.config.KokoroTTSPath); err != nil {
+	if _, err := os.Stat(cfg.KokoroTTSPath); err != nil {
 		if os.IsNotExist(err) {
 			log.Debug().
-				Str("path", h.config.KokoroTTSPath).
+				Str("path", cfg.KokoroTTSPath).
 				Msg("Kokoro TTS executable not found")
 
-			c.JSON(http.StatusOK, TTSHealthResponse{
+			return TTSHealthResponse{
 				Available: false,
 				Provider:  "browser",
 				Message:   "Kokoro TTS not configured, using browser TTS",
-			})
-			return
+			}
 		}
 		// Handle other errors (permission, I/O, etc.)
 		log.Error().
 			Err(err).
-			Str("path", h.config.KokoroTTSPath).
+			Str("path", cfg.KokoroTTSPath).
 			Msg("Failed to check Kokoro TTS executable")
 
-		c.JSON(http.StatusOK, TTSHealthResponse{
+		return TTSHealthResponse{
 			Available: false,
 			Provider:  "browser",
 			Message:   fmt.Sprintf("Kokoro TTS inaccessible: %v", err),
-		})
-		return
+		}
 	}
 
 	// Check if model files exist
-	if _, err := os.Stat(h.config.KokoroTTSModelPath); err != nil {
+	if _, err := os.Stat(cfg.KokoroTTSModelPath); err != nil {
 		if os.IsNotExist(err) {
 			log.Debug().
-				Str("path", h.config.KokoroTTSModelPath).
+				Str("path", cfg.KokoroTTSModelPath).
 				Msg("Kokoro model file not found")
 
-			c.JSON(http.StatusOK, TTSHealthResponse{
+			return TTSHealthResponse{
 				Available: false,
 				Provider:  "browser",
 				Message:   "Kokoro model files not found, using browser TTS",
-			})
-			return
+			}
 		}
 		// Handle other errors (permission, I/O, etc.)
 		log.Error().
 			Err(err).
-			Str("path", h.config.KokoroTTSModelPath).
+			Str("path", cfg.KokoroTTSModelPath).
 			Msg("Failed to check Kokoro model file")
 
-		c.JSON(http.StatusOK, TTSHealthResponse{
+		return TTSHealthResponse{
 			Available: false,
 			Provider:  "browser",
 			Message:   fmt.Sprintf("Kokoro TTS inaccessible: %v", err),
-		})
-		return
+		}
 	}
 
-	if _, err := os.Stat(h.config.KokoroTTSVoicesPath); err != nil {
+	if _, err := os.Stat(cfg.KokoroTTSVoicesPath); err != nil {
 		if os.IsNotExist(err) {
 			log.Debug().
-				Str("path", h.config.KokoroTTSVoicesPath).
+				Str("path", cfg.KokoroTTSVoicesPath).
 				Msg("Kokoro voices file not found")
 
-			c.JSON(http.StatusOK, TTSHealthResponse{
+			return TTSHealthResponse{
 				Available: false,
 				Provider:  "browser",
 				Message:   "Kokoro voices file not found, using browser TTS",
-			})
-			return
+			}
 		}
 		// Handle other errors (permission, I/O, etc.)
 		log.Error().
 			Err(err).
-			Str("path", h.config.KokoroTTSVoicesPath).
+			Str("path", cfg.KokoroTTSVoicesPath).
 			Msg("Failed to check Kokoro voices file")
 
-		c.JSON(http.StatusOK, TTSHealthResponse{
+		return TTSHealthResponse{
 			Available: false,
 			Provider:  "browser",
 			Message:   fmt.Sprintf("Kokoro TTS inaccessible: %v", err),
-		})
-		return
+		}
 	}
 
 	// All checks passed - Kokoro TTS is available
 	log.Debug().Msg("Kokoro TTS is available and configured")
 
-	c.JSON(http.StatusOK, TTSHealthResponse{
+	return TTSHealthResponse{
 		Available: true,
 		Provider:  "kokoro",
-		Voice:     h.config.KokoroTTSVoice,
+		Voice:     cfg.KokoroTTSVoice,
 		Message:   "Kokoro TTS available",
-	})
+	}
 }
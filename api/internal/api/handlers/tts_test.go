@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/config"
+)
+
+// fakeSynthesizer stands in for kokoro, tracking how many syntheses run at once
+type fakeSynthesizer struct {
+	dir     string
+	release chan struct{}
+	active  atomic.Int32
+	peak    atomic.Int32
+	started chan struct{}
+}
+
+func newFakeSynthesizer(t *testing.T) *fakeSynthesizer {
+	return &fakeSynthesizer{
+		dir:     t.TempDir(),
+		release: make(chan struct{}),
+		started: make(chan struct{}, 100),
+	}
+}
+
+func (f *fakeSynthesizer) synthesize(ctx context.Context, text string, voice config.TTSPreset) (string, error) {
+	active := f.active.Add(1)
+	defer f.active.Add(-1)
+	for {
+		peak := f.peak.Load()
+		if active <= peak || f.peak.CompareAndSwap(peak, active) {
+			break
+		}
+	}
+	f.started <- struct{}{}
+
+	select {
+	case <-f.release:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	path := filepath.Join(f.dir, "output.wav")
+	os.WriteFile(path, []byte("RIFF"), 0644)
+	return path, nil
+}
+
+func TestTTSGenerate_MaxConcurrentTTS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	generate := func(handler *TTSHandler) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/tts", bytes.NewBufferString(`{"text":"hello"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Generate(c)
+		return w.Code
+	}
+
+	t.Run("bounds concurrent syntheses", func(t *testing.T) {
+		handler := NewTTSHandler(&config.Config{MaxConcurrentTTS: 2, TTSQueueTimeout: 5 * time.Second, KeepTempFiles: true})
+		fake := newFakeSynthesizer(t)
+		handler.synthesize = fake.synthesize
+
+		const requests = 5
+		codes := make(chan int, requests)
+		var wg sync.WaitGroup
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				codes <- generate(handler)
+			}()
+		}
+
+		// Wait until the limit is reached, then let syntheses finish one by one
+		<-fake.started
+		<-fake.started
+		time.Sleep(50 * time.Millisecond)
+		if active := fake.active.Load(); active != 2 {
+			t.Errorf("expected 2 active syntheses at the limit, got %d", active)
+		}
+		close(fake.release)
+		wg.Wait()
+		close(codes)
+
+		if peak := fake.peak.Load(); peak > 2 {
+			t.Errorf("expected at most 2 concurrent syntheses, got %d", peak)
+		}
+		for code := range codes {
+			if code != http.StatusOK {
+				t.Errorf("expected queued requests to succeed, got %d", code)
+			}
+		}
+	})
+
+	t.Run("returns 503 when saturated past the queue timeout", func(t *testing.T) {
+		handler := NewTTSHandler(&config.Config{MaxConcurrentTTS: 1, TTSQueueTimeout: 50 * time.Millisecond, KeepTempFiles: true})
+		fake := newFakeSynthesizer(t)
+		handler.synthesize = fake.synthesize
+
+		first := make(chan int, 1)
+		go func() { first <- generate(handler) }()
+		<-fake.started
+
+		if code := generate(handler); code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503 while saturated, got %d", code)
+		}
+
+		close(fake.release)
+		if code := <-first; code != http.StatusOK {
+			t.Errorf("expected in-flight synthesis to succeed, got %d", code)
+		}
+	})
+}
+
+func TestTTSGenerate_BrowserFallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	failingSynth := func(ctx context.Context, text string, voice config.TTSPreset) (string, error) {
+		return "", errors.New("kokoro-tts failed: CUDA unavailable")
+	}
+
+	generate := func(handler *TTSHandler) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/tts", bytes.NewBufferString(`{"text":"hello there"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Generate(c)
+		return w
+	}
+
+	t.Run("signals browser fallback when allowed", func(t *testing.T) {
+		handler := NewTTSHandler(&config.Config{AllowBrowserFallback: true})
+		handler.synthesize = failingSynth
+
+		w := generate(handler)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var response TTSFallbackResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if response.Fallback != "browser" || response.Text != "hello there" {
+			t.Errorf("unexpected fallback response: %+v", response)
+		}
+	})
+
+	t.Run("returns 500 when fallback is disabled", func(t *testing.T) {
+		handler := NewTTSHandler(&config.Config{})
+		handler.synthesize = failingSynth
+
+		if w := generate(handler); w.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", w.Code)
+		}
+	})
+}
+
+// fetchAudioStats reads the audio counters through the /stats endpoint
+func fetchAudioStats(t *testing.T) AudioStats {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/stats", nil)
+	NewStatsHandler(NewMockSessionManager()).Handle(c)
+
+	var response StatsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse stats response: %v", err)
+	}
+	return response.Audio
+}
+
+func TestTTSGenerate_CountsSynthesizedBytes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	wav := bytes.Repeat([]byte("w"), 2048)
+	handler := NewTTSHandler(&config.Config{})
+	handler.synthesize = func(ctx context.Context, text string, voice config.TTSPreset) (string, error) {
+		path := filepath.Join(t.TempDir(), "output.wav")
+		return path, os.WriteFile(path, wav, 0644)
+	}
+
+	before := fetchAudioStats(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/api/tts", bytes.NewBufferString(`{"text":"hello there"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler.Generate(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	after := fetchAudioStats(t)
+	if got := after.SynthesizedBytes - before.SynthesizedBytes; got != int64(len(wav)) {
+		t.Errorf("expected synthesized bytes to grow by %d, got %d", len(wav), got)
+	}
+	if after.TranscribedBytes != before.TranscribedBytes {
+		t.Error("expected transcribed bytes to be unchanged")
+	}
+}
+
+func TestTTSGenerate_Presets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	cfg := &config.Config{
+		KokoroTTSVoice: "af_sarah",
+		KokoroTTSSpeed: 1,
+		TTSPresets: map[string]config.TTSPreset{
+			"calm": {Voice: "af_nicole", Speed: 0.8},
+			"fast": {Voice: "af_bella", Speed: 1.4},
+		},
+	}
+
+	generate := func(body string) (*httptest.ResponseRecorder, *config.TTSPreset) {
+		var used *config.TTSPreset
+		handler := NewTTSHandler(cfg)
+		handler.synthesize = func(ctx context.Context, text string, voice config.TTSPreset) (string, error) {
+			used = &voice
+			path := filepath.Join(t.TempDir(), "output.wav")
+			return path, os.WriteFile(path, []byte("RIFF"), 0644)
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/tts", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Generate(c)
+		return w, used
+	}
+
+	t.Run("preset resolves to its voice and speed", func(t *testing.T) {
+		w, used := generate(`{"text":"hello","preset":"fast"}`)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if used == nil || *used != (config.TTSPreset{Voice: "af_bella", Speed: 1.4}) {
+			t.Errorf("expected fast preset voice, got %+v", used)
+		}
+	})
+
+	t.Run("no preset uses the configured defaults", func(t *testing.T) {
+		w, used := generate(`{"text":"hello"}`)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if used == nil || *used != (config.TTSPreset{Voice: "af_sarah", Speed: 1}) {
+			t.Errorf("expected default voice, got %+v", used)
+		}
+	})
+
+	t.Run("unknown preset returns 400", func(t *testing.T) {
+		w, used := generate(`{"text":"hello","preset":"dramatic"}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+		if used != nil {
+			t.Error("expected no synthesis for an unknown preset")
+		}
+	})
+
+	t.Run("voice and speed override the defaults", func(t *testing.T) {
+		cfg.TTSVoices = []string{"am_adam"}
+		defer func() { cfg.TTSVoices = nil }()
+		w, used := generate(`{"text":"hello","voice":"am_adam","speed":1.25}`)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if used == nil || *used != (config.TTSPreset{Voice: "am_adam", Speed: 1.25}) {
+			t.Errorf("expected requested voice and speed, got %+v", used)
+		}
+	})
+
+	t.Run("speed overrides a preset's speed only", func(t *testing.T) {
+		w, used := generate(`{"text":"hello","preset":"calm","speed":0.5}`)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if used == nil || *used != (config.TTSPreset{Voice: "af_nicole", Speed: 0.5}) {
+			t.Errorf("expected calm voice at the requested speed, got %+v", used)
+		}
+	})
+
+	t.Run("invalid voice or speed returns 400", func(t *testing.T) {
+		for _, body := range []string{
+			`{"text":"hello","voice":"zz_nobody"}`,
+			`{"text":"hello","speed":0}`,
+			`{"text":"hello","speed":2.5}`,
+		} {
+			w, used := generate(body)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", body, w.Code)
+			}
+			if used != nil {
+				t.Errorf("%s: expected no synthesis", body)
+			}
+		}
+	})
+}
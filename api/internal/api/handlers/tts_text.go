@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"strings"
+	"unicode"
+)
+
+// isEmojiRune reports whether r is an emoji or a rune that only shapes emoji
+// (zero-width joiners, variation selectors, skin tones, keycap enclosures)
+func isEmojiRune(r rune) bool {
+	switch {
+	case r == 0x200d: // zero-width joiner between emoji
+		return true
+	case r >= 0xfe00 && r <= 0xfe0f: // variation selectors
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff: // skin tone modifiers
+		return true
+	case r >= 0x20d0 && r <= 0x20ff: // combining marks for symbols, e.g. keycaps
+		return unicode.Is(unicode.Me, r)
+	}
+	// Symbols below the arrows block (°, ©, ¶) read fine, so only the blocks
+	// emoji live in are treated as emoji
+	return r >= 0x2190 && unicode.Is(unicode.So, r)
+}
+
+// stripUnspeakable removes emoji and replaces control characters other than
+// newlines and tabs with spaces, since kokoro mishandles both. It returns the
+// cleaned text and each distinct rune it removed or replaced, in order of appearance.
+func stripUnspeakable(text string) (string, []rune) {
+	var b strings.Builder
+	b.Grow(len(text))
+	var stripped []rune
+	seen := make(map[rune]bool)
+	record := func(r rune) {
+		if !seen[r] {
+			seen[r] = true
+			stripped = append(stripped, r)
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case r == '\n' || r == '\t':
+			b.WriteRune(r)
+		case unicode.IsControl(r):
+			record(r)
+			b.WriteRune(' ')
+		case isEmojiRune(r):
+			record(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), stripped
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/config"
+)
+
+func TestStripUnspeakable(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantText     string
+		wantStripped string
+	}{
+		{"plain text is unchanged", "Hello, world! It's 5°C — café.", "Hello, world! It's 5°C — café.", ""},
+		{"newlines and tabs are kept", "line one\n\tline two", "line one\n\tline two", ""},
+		{"single emoji removed", "Done 🎉", "Done ", "🎉"},
+		{"emoji with variation selector", "Nice ❤️ work", "Nice  work", "❤️"},
+		{"zwj sequence removed whole", "Team 👩‍💻 ready", "Team  ready", "👩‍💻"},
+		{"skin tone modifier removed", "Wave 👋🏽", "Wave ", "👋🏽"},
+		{"keycap enclosure removed", "Press 1⃣", "Press 1", "⃣"},
+		{"repeated emoji recorded once", "🚀🚀🚀 go", " go", "🚀"},
+		{"control characters become spaces", "bell\aand\x00null\rreturn", "bell and null return", "\a\x00\r"},
+		{"c1 control character replaced", "a\u0085b", "a b", "\u0085"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, stripped := stripUnspeakable(tt.input)
+
+			if text != tt.wantText {
+				t.Errorf("expected text %q, got %q", tt.wantText, text)
+			}
+			if string(stripped) != tt.wantStripped {
+				t.Errorf("expected stripped %q, got %q", tt.wantStripped, string(stripped))
+			}
+		})
+	}
+}
+
+func TestTTSGenerate_StripEmoji(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	generate := func(strip bool, body string) (*httptest.ResponseRecorder, *string) {
+		var spoken *string
+		handler := NewTTSHandler(&config.Config{TTSStripEmoji: strip})
+		handler.synthesize = func(ctx context.Context, text string, voice config.TTSPreset) (string, error) {
+			spoken = &text
+			path := filepath.Join(t.TempDir(), "output.wav")
+			return path, os.WriteFile(path, []byte("RIFF"), 0644)
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/tts", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Generate(c)
+		return w, spoken
+	}
+
+	t.Run("strips emoji before synthesis when enabled", func(t *testing.T) {
+		w, spoken := generate(true, `{"text":"Shipped 🚀 it"}`)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if spoken == nil || *spoken != "Shipped  it" {
+			t.Errorf("expected emoji stripped, got %v", spoken)
+		}
+	})
+
+	t.Run("leaves text untouched when disabled", func(t *testing.T) {
+		w, spoken := generate(false, `{"text":"Shipped 🚀 it"}`)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if spoken == nil || *spoken != "Shipped 🚀 it" {
+			t.Errorf("expected original text, got %v", spoken)
+		}
+	})
+
+	t.Run("emoji-only text returns 400", func(t *testing.T) {
+		w, spoken := generate(true, `{"text":"🎉 🎉"}`)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+		if spoken != nil {
+			t.Error("expected no synthesis for emoji-only text")
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"archive/zip"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/logger"
+)
+
+// voiceLanguages maps the first letter of a kokoro voice ID to its kokoro-tts language
+var voiceLanguages = map[byte]string{
+	'a': "en-us",
+	'b': "en-gb",
+	'e': "es",
+	'f': "fr-fr",
+	'h': "hi",
+	'i': "it",
+	'j': "ja",
+	'p': "pt-br",
+	'z': "cmn",
+}
+
+// TTSVoice describes one voice kokoro-tts can speak with
+type TTSVoice struct {
+	ID       string `json:"id"`
+	Language string `json:"language"`
+	// Default marks the voice used when a request doesn't choose one
+	Default bool `json:"default,omitempty"`
+}
+
+// TTSVoicesResponse lists the voices in the configured voices file
+type TTSVoicesResponse struct {
+	Voices []TTSVoice `json:"voices"`
+}
+
+// Voices lists the voices available in the configured kokoro voices file so
+// clients can offer a voice picker. When kokoro isn't usable it responds like
+// HealthCheck, telling the client to fall back to browser TTS.
+func (h *TTSHandler) Voices(c *gin.Context) {
+	if availability := checkKokoroAvailability(h.config); !availability.Available {
+		respondWithData(h.config, c, http.StatusOK, availability)
+		return
+	}
+
+	ids, err := readVoiceIDs(h.config.KokoroTTSVoicesPath)
+	if err != nil {
+		logger.Get().Error().Err(err).Str("path", h.config.KokoroTTSVoicesPath).Msg("Failed to read Kokoro voices file")
+		respondWithData(h.config, c, http.StatusOK, TTSHealthResponse{
+			Available: false,
+			Provider:  "browser",
+			Message:   "Kokoro voices file unreadable, using browser TTS",
+		})
+		return
+	}
+
+	voices := make([]TTSVoice, 0, len(ids))
+	for _, id := range ids {
+		voices = append(voices, TTSVoice{
+			ID:       id,
+			Language: voiceLanguages[id[0]],
+			Default:  id == h.config.KokoroTTSVoice,
+		})
+	}
+	respondWithData(h.config, c, http.StatusOK, TTSVoicesResponse{Voices: voices})
+}
+
+// readVoiceIDs returns the sorted voice IDs in a kokoro voices file, which is
+// a NumPy .npz archive holding one <voice>.npy array per voice
+func readVoiceIDs(voicesPath string) ([]string, error) {
+	archive, err := zip.OpenReader(voicesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open voices archive: %w", err)
+	}
+	defer archive.Close()
+
+	ids := make([]string, 0, len(archive.File))
+	for _, file := range archive.File {
+		id, ok := strings.CutSuffix(path.Base(file.Name), ".npy")
+		if ok && id != "" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
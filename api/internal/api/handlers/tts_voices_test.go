@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/config"
+)
+
+// writeVoicesArchive writes a kokoro-style .npz voices file holding the given voices
+func writeVoicesArchive(t *testing.T, path string, voices ...string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create voices file: %v", err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for _, voice := range voices {
+		entry, err := w.Create(voice + ".npy")
+		if err != nil {
+			t.Fatalf("failed to add voice: %v", err)
+		}
+		entry.Write([]byte("\x93NUMPY"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to write voices file: %v", err)
+	}
+}
+
+func TestTTSVoices(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		KokoroTTSPath:       filepath.Join(dir, "kokoro-tts"),
+		KokoroTTSModelPath:  filepath.Join(dir, "kokoro.onnx"),
+		KokoroTTSVoicesPath: filepath.Join(dir, "voices.bin"),
+		KokoroTTSVoice:      "af_sarah",
+	}
+	for _, path := range []string{cfg.KokoroTTSPath, cfg.KokoroTTSModelPath} {
+		os.WriteFile(path, []byte("x"), 0755)
+	}
+
+	voices := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/tts/voices", nil)
+		NewTTSHandler(cfg).Voices(c)
+		return w
+	}
+
+	t.Run("lists voices with language and default", func(t *testing.T) {
+		writeVoicesArchive(t, cfg.KokoroTTSVoicesPath, "bm_george", "af_sarah", "jf_alpha")
+
+		w := voices()
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+
+		var response TTSVoicesResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		expected := []TTSVoice{
+			{ID: "af_sarah", Language: "en-us", Default: true},
+			{ID: "bm_george", Language: "en-gb"},
+			{ID: "jf_alpha", Language: "ja"},
+		}
+		if len(response.Voices) != len(expected) {
+			t.Fatalf("expected %d voices, got %+v", len(expected), response.Voices)
+		}
+		for i, voice := range expected {
+			if response.Voices[i] != voice {
+				t.Errorf("expected %+v, got %+v", voice, response.Voices[i])
+			}
+		}
+	})
+
+	t.Run("wrapped in envelope when enabled", func(t *testing.T) {
+		cfg.EnvelopeResponses = true
+		defer func() { cfg.EnvelopeResponses = false }()
+
+		var response struct {
+			Data TTSVoicesResponse `json:"data"`
+		}
+		json.Unmarshal(voices().Body.Bytes(), &response)
+		if len(response.Data.Voices) != 3 {
+			t.Errorf("expected the voices inside the envelope, got %+v", response)
+		}
+	})
+
+	t.Run("unreadable voices file falls back to browser TTS", func(t *testing.T) {
+		os.WriteFile(cfg.KokoroTTSVoicesPath, []byte("not an archive"), 0644)
+
+		var response TTSHealthResponse
+		json.Unmarshal(voices().Body.Bytes(), &response)
+		if response.Available || response.Provider != "browser" {
+			t.Errorf("expected browser fallback, got %+v", response)
+		}
+	})
+
+	t.Run("missing kokoro falls back to browser TTS", func(t *testing.T) {
+		os.Remove(cfg.KokoroTTSPath)
+
+		var response TTSHealthResponse
+		json.Unmarshal(voices().Body.Bytes(), &response)
+		if response.Available || response.Provider != "browser" {
+			t.Errorf("expected browser fallback, got %+v", response)
+		}
+	})
+}
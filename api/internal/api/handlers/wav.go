@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// wavDuration reads a WAV file's chunk headers and returns how long its audio
+// plays: the data chunk size divided by the fmt chunk's byte rate. Only the
+// headers are read, not the samples.
+func wavDuration(path string) (time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var riff [12]byte
+	if _, err := io.ReadFull(f, riff[:]); err != nil {
+		return 0, fmt.Errorf("failed to read WAV header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return 0, errors.New("not a WAV file")
+	}
+
+	var byteRate uint32
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(f, header[:]); err != nil {
+			return 0, fmt.Errorf("WAV file has no data chunk: %w", err)
+		}
+		id := string(header[0:4])
+		size := binary.LittleEndian.Uint32(header[4:8])
+
+		switch id {
+		case "fmt ":
+			// Byte rate sits 8 bytes into the fmt chunk, after format, channels, and sample rate
+			var format [12]byte
+			if size < uint32(len(format)) {
+				return 0, errors.New("WAV fmt chunk is too short")
+			}
+			if _, err := io.ReadFull(f, format[:]); err != nil {
+				return 0, fmt.Errorf("failed to read WAV fmt chunk: %w", err)
+			}
+			byteRate = binary.LittleEndian.Uint32(format[8:12])
+			size -= uint32(len(format))
+		case "data":
+			if byteRate == 0 {
+				return 0, errors.New("WAV data chunk precedes a valid fmt chunk")
+			}
+			return time.Duration(uint64(size) * uint64(time.Second) / uint64(byteRate)), nil
+		}
+
+		// Chunks are padded to an even length
+		if _, err := f.Seek(int64(size)+int64(size%2), io.SeekCurrent); err != nil {
+			return 0, fmt.Errorf("failed to skip WAV chunk: %w", err)
+		}
+	}
+}
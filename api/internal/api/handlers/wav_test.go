@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/middleware"
+	"github.com/sean/janus/internal/config"
+)
+
+// buildWAV returns a 16-bit mono PCM WAV at sampleRate with dataBytes of
+// silence, optionally preceded by a LIST chunk like some encoders write
+func buildWAV(sampleRate uint32, dataBytes uint32, withList bool) []byte {
+	var b bytes.Buffer
+	le := func(v any) { binary.Write(&b, binary.LittleEndian, v) }
+
+	b.WriteString("RIFF")
+	le(uint32(0)) // RIFF size is not used when reading the duration
+	b.WriteString("WAVE")
+
+	b.WriteString("fmt ")
+	le(uint32(16))
+	le(uint16(1))      // PCM
+	le(uint16(1))      // mono
+	le(sampleRate)     // sample rate
+	le(sampleRate * 2) // byte rate
+	le(uint16(2))      // block align
+	le(uint16(16))     // bits per sample
+
+	if withList {
+		b.WriteString("LIST")
+		le(uint32(5))
+		b.WriteString("INFOx\x00") // odd size plus padding byte
+	}
+
+	b.WriteString("data")
+	le(dataBytes)
+	b.Write(make([]byte, dataBytes))
+	return b.Bytes()
+}
+
+func writeWAV(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "output.wav")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write WAV: %v", err)
+	}
+	return path
+}
+
+func TestWAVDuration(t *testing.T) {
+	t.Run("computes duration from data size and byte rate", func(t *testing.T) {
+		path := writeWAV(t, buildWAV(24000, 72000, false))
+
+		duration, err := wavDuration(path)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if duration != 1500*time.Millisecond {
+			t.Errorf("expected 1.5s, got %v", duration)
+		}
+	})
+
+	t.Run("skips chunks before the data chunk", func(t *testing.T) {
+		path := writeWAV(t, buildWAV(16000, 16000, true))
+
+		duration, err := wavDuration(path)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if duration != 500*time.Millisecond {
+			t.Errorf("expected 0.5s, got %v", duration)
+		}
+	})
+
+	t.Run("rejects files that are not WAV", func(t *testing.T) {
+		path := writeWAV(t, []byte("not audio at all"))
+
+		if _, err := wavDuration(path); err == nil {
+			t.Error("expected an error for a non-WAV file")
+		}
+	})
+}
+
+func TestTTSGenerate_AudioDurationHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	wav := buildWAV(24000, 48000*3, false)
+	handler := NewTTSHandler(&config.Config{})
+	handler.synthesize = func(ctx context.Context, text string, voice config.TTSPreset) (string, error) {
+		return writeWAV(t, wav), nil
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/api/tts", bytes.NewBufferString(`{"text":"hello there"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler.Generate(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get(middleware.AudioDurationHeader); got != "3000" {
+		t.Errorf("expected %s of 3000, got %q", middleware.AudioDurationHeader, got)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/logger"
+)
+
+const (
+	// whisperWarmupTimeout bounds the warmup run, which includes loading the model
+	whisperWarmupTimeout = 5 * time.Minute
+	// warmupClipSampleRate and warmupClipDuration describe the silent warmup clip
+	warmupClipSampleRate = 16000
+	warmupClipDuration   = 500 * time.Millisecond
+)
+
+// WarmupWhisper transcribes a short silent clip so Whisper loads its model
+// before the first real request. It does nothing unless WarmupWhisperOnStart is
+// set. The outcome is logged; failures don't prevent the server from starting.
+func WarmupWhisper(ctx context.Context, cfg *config.Config) error {
+	if !cfg.WarmupWhisperOnStart {
+		return nil
+	}
+	log := logger.Get()
+
+	dir, err := os.MkdirTemp("", "janus-whisper-warmup")
+	if err != nil {
+		log.Warn().Err(err).Msg("Whisper warmup failed")
+		return fmt.Errorf("failed to create warmup directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	clipPath := filepath.Join(dir, "warmup.wav")
+	if err := os.WriteFile(clipPath, silentWAV(warmupClipDuration), 0644); err != nil {
+		log.Warn().Err(err).Msg("Whisper warmup failed")
+		return fmt.Errorf("failed to write warmup clip: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, whisperWarmupTimeout)
+	defer cancel()
+
+	log.Info().
+		Str("whisper_path", cfg.WhisperPath).
+		Str("model", cfg.WhisperModel).
+		Msg("Warming up Whisper")
+
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, cfg.WhisperPath,
+		clipPath,
+		"--model", cfg.WhisperModel,
+		"--output_format", "txt",
+		"--output_dir", dir,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		log.Warn().
+			Err(err).
+			Str("output", string(output)).
+			Dur("duration", time.Since(start)).
+			Msg("Whisper warmup failed")
+		return fmt.Errorf("whisper warmup failed: %w", err)
+	}
+
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Msg("Whisper warmup complete")
+	return nil
+}
+
+// silentWAV returns a 16-bit mono PCM WAV file of silence lasting duration
+func silentWAV(duration time.Duration) []byte {
+	const bitsPerSample = 16
+	const channels = 1
+	blockAlign := channels * bitsPerSample / 8
+	dataSize := int(duration.Seconds()*warmupClipSampleRate) * blockAlign
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16)) // fmt chunk size
+	binary.Write(&buf, binary.LittleEndian, uint16(1))  // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(warmupClipSampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(warmupClipSampleRate*blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(bitsPerSample))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	buf.Write(make([]byte, dataSize))
+	return buf.Bytes()
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWarmupWhisper(t *testing.T) {
+	newFakeWhisper := func(t *testing.T) (string, string) {
+		argsFile := filepath.Join(t.TempDir(), "args")
+		fake := writeFakeScript(t, "whisper", `echo "$*" > `+argsFile+`
+head -c 4 "$1" >> `+argsFile+`
+`)
+		return fake, argsFile
+	}
+
+	t.Run("runs whisper on a silent clip when enabled", func(t *testing.T) {
+		fake, argsFile := newFakeWhisper(t)
+		cfg := newTestConfig()
+		cfg.WhisperPath = fake
+		cfg.WhisperModel = "base"
+		cfg.WarmupWhisperOnStart = true
+
+		if err := WarmupWhisper(context.Background(), cfg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		args, err := os.ReadFile(argsFile)
+		if err != nil {
+			t.Fatalf("expected fake whisper to run: %v", err)
+		}
+		if !strings.Contains(string(args), "--model base") {
+			t.Errorf("expected configured model in args, got %q", string(args))
+		}
+		if !strings.HasSuffix(string(args), "RIFF") {
+			t.Errorf("expected a WAV clip to be transcribed, got %q", string(args))
+		}
+	})
+
+	t.Run("does nothing when disabled", func(t *testing.T) {
+		fake, argsFile := newFakeWhisper(t)
+		cfg := newTestConfig()
+		cfg.WhisperPath = fake
+
+		if err := WarmupWhisper(context.Background(), cfg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := os.Stat(argsFile); !os.IsNotExist(err) {
+			t.Error("expected whisper not to run when warmup is disabled")
+		}
+	})
+
+	t.Run("reports whisper failures", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.WhisperPath = writeFakeScript(t, "whisper", "exit 1\n")
+		cfg.WarmupWhisperOnStart = true
+
+		if err := WarmupWhisper(context.Background(), cfg); err == nil {
+			t.Error("expected an error from a failing whisper")
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net"
+
+	"golang.org/x/net/netutil"
+)
+
+// Listen opens a TCP listener on addr. When maxConnections is positive, at most
+// that many connections are accepted at once; further clients wait in the
+// kernel backlog until an existing connection closes.
+func Listen(addr string, maxConnections int) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxConnections > 0 {
+		listener = netutil.LimitListener(listener, maxConnections)
+	}
+
+	return listener, nil
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestListen_MaxConnections(t *testing.T) {
+	listener, err := Listen("127.0.0.1:0", 1)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		client, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("failed to dial: %v", err)
+		}
+		defer client.Close()
+	}
+
+	var first net.Conn
+	select {
+	case first = <-accepted:
+	case <-time.After(time.Second):
+		t.Fatal("expected the first connection to be accepted")
+	}
+
+	select {
+	case <-accepted:
+		t.Fatal("expected the second connection to wait while the limit is reached")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Closing the first connection frees its slot for the waiting one
+	first.Close()
+	select {
+	case second := <-accepted:
+		second.Close()
+	case <-time.After(time.Second):
+		t.Fatal("expected the second connection to be accepted once a slot freed")
+	}
+}
+
+func TestListen_Unlimited(t *testing.T) {
+	listener, err := Listen("127.0.0.1:0", 0)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	if _, ok := listener.(*net.TCPListener); !ok {
+		t.Errorf("expected an unwrapped TCP listener, got %T", listener)
+	}
+}
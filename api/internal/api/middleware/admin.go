@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+)
+
+// AdminKeyID identifies callers authenticated with the admin token in audit logs
+const AdminKeyID = "admin-token"
+
+// AdminAuth middleware requires "Authorization: Bearer <token>" matching the
+// configured admin token. With no token configured, admin routes are disabled.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			response.RespondWithError(c, http.StatusForbidden, response.ErrForbidden, "Admin endpoints are disabled")
+			c.Abort()
+			return
+		}
+
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			response.RespondWithError(c, http.StatusUnauthorized, response.ErrUnauthorized, "Invalid or missing admin token")
+			c.Abort()
+			return
+		}
+
+		c.Set("api_key_id", AdminKeyID)
+		c.Next()
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuthRouter(keys []string, publicPaths []string) *gin.Engine {
+	router := gin.New()
+	router.Use(APIKeyAuth(keys, publicPaths))
+	router.GET("/api/health", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	router.POST("/api/ask", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("api_key_id"))
+	})
+	return router
+}
+
+func serveAuth(router *gin.Engine, method string, path string, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if key != "" {
+		req.Header.Set(APIKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestAPIKeyAuth_PublicPathsSkipAuth verifies configured public paths need no key
+func TestAPIKeyAuth_PublicPathsSkipAuth(t *testing.T) {
+	router := newAuthRouter([]string{"secret"}, []string{"/api/health"})
+
+	w := serveAuth(router, "GET", "/api/health", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// TestAPIKeyAuth_OtherPathsRequireKey verifies non-public routes reject missing or wrong keys
+func TestAPIKeyAuth_OtherPathsRequireKey(t *testing.T) {
+	router := newAuthRouter([]string{"secret"}, []string{"/api/health"})
+
+	missing := serveAuth(router, "POST", "/api/ask", "")
+	assert.Equal(t, http.StatusUnauthorized, missing.Code)
+	assert.Contains(t, missing.Body.String(), "INVALID_REQUEST")
+
+	wrong := serveAuth(router, "POST", "/api/ask", "guess")
+	assert.Equal(t, http.StatusUnauthorized, wrong.Code)
+	assert.Contains(t, wrong.Body.String(), "INVALID_REQUEST")
+}
+
+// TestAPIKeyAuth_ValidKeyPasses verifies a configured key is accepted and identified by position
+func TestAPIKeyAuth_ValidKeyPasses(t *testing.T) {
+	router := newAuthRouter([]string{"first", "second"}, nil)
+
+	w := serveAuth(router, "POST", "/api/ask", "second")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "key-2", w.Body.String())
+}
+
+// TestAPIKeyAuth_EmptyPublicPathsProtectHealth verifies health can be protected too
+func TestAPIKeyAuth_EmptyPublicPathsProtectHealth(t *testing.T) {
+	router := newAuthRouter([]string{"secret"}, nil)
+
+	assert.Equal(t, http.StatusUnauthorized, serveAuth(router, "GET", "/api/health", "").Code)
+	assert.Equal(t, http.StatusOK, serveAuth(router, "GET", "/api/health", "secret").Code)
+}
+
+// TestAPIKeyAuth_DisabledWithoutKeys verifies every route is open when no keys are configured
+func TestAPIKeyAuth_DisabledWithoutKeys(t *testing.T) {
+	router := newAuthRouter(nil, nil)
+
+	assert.Equal(t, http.StatusOK, serveAuth(router, "POST", "/api/ask", "").Code)
+}
+
+// TestAPIKeyAuth_BearerKey verifies a key may be sent as an Authorization bearer token
+func TestAPIKeyAuth_BearerKey(t *testing.T) {
+	router := newAuthRouter([]string{"first", "second"}, nil)
+
+	serveBearer := func(value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/ask", nil)
+		req.Header.Set("Authorization", value)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	valid := serveBearer("Bearer first")
+	assert.Equal(t, http.StatusOK, valid.Code)
+	assert.Equal(t, "key-1", valid.Body.String())
+
+	assert.Equal(t, http.StatusUnauthorized, serveBearer("Bearer guess").Code)
+	assert.Equal(t, http.StatusUnauthorized, serveBearer("first").Code)
+}
+
+// TestAPIKeyAuth_HeaderKeyWithOtherBearer verifies X-API-Key still works when
+// the bearer carries something else, such as the admin token
+func TestAPIKeyAuth_HeaderKeyWithOtherBearer(t *testing.T) {
+	router := newAuthRouter([]string{"secret"}, nil)
+
+	req := httptest.NewRequest("POST", "/api/ask", nil)
+	req.Header.Set(APIKeyHeader, "secret")
+	req.Header.Set("Authorization", "Bearer admin-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
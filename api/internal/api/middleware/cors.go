@@ -2,21 +2,43 @@ package middleware
 
 import (
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-// CORSConfig creates a CORS middleware configuration
-func CORSConfig(allowedOrigins string) gin.HandlerFunc {
+// AudioHeader tells voice-ask clients whether the response body is audio ("wav")
+// or the JSON text answer because synthesis failed ("none"). It is exposed so
+// browser clients can read it.
+const AudioHeader = "X-Janus-Audio"
+
+// AudioDurationHeader carries the synthesized audio's length in milliseconds so
+// clients can size a progress bar before playback
+const AudioDurationHeader = "X-Audio-Duration-Ms"
+
+// IdempotencyKeyHeader lets a client retry an ask without it running twice
+const IdempotencyKeyHeader = "X-Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed from an earlier ask
+// with the same idempotency key. It is exposed so browser clients can read it.
+const IdempotentReplayedHeader = "X-Idempotent-Replayed"
+
+// CORSConfig creates a CORS middleware configuration. requestIDHeader is
+// allowed and exposed so browser clients can send and read request IDs.
+func CORSConfig(allowedOrigins string, requestIDHeader string) gin.HandlerFunc {
 	config := cors.Config{
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "Content-Encoding", TimeoutOverrideHeader, APIKeyHeader, IdempotencyKeyHeader},
+		ExposeHeaders:    []string{"Content-Length", "Server", AudioHeader, AudioDurationHeader, IdempotentReplayedHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
+	if requestIDHeader != "" {
+		config.AllowHeaders = append(config.AllowHeaders, requestIDHeader)
+		config.ExposeHeaders = append(config.ExposeHeaders, requestIDHeader)
+	}
 
 	// Support wildcard "*" for development (allow all origins)
 	if allowedOrigins == "*" {
@@ -33,3 +55,28 @@ func CORSConfig(allowedOrigins string) gin.HandlerFunc {
 
 	return cors.New(config)
 }
+
+// ReloadableCORS is a CORS middleware whose allowed origins can be swapped at runtime
+type ReloadableCORS struct {
+	current         atomic.Value // gin.HandlerFunc
+	requestIDHeader string
+}
+
+// NewReloadableCORS creates a CORS middleware that can be reconfigured without a restart
+func NewReloadableCORS(allowedOrigins string, requestIDHeader string) *ReloadableCORS {
+	r := &ReloadableCORS{requestIDHeader: requestIDHeader}
+	r.SetOrigins(allowedOrigins)
+	return r
+}
+
+// SetOrigins atomically replaces the allowed origins for subsequent requests
+func (r *ReloadableCORS) SetOrigins(allowedOrigins string) {
+	r.current.Store(CORSConfig(allowedOrigins, r.requestIDHeader))
+}
+
+// Handler returns the middleware that delegates to the current CORS configuration
+func (r *ReloadableCORS) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r.current.Load().(gin.HandlerFunc)(c)
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/logger"
+)
+
+// MaxURLLength middleware rejects requests whose URL (path plus query string)
+// is longer than limit bytes with 414 URI Too Long. A limit of 0 disables the check.
+func MaxURLLength(limit int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		length := len(c.Request.RequestURI)
+		if length == 0 {
+			// Requests built in-process may not carry a raw RequestURI
+			length = len(c.Request.URL.RequestURI())
+		}
+
+		if length > limit {
+			logger.Get().Warn().
+				Str("method", c.Request.Method).
+				Str("path", c.Request.URL.Path).
+				Int("url_length", length).
+				Int("max_url_length", limit).
+				Msg("Request URL too long")
+			response.RespondWithError(c, http.StatusRequestURITooLong, response.ErrURITooLong, "Request URL exceeds maximum length")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// MaxConcurrentRequests middleware bounds in-flight requests across the whole
+// server. Requests arriving while limit are already being served are rejected
+// immediately with 503 rather than queued. A limit of 0 disables the check.
+func MaxConcurrentRequests(limit int) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	slots := make(chan struct{}, limit)
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			logger.Get().Warn().
+				Str("method", c.Request.Method).
+				Str("path", c.Request.URL.Path).
+				Int("max_concurrent_requests", limit).
+				Msg("Too many concurrent requests")
+			response.RespondWithError(c, http.StatusServiceUnavailable, response.ErrServerBusy, "Server is busy, try again later")
+			c.Abort()
+			return
+		}
+		defer func() { <-slots }()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMaxURLLength_RejectsOverlongQuery verifies an overlong query string is rejected with 414
+func TestMaxURLLength_RejectsOverlongQuery(t *testing.T) {
+	router := gin.New()
+	router.Use(MaxURLLength(256))
+
+	handled := false
+	router.POST("/api/ask", func(c *gin.Context) {
+		handled = true
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest("POST", "/api/ask?session_id="+strings.Repeat("a", 1024), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestURITooLong, w.Code)
+	assert.Contains(t, w.Body.String(), "URI_TOO_LONG")
+	assert.False(t, handled, "handler should not run for overlong URLs")
+}
+
+// TestMaxURLLength_AllowsNormalRequests verifies URLs within the limit pass through
+func TestMaxURLLength_AllowsNormalRequests(t *testing.T) {
+	router := gin.New()
+	router.Use(MaxURLLength(256))
+
+	router.POST("/api/ask", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest("POST", "/api/ask?session_id=123e4567-e89b-12d3-a456-426614174000", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// TestMaxURLLength_DisabledWhenZero verifies a zero limit disables the check
+func TestMaxURLLength_DisabledWhenZero(t *testing.T) {
+	router := gin.New()
+	router.Use(MaxURLLength(0))
+
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest("GET", "/test?q="+strings.Repeat("a", 8192), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// TestMaxConcurrentRequests_RejectsPastLimit verifies requests beyond the in-flight limit get 503
+func TestMaxConcurrentRequests_RejectsPastLimit(t *testing.T) {
+	router := gin.New()
+	router.Use(MaxConcurrentRequests(2))
+
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	router.GET("/slow", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.String(http.StatusOK, "ok")
+	})
+
+	// Fill both slots with requests that block until released
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+			codes[i] = w.Code
+		}(i)
+	}
+	<-entered
+	<-entered
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "SERVER_BUSY")
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK}, codes)
+
+	// Slots are returned once requests finish
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// TestMaxConcurrentRequests_DisabledWhenZero verifies a zero limit lets requests through
+func TestMaxConcurrentRequests_DisabledWhenZero(t *testing.T) {
+	router := gin.New()
+	router.Use(MaxConcurrentRequests(0))
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
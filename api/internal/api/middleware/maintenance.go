@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+)
+
+// DefaultMaintenanceMessage is returned to clients when no custom message is set
+const DefaultMaintenanceMessage = "Server is undergoing maintenance, please try again later"
+
+// MaintenanceMode is a runtime toggle that makes guarded routes return 503
+type MaintenanceMode struct {
+	mu      sync.RWMutex
+	enabled bool
+	message string
+}
+
+// NewMaintenanceMode creates a maintenance toggle, initially disabled
+func NewMaintenanceMode() *MaintenanceMode {
+	return &MaintenanceMode{}
+}
+
+// Enable turns maintenance mode on with an optional client-facing message
+func (m *MaintenanceMode) Enable(message string) {
+	if message == "" {
+		message = DefaultMaintenanceMessage
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enabled = true
+	m.message = message
+}
+
+// Disable turns maintenance mode off
+func (m *MaintenanceMode) Disable() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enabled = false
+	m.message = ""
+}
+
+// Status reports whether maintenance mode is on and its message
+func (m *MaintenanceMode) Status() (enabled bool, message string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled, m.message
+}
+
+// Handler rejects requests with 503 while maintenance mode is enabled.
+// Apply it only to routes that should go dark; health and admin routes stay outside it.
+func (m *MaintenanceMode) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if enabled, message := m.Status(); enabled {
+			response.RespondWithError(c, http.StatusServiceUnavailable, response.ErrMaintenance, message)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/logger"
+)
+
+// rateLimitWindow is the period request limits are expressed over
+const rateLimitWindow = time.Minute
+
+// rateBucket is a token bucket for one client. tokens is the balance as of
+// updated; it refills continuously at rate tokens per second up to capacity.
+type rateBucket struct {
+	tokens   float64
+	updated  time.Time
+	capacity float64
+	rate     float64
+}
+
+// refill brings the bucket's balance up to now
+func (b *rateBucket) refill(now time.Time) {
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.updated).Seconds()*b.rate)
+	b.updated = now
+}
+
+// RateLimiter limits requests per client IP with a token bucket per client: it
+// holds up to burst requests and refills at the per-minute limit. The IP comes
+// from gin's ClientIP, so X-Forwarded-For is honored from trusted proxies.
+// Origins listed in perOrigin get their own limit and bucket per IP, with a
+// burst equal to the limit; all other requests from an IP share one bucket at
+// the default limit and burst, whatever Origin they send, so rotating the
+// header can't reset the limit. A limit of 0 or less allows everything.
+// The limits can be replaced at runtime with SetLimits.
+type RateLimiter struct {
+	now func() time.Time
+
+	// mu guards the limits as well as the buckets
+	mu           sync.Mutex
+	defaultLimit int
+	defaultBurst int
+	perOrigin    map[string]int
+	buckets      map[string]*rateBucket
+	lastSweep    time.Time
+}
+
+// NewRateLimiter creates a limiter with a default per-minute limit and per-origin overrides
+func NewRateLimiter(defaultLimit int, perOrigin map[string]int) *RateLimiter {
+	return NewRateLimiterWithBurst(defaultLimit, 0, perOrigin)
+}
+
+// NewRateLimiterWithBurst creates a limiter like NewRateLimiter whose default
+// buckets hold burst requests. A burst of 0 or less equals the per-minute limit.
+func NewRateLimiterWithBurst(defaultLimit int, burst int, perOrigin map[string]int) *RateLimiter {
+	return &RateLimiter{
+		defaultLimit: defaultLimit,
+		defaultBurst: burst,
+		perOrigin:    perOrigin,
+		now:          time.Now,
+		buckets:      make(map[string]*rateBucket),
+	}
+}
+
+// SetLimits replaces the limits like those given to NewRateLimiterWithBurst.
+// Existing buckets are dropped so every client is held to the new limits at once.
+func (l *RateLimiter) SetLimits(defaultLimit int, burst int, perOrigin map[string]int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.defaultLimit = defaultLimit
+	l.defaultBurst = burst
+	l.perOrigin = perOrigin
+	l.buckets = make(map[string]*rateBucket)
+}
+
+// currentLimit returns the per-minute limit that applies to origin right now
+func (l *RateLimiter) currentLimit(origin string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limitFor(origin)
+}
+
+// limitFor returns the per-minute limit that applies to origin. l.mu must be held.
+func (l *RateLimiter) limitFor(origin string) int {
+	if limit, ok := l.perOrigin[origin]; ok {
+		return limit
+	}
+	return l.defaultLimit
+}
+
+// burstFor returns how many requests origin's bucket holds. l.mu must be held.
+func (l *RateLimiter) burstFor(origin string) int {
+	if limit, ok := l.perOrigin[origin]; ok {
+		return limit
+	}
+	if l.defaultBurst > 0 {
+		return l.defaultBurst
+	}
+	return l.defaultLimit
+}
+
+// allow takes a token for ip, or origin+ip for a configured origin, and reports whether one was available.
+// When rejected, retryAfter is how long until the next token arrives.
+func (l *RateLimiter) allow(origin, ip string) (allowed bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.limitFor(origin)
+	if limit <= 0 {
+		return true, 0
+	}
+
+	now := l.now()
+	l.sweep(now)
+
+	// Only configured origins get separate buckets; the header is client-controlled
+	key := ip
+	if _, ok := l.perOrigin[origin]; ok {
+		key = origin + "|" + ip
+	}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		capacity := float64(l.burstFor(origin))
+		bucket = &rateBucket{
+			tokens:   capacity,
+			updated:  now,
+			capacity: capacity,
+			rate:     float64(limit) / rateLimitWindow.Seconds(),
+		}
+		l.buckets[key] = bucket
+	}
+	bucket.refill(now)
+
+	if bucket.tokens < 1 {
+		wait := (1 - bucket.tokens) / bucket.rate
+		return false, time.Duration(wait * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep drops buckets that have refilled completely at most once per window.
+// A full bucket is indistinguishable from a new one, so idle clients don't accumulate.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitWindow {
+		return
+	}
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= bucket.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Handler rejects requests over the client's limit with 429 and a Retry-After header
+func (l *RateLimiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		allowed, retryAfter := l.allow(origin, c.ClientIP())
+		if !allowed {
+			logger.Get().Warn().
+				Str("origin", origin).
+				Str("client_ip", c.ClientIP()).
+				Int("limit_per_minute", l.currentLimit(origin)).
+				Msg("Rate limit exceeded")
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			response.RespondWithError(c, http.StatusTooManyRequests, response.ErrRateLimited, "Too many requests, try again later")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRateLimitedRouter serves GET /test behind limiter
+func newRateLimitedRouter(limiter *RateLimiter) *gin.Engine {
+	router := gin.New()
+	router.Use(limiter.Handler())
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return router
+}
+
+// sendFrom issues n requests with the given Origin and returns the status codes
+func sendFrom(router *gin.Engine, origin string, n int) []int {
+	codes := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	return codes
+}
+
+// TestRateLimiter_PerOriginLimits verifies configured origins get their own limit while others use the default
+func TestRateLimiter_PerOriginLimits(t *testing.T) {
+	limiter := NewRateLimiter(2, map[string]int{"app://mobile": 4})
+	router := newRateLimitedRouter(limiter)
+
+	mobile := sendFrom(router, "app://mobile", 5)
+	assert.Equal(t, []int{200, 200, 200, 200, 429}, mobile, "configured origin should get its specific limit")
+
+	web := sendFrom(router, "https://janus.example", 3)
+	assert.Equal(t, []int{200, 200, 429}, web, "unconfigured origin should use the default limit")
+
+	noOrigin := sendFrom(newRateLimitedRouter(NewRateLimiter(2, map[string]int{"app://mobile": 4})), "", 3)
+	assert.Equal(t, []int{200, 200, 429}, noOrigin, "requests without an Origin should use the default limit")
+}
+
+// TestRateLimiter_RotatingOriginSharesBucket verifies unconfigured origins don't
+// get a fresh bucket each, so rotating the header can't reset the limit
+func TestRateLimiter_RotatingOriginSharesBucket(t *testing.T) {
+	router := newRateLimitedRouter(NewRateLimiter(2, map[string]int{"app://mobile": 4}))
+
+	codes := []int{
+		sendFrom(router, "https://one.example", 1)[0],
+		sendFrom(router, "https://two.example", 1)[0],
+		sendFrom(router, "https://three.example", 1)[0],
+		sendFrom(router, "", 1)[0],
+	}
+	assert.Equal(t, []int{200, 200, 429, 429}, codes, "unconfigured origins from one IP should share its bucket")
+	assert.Equal(t, []int{200}, sendFrom(router, "app://mobile", 1), "a configured origin keeps its own bucket")
+}
+
+// TestRateLimiter_RetryAfterAndReset verifies rejected requests carry Retry-After and the window resets
+func TestRateLimiter_RetryAfterAndReset(t *testing.T) {
+	clock := time.Now()
+	limiter := NewRateLimiter(1, nil)
+	limiter.now = func() time.Time { return clock }
+	router := newRateLimitedRouter(limiter)
+
+	sendFrom(router, "", 1)
+	clock = clock.Add(20 * time.Second)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "40", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "RATE_LIMITED")
+
+	clock = clock.Add(40 * time.Second)
+	assert.Equal(t, []int{200}, sendFrom(router, "", 1), "limit should reset after the window")
+}
+
+// TestRateLimiter_DisabledWhenZero verifies a zero limit allows everything, including per origin
+func TestRateLimiter_DisabledWhenZero(t *testing.T) {
+	limiter := NewRateLimiter(0, map[string]int{"app://mobile": 0})
+	router := newRateLimitedRouter(limiter)
+
+	for _, code := range sendFrom(router, "app://mobile", 10) {
+		assert.Equal(t, http.StatusOK, code)
+	}
+	for _, code := range sendFrom(router, "", 10) {
+		assert.Equal(t, http.StatusOK, code)
+	}
+}
+
+// TestRateLimiter_BurstThenRefill verifies a client may burst past the steady
+// rate up to its bucket size and then gets tokens back at the per-minute rate
+func TestRateLimiter_BurstThenRefill(t *testing.T) {
+	clock := time.Now()
+	limiter := NewRateLimiterWithBurst(6, 3, nil)
+	limiter.now = func() time.Time { return clock }
+	router := newRateLimitedRouter(limiter)
+
+	assert.Equal(t, []int{200, 200, 200, 429}, sendFrom(router, "", 4), "burst should allow 3 then reject")
+
+	// 6 per minute is one token every 10 seconds
+	clock = clock.Add(10 * time.Second)
+	assert.Equal(t, []int{200, 429}, sendFrom(router, "", 2), "one token should refill after 10s")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+
+	clock = clock.Add(time.Hour)
+	assert.Equal(t, []int{200, 200, 200, 429}, sendFrom(router, "", 4), "bucket should refill only up to the burst")
+}
+
+// TestRateLimiter_ForwardedClientsLimitedSeparately verifies clients behind a
+// trusted proxy are keyed by their X-Forwarded-For address
+func TestRateLimiter_ForwardedClientsLimitedSeparately(t *testing.T) {
+	router := newRateLimitedRouter(NewRateLimiter(1, nil))
+	// httptest requests come from 192.0.2.1
+	require.NoError(t, router.SetTrustedProxies([]string{"192.0.2.1"}))
+
+	send := func(forwardedFor string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("203.0.113.1"))
+	assert.Equal(t, http.StatusTooManyRequests, send("203.0.113.1"))
+	assert.Equal(t, http.StatusOK, send("203.0.113.2"), "another forwarded client should have its own bucket")
+}
+
+// TestRateLimiter_SweepsIdleBuckets verifies buckets of clients that have gone
+// idle long enough to refill are garbage-collected
+func TestRateLimiter_SweepsIdleBuckets(t *testing.T) {
+	clock := time.Now()
+	limiter := NewRateLimiter(60, nil)
+	limiter.now = func() time.Time { return clock }
+
+	limiter.allow("", "203.0.113.1")
+	limiter.allow("", "203.0.113.2")
+	assert.Len(t, limiter.buckets, 2)
+
+	clock = clock.Add(2 * time.Minute)
+	limiter.allow("", "203.0.113.3")
+
+	assert.Len(t, limiter.buckets, 1, "idle buckets should be dropped")
+	assert.Contains(t, limiter.buckets, "203.0.113.3")
+}
@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"context"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,23 +16,85 @@ import (
 const (
 	// DefaultRequestTimeout is the maximum time for a request
 	DefaultRequestTimeout = 60 * time.Second
+	// MaxIncomingRequestIDLength bounds client-supplied request IDs to keep logs sane
+	MaxIncomingRequestIDLength = 128
+	// TimeoutOverrideHeader lets clients request a longer timeout for a single request
+	TimeoutOverrideHeader = "X-Timeout-Seconds"
+	// maxStackLines bounds the stack trace included in development panic responses
+	maxStackLines = 40
 )
 
-// RequestID middleware adds a unique ID to each request
-func RequestID() gin.HandlerFunc {
+// RequestID middleware adds a unique ID to each request.
+// A valid ID supplied by the client in headerName is reused so requests can be
+// correlated across services; otherwise a new UUID is generated.
+func RequestID(headerName string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		requestID := uuid.New().String()
+		requestID := c.GetHeader(headerName)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
 		c.Set("request_id", requestID)
-		c.Header("X-Request-ID", requestID)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
+		c.Header(headerName, requestID)
+		c.Next()
+	}
+}
+
+// ServerHeader sets the Server response header to "Janus/<version>" so clients
+// can tell which service and build answered
+func ServerHeader(version string) gin.HandlerFunc {
+	value := "Janus/" + version
+	return func(c *gin.Context) {
+		c.Header("Server", value)
 		c.Next()
 	}
 }
 
+// isValidRequestID reports whether a client-supplied request ID is safe to log and echo
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > MaxIncomingRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		isAlnum := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !isAlnum && r != '-' && r != '_' && r != '.' && r != ':' {
+			return false
+		}
+	}
+	return true
+}
+
 // RequestTimeout middleware enforces request timeout by setting a context deadline.
 // Handlers should check c.Request.Context().Done() and return early on timeout.
 func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return RequestTimeoutWithOverride(timeout, 0)
+}
+
+// RequestTimeoutWithOverride behaves like RequestTimeout but honors a positive
+// X-Timeout-Seconds header, clamped to maxTimeout. A maxTimeout of 0 disables
+// the override. Malformed header values are ignored. Requests to
+// untimedPaths, matched exactly against the request path, get no deadline so
+// long-lived streams run until the client disconnects.
+func RequestTimeoutWithOverride(timeout, maxTimeout time.Duration, untimedPaths ...string) gin.HandlerFunc {
+	untimed := make(map[string]bool, len(untimedPaths))
+	for _, path := range untimedPaths {
+		untimed[path] = true
+	}
+
 	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		if untimed[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		effective := timeout
+		if maxTimeout > 0 {
+			if requested, ok := parseTimeoutOverride(c.GetHeader(TimeoutOverrideHeader)); ok {
+				effective = min(requested, maxTimeout)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), effective)
 		defer cancel()
 
 		c.Request = c.Request.WithContext(ctx)
@@ -37,8 +102,27 @@ func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
 	}
 }
 
-// Recovery middleware recovers from panics
+// parseTimeoutOverride parses a whole number of seconds from the override header
+func parseTimeoutOverride(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 1 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// Recovery middleware recovers from panics with a generic 500 response
 func Recovery() gin.HandlerFunc {
+	return RecoveryWithDetails(false)
+}
+
+// RecoveryWithDetails behaves like Recovery, but when includeDetails is set the
+// 500 response also carries the panic value and a trimmed stack trace.
+// Only enable it in development.
+func RecoveryWithDetails(includeDetails bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
@@ -56,7 +140,11 @@ func Recovery() gin.HandlerFunc {
 					Interface("panic", err).
 					Msg("Panic recovered")
 
-				response.RespondWithError(c, 500, response.ErrInternalServer, "An unexpected error occurred")
+				if includeDetails {
+					response.RespondWithPanic(c, err, trimmedStack(debug.Stack()))
+				} else {
+					response.RespondWithError(c, 500, response.ErrInternalServer, "An unexpected error occurred")
+				}
 				c.Abort()
 			}
 		}()
@@ -64,8 +152,35 @@ func Recovery() gin.HandlerFunc {
 	}
 }
 
-// Logger middleware logs all requests
-func Logger() gin.HandlerFunc {
+// trimmedStack splits a goroutine stack into lines starting at the panic call,
+// dropping the goroutine header and the recovery frames above it, and keeps at
+// most maxStackLines lines
+func trimmedStack(stack []byte) []string {
+	lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+
+	// The frame that panicked follows the runtime panic frame
+	for i, line := range lines {
+		if strings.HasPrefix(line, "panic(") {
+			lines = lines[i:]
+			break
+		}
+	}
+
+	if len(lines) > maxStackLines {
+		lines = lines[:maxStackLines]
+	}
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return lines
+}
+
+// RequestObserver is told about each completed request. route is the matched
+// route template, or empty when no route matched.
+type RequestObserver func(method string, route string, status int, duration time.Duration)
+
+// Logger middleware logs all requests and reports each one's timing to observers
+func Logger(observers ...RequestObserver) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
@@ -97,5 +212,9 @@ func Logger() gin.HandlerFunc {
 			Int("status", status).
 			Dur("duration", duration).
 			Msg("Request completed")
+
+		for _, observe := range observers {
+			observe(method, c.FullPath(), status, duration)
+		}
 	}
 }
@@ -1,12 +1,16 @@
 package middleware
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sean/janus/internal/api/response"
+	"github.com/sean/janus/internal/logger"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,7 +21,7 @@ func init() {
 // TestRequestID verifies RequestID middleware generates unique IDs
 func TestRequestID(t *testing.T) {
 	router := gin.New()
-	router.Use(RequestID())
+	router.Use(RequestID("X-Request-ID"))
 
 	var capturedID1, capturedID2 string
 
@@ -53,7 +57,7 @@ func TestRequestID(t *testing.T) {
 // TestRequestTimeout_CompletesWithinTimeout verifies requests complete normally within timeout
 func TestRequestTimeout_CompletesWithinTimeout(t *testing.T) {
 	router := gin.New()
-	router.Use(RequestID())
+	router.Use(RequestID("X-Request-ID"))
 	router.Use(RequestTimeout(2 * time.Second))
 
 	router.GET("/fast", func(c *gin.Context) {
@@ -72,7 +76,7 @@ func TestRequestTimeout_CompletesWithinTimeout(t *testing.T) {
 // TestRequestTimeout_ExceedsTimeout verifies context deadline is enforced
 func TestRequestTimeout_ExceedsTimeout(t *testing.T) {
 	router := gin.New()
-	router.Use(RequestID())
+	router.Use(RequestID("X-Request-ID"))
 	router.Use(RequestTimeout(100 * time.Millisecond))
 
 	handlerCalled := false
@@ -103,7 +107,7 @@ func TestRequestTimeout_ExceedsTimeout(t *testing.T) {
 // TestRecovery_CatchesPanic verifies panic recovery
 func TestRecovery_CatchesPanic(t *testing.T) {
 	router := gin.New()
-	router.Use(RequestID())
+	router.Use(RequestID("X-Request-ID"))
 	router.Use(Recovery())
 
 	router.GET("/panic", func(c *gin.Context) {
@@ -121,6 +125,49 @@ func TestRecovery_CatchesPanic(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "request_id")
 }
 
+// TestRecoveryWithDetails_IncludesStackInDebug verifies debug responses carry the panic and stack
+func TestRecoveryWithDetails_IncludesStackInDebug(t *testing.T) {
+	router := gin.New()
+	router.Use(RecoveryWithDetails(true))
+
+	router.GET("/panic", func(c *gin.Context) {
+		panic("debug panic")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var body response.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, response.ErrInternalServer, body.Error)
+	assert.Equal(t, "debug panic", body.Panic)
+	assert.NotEmpty(t, body.Stack)
+	assert.LessOrEqual(t, len(body.Stack), maxStackLines)
+	assert.True(t, strings.HasPrefix(body.Stack[0], "panic("), "stack should start at the panic, got %q", body.Stack[0])
+	assert.Contains(t, strings.Join(body.Stack, "\n"), "TestRecoveryWithDetails_IncludesStackInDebug")
+}
+
+// TestRecoveryWithDetails_GenericWithoutDebug verifies production responses reveal nothing about the panic
+func TestRecoveryWithDetails_GenericWithoutDebug(t *testing.T) {
+	router := gin.New()
+	router.Use(RecoveryWithDetails(false))
+
+	router.GET("/panic", func(c *gin.Context) {
+		panic("secret internals")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "INTERNAL_SERVER_ERROR")
+	assert.NotContains(t, w.Body.String(), "secret internals")
+	assert.NotContains(t, w.Body.String(), "stack")
+	assert.NotContains(t, w.Body.String(), ".go:")
+}
+
 // TestRecovery_DoesNotAffectNormalRequests verifies recovery doesn't affect normal flow
 func TestRecovery_DoesNotAffectNormalRequests(t *testing.T) {
 	router := gin.New()
@@ -141,7 +188,7 @@ func TestRecovery_DoesNotAffectNormalRequests(t *testing.T) {
 // TestLogger_LogsRequests verifies logger middleware logs correctly
 func TestLogger_LogsRequests(t *testing.T) {
 	router := gin.New()
-	router.Use(RequestID())
+	router.Use(RequestID("X-Request-ID"))
 	router.Use(Logger())
 
 	router.GET("/test", func(c *gin.Context) {
@@ -162,7 +209,7 @@ func TestLogger_LogsRequests(t *testing.T) {
 func TestMiddlewareChain(t *testing.T) {
 	router := gin.New()
 	router.Use(Recovery())
-	router.Use(RequestID())
+	router.Use(RequestID("X-Request-ID"))
 	router.Use(Logger())
 	router.Use(RequestTimeout(1 * time.Second))
 
@@ -220,7 +267,7 @@ func TestRequestTimeout_ContextPropagation(t *testing.T) {
 // TestRequestID_HeaderSet verifies X-Request-ID header is set
 func TestRequestID_HeaderSet(t *testing.T) {
 	router := gin.New()
-	router.Use(RequestID())
+	router.Use(RequestID("X-Request-ID"))
 
 	router.GET("/header", func(c *gin.Context) {
 		c.String(http.StatusOK, "ok")
@@ -234,3 +281,200 @@ func TestRequestID_HeaderSet(t *testing.T) {
 	assert.NotEmpty(t, header)
 	assert.Len(t, header, 36) // UUID format length
 }
+
+// TestRequestID_Context verifies the request ID is carried on the request context for log correlation
+func TestRequestID_Context(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestID("X-Request-ID"))
+
+	var fromContext string
+	router.GET("/context", func(c *gin.Context) {
+		fromContext = logger.RequestIDFromContext(c.Request.Context())
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest("GET", "/context", nil)
+	req.Header.Set("X-Request-ID", "req-abc-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "req-abc-123", fromContext)
+}
+
+// TestRequestID_CustomHeader verifies the configured header is set on responses
+func TestRequestID_CustomHeader(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestID("X-Correlation-ID"))
+
+	router.GET("/header", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest("GET", "/header", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Len(t, w.Header().Get("X-Correlation-ID"), 36)
+	assert.Empty(t, w.Header().Get("X-Request-ID"))
+}
+
+// TestRequestID_ReadsIncomingHeader verifies a client-supplied ID is reused
+func TestRequestID_ReadsIncomingHeader(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestID("X-Correlation-ID"))
+
+	var capturedID string
+	router.GET("/header", func(c *gin.Context) {
+		capturedID = c.GetString("request_id")
+		c.String(http.StatusOK, "ok")
+	})
+
+	t.Run("reuses valid incoming ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/header", nil)
+		req.Header.Set("X-Correlation-ID", "upstream-trace-42")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, "upstream-trace-42", capturedID)
+		assert.Equal(t, "upstream-trace-42", w.Header().Get("X-Correlation-ID"))
+	})
+
+	t.Run("replaces invalid incoming ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/header", nil)
+		req.Header.Set("X-Correlation-ID", "bad id\nwith newline")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Len(t, capturedID, 36)
+		assert.Equal(t, capturedID, w.Header().Get("X-Correlation-ID"))
+	})
+}
+
+// TestRequestTimeoutWithOverride_ExtendsDeadline verifies a valid header extends the deadline
+func TestRequestTimeoutWithOverride_ExtendsDeadline(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestTimeoutWithOverride(1*time.Second, 30*time.Second))
+
+	var remaining time.Duration
+	router.GET("/context", func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		assert.True(t, ok)
+		remaining = time.Until(deadline)
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest("GET", "/context", nil)
+	req.Header.Set(TimeoutOverrideHeader, "10")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Greater(t, remaining, 9*time.Second)
+	assert.LessOrEqual(t, remaining, 10*time.Second)
+}
+
+// TestRequestTimeoutWithOverride_ClampsToMax verifies an excessive header is clamped
+func TestRequestTimeoutWithOverride_ClampsToMax(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestTimeoutWithOverride(1*time.Second, 5*time.Second))
+
+	var remaining time.Duration
+	router.GET("/context", func(c *gin.Context) {
+		deadline, _ := c.Request.Context().Deadline()
+		remaining = time.Until(deadline)
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest("GET", "/context", nil)
+	req.Header.Set(TimeoutOverrideHeader, "3600")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Greater(t, remaining, 4*time.Second)
+	assert.LessOrEqual(t, remaining, 5*time.Second)
+}
+
+// TestRequestTimeoutWithOverride_IgnoresInvalidHeader verifies malformed values fall back to the default
+func TestRequestTimeoutWithOverride_IgnoresInvalidHeader(t *testing.T) {
+	for _, value := range []string{"abc", "-5", "0", "1.5"} {
+		t.Run(value, func(t *testing.T) {
+			router := gin.New()
+			router.Use(RequestTimeoutWithOverride(1*time.Second, 30*time.Second))
+
+			var remaining time.Duration
+			router.GET("/context", func(c *gin.Context) {
+				deadline, _ := c.Request.Context().Deadline()
+				remaining = time.Until(deadline)
+				c.String(http.StatusOK, "ok")
+			})
+
+			req := httptest.NewRequest("GET", "/context", nil)
+			req.Header.Set(TimeoutOverrideHeader, value)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.LessOrEqual(t, remaining, 1*time.Second)
+		})
+	}
+}
+
+// TestRequestTimeout_IgnoresOverrideHeader verifies the plain middleware does not honor the header
+func TestRequestTimeout_IgnoresOverrideHeader(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestTimeout(1 * time.Second))
+
+	var remaining time.Duration
+	router.GET("/context", func(c *gin.Context) {
+		deadline, _ := c.Request.Context().Deadline()
+		remaining = time.Until(deadline)
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest("GET", "/context", nil)
+	req.Header.Set(TimeoutOverrideHeader, "10")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.LessOrEqual(t, remaining, 1*time.Second)
+}
+
+// TestServerHeader verifies every response identifies the server and version
+func TestServerHeader(t *testing.T) {
+	router := gin.New()
+	router.Use(ServerHeader("v1.4.0"))
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+
+	for _, path := range []string{"/test", "/fail", "/missing"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+		assert.Equal(t, "Janus/v1.4.0", w.Header().Get("Server"), path)
+	}
+}
+
+// TestRequestTimeoutWithOverride_UntimedPaths verifies listed paths get no deadline
+func TestRequestTimeoutWithOverride_UntimedPaths(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestTimeoutWithOverride(1*time.Second, 30*time.Second, "/stream"))
+
+	hasDeadline := map[string]bool{}
+	handler := func(c *gin.Context) {
+		_, ok := c.Request.Context().Deadline()
+		hasDeadline[c.Request.URL.Path] = ok
+		c.String(http.StatusOK, "ok")
+	}
+	router.GET("/stream", handler)
+	router.GET("/context", handler)
+
+	for _, path := range []string{"/stream", "/context"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	assert.False(t, hasDeadline["/stream"], "untimed path should have no deadline")
+	assert.True(t, hasDeadline["/context"], "other paths should keep the timeout")
+}
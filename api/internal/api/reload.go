@@ -0,0 +1,99 @@
+package api
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/sean/janus/internal/api/middleware"
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/logger"
+)
+
+// reloadableFields are the config fields that can change without a restart
+var reloadableFields = map[string]bool{
+	"LogLevel":            true,
+	"CORSAllowedOrigins":  true,
+	"RateLimitPerMinute":  true,
+	"RateLimitBurst":      true,
+	"PerOriginRateLimits": true,
+}
+
+// Reloader applies reloadable configuration changes to a running server
+type Reloader struct {
+	mu          sync.Mutex
+	current     config.Config
+	cors        *middleware.ReloadableCORS
+	rateLimiter *middleware.RateLimiter
+}
+
+// NewReloader creates a reloader tracking the configuration the server started with
+func NewReloader(cfg *config.Config, cors *middleware.ReloadableCORS, rateLimiter *middleware.RateLimiter) *Reloader {
+	return &Reloader{
+		current:     *cfg,
+		cors:        cors,
+		rateLimiter: rateLimiter,
+	}
+}
+
+// Apply compares next against the running configuration, applies changed
+// reloadable fields, and warns about changed fields that require a restart.
+// It returns the names of the fields that were applied and ignored.
+func (r *Reloader) Apply(next *config.Config) (applied []string, ignored []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	log := logger.Get()
+
+	var changed []string
+	currentValue := reflect.ValueOf(r.current)
+	nextValue := reflect.ValueOf(*next)
+	configType := currentValue.Type()
+
+	for i := 0; i < configType.NumField(); i++ {
+		name := configType.Field(i).Name
+		if reflect.DeepEqual(currentValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			continue
+		}
+		if !reloadableFields[name] {
+			ignored = append(ignored, name)
+			continue
+		}
+		changed = append(changed, name)
+	}
+
+	rateLimitsChanged := false
+	for _, name := range changed {
+		switch name {
+		case "LogLevel":
+			if err := logger.SetLevel(next.LogLevel); err != nil {
+				log.Warn().Err(err).Str("log_level", next.LogLevel).Msg("Invalid log level on reload, keeping current level")
+				continue
+			}
+			r.current.LogLevel = next.LogLevel
+		case "CORSAllowedOrigins":
+			r.cors.SetOrigins(next.CORSAllowedOrigins)
+			r.current.CORSAllowedOrigins = next.CORSAllowedOrigins
+		case "RateLimitPerMinute", "RateLimitBurst", "PerOriginRateLimits":
+			rateLimitsChanged = true
+		}
+		applied = append(applied, name)
+	}
+	// The rate limits are set together so one reload changing several swaps the buckets once
+	if rateLimitsChanged {
+		r.rateLimiter.SetLimits(next.RateLimitPerMinute, next.RateLimitBurst, next.PerOriginRateLimits)
+		r.current.RateLimitPerMinute = next.RateLimitPerMinute
+		r.current.RateLimitBurst = next.RateLimitBurst
+		r.current.PerOriginRateLimits = next.PerOriginRateLimits
+	}
+
+	if len(ignored) > 0 {
+		log.Warn().
+			Strs("fields", ignored).
+			Msg("Configuration changes require a restart and were ignored")
+	}
+	log.Info().
+		Strs("applied", applied).
+		Msg("Configuration reloaded")
+
+	return applied, ignored
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/sean/janus/internal/session"
+)
+
+func TestReloader_Apply(t *testing.T) {
+	t.Run("applies reloadable fields and ignores the rest", func(t *testing.T) {
+		defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+		cfg := newTestConfig()
+		router, reloader := NewRouter(cfg, session.NewMemorySessionManager())
+
+		next := *cfg
+		next.LogLevel = "warn"
+		next.CORSAllowedOrigins = "https://app.example.com"
+		next.Port = "9999"
+
+		applied, ignored := reloader.Apply(&next)
+
+		if len(applied) != 2 {
+			t.Errorf("expected 2 applied fields, got %v", applied)
+		}
+		if len(ignored) != 1 || ignored[0] != "Port" {
+			t.Errorf("expected Port to be ignored, got %v", ignored)
+		}
+		if zerolog.GlobalLevel() != zerolog.WarnLevel {
+			t.Errorf("expected global level warn, got %s", zerolog.GlobalLevel())
+		}
+
+		// The new CORS origins take effect on the live router
+		req := httptest.NewRequest("GET", "/api/health", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("expected reloaded origin to be allowed, got %q", got)
+		}
+
+		req = httptest.NewRequest("GET", "/api/health", nil)
+		req.Header.Set("Origin", "https://other.example.com")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected other origin to be rejected with 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("no changes applies nothing", func(t *testing.T) {
+		cfg := newTestConfig()
+		_, reloader := NewRouter(cfg, session.NewMemorySessionManager())
+
+		same := *cfg
+		applied, ignored := reloader.Apply(&same)
+
+		if len(applied) != 0 || len(ignored) != 0 {
+			t.Errorf("expected no changes, got applied=%v ignored=%v", applied, ignored)
+		}
+	})
+
+	t.Run("applies new rate limits to the live router", func(t *testing.T) {
+		cfg := newTestConfig()
+		router, reloader := NewRouter(cfg, session.NewMemorySessionManager())
+
+		send := func(origin string) int {
+			req := httptest.NewRequest("GET", "/api/health", nil)
+			req.Header.Set("Origin", origin)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		next := *cfg
+		next.CORSAllowedOrigins = "*"
+		next.RateLimitPerMinute = 1
+		next.RateLimitBurst = 2
+		next.PerOriginRateLimits = map[string]int{"https://app.example.com": 3}
+		applied, _ := reloader.Apply(&next)
+
+		if len(applied) != 3 {
+			t.Errorf("expected the 3 rate limit fields to be applied, got %v", applied)
+		}
+		for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+			if code := send(""); code != want {
+				t.Errorf("default request %d: expected status %d, got %d", i+1, want, code)
+			}
+		}
+		for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+			if code := send("https://app.example.com"); code != want {
+				t.Errorf("origin request %d: expected status %d, got %d", i+1, want, code)
+			}
+		}
+
+		// Turning the limit off again lets everything through
+		off := next
+		off.RateLimitPerMinute = 0
+		off.PerOriginRateLimits = nil
+		reloader.Apply(&off)
+		if code := send(""); code != http.StatusOK {
+			t.Errorf("expected the limit to be lifted, got status %d", code)
+		}
+	})
+
+	t.Run("invalid log level keeps current level", func(t *testing.T) {
+		defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+		cfg := newTestConfig()
+		_, reloader := NewRouter(cfg, session.NewMemorySessionManager())
+
+		next := *cfg
+		next.LogLevel = "shouting"
+		reloader.Apply(&next)
+
+		if zerolog.GlobalLevel() != zerolog.InfoLevel {
+			t.Errorf("expected level to stay info, got %s", zerolog.GlobalLevel())
+		}
+	})
+}
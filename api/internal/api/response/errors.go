@@ -1,6 +1,9 @@
 package response
 
 import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -8,10 +11,15 @@ import (
 
 // ErrorResponse is the standard error response format
 type ErrorResponse struct {
-	Error     string `json:"error"`
-	Details   string `json:"details,omitempty"`
-	RequestID string `json:"request_id,omitempty"`
-	Timestamp string `json:"timestamp"`
+	Error     string      `json:"error"`
+	Details   string      `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	Timestamp interface{} `json:"timestamp"`
+	// Subtype narrows Error for failures reported by an upstream tool, such as cursor-agent
+	Subtype string `json:"subtype,omitempty"`
+	// Panic and Stack are only populated by RespondWithPanic in development
+	Panic string   `json:"panic,omitempty"`
+	Stack []string `json:"stack,omitempty"`
 }
 
 // Error codes
@@ -23,21 +31,101 @@ const (
 	ErrProcessCommunication = "PROCESS_COMMUNICATION_FAILED"
 	ErrTimeout              = "REQUEST_TIMEOUT"
 	ErrInternalServer       = "INTERNAL_SERVER_ERROR"
+	ErrURITooLong           = "URI_TOO_LONG"
+	ErrRateLimited          = "RATE_LIMITED"
+	ErrMaintenance          = "MAINTENANCE_MODE"
+	ErrUnauthorized         = "UNAUTHORIZED"
+	ErrForbidden            = "FORBIDDEN"
+	ErrServerBusy           = "SERVER_BUSY"
+	ErrQuestionRejected     = "QUESTION_REJECTED"
+	ErrQuestionCancelled    = "QUESTION_CANCELLED"
+	ErrSessionBusy          = "SESSION_BUSY"
+	ErrCursorAgent          = "CURSOR_AGENT_ERROR"
 )
 
+// Timestamp formats for response envelopes
+const (
+	// TimestampRFC3339 renders timestamps as RFC3339 strings
+	TimestampRFC3339 = "rfc3339"
+	// TimestampEpochMillis renders timestamps as integer milliseconds since the Unix epoch
+	TimestampEpochMillis = "epoch_ms"
+)
+
+// epochMillis is set when responses should carry epoch-millisecond timestamps
+var epochMillis atomic.Bool
+
+// SetTimestampFormat selects how response timestamps are encoded. Unrecognized
+// formats fall back to TimestampRFC3339.
+func SetTimestampFormat(format string) {
+	epochMillis.Store(format == TimestampEpochMillis)
+}
+
+// timestamp returns the current time in the configured response format
+func timestamp() interface{} {
+	now := time.Now()
+	if epochMillis.Load() {
+		return now.UnixMilli()
+	}
+	return now.Format(time.RFC3339)
+}
+
+// DataResponse is the standard envelope for successful responses, mirroring ErrorResponse
+type DataResponse struct {
+	Data      interface{} `json:"data"`
+	RequestID string      `json:"request_id,omitempty"`
+	Timestamp interface{} `json:"timestamp"`
+}
+
+// RespondWithData sends data wrapped in the standard success envelope
+func RespondWithData(c *gin.Context, status int, data interface{}) {
+	c.JSON(status, DataResponse{
+		Data:      data,
+		RequestID: requestIDFrom(c),
+		Timestamp: timestamp(),
+	})
+}
+
 // RespondWithError sends a standardized error response
 func RespondWithError(c *gin.Context, status int, errorCode string, details string) {
-	requestID := ""
-	if id, exists := c.Get("request_id"); exists {
-		if idStr, ok := id.(string); ok {
-			requestID = idStr
-		}
-	}
+	c.JSON(status, ErrorResponse{
+		Error:     errorCode,
+		Details:   details,
+		RequestID: requestIDFrom(c),
+		Timestamp: timestamp(),
+	})
+}
 
+// RespondWithSubtype sends a standardized error response that also carries the
+// upstream error's subtype
+func RespondWithSubtype(c *gin.Context, status int, errorCode string, subtype string, details string) {
 	c.JSON(status, ErrorResponse{
 		Error:     errorCode,
 		Details:   details,
-		RequestID: requestID,
-		Timestamp: time.Now().Format(time.RFC3339),
+		RequestID: requestIDFrom(c),
+		Timestamp: timestamp(),
+		Subtype:   subtype,
+	})
+}
+
+// RespondWithPanic sends a 500 error response that includes the recovered panic
+// value and stack. Only use it in development; it exposes server internals.
+func RespondWithPanic(c *gin.Context, panicValue interface{}, stack []string) {
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Error:     ErrInternalServer,
+		Details:   "An unexpected error occurred",
+		RequestID: requestIDFrom(c),
+		Timestamp: timestamp(),
+		Panic:     fmt.Sprint(panicValue),
+		Stack:     stack,
 	})
 }
+
+// requestIDFrom returns the request ID set by the RequestID middleware, if any
+func requestIDFrom(c *gin.Context) string {
+	if id, exists := c.Get("request_id"); exists {
+		if idStr, ok := id.(string); ok {
+			return idStr
+		}
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRespondWithError_TimestampFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() { SetTimestampFormat(TimestampRFC3339) })
+
+	respond := func(t *testing.T) map[string]interface{} {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		RespondWithError(c, http.StatusBadRequest, ErrInvalidRequest, "bad")
+
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return body
+	}
+
+	t.Run("rfc3339 encodes a string", func(t *testing.T) {
+		SetTimestampFormat(TimestampRFC3339)
+
+		body := respond(t)
+		ts, ok := body["timestamp"].(string)
+		if !ok {
+			t.Fatalf("expected string timestamp, got %T", body["timestamp"])
+		}
+		if _, err := time.Parse(time.RFC3339, ts); err != nil {
+			t.Errorf("expected RFC3339 timestamp, got %q", ts)
+		}
+	})
+
+	t.Run("epoch_ms encodes integer milliseconds", func(t *testing.T) {
+		SetTimestampFormat(TimestampEpochMillis)
+		before := time.Now().UnixMilli()
+
+		body := respond(t)
+		ms, ok := body["timestamp"].(float64)
+		if !ok {
+			t.Fatalf("expected numeric timestamp, got %T", body["timestamp"])
+		}
+		if int64(ms) < before || int64(ms) > time.Now().UnixMilli() {
+			t.Errorf("expected current epoch millis, got %v", ms)
+		}
+	})
+
+	t.Run("envelope follows the same format", func(t *testing.T) {
+		SetTimestampFormat(TimestampEpochMillis)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		RespondWithData(c, http.StatusOK, gin.H{"ok": true})
+
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if _, ok := body["timestamp"].(float64); !ok {
+			t.Errorf("expected numeric envelope timestamp, got %T", body["timestamp"])
+		}
+	})
+}
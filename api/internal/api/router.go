@@ -1,63 +1,168 @@
 package api
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sean/janus/internal/api/handlers"
 	"github.com/sean/janus/internal/api/middleware"
+	"github.com/sean/janus/internal/api/response"
 	"github.com/sean/janus/internal/config"
 	"github.com/sean/janus/internal/logger"
+	"github.com/sean/janus/internal/metrics"
 	"github.com/sean/janus/internal/session"
+	"github.com/sean/janus/internal/version"
 )
 
 // SetupRouter configures and returns a Gin router
 func SetupRouter(cfg *config.Config, sessionManager session.Manager) *gin.Engine {
+	router, _ := NewRouter(cfg, sessionManager)
+	return router
+}
+
+// RouterOption configures optional dependencies of the router
+type RouterOption func(*routerOptions)
+
+type routerOptions struct {
+	cleanupMonitor handlers.CleanupMonitor
+}
+
+// WithCleanupMonitor lets the full health check report a stalled session cleanup loop
+func WithCleanupMonitor(monitor handlers.CleanupMonitor) RouterOption {
+	return func(o *routerOptions) {
+		o.cleanupMonitor = monitor
+	}
+}
+
+// NewRouter configures a Gin router and returns it with a Reloader for
+// applying configuration changes to the running server
+func NewRouter(cfg *config.Config, sessionManager session.Manager, opts ...RouterOption) (*gin.Engine, *Reloader) {
+	var options routerOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	// Set Gin mode based on log level
 	if cfg.LogLevel == "debug" {
 		gin.SetMode(gin.DebugMode)
 	} else {
 		gin.SetMode(gin.ReleaseMode)
 	}
+	response.SetTimestampFormat(cfg.TimestampFormat)
 
 	// Use gin.New() instead of Default() to have full control over middleware
 	router := gin.New()
+	// Only believe X-Forwarded-For from configured proxies so clients cannot pick their own address
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Get().Error().Err(err).Msg("Invalid trusted proxies, ignoring X-Forwarded-For")
+		_ = router.SetTrustedProxies(nil)
+	}
+
+	// Metrics are fed request timings by the logger middleware
+	var requestMetrics *metrics.Metrics
+	var requestObservers []middleware.RequestObserver
+	if cfg.MetricsEnabled {
+		requestMetrics = metrics.New(func() int { return len(sessionManager.GetAllSessionsShallow()) })
+		requestObservers = append(requestObservers, requestMetrics.ObserveRequest)
+	}
+	cors := middleware.NewReloadableCORS(cfg.CORSAllowedOrigins, cfg.RequestIDHeader)
+	rateLimiter := middleware.NewRateLimiterWithBurst(cfg.RateLimitPerMinute, cfg.RateLimitBurst, cfg.PerOriginRateLimits)
 
 	// Apply middleware in correct order
-	router.Use(middleware.Recovery())                                       // 1st - catch panics
-	router.Use(middleware.RequestID())                                      // 2nd - add request ID
-	router.Use(middleware.Logger())                                         // 3rd - log with ID
-	router.Use(middleware.RequestTimeout(middleware.DefaultRequestTimeout)) // 4th - enforce timeout
-	router.Use(middleware.CORSConfig(cfg.CORSAllowedOrigins))               // 5th - CORS headers
+	maxTimeout := time.Duration(cfg.MaxRequestTimeoutSeconds) * time.Second
+	// The log tail streams for as long as the admin watches it
+	untimedPaths := []string{cfg.RoutePrefix + "/admin/logs/tail"}
+	router.Use(middleware.RecoveryWithDetails(cfg.LogLevel == "debug"))                                              // 1st - catch panics
+	router.Use(middleware.ServerHeader(version.Get()))                                                               // 2nd - identify the server
+	router.Use(middleware.RequestID(cfg.RequestIDHeader))                                                            // 3rd - add request ID
+	router.Use(middleware.Logger(requestObservers...))                                                               // 4th - log with ID
+	router.Use(middleware.MaxConcurrentRequests(cfg.MaxConcurrentRequests))                                          // 5th - shed load when saturated
+	router.Use(middleware.MaxURLLength(cfg.MaxURLLength))                                                            // 6th - reject overlong URLs
+	router.Use(middleware.RequestTimeoutWithOverride(middleware.DefaultRequestTimeout, maxTimeout, untimedPaths...)) // 7th - enforce timeout
+	router.Use(cors.Handler())                                                                                       // 8th - CORS headers
+	router.Use(rateLimiter.Handler())                                                                                // 9th - per-client rate limit
 
 	// Create handlers
-	healthHandler := handlers.NewHealthHandler(sessionManager)
-	sessionHandler := handlers.NewSessionHandler(sessionManager, cfg.WorkspaceDir)
+	// Handlers that only report on sessions get a view that cannot mutate them
+	readOnlySessions := session.NewReadOnlyManager(sessionManager)
+	healthHandler := handlers.NewHealthHandler(readOnlySessions, cfg.HealthCacheTTL)
+	if options.cleanupMonitor != nil {
+		healthHandler.SetCleanupMonitor(options.cleanupMonitor)
+	}
+	if locator, ok := sessionManager.(session.CursorAgentLocator); ok {
+		healthHandler.SetCursorAgentLocator(locator, cfg.CursorAgentRediscovery)
+	}
+	statsHandler := handlers.NewStatsHandler(readOnlySessions)
+	clientConfigHandler := handlers.NewClientConfigHandler(cfg)
+	sessionHandler := handlers.NewSessionHandler(sessionManager, cfg)
+	sessionHandler.SetMemoryReader(healthHandler.MemoryUsageMB)
 	ttsHandler := handlers.NewTTSHandler(cfg)
 	transcribeHandler := handlers.NewTranscribeHandler(cfg)
+	voiceAskHandler := handlers.NewVoiceAskHandler(sessionHandler, ttsHandler)
+	maintenance := middleware.NewMaintenanceMode()
+	drain := handlers.NewDrainMode()
+	healthHandler.SetDrainMode(drain)
+	adminHandler := handlers.NewAdminHandler(cfg, maintenance, drain)
 
-	// API routes
-	api := router.Group("/api")
+	// Prometheus scrapes the conventional path, outside the API prefix
+	if requestMetrics != nil {
+		router.GET("/metrics", gin.WrapH(requestMetrics.Handler()))
+	}
+
+	// API routes require an API key when API_KEYS is set; /metrics above stays
+	// open so Prometheus can scrape it without one
+	api := router.Group(cfg.RoutePrefix, middleware.APIKeyAuth(cfg.APIKeys, cfg.PublicPaths))
 	{
-		// Health check
+		// Health checks stay available during maintenance
 		api.GET("/health", healthHandler.Handle)
+		api.GET("/health/full", healthHandler.Full)
+		api.GET("/health/ready", healthHandler.Ready)
+		api.GET("/tts/health", ttsHandler.HealthCheck)
+
+		// Admin
+		admin := api.Group("/admin", middleware.AdminAuth(cfg.AdminToken))
+		admin.POST("/maintenance", adminHandler.SetMaintenance)
+		admin.POST("/drain", adminHandler.SetDrain)
+		admin.GET("/logs/tail", adminHandler.TailLogs)
+	}
+
+	// Everything else returns 503 while maintenance mode is enabled
+	guarded := api.Group("", maintenance.Handler())
+	{
+		// Runtime statistics
+		guarded.GET("/stats", statsHandler.Handle)
+
+		// Recommended settings for clients
+		guarded.GET("/client-config", clientConfigHandler.Handle)
 
 		// Session management
-		api.POST("/session/start", sessionHandler.Start)
-		api.POST("/ask", sessionHandler.Ask)
-		api.POST("/heartbeat", sessionHandler.Heartbeat)
-		api.POST("/session/end", sessionHandler.End)
+		guarded.POST("/session/start", sessionHandler.Start)
+		guarded.POST("/session/ensure", sessionHandler.Ensure)
+		guarded.POST("/ask", sessionHandler.Ask)
+		guarded.POST("/ask/voice", voiceAskHandler.Handle)
+		guarded.POST("/ask/stream", sessionHandler.AskStream)
+		guarded.POST("/ask/cancel", sessionHandler.CancelAsk)
+		guarded.POST("/heartbeat", sessionHandler.Heartbeat)
+		guarded.POST("/session/end", sessionHandler.End)
+		guarded.POST("/session/cursor-chat", sessionHandler.UpdateCursorChat)
+		guarded.GET("/session/search", sessionHandler.Search)
+		guarded.GET("/session/context", sessionHandler.ProjectContext)
+		guarded.GET("/session/conversation", sessionHandler.Conversation)
+		guarded.GET("/session/history", sessionHandler.History)
+		guarded.GET("/session/info", sessionHandler.Info)
 
 		// Text-to-speech
-		api.GET("/tts/health", ttsHandler.HealthCheck)
-		api.POST("/tts", ttsHandler.Generate)
+		guarded.POST("/tts", ttsHandler.Generate)
+		guarded.GET("/tts/voices", ttsHandler.Voices)
 
 		// Speech-to-text
-		api.POST("/transcribe", transcribeHandler.Transcribe)
+		guarded.POST("/transcribe", transcribeHandler.Transcribe)
 	}
 
 	// Log registered routes
 	logRoutes(router)
 
-	return router
+	return router, NewReloader(cfg, cors, rateLimiter)
 }
 
 // logRoutes logs all registered routes with zerolog
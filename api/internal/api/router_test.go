@@ -0,0 +1,371 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/session"
+)
+
+// newTestConfig returns a config with defaults suitable for router tests
+func newTestConfig() *config.Config {
+	return &config.Config{
+		Port:                  config.DefaultPort,
+		LogLevel:              config.DefaultLogLevel,
+		SessionTimeoutMinutes: config.DefaultSessionTimeoutMinutes,
+		CORSAllowedOrigins:    config.DefaultCORSAllowedOrigins,
+		WorkspaceDir:          config.DefaultWorkspaceDir,
+		RoutePrefix:           config.DefaultRoutePrefix,
+		RequestIDHeader:       config.DefaultRequestIDHeader,
+		TrustedProxies:        []string{"127.0.0.1", "::1"},
+	}
+}
+
+func TestSetupRouter_RoutePrefix(t *testing.T) {
+	t.Run("default prefix serves health", func(t *testing.T) {
+		router := SetupRouter(newTestConfig(), session.NewMemorySessionManager())
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/health", nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+	})
+
+	t.Run("custom prefix serves routes and old prefix 404s", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.RoutePrefix = "/janus"
+		router := SetupRouter(cfg, session.NewMemorySessionManager())
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/janus/health", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200 for custom prefix, got %d", w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/janus/session/start", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200 for session start, got %d", w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/health", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404 for old prefix, got %d", w.Code)
+		}
+	})
+}
+
+func TestSetupRouter_MaintenanceMode(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AdminToken = "secret-admin-token"
+	router := SetupRouter(cfg, session.NewMemorySessionManager())
+
+	serve := func(method, path, body string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	ask := func() int {
+		return serve("POST", "/api/ask?session_id=missing", `{"question":"hi"}`, "").Code
+	}
+
+	if code := ask(); code != http.StatusNotFound {
+		t.Fatalf("expected ask to reach the handler before maintenance, got %d", code)
+	}
+
+	t.Run("rejects toggles without the admin token", func(t *testing.T) {
+		if w := serve("POST", "/api/admin/maintenance", `{"enabled":true}`, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", w.Code)
+		}
+		if w := serve("POST", "/api/admin/maintenance", `{"enabled":true}`, "wrong"); w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401 for wrong token, got %d", w.Code)
+		}
+		if code := ask(); code == http.StatusServiceUnavailable {
+			t.Error("expected maintenance to remain off")
+		}
+	})
+
+	t.Run("enabling returns 503 for ask while health works", func(t *testing.T) {
+		w := serve("POST", "/api/admin/maintenance", `{"enabled":true,"message":"Upgrading, back soon"}`, cfg.AdminToken)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200 enabling maintenance, got %d: %s", w.Code, w.Body.String())
+		}
+
+		w = serve("POST", "/api/ask?session_id=missing", `{"question":"hi"}`, "")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status 503 during maintenance, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "Upgrading, back soon") {
+			t.Errorf("expected custom maintenance message, got %s", w.Body.String())
+		}
+
+		if w := serve("GET", "/api/health", "", ""); w.Code != http.StatusOK {
+			t.Errorf("expected health to stay available, got %d", w.Code)
+		}
+	})
+
+	t.Run("disabling restores normal flow", func(t *testing.T) {
+		if w := serve("POST", "/api/admin/maintenance", `{"enabled":false}`, cfg.AdminToken); w.Code != http.StatusOK {
+			t.Fatalf("expected status 200 disabling maintenance, got %d", w.Code)
+		}
+		if code := ask(); code != http.StatusNotFound {
+			t.Errorf("expected ask to reach the handler again, got %d", code)
+		}
+	})
+}
+
+func TestSetupRouter_DrainMode(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AdminToken = "secret-admin-token"
+	router := SetupRouter(cfg, session.NewMemorySessionManager())
+
+	serve := func(method, path, body string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve("GET", "/api/health/ready", "", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected ready before draining, got %d", w.Code)
+	}
+
+	w := serve("POST", "/api/session/start", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected session start to succeed, got %d", w.Code)
+	}
+	var started struct {
+		SessionID string `json:"session_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &started)
+
+	t.Run("rejects toggles without the admin token", func(t *testing.T) {
+		if w := serve("POST", "/api/admin/drain", `{"enabled":true}`, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("draining fails readiness while sessions keep working", func(t *testing.T) {
+		if w := serve("POST", "/api/admin/drain", `{"enabled":true}`, cfg.AdminToken); w.Code != http.StatusOK {
+			t.Fatalf("expected status 200 enabling drain, got %d: %s", w.Code, w.Body.String())
+		}
+
+		w := serve("GET", "/api/health/ready", "", "")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status 503 while draining, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), `"active_sessions":1`) {
+			t.Errorf("expected the active session count, got %s", w.Body.String())
+		}
+
+		if w := serve("POST", "/api/heartbeat?session_id="+started.SessionID, "", ""); w.Code != http.StatusOK {
+			t.Errorf("expected heartbeat to keep working while draining, got %d", w.Code)
+		}
+		if w := serve("GET", "/api/session/conversation?session_id="+started.SessionID, "", ""); w.Code != http.StatusOK {
+			t.Errorf("expected conversation to keep working while draining, got %d", w.Code)
+		}
+		if w := serve("GET", "/api/health", "", ""); w.Code != http.StatusOK {
+			t.Errorf("expected health to stay ok while draining, got %d", w.Code)
+		}
+	})
+
+	t.Run("disabling restores readiness", func(t *testing.T) {
+		if w := serve("POST", "/api/admin/drain", `{"enabled":false}`, cfg.AdminToken); w.Code != http.StatusOK {
+			t.Fatalf("expected status 200 disabling drain, got %d", w.Code)
+		}
+		if w := serve("GET", "/api/health/ready", "", ""); w.Code != http.StatusOK {
+			t.Errorf("expected ready after draining stops, got %d", w.Code)
+		}
+	})
+}
+
+func TestSetupRouter_Metrics(t *testing.T) {
+	t.Run("serves request metrics when enabled", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.MetricsEnabled = true
+		router := SetupRouter(cfg, session.NewMemorySessionManager())
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/health", nil))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), `janus_http_requests_total{method="GET",path="/api/health",status="200"} 1`) {
+			t.Errorf("expected the health request to be counted, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("scrapes without an API key", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.MetricsEnabled = true
+		cfg.APIKeys = []string{"secret"}
+		router := SetupRouter(cfg, session.NewMemorySessionManager())
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		router := SetupRouter(newTestConfig(), session.NewMemorySessionManager())
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestSetupRouter_DefaultPublicPathsFollowRoutePrefix(t *testing.T) {
+	t.Setenv("ROUTE_PREFIX", "/v2")
+	t.Setenv("API_KEYS", "secret")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	router := SetupRouter(cfg, session.NewMemorySessionManager())
+
+	for _, path := range []string{"/v2/health", "/v2/health/ready"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected %s to be public, got status %d", path, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v2/stats", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected other routes to need a key, got status %d", w.Code)
+	}
+}
+
+func TestSetupRouter_TrustedProxies(t *testing.T) {
+	send := func(router http.Handler, remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest("GET", "/api/health", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("spoofed X-Forwarded-For from an untrusted peer is ignored", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.RateLimitPerMinute = 1
+		router := SetupRouter(cfg, session.NewMemorySessionManager())
+
+		if code := send(router, "198.51.100.7:4000", "203.0.113.1"); code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", code)
+		}
+		if code := send(router, "198.51.100.7:4000", "203.0.113.2"); code != http.StatusTooManyRequests {
+			t.Errorf("expected a new X-Forwarded-For not to reset the limit, got %d", code)
+		}
+	})
+
+	t.Run("forwarded clients of a trusted proxy are limited separately", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.RateLimitPerMinute = 1
+		router := SetupRouter(cfg, session.NewMemorySessionManager())
+
+		if code := send(router, "127.0.0.1:4000", "203.0.113.1"); code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", code)
+		}
+		if code := send(router, "127.0.0.1:4000", "203.0.113.2"); code != http.StatusOK {
+			t.Errorf("expected another forwarded client to have its own bucket, got %d", code)
+		}
+	})
+}
+
+func TestSetupRouter_AdminDisabledWithoutToken(t *testing.T) {
+	router := SetupRouter(newTestConfig(), session.NewMemorySessionManager())
+
+	req := httptest.NewRequest("POST", "/api/admin/maintenance", strings.NewReader(`{"enabled":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 with no admin token configured, got %d", w.Code)
+	}
+}
+
+func TestSetupRouter_CORSHeaders(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.CORSAllowedOrigins = "https://app.example.com"
+	router := SetupRouter(cfg, session.NewMemorySessionManager())
+
+	t.Run("preflight allows the idempotency key", func(t *testing.T) {
+		req := httptest.NewRequest("OPTIONS", "/api/ask", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "X-Idempotency-Key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %d", w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(strings.ToLower(got), "x-idempotency-key") {
+			t.Errorf("expected X-Idempotency-Key to be allowed, got %q", got)
+		}
+	})
+
+	t.Run("allows and exposes the configured request ID header", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.CORSAllowedOrigins = "https://app.example.com"
+		cfg.RequestIDHeader = "X-Correlation-ID"
+		router := SetupRouter(cfg, session.NewMemorySessionManager())
+
+		req := httptest.NewRequest("OPTIONS", "/api/ask", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "X-Correlation-ID")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if got := w.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(strings.ToLower(got), "x-correlation-id") {
+			t.Errorf("expected X-Correlation-ID to be allowed, got %q", got)
+		}
+
+		req = httptest.NewRequest("GET", "/api/health", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if got := w.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(strings.ToLower(got), "x-correlation-id") {
+			t.Errorf("expected X-Correlation-ID to be exposed, got %q", got)
+		}
+	})
+
+	t.Run("exposes the replayed marker", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/health", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(strings.ToLower(got), "x-idempotent-replayed") {
+			t.Errorf("expected X-Idempotent-Replayed to be exposed, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/sean/janus/internal/config"
+)
+
+// NewServer creates the HTTP server for handler with the configured timeouts,
+// so slow or stalled clients can't hold connections open indefinitely. A zero
+// timeout leaves that limit disabled.
+func NewServer(cfg *config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%s", cfg.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ServerReadHeaderTimeout,
+		ReadTimeout:       cfg.ServerReadTimeout,
+		WriteTimeout:      cfg.ServerWriteTimeout,
+		IdleTimeout:       cfg.ServerIdleTimeout,
+	}
+}
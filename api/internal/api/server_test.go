@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sean/janus/internal/config"
+)
+
+func TestNewServer_Timeouts(t *testing.T) {
+	cfg := &config.Config{
+		Port:                    "3000",
+		ServerReadHeaderTimeout: 10 * time.Second,
+		ServerReadTimeout:       5 * time.Minute,
+		ServerWriteTimeout:      6 * time.Minute,
+		ServerIdleTimeout:       2 * time.Minute,
+	}
+	handler := http.NewServeMux()
+
+	srv := NewServer(cfg, handler)
+
+	if srv.Addr != ":3000" {
+		t.Errorf("expected address :3000, got %q", srv.Addr)
+	}
+	if srv.Handler != handler {
+		t.Error("expected the router to be the server's handler")
+	}
+	if srv.ReadHeaderTimeout != 10*time.Second {
+		t.Errorf("expected ReadHeaderTimeout 10s, got %v", srv.ReadHeaderTimeout)
+	}
+	if srv.ReadTimeout != 5*time.Minute {
+		t.Errorf("expected ReadTimeout 5m, got %v", srv.ReadTimeout)
+	}
+	if srv.WriteTimeout != 6*time.Minute {
+		t.Errorf("expected WriteTimeout 6m, got %v", srv.WriteTimeout)
+	}
+	if srv.IdleTimeout != 2*time.Minute {
+		t.Errorf("expected IdleTimeout 2m, got %v", srv.IdleTimeout)
+	}
+}
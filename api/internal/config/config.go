@@ -1,30 +1,136 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Port                  string
-	LogLevel              string
-	SessionTimeoutMinutes int
-	ContextDir            string
-	MaxContextSummaries   int
-	GitRecentDays         int
-	CORSAllowedOrigins    string
-	WorkspaceDir          string
-	KokoroTTSPath         string
-	KokoroTTSModelPath    string
-	KokoroTTSVoicesPath   string
-	KokoroTTSVoice        string
-	KokoroTTSSpeed        float64
-	WhisperPath           string
-	WhisperModel          string
+	Port                      string
+	LogLevel                  string
+	SessionTimeoutMinutes     int
+	ContextDir                string
+	MaxContextSummaries       int
+	GitRecentDays             int
+	CORSAllowedOrigins        string
+	WorkspaceDir              string
+	KokoroTTSPath             string
+	KokoroTTSModelPath        string
+	KokoroTTSVoicesPath       string
+	KokoroTTSVoice            string
+	KokoroTTSSpeed            float64
+	WhisperPath               string
+	WhisperModel              string
+	LockMetrics               bool
+	RoutePrefix               string
+	RequestIDHeader           string
+	SSEKeepaliveInterval      time.Duration
+	CursorAgentPath           string
+	CursorAgentFallbackModels []string
+	NormalizeQuestions        bool
+	MaxRequestTimeoutSeconds  int
+	MaxUploadBytes            int64
+	KeepTempFiles             bool
+	LongConversationThreshold int
+	MaxCursorOutputBytes      int
+	MaxURLLength              int
+	AuditLog                  bool
+	HealthCacheTTL            time.Duration
+	IdempotentEnd             bool
+	MaxConcurrentTTS          int
+	TTSQueueTimeout           time.Duration
+	GitPath                   string
+	EnvelopeResponses         bool
+	MaxHeartbeatsPerMinute    int
+	AllowBrowserFallback      bool
+	MaxConversationBytes      int
+	AdminToken                string
+	ConversationArchiveDir    string
+	ArchiveRetentionDays      int
+	SignSessionIDs            bool
+	SessionIDSecret           string
+	CursorAgentUseStdin       bool
+	CursorAgentStdinThreshold int
+	MemoryCriticalMB          int
+	RateLimitPerMinute        int
+	PerOriginRateLimits       map[string]int
+	MinAudioBytes             int64
+	MaxConcurrentRequests     int
+	MaxConnections            int
+	TTSPresets                map[string]TTSPreset
+	MaxAsksPerIP              int
+	CursorAgentModes          map[string][]string
+	TimestampFormat           string
+	WarmupWhisperOnStart      bool
+	IdleWarningThreshold      time.Duration
+	DedupeConcurrentAsks      bool
+	StrictJSON                bool
+	DependencyWaitSeconds     int
+	FailFastOnMissingDeps     bool
+	VoiceClientPreamble       string
+	VoiceClientFilters        []string
+	TextClientPreamble        string
+	TextClientFilters         []string
+	ArchiveBackend            string
+	S3Endpoint                string
+	S3Bucket                  string
+	S3Region                  string
+	S3AccessKeyID             string
+	S3SecretAccessKey         string
+	ArchiveWorkers            int
+	ArchiveQueueSize          int
+	ArchiveQueuePolicy        string
+	MaxSessionTimeoutMinutes  int
+	TranscribeCacheTTL        time.Duration
+	SSESlowClientTimeout      time.Duration
+	ServerReadHeaderTimeout   time.Duration
+	ServerReadTimeout         time.Duration
+	ServerWriteTimeout        time.Duration
+	ServerIdleTimeout         time.Duration
+	TTSStripEmoji             bool
+	PersistentCursorProcess   bool
+	APIKeys                   []string
+	PublicPaths               []string
+	AnswerWebhookURL          string
+	AnswerWebhookTimeout      time.Duration
+	AnswerWebhookRetries      int
+	InitialSessionCapacity    int
+	SessionShards             int
+	TranslatePath             string
+	SessionPersistence        string
+	SessionPersistDebounce    time.Duration
+	QuestionDenyPatterns      []string
+	MaxStoredAnswers          int
+	SerializeAsks             bool
+	RejectConcurrentAsks      bool
+	MaxConcurrentAsks         int
+	MetricsEnabled            bool
+	SessionUsageSummary       bool
+	RateLimitBurst            int
+	CursorAgentRediscovery    time.Duration
+	TTSVoices                 []string
+	AskIdempotencyTTL         time.Duration
+	CursorErrorStatuses       map[string]int
+	TTSCacheMaxMB             int
+	TrustedProxies            []string
+	AnswerWebhookHosts        []string
+	MemoryPressureIdleCutoff  time.Duration
+}
+
+// TTSPreset is a named voice and speed combination a TTS request can select
+type TTSPreset struct {
+	Voice string
+	Speed float64
 }
 
 const (
@@ -59,6 +165,175 @@ const (
 	DefaultWhisperPath = "/home/sean/whisper-local/.venv/bin/whisper"
 	// DefaultWhisperModel is the default Whisper model to use
 	DefaultWhisperModel = "base"
+	// DefaultLockMetrics controls session manager lock instrumentation (off to avoid overhead)
+	DefaultLockMetrics = false
+	// DefaultRoutePrefix is the path prefix all API routes are mounted under
+	DefaultRoutePrefix = "/api"
+	// DefaultRequestIDHeader is the header used to read and return request IDs
+	DefaultRequestIDHeader = "X-Request-ID"
+	// DefaultSSEKeepaliveInterval is how often idle SSE streams send keepalive comments
+	DefaultSSEKeepaliveInterval = 15 * time.Second
+	// DefaultCursorAgentPath is the cursor-agent executable, resolved from PATH
+	DefaultCursorAgentPath = "cursor-agent"
+	// DefaultNormalizeQuestions controls lowercasing when building question cache keys
+	DefaultNormalizeQuestions = false
+	// DefaultMaxRequestTimeoutSeconds caps the per-request X-Timeout-Seconds override
+	DefaultMaxRequestTimeoutSeconds = 300
+	// DefaultMaxUploadBytes is the largest audio upload accepted for transcription (0 disables the limit)
+	DefaultMaxUploadBytes = 25 << 20
+	// DefaultKeepTempFiles retains per-request audio temp files for debugging
+	DefaultKeepTempFiles = false
+	// DefaultLongConversationThreshold is the message count past which sessions are flagged as long (0 disables)
+	DefaultLongConversationThreshold = 50
+	// DefaultMaxCursorOutputBytes bounds captured cursor-agent output per ask (0 disables)
+	DefaultMaxCursorOutputBytes = 10 << 20
+	// DefaultMaxURLLength is the longest request URL (path and query) accepted (0 disables)
+	DefaultMaxURLLength = 2048
+	// DefaultAuditLog controls structured audit entries for admin endpoints
+	DefaultAuditLog = true
+	// DefaultHealthCacheTTL is how long /health reuses memory stats between reads
+	DefaultHealthCacheTTL = 5 * time.Second
+	// DefaultIdempotentEnd makes ending a nonexistent session a 200 no-op instead of 404
+	DefaultIdempotentEnd = false
+	// DefaultMaxConcurrentTTS limits simultaneous kokoro syntheses (0 disables the limit)
+	DefaultMaxConcurrentTTS = 2
+	// DefaultTTSQueueTimeout is how long a TTS request waits for a free slot before 503
+	DefaultTTSQueueTimeout = 10 * time.Second
+	// DefaultGitPath is the git executable used for session branch checkout, resolved from PATH
+	DefaultGitPath = "git"
+	// DefaultEnvelopeResponses wraps successful responses in {data, request_id, timestamp}
+	DefaultEnvelopeResponses = false
+	// DefaultMaxHeartbeatsPerMinute is the per-session heartbeat rate limit (0 disables)
+	DefaultMaxHeartbeatsPerMinute = 20
+	// DefaultAllowBrowserFallback makes failed TTS tell clients to use browser TTS instead of erroring
+	DefaultAllowBrowserFallback = false
+	// DefaultMaxConversationBytes bounds stored conversation content per session (0 disables)
+	DefaultMaxConversationBytes = 0
+	// DefaultConversationArchiveDir is where archived conversation files are stored
+	DefaultConversationArchiveDir = ".janus/conversation-archive"
+	// DefaultArchiveRetentionDays is how long archived conversations are kept (0 keeps them forever)
+	DefaultArchiveRetentionDays = 30
+	// DefaultSignSessionIDs appends an HMAC to issued session IDs and rejects unsigned or tampered ones
+	DefaultSignSessionIDs = false
+	// DefaultCursorAgentUseStdin always passes questions to cursor-agent on stdin
+	DefaultCursorAgentUseStdin = false
+	// DefaultCursorAgentStdinThreshold is the question length (bytes) sent on stdin to avoid ARG_MAX (0 disables)
+	DefaultCursorAgentStdinThreshold = 64 << 10
+	// DefaultMemoryCriticalMB is the heap usage at which new sessions are rejected (0 disables)
+	DefaultMemoryCriticalMB = 0
+	// DefaultRateLimitPerMinute is the per-client (origin + IP) request limit (0 disables)
+	DefaultRateLimitPerMinute = 0
+	// DefaultMinAudioBytes is the smallest audio upload worth transcribing (0 disables)
+	DefaultMinAudioBytes = 1024
+	// DefaultMaxConcurrentRequests caps in-flight HTTP requests server-wide (0 disables)
+	DefaultMaxConcurrentRequests = 0
+	// DefaultMaxConnections caps simultaneously open TCP connections (0 disables)
+	DefaultMaxConnections = 0
+	// DefaultMaxAsksPerIP caps concurrent /ask requests from one client IP (0 disables)
+	DefaultMaxAsksPerIP = 0
+	// DefaultTimestampFormat is how response timestamps are encoded ("rfc3339" or "epoch_ms")
+	DefaultTimestampFormat = "rfc3339"
+	// DefaultWarmupWhisperOnStart transcribes a silent clip at startup to preload the Whisper model
+	DefaultWarmupWhisperOnStart = false
+	// DefaultIdleWarningThreshold is the idle time after which responses warn the session may expire (0 disables)
+	DefaultIdleWarningThreshold = 0
+	// DefaultDedupeConcurrentAsks makes identical concurrent asks on a session share one cursor-agent run
+	DefaultDedupeConcurrentAsks = false
+	// DefaultStrictJSON rejects request bodies containing fields the endpoint doesn't accept
+	DefaultStrictJSON = false
+	// DefaultDependencyWaitSeconds is how long startup waits for cursor-agent and model files (0 disables)
+	DefaultDependencyWaitSeconds = 0
+	// DefaultFailFastOnMissingDeps exits at startup if dependencies are still missing after the wait
+	DefaultFailFastOnMissingDeps = false
+	// DefaultVoiceClientPreamble is appended to questions from client_type "voice"
+	DefaultVoiceClientPreamble = "Answer concisely in a few plain spoken sentences, without markdown, code blocks, or lists."
+	// DefaultVoiceClientFilters are the answer filters applied for client_type "voice"
+	DefaultVoiceClientFilters = AnswerFilterStripMarkdown + "," + AnswerFilterCollapseWhitespace
+	// DefaultTextClientPreamble is appended to questions from client_type "text"
+	DefaultTextClientPreamble = "Format the answer as full markdown, using code blocks and lists where helpful."
+	// DefaultTextClientFilters are the answer filters applied for client_type "text"
+	DefaultTextClientFilters = ""
+	// DefaultArchiveBackend is where ended sessions' transcripts are archived ("none", "local", "s3", or "both")
+	DefaultArchiveBackend = "none"
+	// DefaultS3Region is the signing region for S3-compatible archive storage
+	DefaultS3Region = "us-east-1"
+	// DefaultArchiveWorkers is how many background workers archive sessions (0 archives synchronously)
+	DefaultArchiveWorkers = 2
+	// DefaultArchiveQueueSize is how many archive jobs can wait for a worker
+	DefaultArchiveQueueSize = 64
+	// DefaultArchiveQueuePolicy is what happens when the archive queue is full ("drop" or "block")
+	DefaultArchiveQueuePolicy = "drop"
+	// DefaultMaxSessionTimeoutMinutes caps the timeout_minutes a client may request for a session
+	DefaultMaxSessionTimeoutMinutes = 240
+	// DefaultTranscribeCacheTTL is how long transcriptions are reused for identical audio (0 disables)
+	DefaultTranscribeCacheTTL = 0
+	// DefaultSSESlowClientTimeout is how long an SSE client may leave its buffer full before being dropped (0 disables)
+	DefaultSSESlowClientTimeout = 10 * time.Second
+	// DefaultServerReadHeaderTimeout bounds how long a client may take to send request headers
+	DefaultServerReadHeaderTimeout = 10 * time.Second
+	// DefaultServerReadTimeout bounds reading a whole request, leaving room for large audio uploads
+	DefaultServerReadTimeout = 5 * time.Minute
+	// DefaultServerWriteTimeout bounds writing a response; it must outlast the longest
+	// request timeout, and SSE streams lift it for their own connection
+	DefaultServerWriteTimeout = 6 * time.Minute
+	// DefaultServerIdleTimeout is how long an idle keep-alive connection stays open
+	DefaultServerIdleTimeout = 2 * time.Minute
+	// DefaultTTSStripEmoji leaves TTS text untouched unless stripping emoji and control characters is enabled
+	DefaultTTSStripEmoji = false
+	// DefaultPersistentCursorProcess spawns a fresh cursor-agent per ask rather than keeping one per session
+	DefaultPersistentCursorProcess = false
+	// DefaultPublicPaths are the routes, relative to the route prefix, reachable without an API key when API_KEYS is set
+	DefaultPublicPaths = "/health,/health/ready"
+	// DefaultAnswerWebhookTimeout bounds each attempt to deliver an answer to its webhook
+	DefaultAnswerWebhookTimeout = 5 * time.Second
+	// DefaultAnswerWebhookRetries is how many times a failed webhook delivery is retried
+	DefaultAnswerWebhookRetries = 3
+	// DefaultInitialSessionCapacity is how many sessions storage is pre-sized for (0 grows on demand)
+	DefaultInitialSessionCapacity = 0
+	// DefaultSessionShards is how many independently locked maps sessions are split across
+	DefaultSessionShards = 1
+	// DefaultSessionPersistence is where sessions are kept across restarts ("none" or "file")
+	DefaultSessionPersistence = "none"
+	// DefaultSessionPersistDebounce batches session changes into one write to disk
+	DefaultSessionPersistDebounce = 2 * time.Second
+	// DefaultMaxStoredAnswers is how many recent answers keep their full text per session (0 keeps all)
+	DefaultMaxStoredAnswers = 0
+	// DefaultSerializeAsks runs one question at a time per session so overlapping asks can't corrupt its cursor chat
+	DefaultSerializeAsks = true
+	// DefaultRejectConcurrentAsks queues a session's overlapping asks rather than rejecting them with 409
+	DefaultRejectConcurrentAsks = false
+	// DefaultMaxConcurrentAsks caps simultaneous cursor-agent runs across sessions, queueing the rest in order (0 disables)
+	DefaultMaxConcurrentAsks = 0
+	// DefaultMetricsEnabled serves Prometheus metrics at /metrics
+	DefaultMetricsEnabled = true
+	// DefaultSessionUsageSummary leaves usage summaries out of session end responses and logs
+	DefaultSessionUsageSummary = false
+	// DefaultRateLimitBurst lets a client burst up to its full per-minute limit (0 means the limit)
+	DefaultRateLimitBurst = 0
+	// DefaultCursorAgentRediscoverInterval is how often health checks re-resolve the cursor-agent path (0 disables)
+	DefaultCursorAgentRediscoverInterval = 30 * time.Second
+	// DefaultTTSVoices are the kokoro voices a TTS request may select by name
+	DefaultTTSVoices = "af_alloy,af_aoede,af_bella,af_heart,af_jessica,af_kore,af_nicole,af_nova,af_river,af_sarah,af_sky," +
+		"am_adam,am_echo,am_eric,am_fenrir,am_liam,am_michael,am_onyx,am_puck,am_santa," +
+		"bf_alice,bf_emma,bf_isabella,bf_lily,bm_daniel,bm_fable,bm_george,bm_lewis"
+	// DefaultAskIdempotencyTTL is how long an ask's response is replayed to retries with its X-Idempotency-Key (0 disables)
+	DefaultAskIdempotencyTTL = 10 * time.Minute
+	// DefaultCursorErrorStatuses maps cursor-agent error subtypes to HTTP statuses; other subtypes get 500
+	DefaultCursorErrorStatuses = "rate_limit=429,auth=401"
+	// DefaultTTSCacheMaxMB caps the on-disk cache of synthesized audio for repeated text (0 disables)
+	DefaultTTSCacheMaxMB = 100
+	// DefaultTrustedProxies lists the peers whose X-Forwarded-For header is believed; others are identified by their own address
+	DefaultTrustedProxies = "127.0.0.1,::1"
+	// DefaultMemoryPressureIdleCutoff is how long a session must be idle to be swept when memory turns critical
+	DefaultMemoryPressureIdleCutoff = 2 * time.Minute
+)
+
+// Answer filters that can post-process cursor-agent answers for a client type
+const (
+	// AnswerFilterStripMarkdown removes markdown syntax, leaving plain text
+	AnswerFilterStripMarkdown = "strip_markdown"
+	// AnswerFilterCollapseWhitespace joins lines and runs of whitespace into single spaces
+	AnswerFilterCollapseWhitespace = "collapse_whitespace"
 )
 
 // Load reads configuration from environment variables
@@ -66,22 +341,130 @@ func Load() (*Config, error) {
 	// Try to load .env file (ignore error if it doesn't exist)
 	_ = godotenv.Load()
 
+	return build()
+}
+
+// Reload re-reads configuration for a running server. Unlike Load, values in
+// the .env file override variables set by the previous load so edits take effect.
+func Reload() (*Config, error) {
+	_ = godotenv.Overload()
+
+	return build()
+}
+
+// build constructs and validates a Config from the current environment
+func build() (*Config, error) {
+	routePrefix := strings.TrimRight(getEnv("ROUTE_PREFIX", DefaultRoutePrefix), "/")
 	cfg := &Config{
-		Port:                  getEnv("PORT", DefaultPort),
-		LogLevel:              getEnv("LOG_LEVEL", DefaultLogLevel),
-		SessionTimeoutMinutes: getEnvAsInt("SESSION_TIMEOUT_MINUTES", DefaultSessionTimeoutMinutes),
-		ContextDir:            getEnv("CONTEXT_DIR", DefaultContextDir),
-		MaxContextSummaries:   getEnvAsInt("MAX_CONTEXT_SUMMARIES", DefaultMaxContextSummaries),
-		GitRecentDays:         getEnvAsInt("GIT_RECENT_DAYS", DefaultGitRecentDays),
-		CORSAllowedOrigins:    getEnv("CORS_ALLOWED_ORIGINS", DefaultCORSAllowedOrigins),
-		WorkspaceDir:          getEnv("WORKSPACE_DIR", DefaultWorkspaceDir),
-		KokoroTTSPath:         getEnv("KOKORO_TTS_PATH", DefaultKokoroTTSPath),
-		KokoroTTSModelPath:    getEnv("KOKORO_TTS_MODEL_PATH", DefaultKokoroTTSModelPath),
-		KokoroTTSVoicesPath:   getEnv("KOKORO_TTS_VOICES_PATH", DefaultKokoroTTSVoicesPath),
-		KokoroTTSVoice:        getEnv("KOKORO_TTS_VOICE", DefaultKokoroTTSVoice),
-		KokoroTTSSpeed:        getEnvAsFloat("KOKORO_TTS_SPEED", DefaultKokoroTTSSpeed),
-		WhisperPath:           getEnv("WHISPER_PATH", DefaultWhisperPath),
-		WhisperModel:          getEnv("WHISPER_MODEL", DefaultWhisperModel),
+		Port:                      getEnv("PORT", DefaultPort),
+		LogLevel:                  getEnv("LOG_LEVEL", DefaultLogLevel),
+		SessionTimeoutMinutes:     getEnvAsInt("SESSION_TIMEOUT_MINUTES", DefaultSessionTimeoutMinutes),
+		ContextDir:                getEnv("CONTEXT_DIR", DefaultContextDir),
+		MaxContextSummaries:       getEnvAsInt("MAX_CONTEXT_SUMMARIES", DefaultMaxContextSummaries),
+		GitRecentDays:             getEnvAsInt("GIT_RECENT_DAYS", DefaultGitRecentDays),
+		CORSAllowedOrigins:        getEnv("CORS_ALLOWED_ORIGINS", DefaultCORSAllowedOrigins),
+		WorkspaceDir:              getEnv("WORKSPACE_DIR", DefaultWorkspaceDir),
+		KokoroTTSPath:             getEnv("KOKORO_TTS_PATH", DefaultKokoroTTSPath),
+		KokoroTTSModelPath:        getEnv("KOKORO_TTS_MODEL_PATH", DefaultKokoroTTSModelPath),
+		KokoroTTSVoicesPath:       getEnv("KOKORO_TTS_VOICES_PATH", DefaultKokoroTTSVoicesPath),
+		KokoroTTSVoice:            getEnv("KOKORO_TTS_VOICE", DefaultKokoroTTSVoice),
+		KokoroTTSSpeed:            getEnvAsFloat("KOKORO_TTS_SPEED", DefaultKokoroTTSSpeed),
+		WhisperPath:               getEnv("WHISPER_PATH", DefaultWhisperPath),
+		WhisperModel:              getEnv("WHISPER_MODEL", DefaultWhisperModel),
+		LockMetrics:               getEnvAsBool("LOCK_METRICS", DefaultLockMetrics),
+		RoutePrefix:               routePrefix,
+		RequestIDHeader:           getEnv("REQUEST_ID_HEADER", DefaultRequestIDHeader),
+		SSEKeepaliveInterval:      getEnvAsDuration("SSE_KEEPALIVE_INTERVAL", DefaultSSEKeepaliveInterval),
+		CursorAgentPath:           getEnv("CURSOR_AGENT_PATH", DefaultCursorAgentPath),
+		CursorAgentFallbackModels: getEnvAsList("CURSOR_AGENT_FALLBACK_MODELS"),
+		NormalizeQuestions:        getEnvAsBool("NORMALIZE_QUESTIONS", DefaultNormalizeQuestions),
+		MaxRequestTimeoutSeconds:  getEnvAsInt("MAX_REQUEST_TIMEOUT_SECONDS", DefaultMaxRequestTimeoutSeconds),
+		MaxUploadBytes:            int64(getEnvAsInt("MAX_UPLOAD_BYTES", DefaultMaxUploadBytes)),
+		KeepTempFiles:             getEnvAsBool("KEEP_TEMP_FILES", DefaultKeepTempFiles),
+		LongConversationThreshold: getEnvAsInt("LONG_CONVERSATION_THRESHOLD", DefaultLongConversationThreshold),
+		MaxCursorOutputBytes:      getEnvAsInt("MAX_CURSOR_OUTPUT_BYTES", DefaultMaxCursorOutputBytes),
+		MaxURLLength:              getEnvAsInt("MAX_URL_LENGTH", DefaultMaxURLLength),
+		AuditLog:                  getEnvAsBool("AUDIT_LOG", DefaultAuditLog),
+		HealthCacheTTL:            getEnvAsDuration("HEALTH_CACHE_TTL", DefaultHealthCacheTTL),
+		IdempotentEnd:             getEnvAsBool("IDEMPOTENT_END", DefaultIdempotentEnd),
+		MaxConcurrentTTS:          getEnvAsInt("MAX_CONCURRENT_TTS", DefaultMaxConcurrentTTS),
+		TTSQueueTimeout:           getEnvAsDuration("TTS_QUEUE_TIMEOUT", DefaultTTSQueueTimeout),
+		GitPath:                   getEnv("GIT_PATH", DefaultGitPath),
+		EnvelopeResponses:         getEnvAsBool("ENVELOPE_RESPONSES", DefaultEnvelopeResponses),
+		MaxHeartbeatsPerMinute:    getEnvAsInt("MAX_HEARTBEATS_PER_MINUTE", DefaultMaxHeartbeatsPerMinute),
+		AllowBrowserFallback:      getEnvAsBool("ALLOW_BROWSER_FALLBACK", DefaultAllowBrowserFallback),
+		MaxConversationBytes:      getEnvAsInt("MAX_CONVERSATION_BYTES", DefaultMaxConversationBytes),
+		AdminToken:                getEnv("ADMIN_TOKEN", ""),
+		ConversationArchiveDir:    getEnv("CONVERSATION_ARCHIVE_DIR", DefaultConversationArchiveDir),
+		ArchiveRetentionDays:      getEnvAsInt("ARCHIVE_RETENTION_DAYS", DefaultArchiveRetentionDays),
+		SignSessionIDs:            getEnvAsBool("SIGN_SESSION_IDS", DefaultSignSessionIDs),
+		SessionIDSecret:           getEnv("SESSION_ID_SECRET", ""),
+		CursorAgentUseStdin:       getEnvAsBool("CURSOR_AGENT_USE_STDIN", DefaultCursorAgentUseStdin),
+		CursorAgentStdinThreshold: getEnvAsInt("CURSOR_AGENT_STDIN_THRESHOLD", DefaultCursorAgentStdinThreshold),
+		MemoryCriticalMB:          getEnvAsInt("MEMORY_CRITICAL_MB", DefaultMemoryCriticalMB),
+		RateLimitPerMinute:        getEnvAsInt("RATE_LIMIT_RPM", getEnvAsInt("RATE_LIMIT_PER_MINUTE", DefaultRateLimitPerMinute)),
+		PerOriginRateLimits:       getEnvAsIntMap("PER_ORIGIN_RATE_LIMITS"),
+		MinAudioBytes:             int64(getEnvAsInt("MIN_AUDIO_BYTES", DefaultMinAudioBytes)),
+		MaxConcurrentRequests:     getEnvAsInt("MAX_CONCURRENT_REQUESTS", DefaultMaxConcurrentRequests),
+		MaxConnections:            getEnvAsInt("MAX_CONNECTIONS", DefaultMaxConnections),
+		TTSPresets:                getEnvAsTTSPresets("TTS_PRESETS"),
+		MaxAsksPerIP:              getEnvAsInt("MAX_ASKS_PER_IP", DefaultMaxAsksPerIP),
+		CursorAgentModes:          getEnvAsArgTemplates("CURSOR_AGENT_MODES"),
+		TimestampFormat:           getEnv("TIMESTAMP_FORMAT", DefaultTimestampFormat),
+		WarmupWhisperOnStart:      getEnvAsBool("WARMUP_WHISPER_ON_START", DefaultWarmupWhisperOnStart),
+		IdleWarningThreshold:      getEnvAsDuration("IDLE_WARNING_THRESHOLD", DefaultIdleWarningThreshold),
+		DedupeConcurrentAsks:      getEnvAsBool("DEDUPE_CONCURRENT_ASKS", DefaultDedupeConcurrentAsks),
+		StrictJSON:                getEnvAsBool("STRICT_JSON", DefaultStrictJSON),
+		DependencyWaitSeconds:     getEnvAsInt("DEPENDENCY_WAIT_SECONDS", DefaultDependencyWaitSeconds),
+		FailFastOnMissingDeps:     getEnvAsBool("FAIL_FAST_ON_MISSING_DEPS", DefaultFailFastOnMissingDeps),
+		VoiceClientPreamble:       getEnv("VOICE_CLIENT_PREAMBLE", DefaultVoiceClientPreamble),
+		VoiceClientFilters:        splitList(getEnv("VOICE_CLIENT_FILTERS", DefaultVoiceClientFilters)),
+		TextClientPreamble:        getEnv("TEXT_CLIENT_PREAMBLE", DefaultTextClientPreamble),
+		TextClientFilters:         splitList(getEnv("TEXT_CLIENT_FILTERS", DefaultTextClientFilters)),
+		ArchiveBackend:            getEnv("ARCHIVE_BACKEND", DefaultArchiveBackend),
+		S3Endpoint:                getEnv("S3_ENDPOINT", ""),
+		S3Bucket:                  getEnv("S3_BUCKET", ""),
+		S3Region:                  getEnv("S3_REGION", DefaultS3Region),
+		S3AccessKeyID:             getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:         getEnv("S3_SECRET_ACCESS_KEY", ""),
+		ArchiveWorkers:            getEnvAsInt("ARCHIVE_WORKERS", DefaultArchiveWorkers),
+		ArchiveQueueSize:          getEnvAsInt("ARCHIVE_QUEUE_SIZE", DefaultArchiveQueueSize),
+		ArchiveQueuePolicy:        getEnv("ARCHIVE_QUEUE_POLICY", DefaultArchiveQueuePolicy),
+		MaxSessionTimeoutMinutes:  getEnvAsInt("MAX_SESSION_TIMEOUT_MINUTES", DefaultMaxSessionTimeoutMinutes),
+		TranscribeCacheTTL:        getEnvAsDuration("TRANSCRIBE_CACHE_TTL", DefaultTranscribeCacheTTL),
+		SSESlowClientTimeout:      getEnvAsDuration("SSE_SLOW_CLIENT_TIMEOUT", DefaultSSESlowClientTimeout),
+		ServerReadHeaderTimeout:   getEnvAsDuration("SERVER_READ_HEADER_TIMEOUT", DefaultServerReadHeaderTimeout),
+		ServerReadTimeout:         getEnvAsDuration("SERVER_READ_TIMEOUT", DefaultServerReadTimeout),
+		ServerWriteTimeout:        getEnvAsDuration("SERVER_WRITE_TIMEOUT", DefaultServerWriteTimeout),
+		ServerIdleTimeout:         getEnvAsDuration("SERVER_IDLE_TIMEOUT", DefaultServerIdleTimeout),
+		TTSStripEmoji:             getEnvAsBool("TTS_STRIP_EMOJI", DefaultTTSStripEmoji),
+		PersistentCursorProcess:   getEnvAsBool("PERSISTENT_CURSOR_PROCESS", DefaultPersistentCursorProcess),
+		APIKeys:                   getEnvAsList("API_KEYS"),
+		PublicPaths:               getEnvAsListOrDefault("PUBLIC_PATHS", defaultPublicPaths(routePrefix)),
+		AnswerWebhookURL:          getEnv("ANSWER_WEBHOOK_URL", ""),
+		AnswerWebhookTimeout:      getEnvAsDuration("ANSWER_WEBHOOK_TIMEOUT", DefaultAnswerWebhookTimeout),
+		AnswerWebhookRetries:      getEnvAsInt("ANSWER_WEBHOOK_RETRIES", DefaultAnswerWebhookRetries),
+		InitialSessionCapacity:    getEnvAsInt("INITIAL_SESSION_CAPACITY", DefaultInitialSessionCapacity),
+		SessionShards:             getEnvAsInt("SESSION_SHARDS", DefaultSessionShards),
+		TranslatePath:             getEnv("TRANSLATE_PATH", ""),
+		SessionPersistence:        getEnv("SESSION_PERSISTENCE", DefaultSessionPersistence),
+		SessionPersistDebounce:    getEnvAsDuration("SESSION_PERSIST_DEBOUNCE", DefaultSessionPersistDebounce),
+		QuestionDenyPatterns:      getEnvAsJSONList("QUESTION_DENY_PATTERNS"),
+		MaxStoredAnswers:          getEnvAsInt("MAX_STORED_ANSWERS", DefaultMaxStoredAnswers),
+		SerializeAsks:             getEnvAsBool("SERIALIZE_ASKS", DefaultSerializeAsks),
+		RejectConcurrentAsks:      getEnvAsBool("REJECT_CONCURRENT_ASKS", DefaultRejectConcurrentAsks),
+		MaxConcurrentAsks:         getEnvAsInt("MAX_CONCURRENT_ASKS", DefaultMaxConcurrentAsks),
+		MetricsEnabled:            getEnvAsBool("METRICS_ENABLED", DefaultMetricsEnabled),
+		SessionUsageSummary:       getEnvAsBool("SESSION_USAGE_SUMMARY", DefaultSessionUsageSummary),
+		RateLimitBurst:            getEnvAsInt("RATE_LIMIT_BURST", DefaultRateLimitBurst),
+		CursorAgentRediscovery:    getEnvAsDuration("CURSOR_AGENT_REDISCOVER_INTERVAL", DefaultCursorAgentRediscoverInterval),
+		TTSVoices:                 getEnvAsListOrDefault("TTS_VOICES", DefaultTTSVoices),
+		AskIdempotencyTTL:         getEnvAsDuration("ASK_IDEMPOTENCY_TTL", DefaultAskIdempotencyTTL),
+		CursorErrorStatuses:       getEnvAsIntMapOrDefault("CURSOR_ERROR_STATUSES", DefaultCursorErrorStatuses),
+		TTSCacheMaxMB:             getEnvAsInt("TTS_CACHE_MAX_MB", DefaultTTSCacheMaxMB),
+		TrustedProxies:            getEnvAsListOrDefault("TRUSTED_PROXIES", DefaultTrustedProxies),
+		AnswerWebhookHosts:        getEnvAsList("ANSWER_WEBHOOK_HOSTS"),
+		MemoryPressureIdleCutoff:  getEnvAsDuration("MEMORY_PRESSURE_IDLE_CUTOFF", DefaultMemoryPressureIdleCutoff),
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -101,9 +484,229 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("SESSION_TIMEOUT_MINUTES must be at least 1")
 	}
 
+	if c.RoutePrefix != "" && !strings.HasPrefix(c.RoutePrefix, "/") {
+		return fmt.Errorf("ROUTE_PREFIX must start with '/'")
+	}
+
+	if c.MaxRequestTimeoutSeconds < 0 {
+		return fmt.Errorf("MAX_REQUEST_TIMEOUT_SECONDS cannot be negative")
+	}
+
+	if c.MaxUploadBytes < 0 {
+		return fmt.Errorf("MAX_UPLOAD_BYTES cannot be negative")
+	}
+
+	if c.LongConversationThreshold < 0 {
+		return fmt.Errorf("LONG_CONVERSATION_THRESHOLD cannot be negative")
+	}
+
+	if c.MaxCursorOutputBytes < 0 {
+		return fmt.Errorf("MAX_CURSOR_OUTPUT_BYTES cannot be negative")
+	}
+
+	if c.MaxURLLength < 0 {
+		return fmt.Errorf("MAX_URL_LENGTH cannot be negative")
+	}
+
+	if c.MaxConcurrentTTS < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_TTS cannot be negative")
+	}
+
+	if c.MaxConversationBytes < 0 {
+		return fmt.Errorf("MAX_CONVERSATION_BYTES cannot be negative")
+	}
+
+	if c.ArchiveRetentionDays < 0 {
+		return fmt.Errorf("ARCHIVE_RETENTION_DAYS cannot be negative")
+	}
+
+	if c.CursorAgentStdinThreshold < 0 {
+		return fmt.Errorf("CURSOR_AGENT_STDIN_THRESHOLD cannot be negative")
+	}
+
+	if c.MemoryCriticalMB < 0 {
+		return fmt.Errorf("MEMORY_CRITICAL_MB cannot be negative")
+	}
+
+	if c.MemoryPressureIdleCutoff < 0 {
+		return fmt.Errorf("MEMORY_PRESSURE_IDLE_CUTOFF cannot be negative")
+	}
+
+	if c.RateLimitPerMinute < 0 {
+		return fmt.Errorf("RATE_LIMIT_RPM cannot be negative")
+	}
+
+	if c.RateLimitBurst < 0 {
+		return fmt.Errorf("RATE_LIMIT_BURST cannot be negative")
+	}
+
+	if c.MinAudioBytes < 0 {
+		return fmt.Errorf("MIN_AUDIO_BYTES cannot be negative")
+	}
+
+	if c.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_REQUESTS cannot be negative")
+	}
+
+	if c.MaxConnections < 0 {
+		return fmt.Errorf("MAX_CONNECTIONS cannot be negative")
+	}
+
+	if c.MaxAsksPerIP < 0 {
+		return fmt.Errorf("MAX_ASKS_PER_IP cannot be negative")
+	}
+
+	if c.DependencyWaitSeconds < 0 {
+		return fmt.Errorf("DEPENDENCY_WAIT_SECONDS cannot be negative")
+	}
+
+	if c.IdleWarningThreshold < 0 {
+		return fmt.Errorf("IDLE_WARNING_THRESHOLD cannot be negative")
+	}
+
+	if c.IdleWarningThreshold >= time.Duration(c.SessionTimeoutMinutes)*time.Minute {
+		return fmt.Errorf("IDLE_WARNING_THRESHOLD must be less than SESSION_TIMEOUT_MINUTES")
+	}
+
+	if c.TimestampFormat != "rfc3339" && c.TimestampFormat != "epoch_ms" {
+		return fmt.Errorf("TIMESTAMP_FORMAT must be 'rfc3339' or 'epoch_ms'")
+	}
+
+	for _, filter := range append(append([]string{}, c.VoiceClientFilters...), c.TextClientFilters...) {
+		if filter != AnswerFilterStripMarkdown && filter != AnswerFilterCollapseWhitespace {
+			return fmt.Errorf("unknown answer filter %q in VOICE_CLIENT_FILTERS or TEXT_CLIENT_FILTERS", filter)
+		}
+	}
+
+	switch c.ArchiveBackend {
+	case "none", "local":
+	case "s3", "both":
+		if c.S3Endpoint == "" || c.S3Bucket == "" {
+			return fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required when ARCHIVE_BACKEND is '%s'", c.ArchiveBackend)
+		}
+	default:
+		return fmt.Errorf("ARCHIVE_BACKEND must be 'none', 'local', 's3', or 'both'")
+	}
+
+	if c.ArchiveWorkers < 0 {
+		return fmt.Errorf("ARCHIVE_WORKERS cannot be negative")
+	}
+
+	if c.ArchiveWorkers > 0 && c.ArchiveQueueSize < 1 {
+		return fmt.Errorf("ARCHIVE_QUEUE_SIZE must be at least 1 when ARCHIVE_WORKERS is set")
+	}
+
+	if c.ArchiveQueuePolicy != "drop" && c.ArchiveQueuePolicy != "block" {
+		return fmt.Errorf("ARCHIVE_QUEUE_POLICY must be 'drop' or 'block'")
+	}
+
+	if c.MaxSessionTimeoutMinutes < 1 {
+		return fmt.Errorf("MAX_SESSION_TIMEOUT_MINUTES must be at least 1")
+	}
+
+	if c.TranscribeCacheTTL < 0 {
+		return fmt.Errorf("TRANSCRIBE_CACHE_TTL cannot be negative")
+	}
+
+	if c.SSESlowClientTimeout < 0 {
+		return fmt.Errorf("SSE_SLOW_CLIENT_TIMEOUT cannot be negative")
+	}
+
+	if c.ServerReadHeaderTimeout < 0 || c.ServerReadTimeout < 0 || c.ServerWriteTimeout < 0 || c.ServerIdleTimeout < 0 {
+		return fmt.Errorf("SERVER_*_TIMEOUT values cannot be negative")
+	}
+
+	if c.ServerWriteTimeout > 0 && c.ServerWriteTimeout <= time.Duration(c.MaxRequestTimeoutSeconds)*time.Second {
+		return fmt.Errorf("SERVER_WRITE_TIMEOUT must exceed MAX_REQUEST_TIMEOUT_SECONDS (or be 0 to disable)")
+	}
+
+	for _, path := range c.PublicPaths {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("PUBLIC_PATHS entries must start with '/', got %q", path)
+		}
+	}
+
+	if c.AnswerWebhookURL != "" && !IsWebhookURL(c.AnswerWebhookURL) {
+		return fmt.Errorf("ANSWER_WEBHOOK_URL must be an absolute http or https URL")
+	}
+
+	if c.AnswerWebhookTimeout <= 0 {
+		return fmt.Errorf("ANSWER_WEBHOOK_TIMEOUT must be positive")
+	}
+
+	if c.AnswerWebhookRetries < 0 {
+		return fmt.Errorf("ANSWER_WEBHOOK_RETRIES cannot be negative")
+	}
+
+	if c.InitialSessionCapacity < 0 {
+		return fmt.Errorf("INITIAL_SESSION_CAPACITY cannot be negative")
+	}
+
+	if c.SessionShards < 1 {
+		return fmt.Errorf("SESSION_SHARDS must be at least 1")
+	}
+
+	if c.SessionPersistence != "none" && c.SessionPersistence != "file" {
+		return fmt.Errorf("SESSION_PERSISTENCE must be 'none' or 'file'")
+	}
+
+	if c.SessionPersistDebounce < 0 {
+		return fmt.Errorf("SESSION_PERSIST_DEBOUNCE cannot be negative")
+	}
+
+	for _, pattern := range c.QuestionDenyPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("QUESTION_DENY_PATTERNS contains an invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	if c.MaxStoredAnswers < 0 {
+		return fmt.Errorf("MAX_STORED_ANSWERS cannot be negative")
+	}
+
+	if c.MaxConcurrentAsks < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_ASKS cannot be negative")
+	}
+
+	if c.CursorAgentRediscovery < 0 {
+		return fmt.Errorf("CURSOR_AGENT_REDISCOVER_INTERVAL cannot be negative")
+	}
+
+	if c.AskIdempotencyTTL < 0 {
+		return fmt.Errorf("ASK_IDEMPOTENCY_TTL cannot be negative")
+	}
+
+	for subtype, status := range c.CursorErrorStatuses {
+		if status < 400 || status > 599 {
+			return fmt.Errorf("CURSOR_ERROR_STATUSES maps %q to %d, which is not an HTTP error status", subtype, status)
+		}
+	}
+
+	if c.TTSCacheMaxMB < 0 {
+		return fmt.Errorf("TTS_CACHE_MAX_MB cannot be negative")
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return fmt.Errorf("TRUSTED_PROXIES entry %q is not an IP address or CIDR range", proxy)
+			}
+		}
+	}
+
+	if c.SignSessionIDs && c.SessionIDSecret == "" {
+		return fmt.Errorf("SESSION_ID_SECRET is required when SIGN_SESSION_IDS is enabled")
+	}
+
 	return nil
 }
 
+// IsWebhookURL reports whether raw is an absolute http or https URL with a host
+func IsWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
 // getEnv reads an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -141,3 +744,189 @@ func getEnvAsFloat(key string, defaultValue float64) float64 {
 
 	return value
 }
+
+// getEnvAsBool reads an environment variable as bool or returns a default value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvAsDuration reads an environment variable as a duration (e.g. "15s") or returns a default value
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := time.ParseDuration(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvAsList reads a comma-separated environment variable, trimming whitespace
+// and dropping empty entries. Returns nil when unset.
+func getEnvAsList(key string) []string {
+	return splitList(os.Getenv(key))
+}
+
+// defaultPublicPaths mounts DefaultPublicPaths under routePrefix, so the health
+// checks stay public wherever the API is served
+func defaultPublicPaths(routePrefix string) string {
+	paths := splitList(DefaultPublicPaths)
+	for i, path := range paths {
+		paths[i] = routePrefix + path
+	}
+	return strings.Join(paths, ",")
+}
+
+// getEnvAsListOrDefault reads a comma-separated environment variable like
+// getEnvAsList, falling back to defaultValue only when the variable is unset so
+// an explicitly empty value yields an empty list
+func getEnvAsListOrDefault(key string, defaultValue string) []string {
+	valueStr, ok := os.LookupEnv(key)
+	if !ok {
+		valueStr = defaultValue
+	}
+	return splitList(valueStr)
+}
+
+// getEnvAsJSONList reads an environment variable holding a JSON array of
+// strings, for lists whose entries may contain commas (such as regular
+// expressions), e.g. '["(?i)password", "rm -rf"]'. A value that isn't a JSON
+// array is taken as a single entry. Returns nil when unset.
+func getEnvAsJSONList(key string) []string {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return nil
+	}
+
+	var values []string
+	if err := json.Unmarshal([]byte(valueStr), &values); err != nil {
+		return []string{valueStr}
+	}
+	return values
+}
+
+// splitList splits a comma-separated value, trimming whitespace and dropping
+// empty entries. Returns nil for an empty value.
+func splitList(valueStr string) []string {
+	if valueStr == "" {
+		return nil
+	}
+
+	var values []string
+	for _, item := range strings.Split(valueStr, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+
+	return values
+}
+
+// getEnvAsIntMap reads a comma-separated list of key=integer pairs, e.g.
+// "https://app.example=120,capacitor://localhost=300". Keys may themselves
+// contain '=' since the value is taken after the last one. Malformed entries
+// are skipped. Returns nil when unset.
+func getEnvAsIntMap(key string) map[string]int {
+	return parseIntMap(os.Getenv(key))
+}
+
+// getEnvAsIntMapOrDefault is getEnvAsIntMap with defaultValue used when the
+// variable is unset. Setting it to an empty string yields no entries.
+func getEnvAsIntMapOrDefault(key string, defaultValue string) map[string]int {
+	valueStr, ok := os.LookupEnv(key)
+	if !ok {
+		valueStr = defaultValue
+	}
+	return parseIntMap(valueStr)
+}
+
+// parseIntMap parses the key=integer list read by getEnvAsIntMap
+func parseIntMap(valueStr string) map[string]int {
+	if valueStr == "" {
+		return nil
+	}
+
+	values := make(map[string]int)
+	for _, item := range strings.Split(valueStr, ",") {
+		idx := strings.LastIndex(item, "=")
+		if idx <= 0 {
+			continue
+		}
+		name := strings.TrimSpace(item[:idx])
+		value, err := strconv.Atoi(strings.TrimSpace(item[idx+1:]))
+		if name == "" || err != nil {
+			continue
+		}
+		values[name] = value
+	}
+
+	return values
+}
+
+// getEnvAsTTSPresets reads a comma-separated list of name=voice:speed entries, e.g.
+// "calm=af_sarah:0.8,fast=af_bella:1.4". Entries without a name, voice, or
+// positive speed are skipped. Returns nil when unset.
+func getEnvAsTTSPresets(key string) map[string]TTSPreset {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return nil
+	}
+
+	presets := make(map[string]TTSPreset)
+	for _, item := range strings.Split(valueStr, ",") {
+		name, spec, found := strings.Cut(item, "=")
+		if !found {
+			continue
+		}
+		voice, speedStr, found := strings.Cut(spec, ":")
+		if !found {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		voice = strings.TrimSpace(voice)
+		speed, err := strconv.ParseFloat(strings.TrimSpace(speedStr), 64)
+		if name == "" || voice == "" || err != nil || speed <= 0 {
+			continue
+		}
+		presets[name] = TTSPreset{Voice: voice, Speed: speed}
+	}
+
+	return presets
+}
+
+// getEnvAsArgTemplates reads a semicolon-separated list of name=arguments
+// entries, e.g. "summarize=summarize --brief;review=review". Arguments are split
+// on whitespace. Entries without a name or arguments are skipped. Returns nil when unset.
+func getEnvAsArgTemplates(key string) map[string][]string {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return nil
+	}
+
+	templates := make(map[string][]string)
+	for _, item := range strings.Split(valueStr, ";") {
+		name, argStr, found := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		args := strings.Fields(argStr)
+		if !found || name == "" || len(args) == 0 {
+			continue
+		}
+		templates[name] = args
+	}
+
+	return templates
+}
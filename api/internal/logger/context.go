@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// requestIDKey is the context key for the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying requestID for log correlation
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or "" if none
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext returns the global logger, tagged with the request ID carried by
+// ctx so that work done on behalf of a request (e.g. subprocesses) can be
+// correlated with it
+func FromContext(ctx context.Context) *zerolog.Logger {
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		return &Logger
+	}
+	tagged := Logger.With().Str("request_id", requestID).Logger()
+	return &tagged
+}
@@ -30,8 +30,8 @@ func Init(logLevel string) {
 		NoColor:    false, // Enable colors
 	}
 
-	// Create logger with pretty output
-	Logger = zerolog.New(output).
+	// Create logger with pretty output, keeping raw JSON lines for the log tail
+	Logger = zerolog.New(zerolog.MultiLevelWriter(output, Tail)).
 		Level(level).
 		With().
 		Timestamp().
@@ -57,8 +57,8 @@ func InitJSON(logLevel string, output io.Writer) {
 	// Set global log level
 	zerolog.SetGlobalLevel(level)
 
-	// Create logger with JSON output
-	Logger = zerolog.New(output).
+	// Create logger with JSON output, also kept for the log tail
+	Logger = zerolog.New(zerolog.MultiLevelWriter(output, Tail)).
 		Level(level).
 		With().
 		Timestamp().
@@ -73,6 +73,20 @@ func InitJSON(logLevel string, output io.Writer) {
 		Msg("Logger initialized")
 }
 
+// SetLevel changes the log level of the running logger
+func SetLevel(logLevel string) error {
+	level, err := zerolog.ParseLevel(logLevel)
+	if err != nil {
+		return err
+	}
+
+	zerolog.SetGlobalLevel(level)
+	Logger = Logger.Level(level)
+	log.Logger = Logger
+
+	return nil
+}
+
 // Get returns the global logger instance
 func Get() *zerolog.Logger {
 	return &Logger
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"sync"
+)
+
+const (
+	// DefaultTailLines is how many recent log lines the global Tail buffer keeps
+	DefaultTailLines = 500
+	// tailSubscriberBuffer is how many lines a slow subscriber can fall behind before lines are dropped
+	tailSubscriberBuffer = 100
+)
+
+// Tail holds recent log lines from loggers created by Init and InitJSON
+var Tail = NewRingBuffer(DefaultTailLines)
+
+// RingBuffer is an io.Writer that keeps the most recent log lines in memory and
+// fans new lines out to subscribers. Each Write is treated as one line, which
+// matches how zerolog writes events.
+type RingBuffer struct {
+	mu          sync.Mutex
+	lines       [][]byte
+	next        int
+	full        bool
+	subscribers map[chan []byte]struct{}
+}
+
+// NewRingBuffer creates a buffer holding up to size lines
+func NewRingBuffer(size int) *RingBuffer {
+	if size < 1 {
+		size = 1
+	}
+	return &RingBuffer{
+		lines:       make([][]byte, size),
+		subscribers: make(map[chan []byte]struct{}),
+	}
+}
+
+// Write stores a copy of p as the newest line and delivers it to subscribers.
+// Subscribers that are not keeping up miss the line rather than blocking logging.
+func (r *RingBuffer) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+
+	for ch := range r.subscribers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+
+	return len(p), nil
+}
+
+// Lines returns the buffered lines, oldest first
+func (r *RingBuffer) Lines() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.linesLocked()
+}
+
+// Subscribe returns the buffered lines and a channel receiving every line
+// written afterwards, with no gap between the two. Call unsubscribe when done.
+func (r *RingBuffer) Subscribe() (recent [][]byte, lines <-chan []byte, unsubscribe func()) {
+	ch := make(chan []byte, tailSubscriberBuffer)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers[ch] = struct{}{}
+
+	var once sync.Once
+	return r.linesLocked(), ch, func() {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			delete(r.subscribers, ch)
+		})
+	}
+}
+
+// linesLocked copies out the buffered lines in order. Callers must hold mu.
+func (r *RingBuffer) linesLocked() [][]byte {
+	if !r.full {
+		return append([][]byte(nil), r.lines[:r.next]...)
+	}
+	ordered := make([][]byte, 0, len(r.lines))
+	ordered = append(ordered, r.lines[r.next:]...)
+	return append(ordered, r.lines[:r.next]...)
+}
@@ -0,0 +1,35 @@
+package logger
+
+import "testing"
+
+func TestRingBuffer(t *testing.T) {
+	t.Run("keeps only the most recent lines in order", func(t *testing.T) {
+		ring := NewRingBuffer(2)
+		ring.Write([]byte("one\n"))
+		ring.Write([]byte("two\n"))
+		ring.Write([]byte("three\n"))
+
+		lines := ring.Lines()
+		if len(lines) != 2 || string(lines[0]) != "two\n" || string(lines[1]) != "three\n" {
+			t.Errorf("unexpected lines: %q", lines)
+		}
+	})
+
+	t.Run("subscribers stop receiving after unsubscribe", func(t *testing.T) {
+		ring := NewRingBuffer(2)
+		_, lines, unsubscribe := ring.Subscribe()
+
+		ring.Write([]byte("first"))
+		if got := <-lines; string(got) != "first" {
+			t.Errorf("expected first, got %q", got)
+		}
+
+		unsubscribe()
+		ring.Write([]byte("second"))
+		select {
+		case got := <-lines:
+			t.Errorf("expected no delivery after unsubscribe, got %q", got)
+		default:
+		}
+	})
+}
@@ -0,0 +1,100 @@
+// Package metrics exposes Prometheus metrics for requests, sessions, and the
+// external processes the server runs.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// External processes whose invocations are counted
+const (
+	ProcessCursorAgent = "cursor-agent"
+	ProcessWhisper     = "whisper"
+	ProcessKokoroTTS   = "kokoro-tts"
+)
+
+// UnmatchedRoute labels requests that matched no route, keeping arbitrary
+// client paths out of the label values
+const UnmatchedRoute = "unmatched"
+
+// Process counters are shared by every registry, since the processes are run
+// from packages that have no handle on the router's Metrics
+var (
+	processInvocations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "janus_process_invocations_total",
+		Help: "External process invocations by process.",
+	}, []string{"process"})
+	processFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "janus_process_failures_total",
+		Help: "External process invocations that failed, by process.",
+	}, []string{"process"})
+)
+
+// RecordProcess counts one invocation of process, and a failure when err is set
+func RecordProcess(process string, err error) {
+	processInvocations.WithLabelValues(process).Inc()
+	if err != nil {
+		processFailures.WithLabelValues(process).Inc()
+	}
+}
+
+// Metrics holds a router's collectors in their own registry, so several
+// routers (as in tests) never register the same collector twice
+type Metrics struct {
+	registry  *prometheus.Registry
+	requests  *prometheus.CounterVec
+	durations *prometheus.HistogramVec
+}
+
+// New creates the metrics for one router. activeSessions is called on each
+// scrape to report the current session count.
+func New(activeSessions func() int) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "janus_http_requests_total",
+			Help: "HTTP requests by method, route, and status code.",
+		}, []string{"method", "path", "status"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "janus_http_request_duration_seconds",
+			Help: "HTTP request durations by method and route.",
+			// cursor-agent asks routinely take tens of seconds
+			Buckets: []float64{0.005, 0.025, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		}, []string{"method", "path"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.durations,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "janus_active_sessions",
+			Help: "Sessions currently held by the server.",
+		}, func() float64 { return float64(activeSessions()) }),
+		processInvocations,
+		processFailures,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// ObserveRequest records a completed request. path should be the route
+// template rather than the raw URL to keep label cardinality bounded.
+func (m *Metrics) ObserveRequest(method string, path string, status int, duration time.Duration) {
+	if path == "" {
+		path = UnmatchedRoute
+	}
+	m.requests.WithLabelValues(method, path, strconv.Itoa(status)).Inc()
+	m.durations.WithLabelValues(method, path).Observe(duration.Seconds())
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
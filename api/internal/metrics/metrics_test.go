@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrape returns the text exposition of m
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	return w.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	sessions := 3
+	m := New(func() int { return sessions })
+
+	m.ObserveRequest("GET", "/api/health", 200, 20*time.Millisecond)
+	m.ObserveRequest("GET", "/api/health", 200, 30*time.Millisecond)
+	m.ObserveRequest("POST", "", 404, time.Millisecond)
+	RecordProcess(ProcessWhisper, nil)
+	RecordProcess(ProcessWhisper, errors.New("exit status 1"))
+
+	body := scrape(t, m)
+
+	for _, want := range []string{
+		`janus_http_requests_total{method="GET",path="/api/health",status="200"} 2`,
+		`janus_http_requests_total{method="POST",path="unmatched",status="404"} 1`,
+		`janus_http_request_duration_seconds_count{method="GET",path="/api/health"} 2`,
+		`janus_active_sessions 3`,
+		`janus_process_invocations_total{process="whisper"}`,
+		`janus_process_failures_total{process="whisper"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in metrics output", want)
+		}
+	}
+
+	sessions = 5
+	if body := scrape(t, m); !strings.Contains(body, "janus_active_sessions 5") {
+		t.Error("expected the session gauge to be read on each scrape")
+	}
+}
+
+func TestNew_SeparateRegistries(t *testing.T) {
+	// Shared process counters must not make a second registry panic
+	New(func() int { return 0 })
+	New(func() int { return 0 })
+}
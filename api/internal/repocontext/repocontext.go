@@ -0,0 +1,123 @@
+// Package repocontext gathers project context for a workspace: files changed
+// recently in git and conversation summaries kept in the context directory.
+package repocontext
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SummariesDir is the context directory subfolder holding conversation summaries
+const SummariesDir = "conversation-summaries"
+
+// Summary is a stored conversation summary
+type Summary struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// Context is the project context gathered for a workspace
+type Context struct {
+	RecentFiles []string  `json:"recent_files"`
+	Summaries   []Summary `json:"summaries"`
+}
+
+// Options configures what Gather collects
+type Options struct {
+	GitPath      string
+	WorkspaceDir string
+	// ContextDir is resolved against WorkspaceDir when relative
+	ContextDir   string
+	RecentDays   int
+	MaxSummaries int
+}
+
+// Gather collects recently changed files and the newest conversation summaries
+func Gather(ctx context.Context, opts Options) (*Context, error) {
+	files, err := RecentFiles(ctx, opts.GitPath, opts.WorkspaceDir, opts.RecentDays)
+	if err != nil {
+		return nil, err
+	}
+
+	contextDir := opts.ContextDir
+	if !filepath.IsAbs(contextDir) {
+		contextDir = filepath.Join(opts.WorkspaceDir, contextDir)
+	}
+	summaries, err := Summaries(contextDir, opts.MaxSummaries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Context{RecentFiles: files, Summaries: summaries}, nil
+}
+
+// RecentFiles lists files changed in commits from the last days, most recently
+// changed first, without duplicates
+func RecentFiles(ctx context.Context, gitPath string, workspaceDir string, days int) ([]string, error) {
+	output, err := exec.CommandContext(ctx, gitPath,
+		"-C", workspaceDir,
+		"log",
+		fmt.Sprintf("--since=%d.days", days),
+		"--name-only",
+		"--pretty=format:",
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+
+	files := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		file := strings.TrimSpace(line)
+		if file == "" || seen[file] {
+			continue
+		}
+		seen[file] = true
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// Summaries reads up to max of the newest markdown summaries in contextDir's
+// summaries folder. Summary files are named by timestamp (YYYY-MM-DD-HH-MM.md),
+// so newest sorts last. A missing folder yields no summaries.
+func Summaries(contextDir string, max int) ([]Summary, error) {
+	summaries := make([]Summary, 0)
+	if max <= 0 {
+		return summaries, nil
+	}
+
+	dir := filepath.Join(contextDir, SummariesDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return summaries, nil
+		}
+		return nil, fmt.Errorf("failed to read summaries: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) == ".md" {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+
+	for _, name := range names {
+		if len(summaries) == max {
+			break
+		}
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read summary %s: %w", name, err)
+		}
+		summaries = append(summaries, Summary{Name: name, Content: string(content)})
+	}
+	return summaries, nil
+}
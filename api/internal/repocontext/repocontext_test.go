@@ -0,0 +1,108 @@
+package repocontext
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFakeGit writes a git stand-in that records its arguments to argsFile
+// and prints output
+func writeFakeGit(t *testing.T, argsFile string, output string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "git")
+	script := "#!/bin/sh\necho \"$*\" > " + argsFile + "\nprintf '" + output + "'\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake git: %v", err)
+	}
+	return path
+}
+
+// writeSummaries creates summary files in contextDir's summaries folder
+func writeSummaries(t *testing.T, contextDir string, names ...string) {
+	t.Helper()
+	dir := filepath.Join(contextDir, SummariesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create summaries dir: %v", err)
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("summary of "+name), 0644); err != nil {
+			t.Fatalf("failed to write summary: %v", err)
+		}
+	}
+}
+
+func TestRecentFiles(t *testing.T) {
+	t.Run("lists changed files once in recency order", func(t *testing.T) {
+		argsFile := filepath.Join(t.TempDir(), "args")
+		git := writeFakeGit(t, argsFile, `api/main.go\nREADME.md\n\napi/main.go\nweb/app.ts\n`)
+
+		files, err := RecentFiles(context.Background(), git, "/workspace", 3)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(files, ",") != "api/main.go,README.md,web/app.ts" {
+			t.Errorf("unexpected files: %v", files)
+		}
+		args, _ := os.ReadFile(argsFile)
+		if !strings.Contains(string(args), "-C /workspace log --since=3.days") {
+			t.Errorf("unexpected git args: %q", string(args))
+		}
+	})
+
+	t.Run("reports git failures", func(t *testing.T) {
+		git := filepath.Join(t.TempDir(), "git")
+		os.WriteFile(git, []byte("#!/bin/sh\nexit 128\n"), 0755)
+
+		if _, err := RecentFiles(context.Background(), git, "/workspace", 3); err == nil {
+			t.Error("expected an error from a failing git")
+		}
+	})
+}
+
+func TestSummaries(t *testing.T) {
+	t.Run("returns the newest summaries up to max", func(t *testing.T) {
+		contextDir := t.TempDir()
+		writeSummaries(t, contextDir, "2025-01-01-09-00.md", "2025-01-03-09-00.md", "2025-01-02-09-00.md", "notes.txt")
+
+		summaries, err := Summaries(contextDir, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(summaries) != 2 || summaries[0].Name != "2025-01-03-09-00.md" || summaries[1].Name != "2025-01-02-09-00.md" {
+			t.Fatalf("unexpected summaries: %+v", summaries)
+		}
+		if summaries[0].Content != "summary of 2025-01-03-09-00.md" {
+			t.Errorf("unexpected content: %q", summaries[0].Content)
+		}
+	})
+
+	t.Run("missing folder yields no summaries", func(t *testing.T) {
+		summaries, err := Summaries(t.TempDir(), 3)
+		if err != nil || len(summaries) != 0 {
+			t.Errorf("expected no summaries and no error, got %v, %v", summaries, err)
+		}
+	})
+}
+
+func TestGather(t *testing.T) {
+	workspace := t.TempDir()
+	writeSummaries(t, filepath.Join(workspace, ".janus"), "2025-01-01-09-00.md")
+	git := writeFakeGit(t, filepath.Join(t.TempDir(), "args"), `api/main.go\n`)
+
+	gathered, err := Gather(context.Background(), Options{
+		GitPath:      git,
+		WorkspaceDir: workspace,
+		ContextDir:   ".janus",
+		RecentDays:   3,
+		MaxSummaries: 3,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gathered.RecentFiles) != 1 || len(gathered.Summaries) != 1 {
+		t.Errorf("expected a relative context dir to resolve against the workspace, got %+v", gathered)
+	}
+}
@@ -0,0 +1,115 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sean/janus/internal/logger"
+)
+
+const (
+	// DefaultArchivePruneInterval is how often to scan for expired conversation archives
+	DefaultArchivePruneInterval = 1 * time.Hour
+)
+
+// ArchivePruner periodically deletes conversation archive files older than a retention period
+type ArchivePruner struct {
+	dir       string
+	retention time.Duration
+	interval  time.Duration
+	ctx       context.Context
+	cancel    context.CancelFunc
+	stopOnce  sync.Once
+}
+
+// NewArchivePruner creates a pruner for archive files in dir
+func NewArchivePruner(dir string, retention time.Duration, interval time.Duration) *ArchivePruner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ArchivePruner{
+		dir:       dir,
+		retention: retention,
+		interval:  interval,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start prunes once immediately, then begins the pruning goroutine
+func (p *ArchivePruner) Start() {
+	logger.Get().Info().
+		Str("dir", p.dir).
+		Dur("interval", p.interval).
+		Dur("retention", p.retention).
+		Msg("Starting archive pruner")
+	p.Prune()
+	go p.run()
+}
+
+// Stop gracefully stops the pruning goroutine
+func (p *ArchivePruner) Stop() {
+	logger.Get().Info().Msg("Stopping archive pruner")
+	p.stopOnce.Do(func() {
+		p.cancel()
+	})
+}
+
+// run is the main pruning loop
+func (p *ArchivePruner) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			logger.Get().Info().Msg("Archive pruner stopped")
+			return
+		case <-ticker.C:
+			p.Prune()
+		}
+	}
+}
+
+// Prune removes regular files in the archive directory last modified before the
+// retention cutoff and returns how many were deleted. A missing directory is not an error.
+func (p *ArchivePruner) Prune() int {
+	log := logger.Get()
+
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn().Err(err).Str("dir", p.dir).Msg("Failed to read archive directory")
+		}
+		return 0
+	}
+
+	cutoff := time.Now().Add(-p.retention)
+	removed := 0
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+
+		path := filepath.Join(p.dir, entry.Name())
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Warn().Err(err).Str("file", path).Msg("Failed to remove expired archive")
+			continue
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		log.Info().
+			Int("removed", removed).
+			Str("dir", p.dir).
+			Msg("Pruned expired conversation archives")
+	}
+
+	return removed
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestArchivePruner_Prune(t *testing.T) {
+	t.Run("removes archives older than retention and keeps recent ones", func(t *testing.T) {
+		dir := t.TempDir()
+		oldPath := filepath.Join(dir, "old.json")
+		recentPath := filepath.Join(dir, "recent.json")
+		for _, path := range []string{oldPath, recentPath} {
+			if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+				t.Fatalf("failed to write archive: %v", err)
+			}
+		}
+		old := time.Now().Add(-10 * 24 * time.Hour)
+		if err := os.Chtimes(oldPath, old, old); err != nil {
+			t.Fatalf("failed to age archive: %v", err)
+		}
+
+		pruner := NewArchivePruner(dir, 7*24*time.Hour, time.Hour)
+		removed := pruner.Prune()
+
+		if removed != 1 {
+			t.Errorf("expected 1 archive removed, got %d", removed)
+		}
+		if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+			t.Errorf("expected old archive to be removed, stat err: %v", err)
+		}
+		if _, err := os.Stat(recentPath); err != nil {
+			t.Errorf("expected recent archive to remain: %v", err)
+		}
+	})
+
+	t.Run("ignores a missing directory", func(t *testing.T) {
+		pruner := NewArchivePruner(filepath.Join(t.TempDir(), "missing"), time.Hour, time.Hour)
+
+		if removed := pruner.Prune(); removed != 0 {
+			t.Errorf("expected nothing removed, got %d", removed)
+		}
+	})
+}
+
+func TestArchivePruner_StartStop(t *testing.T) {
+	pruner := NewArchivePruner(t.TempDir(), time.Hour, 100*time.Millisecond)
+
+	pruner.Start()
+	pruner.Stop()
+	pruner.Stop() // Safe to call twice
+
+	select {
+	case <-pruner.ctx.Done():
+	default:
+		t.Error("context was not cancelled after Stop")
+	}
+}
@@ -0,0 +1,136 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sean/janus/internal/logger"
+)
+
+const (
+	// ArchivePolicyDrop discards an archive job when the queue is full
+	ArchivePolicyDrop = "drop"
+	// ArchivePolicyBlock makes the caller wait for queue space, bounded by its context
+	ArchivePolicyBlock = "block"
+)
+
+// ErrArchiveQueueFull is returned when a job is dropped because the queue is full
+var ErrArchiveQueueFull = errors.New("archive queue is full")
+
+// ErrArchiveQueueStopped is returned for jobs submitted after Stop
+var ErrArchiveQueueStopped = errors.New("archive queue is stopped")
+
+// ArchiveQueue is an Archiver that hands sessions to a bounded pool of workers
+// running the wrapped archiver, so callers return without waiting on storage
+type ArchiveQueue struct {
+	archiver Archiver
+	jobs     chan *Session
+	workers  int
+	policy   string
+	dropped  atomic.Int64
+	// mu guards closed; submitters hold the read lock while sending so Stop can't close jobs under them
+	mu       sync.RWMutex
+	closed   bool
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewArchiveQueue creates a queue of size jobs served by workers goroutines.
+// policy decides what happens when the queue is full (ArchivePolicyDrop or ArchivePolicyBlock).
+func NewArchiveQueue(archiver Archiver, workers int, size int, policy string) *ArchiveQueue {
+	return &ArchiveQueue{
+		archiver: archiver,
+		jobs:     make(chan *Session, size),
+		workers:  workers,
+		policy:   policy,
+	}
+}
+
+// Start launches the worker goroutines
+func (q *ArchiveQueue) Start() {
+	logger.Get().Info().
+		Int("workers", q.workers).
+		Int("queue_size", cap(q.jobs)).
+		Str("policy", q.policy).
+		Msg("Starting archive workers")
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.run()
+	}
+}
+
+// Stop stops accepting jobs and waits for queued ones to finish
+func (q *ArchiveQueue) Stop() {
+	logger.Get().Info().Msg("Stopping archive workers")
+	q.stopOnce.Do(func() {
+		q.mu.Lock()
+		q.closed = true
+		close(q.jobs)
+		q.mu.Unlock()
+	})
+	q.wg.Wait()
+}
+
+// Dropped reports how many jobs were discarded because the queue was full
+func (q *ArchiveQueue) Dropped() int64 {
+	return q.dropped.Load()
+}
+
+// Archive queues the session for archiving. Under ArchivePolicyDrop a full queue
+// discards the job and returns ErrArchiveQueueFull; under ArchivePolicyBlock it
+// waits for space until ctx is done.
+func (q *ArchiveQueue) Archive(ctx context.Context, sess *Session) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrArchiveQueueStopped
+	}
+
+	if q.policy == ArchivePolicyBlock {
+		select {
+		case q.jobs <- sess:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	select {
+	case q.jobs <- sess:
+		return nil
+	default:
+		dropped := q.dropped.Add(1)
+		logger.Get().Warn().
+			Str("session_id", sess.ID).
+			Int("queue_size", cap(q.jobs)).
+			Int64("dropped_total", dropped).
+			Msg("Archive queue full, dropping archive job")
+		return ErrArchiveQueueFull
+	}
+}
+
+// run archives queued sessions until the queue is closed and drained
+func (q *ArchiveQueue) run() {
+	defer q.wg.Done()
+
+	for sess := range q.jobs {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultArchiveTimeout)
+		err := q.archiver.Archive(ctx, sess)
+		cancel()
+
+		if err != nil {
+			logger.Get().Error().
+				Str("session_id", sess.ID).
+				Err(err).
+				Msg("Failed to archive session")
+			continue
+		}
+		logger.Get().Info().
+			Str("session_id", sess.ID).
+			Int("messages", len(sess.ConversationLog)).
+			Msg("Archived session")
+	}
+}
@@ -0,0 +1,134 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedArchiver records archived session IDs, holding each job until the gate is opened
+type gatedArchiver struct {
+	mu       sync.Mutex
+	archived []string
+	started  chan struct{}
+	gate     chan struct{}
+}
+
+func newGatedArchiver() *gatedArchiver {
+	return &gatedArchiver{started: make(chan struct{}, 100), gate: make(chan struct{})}
+}
+
+func (a *gatedArchiver) Archive(ctx context.Context, sess *Session) error {
+	a.started <- struct{}{}
+	<-a.gate
+	a.mu.Lock()
+	a.archived = append(a.archived, sess.ID)
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *gatedArchiver) count() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.archived)
+}
+
+// fillQueue occupies the single worker with one job and the size-1 queue with another
+func fillQueue(t *testing.T, q *ArchiveQueue, archiver *gatedArchiver) {
+	t.Helper()
+	if err := q.Archive(context.Background(), &Session{ID: "running"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case <-archiver.started:
+	case <-time.After(time.Second):
+		t.Fatal("worker never picked up the first job")
+	}
+	if err := q.Archive(context.Background(), &Session{ID: "queued"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestArchiveQueue_ProcessesJobs(t *testing.T) {
+	archiver := newGatedArchiver()
+	close(archiver.gate)
+	q := NewArchiveQueue(archiver, 2, 10, ArchivePolicyDrop)
+	q.Start()
+
+	for i := 0; i < 5; i++ {
+		if err := q.Archive(context.Background(), &Session{ID: fmt.Sprintf("session-%d", i)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	q.Stop()
+
+	if archiver.count() != 5 {
+		t.Errorf("expected 5 archived sessions after Stop drains the queue, got %d", archiver.count())
+	}
+	if err := q.Archive(context.Background(), &Session{ID: "late"}); !errors.Is(err, ErrArchiveQueueStopped) {
+		t.Errorf("expected ErrArchiveQueueStopped after Stop, got %v", err)
+	}
+}
+
+func TestArchiveQueue_FullQueue(t *testing.T) {
+	t.Run("drop policy discards the job and returns immediately", func(t *testing.T) {
+		archiver := newGatedArchiver()
+		q := NewArchiveQueue(archiver, 1, 1, ArchivePolicyDrop)
+		q.Start()
+		fillQueue(t, q, archiver)
+
+		err := q.Archive(context.Background(), &Session{ID: "dropped"})
+
+		if !errors.Is(err, ErrArchiveQueueFull) {
+			t.Errorf("expected ErrArchiveQueueFull, got %v", err)
+		}
+		if q.Dropped() != 1 {
+			t.Errorf("expected 1 dropped job, got %d", q.Dropped())
+		}
+		close(archiver.gate)
+		q.Stop()
+		if archiver.count() != 2 {
+			t.Errorf("expected only the running and queued jobs archived, got %d", archiver.count())
+		}
+	})
+
+	t.Run("block policy waits for space until the context is done", func(t *testing.T) {
+		archiver := newGatedArchiver()
+		q := NewArchiveQueue(archiver, 1, 1, ArchivePolicyBlock)
+		q.Start()
+		fillQueue(t, q, archiver)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := q.Archive(ctx, &Session{ID: "waiting"}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the wait to end with the context, got %v", err)
+		}
+
+		done := make(chan error, 1)
+		go func() { done <- q.Archive(context.Background(), &Session{ID: "admitted"}) }()
+		close(archiver.gate)
+		if err := <-done; err != nil {
+			t.Errorf("expected the job to be admitted once space frees up, got %v", err)
+		}
+		q.Stop()
+		if archiver.count() != 3 || q.Dropped() != 0 {
+			t.Errorf("expected 3 archived and none dropped, got %d archived, %d dropped", archiver.count(), q.Dropped())
+		}
+	})
+}
+
+func TestCleanupInactiveSessions_Archives(t *testing.T) {
+	archiver := &recordingArchiver{}
+	manager := NewMemorySessionManager(WithArchiver(archiver))
+	sess, _ := manager.CreateSession()
+
+	time.Sleep(10 * time.Millisecond)
+	manager.CleanupInactiveSessions(5 * time.Millisecond)
+
+	if len(archiver.archived) != 1 || archiver.archived[0].ID != sess.ID {
+		t.Errorf("expected the reaped session to be archived, got %+v", archiver.archived)
+	}
+}
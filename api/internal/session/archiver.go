@@ -0,0 +1,189 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Archiver stores the transcript of an ended session
+type Archiver interface {
+	Archive(ctx context.Context, sess *Session) error
+}
+
+// ArchiveRecord is the JSON document written for an archived session
+type ArchiveRecord struct {
+	SessionID       string    `json:"session_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastActivity    time.Time `json:"last_activity"`
+	Branch          string    `json:"branch,omitempty"`
+	ConversationLog []Message `json:"conversation_log"`
+}
+
+// ArchiveKey is the file name or object key a session is archived under
+func ArchiveKey(sess *Session) string {
+	return sess.ID + ".json"
+}
+
+// marshalArchive encodes a session as an ArchiveRecord
+func marshalArchive(sess *Session) ([]byte, error) {
+	return json.MarshalIndent(ArchiveRecord{
+		SessionID:       sess.ID,
+		CreatedAt:       sess.CreatedAt,
+		LastActivity:    sess.LastActivity,
+		Branch:          sess.Branch,
+		ConversationLog: sess.ConversationLog,
+	}, "", "  ")
+}
+
+// LocalArchiver writes session transcripts as files in a directory
+type LocalArchiver struct {
+	dir string
+}
+
+// NewLocalArchiver creates an archiver writing to dir, which is created on demand
+func NewLocalArchiver(dir string) *LocalArchiver {
+	return &LocalArchiver{dir: dir}
+}
+
+// Archive writes the session's transcript to <dir>/<ArchiveKey>
+func (a *LocalArchiver) Archive(ctx context.Context, sess *Session) error {
+	data, err := marshalArchive(sess)
+	if err != nil {
+		return fmt.Errorf("failed to encode archive: %w", err)
+	}
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(a.dir, ArchiveKey(sess)), data, 0644); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// S3Config locates an S3-compatible bucket and the credentials to write to it
+type S3Config struct {
+	// Endpoint is the service base URL, e.g. "https://s3.us-east-1.amazonaws.com" or a MinIO URL
+	Endpoint        string
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3Archiver uploads session transcripts to S3-compatible object storage using
+// path-style PUT requests signed with AWS Signature Version 4
+type S3Archiver struct {
+	cfg    S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Archiver creates an archiver uploading to the configured bucket
+func NewS3Archiver(cfg S3Config) *S3Archiver {
+	return &S3Archiver{
+		cfg:    cfg,
+		client: &http.Client{Timeout: DefaultArchiveTimeout},
+		now:    time.Now,
+	}
+}
+
+// Archive uploads the session's transcript as <bucket>/<ArchiveKey>
+func (a *S3Archiver) Archive(ctx context.Context, sess *Session) error {
+	data, err := marshalArchive(sess)
+	if err != nil {
+		return fmt.Errorf("failed to encode archive: %w", err)
+	}
+
+	objectURL := strings.TrimRight(a.cfg.Endpoint, "/") + "/" + url.PathEscape(a.cfg.Bucket) + "/" + url.PathEscape(ArchiveKey(sess))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to build upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	a.sign(req, data)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload archive: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("archive upload returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// sign adds AWS Signature Version 4 headers for an S3 request carrying payload
+func (a *S3Archiver) sign(req *http.Request, payload []byte) {
+	now := a.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + a.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+a.cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, a.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+a.cfg.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// MultiArchiver archives to every wrapped archiver, returning their combined errors
+type MultiArchiver []Archiver
+
+// Archive runs each archiver in order, continuing past failures
+func (m MultiArchiver) Archive(ctx context.Context, sess *Session) error {
+	var errs []error
+	for _, archiver := range m {
+		if err := archiver.Archive(ctx, sess); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,157 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockObjectStore records PUT requests made against an S3-compatible endpoint
+type mockObjectStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	auth    string
+}
+
+func (s *mockObjectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.objects[r.URL.Path] = body
+	s.auth = r.Header.Get("Authorization")
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// recordingArchiver remembers the sessions it was asked to archive
+type recordingArchiver struct {
+	archived []*Session
+	err      error
+}
+
+func (a *recordingArchiver) Archive(ctx context.Context, sess *Session) error {
+	a.archived = append(a.archived, sess)
+	return a.err
+}
+
+func newArchivableSession() *Session {
+	return &Session{
+		ID:              "session-1",
+		CreatedAt:       time.Now(),
+		LastActivity:    time.Now(),
+		ConversationLog: []Message{{Role: "user", Content: "Where is the router?", Timestamp: time.Now()}},
+	}
+}
+
+func TestS3Archiver(t *testing.T) {
+	t.Run("uploads the transcript under the bucket and session key", func(t *testing.T) {
+		store := &mockObjectStore{objects: make(map[string][]byte)}
+		server := httptest.NewServer(store)
+		defer server.Close()
+
+		archiver := NewS3Archiver(S3Config{
+			Endpoint:        server.URL,
+			Bucket:          "janus-archive",
+			Region:          "us-east-1",
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "secret",
+		})
+		if err := archiver.Archive(context.Background(), newArchivableSession()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		body, ok := store.objects["/janus-archive/session-1.json"]
+		if !ok {
+			t.Fatalf("expected object at /janus-archive/session-1.json, got %v", store.objects)
+		}
+		var record ArchiveRecord
+		if err := json.Unmarshal(body, &record); err != nil {
+			t.Fatalf("failed to parse archived object: %v", err)
+		}
+		if record.SessionID != "session-1" || len(record.ConversationLog) != 1 {
+			t.Errorf("unexpected archive record: %+v", record)
+		}
+		if !strings.HasPrefix(store.auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+			t.Errorf("expected a SigV4 authorization header, got %q", store.auth)
+		}
+	})
+
+	t.Run("reports non-2xx responses", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		archiver := NewS3Archiver(S3Config{Endpoint: server.URL, Bucket: "janus-archive", Region: "us-east-1"})
+		if err := archiver.Archive(context.Background(), newArchivableSession()); err == nil {
+			t.Error("expected an error for a 403 response")
+		}
+	})
+}
+
+func TestLocalArchiver(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archive")
+
+	if err := NewLocalArchiver(dir).Archive(context.Background(), newArchivableSession()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "session-1.json")); err != nil {
+		t.Errorf("expected archive file: %v", err)
+	}
+}
+
+func TestMultiArchiver(t *testing.T) {
+	failing := &recordingArchiver{err: errors.New("upload failed")}
+	ok := &recordingArchiver{}
+
+	err := MultiArchiver{failing, ok}.Archive(context.Background(), newArchivableSession())
+
+	if err == nil {
+		t.Error("expected the failing archiver's error")
+	}
+	if len(ok.archived) != 1 {
+		t.Error("expected later archivers to run after a failure")
+	}
+}
+
+func TestEndSession_Archives(t *testing.T) {
+	archiver := &recordingArchiver{}
+	manager := NewMemorySessionManager(WithArchiver(archiver))
+	sess, _ := manager.CreateSession()
+	manager.AddToConversationLog(sess.ID, []Message{{Role: "user", Content: "hello", Timestamp: time.Now()}})
+
+	if err := manager.EndSession(sess.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(archiver.archived) != 1 || archiver.archived[0].ID != sess.ID || len(archiver.archived[0].ConversationLog) != 1 {
+		t.Errorf("expected the ended session to be archived, got %+v", archiver.archived)
+	}
+}
+
+func TestEndSession_SkipsEphemeralArchive(t *testing.T) {
+	archiver := &recordingArchiver{}
+	manager := NewMemorySessionManager(WithArchiver(archiver))
+	sess, _ := manager.CreateSessionWithOptions(SessionOptions{Ephemeral: true})
+
+	if err := manager.EndSession(sess.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(archiver.archived) != 0 {
+		t.Errorf("expected ephemeral session not to be archived, got %+v", archiver.archived)
+	}
+}
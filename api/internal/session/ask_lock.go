@@ -0,0 +1,100 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrSessionBusy is returned when a question is asked while the session is
+// still answering another and concurrent asks are rejected rather than queued
+var ErrSessionBusy = errors.New("session is already answering a question")
+
+// askLock admits one ask at a time for a session. refs counts the asks holding
+// or waiting for it so it can be dropped once unused.
+type askLock struct {
+	sem  chan struct{}
+	refs int
+}
+
+// askLocks serializes asks per session, since overlapping cursor-agent runs
+// resuming the same chat interleave and corrupt its thread. Asks for different
+// sessions never wait on each other.
+type askLocks struct {
+	mu    sync.Mutex
+	locks map[string]*askLock
+}
+
+// acquire waits until session id has no other ask running, or fails with
+// ErrSessionBusy immediately when wait is false. Waiting stops with the
+// context's error if ctx ends first. The returned function releases the lock.
+func (l *askLocks) acquire(ctx context.Context, id string, wait bool) (func(), error) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*askLock)
+	}
+	lock := l.locks[id]
+	if lock == nil {
+		lock = &askLock{sem: make(chan struct{}, 1)}
+		l.locks[id] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	if wait {
+		select {
+		case lock.sem <- struct{}{}:
+		case <-ctx.Done():
+			l.deref(id, lock)
+			return nil, ctx.Err()
+		}
+	} else {
+		select {
+		case lock.sem <- struct{}{}:
+		default:
+			l.deref(id, lock)
+			return nil, ErrSessionBusy
+		}
+	}
+
+	return func() {
+		<-lock.sem
+		l.deref(id, lock)
+	}, nil
+}
+
+// deref drops one reference to lock, removing it once nothing holds or awaits it
+func (l *askLocks) deref(id string, lock *askLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, id)
+	}
+}
+
+// admitAsk waits for an ask on session id to be allowed to run: behind the
+// session's previous ask when asks are serialized, then for one of the global
+// cursor-agent slots when they are limited. The returned function releases both.
+func (m *MemorySessionManager) admitAsk(ctx context.Context, id string) (func(), error) {
+	unlock := func() {}
+	if m.serializeAsks {
+		var err error
+		if unlock, err = m.askLocks.acquire(ctx, id, !m.rejectConcurrentAsks); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.askSlots == nil {
+		return unlock, nil
+	}
+	if err := m.askSlots.acquire(ctx); err != nil {
+		unlock()
+		return nil, err
+	}
+	return func() {
+		m.askSlots.release()
+		unlock()
+	}, nil
+}
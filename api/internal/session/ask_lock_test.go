@@ -0,0 +1,127 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeOverlapCursorAgent records "overlap" in $dir/overlaps whenever it starts
+// while another run is still going, holding $dir/running for a moment
+func fakeOverlapCursorAgent(dir string) string {
+	return `if ! mkdir ` + dir + `/running 2>/dev/null; then echo overlap >> ` + dir + `/overlaps; fi
+sleep 0.2
+rmdir ` + dir + `/running 2>/dev/null
+echo '{"result":"answer","session_id":"chat-1"}'
+`
+}
+
+func TestSerializedAsks(t *testing.T) {
+	t.Run("concurrent asks on a session never overlap", func(t *testing.T) {
+		dir := t.TempDir()
+		fake := writeFakeCursorAgent(t, fakeOverlapCursorAgent(dir))
+		manager := NewMemorySessionManager(WithCursorAgentPath(fake), WithSerializedAsks(true, false))
+		session, _ := manager.CreateSession()
+
+		errs := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				_, _, _, err := manager.AskQuestion(context.Background(), session.ID, "hi", t.TempDir())
+				errs <- err
+			}()
+		}
+		for i := 0; i < 2; i++ {
+			if err := <-errs; err != nil {
+				t.Errorf("expected both asks to succeed, got %v", err)
+			}
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "overlaps")); err == nil {
+			t.Error("expected cursor-agent runs for one session not to overlap")
+		}
+	})
+
+	t.Run("a queued ask resumes the chat the first one started", func(t *testing.T) {
+		fake := writeFakeCursorAgent(t, fakeOverlapCursorAgent(t.TempDir()))
+		manager := NewMemorySessionManager(WithCursorAgentPath(fake), WithSerializedAsks(true, false))
+		session, _ := manager.CreateSession()
+
+		resumed := make(chan bool, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				_, _, r, err := manager.AskQuestion(context.Background(), session.ID, "hi", t.TempDir())
+				if err != nil {
+					t.Errorf("expected the ask to succeed, got %v", err)
+				}
+				resumed <- r
+			}()
+		}
+		first, second := <-resumed, <-resumed
+		if first == second {
+			t.Errorf("expected exactly one ask to start the chat and the other to resume it, got %v and %v", first, second)
+		}
+	})
+
+	t.Run("rejects an overlapping ask when configured", func(t *testing.T) {
+		fake := writeFakeCursorAgent(t, fakeSlowCursorAgent)
+		manager := NewMemorySessionManager(WithCursorAgentPath(fake), WithSerializedAsks(true, true)).(*MemorySessionManager)
+		session, _ := manager.CreateSession()
+
+		done := make(chan struct{})
+		go func() {
+			manager.AskQuestion(context.Background(), session.ID, "slow", t.TempDir())
+			close(done)
+		}()
+		waitForActiveAsk(t, manager, session.ID)
+		// The first ask is tracked just before it takes the lock
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			_, _, _, err := manager.AskQuestion(context.Background(), session.ID, "next", t.TempDir())
+			if errors.Is(err, ErrSessionBusy) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected ErrSessionBusy, got %v", err)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		manager.CancelQuestion(session.ID)
+		<-done
+	})
+
+	t.Run("different sessions do not wait on each other", func(t *testing.T) {
+		var locks askLocks
+
+		releaseA, err := locks.acquire(context.Background(), "a", false)
+		if err != nil {
+			t.Fatalf("failed to lock session a: %v", err)
+		}
+		releaseB, err := locks.acquire(context.Background(), "b", false)
+		if err != nil {
+			t.Errorf("expected session b to be free while a is locked, got %v", err)
+		} else {
+			releaseB()
+		}
+		releaseA()
+
+		if len(locks.locks) != 0 {
+			t.Errorf("expected released locks to be dropped, got %d", len(locks.locks))
+		}
+	})
+
+	t.Run("waiting stops when the context ends", func(t *testing.T) {
+		var locks askLocks
+		release, _ := locks.acquire(context.Background(), "a", true)
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := locks.acquire(ctx, "a", true); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the wait to time out, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package session
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// fifoSemaphore bounds how many asks run cursor-agent at once. Unlike a plain
+// buffered channel, waiters are admitted strictly in arrival order, so under
+// sustained load an early request is never starved by later ones.
+type fifoSemaphore struct {
+	mu      sync.Mutex
+	limit   int
+	active  int
+	waiters *list.List // of chan struct{}, closed when the waiter is granted a slot
+}
+
+// newFIFOSemaphore creates a semaphore admitting limit holders at once
+func newFIFOSemaphore(limit int) *fifoSemaphore {
+	return &fifoSemaphore{limit: limit, waiters: list.New()}
+}
+
+// acquire takes a slot, queueing behind earlier waiters until one is free or
+// ctx ends, in which case the context's error is returned
+func (s *fifoSemaphore) acquire(ctx context.Context) error {
+	s.mu.Lock()
+	if s.active < s.limit && s.waiters.Len() == 0 {
+		s.active++
+		s.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// Granted while giving up: pass the slot on rather than leak it
+			s.mu.Unlock()
+			s.release()
+		default:
+			s.waiters.Remove(elem)
+			s.mu.Unlock()
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees a slot, handing it straight to the longest waiter if any
+func (s *fifoSemaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if front := s.waiters.Front(); front != nil {
+		s.waiters.Remove(front)
+		close(front.Value.(chan struct{}))
+		return
+	}
+	s.active--
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForWaiters blocks until s has n queued waiters
+func waitForWaiters(t *testing.T, s *fifoSemaphore, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mu.Lock()
+		queued := s.waiters.Len()
+		s.mu.Unlock()
+		if queued == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiters, got %d", n, queued)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFIFOSemaphore(t *testing.T) {
+	t.Run("admits waiters in arrival order", func(t *testing.T) {
+		s := newFIFOSemaphore(1)
+		if err := s.acquire(context.Background()); err != nil {
+			t.Fatalf("failed to take the only slot: %v", err)
+		}
+
+		var mu sync.Mutex
+		var order []int
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				s.acquire(context.Background())
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				s.release()
+			}(i)
+			// Each request arrives only after the previous one has queued
+			waitForWaiters(t, s, i+1)
+		}
+
+		s.release()
+		wg.Wait()
+
+		for i, got := range order {
+			if got != i {
+				t.Fatalf("expected slots in arrival order, got %v", order)
+			}
+		}
+	})
+
+	t.Run("a new request queues behind existing waiters", func(t *testing.T) {
+		s := newFIFOSemaphore(1)
+		s.acquire(context.Background())
+
+		go s.acquire(context.Background())
+		waitForWaiters(t, s, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := s.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the late request to wait its turn, got %v", err)
+		}
+	})
+
+	t.Run("a waiter that gives up leaves the queue", func(t *testing.T) {
+		s := newFIFOSemaphore(1)
+		s.acquire(context.Background())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error, 1)
+		go func() { errs <- s.acquire(ctx) }()
+		waitForWaiters(t, s, 1)
+		cancel()
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+
+		s.release()
+		if s.active != 0 || s.waiters.Len() != 0 {
+			t.Errorf("expected an idle semaphore, got %d active and %d waiting", s.active, s.waiters.Len())
+		}
+	})
+}
+
+func TestMaxConcurrentAsks(t *testing.T) {
+	dir := t.TempDir()
+	fake := writeFakeCursorAgent(t, fakeOverlapCursorAgent(dir))
+	manager := NewMemorySessionManager(WithCursorAgentPath(fake), WithMaxConcurrentAsks(1))
+
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		session, _ := manager.CreateSession()
+		go func() {
+			_, _, _, err := manager.AskQuestion(context.Background(), session.ID, "hi", t.TempDir())
+			errs <- err
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("expected every ask to succeed, got %v", err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "overlaps")); err == nil {
+		t.Error("expected at most one cursor-agent run at a time")
+	}
+}
@@ -0,0 +1,57 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrCursorOutputTooLarge is returned when cursor-agent writes more output than allowed
+var ErrCursorOutputTooLarge = errors.New("cursor-agent output exceeded maximum size")
+
+// boundedBuffer is an io.Writer that keeps at most limit bytes. Writes past the
+// limit are discarded rather than failed so the child process can still exit
+// normally instead of blocking on a full pipe; callers check Exceeded afterwards.
+// A limit of 0 or less means unbounded.
+type boundedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+// newBoundedBuffer creates a buffer that retains up to limit bytes
+func newBoundedBuffer(limit int) *boundedBuffer {
+	return &boundedBuffer{limit: limit}
+}
+
+// Write appends p until the limit is reached, then discards the remainder
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	if b.limit <= 0 {
+		return b.buf.Write(p)
+	}
+
+	remaining := b.limit - b.buf.Len()
+	if len(p) > remaining {
+		b.exceeded = true
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+
+	return b.buf.Write(p)
+}
+
+// Exceeded reports whether any output was discarded
+func (b *boundedBuffer) Exceeded() bool {
+	return b.exceeded
+}
+
+// Bytes returns the retained output
+func (b *boundedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// String returns the retained output as a string
+func (b *boundedBuffer) String() string {
+	return b.buf.String()
+}
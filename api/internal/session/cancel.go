@@ -0,0 +1,92 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrQuestionCancelled is returned by an ask stopped through CancelQuestion
+var ErrQuestionCancelled = errors.New("question cancelled")
+
+// CancelManager is implemented by managers that can stop a session's in-flight
+// questions without ending the session
+type CancelManager interface {
+	CancelQuestion(id string) (cancelled bool, err error)
+}
+
+// activeAsk is one in-flight question that CancelQuestion can stop
+type activeAsk struct {
+	cancel context.CancelCauseFunc
+}
+
+// activeAsks tracks each session's in-flight questions behind its own lock,
+// so cancelling never waits on a shard
+type activeAsks struct {
+	mu   sync.Mutex
+	asks map[string]map[*activeAsk]struct{}
+}
+
+// track registers an ask for session id, returning the context the ask must
+// run under and a function that unregisters it once the ask returns
+func (a *activeAsks) track(ctx context.Context, id string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	ask := &activeAsk{cancel: cancel}
+
+	a.mu.Lock()
+	if a.asks == nil {
+		a.asks = make(map[string]map[*activeAsk]struct{})
+	}
+	if a.asks[id] == nil {
+		a.asks[id] = make(map[*activeAsk]struct{})
+	}
+	a.asks[id][ask] = struct{}{}
+	a.mu.Unlock()
+
+	return ctx, func() {
+		a.mu.Lock()
+		delete(a.asks[id], ask)
+		if len(a.asks[id]) == 0 {
+			delete(a.asks, id)
+		}
+		a.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// cancel stops every in-flight ask for session id, reporting how many there were
+func (a *activeAsks) cancel(id string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for ask := range a.asks[id] {
+		ask.cancel(ErrQuestionCancelled)
+	}
+	return len(a.asks[id])
+}
+
+// cancelledError replaces err with ErrQuestionCancelled when ctx was stopped
+// by CancelQuestion, so callers can tell a user cancel from a timeout
+func cancelledError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(context.Cause(ctx), ErrQuestionCancelled) {
+		return fmt.Errorf("%w: %v", ErrQuestionCancelled, err)
+	}
+	return err
+}
+
+// CancelQuestion stops the session's in-flight questions, killing their
+// cursor-agent processes. The session itself is untouched, so the next
+// question works as usual. cancelled reports whether anything was running;
+// cancelling an idle session is a no-op.
+func (m *MemorySessionManager) CancelQuestion(id string) (bool, error) {
+	shard := m.shardFor(id)
+	acquired := m.rlock(shard)
+	_, exists := shard.sessions[id]
+	m.runlock(shard, acquired)
+
+	if !exists {
+		return false, fmt.Errorf("session not found: %s", id)
+	}
+	return m.activeAsks.cancel(id) > 0, nil
+}
@@ -0,0 +1,127 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSlowCursorAgent hangs on the question "slow" and answers anything else
+const fakeSlowCursorAgent = `for last; do :; done
+if [ "$last" = "slow" ]; then exec sleep 10; fi
+echo '{"result":"answer","session_id":"chat-1"}'
+`
+
+// waitForActiveAsk blocks until session id has an in-flight ask
+func waitForActiveAsk(t *testing.T, manager *MemorySessionManager, id string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		manager.activeAsks.mu.Lock()
+		running := len(manager.activeAsks.asks[id])
+		manager.activeAsks.mu.Unlock()
+		if running > 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected an ask to start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCancelQuestion(t *testing.T) {
+	t.Run("stops the running ask and keeps the session usable", func(t *testing.T) {
+		fake := writeFakeCursorAgent(t, fakeSlowCursorAgent)
+		manager := NewMemorySessionManager(WithCursorAgentPath(fake)).(*MemorySessionManager)
+		session, _ := manager.CreateSession()
+
+		errs := make(chan error, 1)
+		go func() {
+			_, _, _, err := manager.AskQuestion(context.Background(), session.ID, "slow", t.TempDir())
+			errs <- err
+		}()
+		waitForActiveAsk(t, manager, session.ID)
+
+		cancelled, err := manager.CancelQuestion(session.ID)
+		if err != nil || !cancelled {
+			t.Fatalf("expected the ask to be cancelled, got %v (err %v)", cancelled, err)
+		}
+
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrQuestionCancelled) {
+				t.Errorf("expected ErrQuestionCancelled, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected the ask to return promptly after cancel")
+		}
+
+		answer, _, _, err := manager.AskQuestion(context.Background(), session.ID, "next", t.TempDir())
+		if err != nil || answer != "answer" {
+			t.Errorf("expected the next question to work, got %q (err %v)", answer, err)
+		}
+	})
+
+	t.Run("concurrent cancels are safe", func(t *testing.T) {
+		fake := writeFakeCursorAgent(t, fakeSlowCursorAgent)
+		manager := NewMemorySessionManager(WithCursorAgentPath(fake)).(*MemorySessionManager)
+		session, _ := manager.CreateSession()
+
+		done := make(chan struct{})
+		go func() {
+			manager.AskQuestion(context.Background(), session.ID, "slow", t.TempDir())
+			close(done)
+		}()
+		waitForActiveAsk(t, manager, session.ID)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				manager.CancelQuestion(session.ID)
+			}()
+		}
+		wg.Wait()
+
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected the ask to return after cancel")
+		}
+	})
+
+	t.Run("nothing running is a no-op", func(t *testing.T) {
+		manager := NewMemorySessionManager().(*MemorySessionManager)
+		session, _ := manager.CreateSession()
+
+		cancelled, err := manager.CancelQuestion(session.ID)
+		if err != nil || cancelled {
+			t.Errorf("expected no-op, got %v (err %v)", cancelled, err)
+		}
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		manager := NewMemorySessionManager().(*MemorySessionManager)
+
+		if _, err := manager.CancelQuestion("missing"); err == nil {
+			t.Error("expected an error for an unknown session")
+		}
+	})
+
+	t.Run("a timeout is not reported as a cancel", func(t *testing.T) {
+		fake := writeFakeCursorAgent(t, fakeSlowCursorAgent)
+		manager := NewMemorySessionManager(WithCursorAgentPath(fake))
+		session, _ := manager.CreateSession()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, _, _, err := manager.AskQuestion(ctx, session.ID, "slow", t.TempDir())
+		if err == nil || errors.Is(err, ErrQuestionCancelled) {
+			t.Errorf("expected a plain timeout error, got %v", err)
+		}
+	})
+}
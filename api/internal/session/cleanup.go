@@ -2,7 +2,9 @@ package session
 
 import (
 	"context"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sean/janus/internal/logger"
@@ -21,6 +23,9 @@ type CleanupService struct {
 	ctx      context.Context
 	cancel   context.CancelFunc
 	stopOnce sync.Once
+	// startedAt and lastRun hold UnixNano times for stall detection
+	startedAt atomic.Int64
+	lastRun   atomic.Int64
 }
 
 // NewCleanupService creates a new cleanup service
@@ -41,6 +46,7 @@ func (s *CleanupService) Start() {
 		Dur("interval", s.interval).
 		Dur("timeout", s.timeout).
 		Msg("Starting cleanup service")
+	s.startedAt.Store(time.Now().UnixNano())
 	go s.run()
 }
 
@@ -63,21 +69,59 @@ func (s *CleanupService) run() {
 			logger.Get().Info().Msg("Cleanup service stopped")
 			return
 		case <-ticker.C:
-			s.cleanupInactiveSessions()
+			s.runOnce()
 		}
 	}
 }
 
+// runOnce performs one cleanup pass, recovering from a panic so the loop keeps
+// running. LastRun only advances when the pass completes.
+func (s *CleanupService) runOnce() {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Get().Error().
+				Interface("panic", r).
+				Str("stack", string(debug.Stack())).
+				Msg("Recovered from panic in cleanup service")
+		}
+	}()
+
+	s.cleanupInactiveSessions()
+	s.lastRun.Store(time.Now().UnixNano())
+}
+
+// LastRun returns when the last cleanup pass completed, or the zero time if none has
+func (s *CleanupService) LastRun() time.Time {
+	if nanos := s.lastRun.Load(); nanos != 0 {
+		return time.Unix(0, nanos)
+	}
+	return time.Time{}
+}
+
+// Stalled reports whether no cleanup pass has completed within twice the
+// interval, counting from Start until the first pass. A service that was
+// never started is not considered stalled.
+func (s *CleanupService) Stalled() bool {
+	since := s.lastRun.Load()
+	if since == 0 {
+		since = s.startedAt.Load()
+	}
+	if since == 0 {
+		return false
+	}
+	return time.Since(time.Unix(0, since)) > 2*s.interval
+}
+
 // cleanupInactiveSessions uses the manager's cleanup method to remove stale sessions
 func (s *CleanupService) cleanupInactiveSessions() {
 	// Get count before cleanup for logging
-	sessionsBefore := len(s.manager.GetAllSessions())
+	sessionsBefore := len(s.manager.GetAllSessionsShallow())
 
 	// Call the manager's cleanup method
 	s.manager.CleanupInactiveSessions(s.timeout)
 
 	// Get count after cleanup
-	sessionsAfter := len(s.manager.GetAllSessions())
+	sessionsAfter := len(s.manager.GetAllSessionsShallow())
 
 	if sessionsBefore != sessionsAfter {
 		removed := sessionsBefore - sessionsAfter
@@ -223,3 +223,74 @@ func TestCleanupService_NoCleanupWhenAllActive(t *testing.T) {
 		t.Errorf("sess2 should still exist: %v", err)
 	}
 }
+
+// panickingManager panics on its first `panics` cleanup calls
+type panickingManager struct {
+	Manager
+	panics int
+}
+
+func (m *panickingManager) CleanupInactiveSessions(timeout time.Duration) {
+	if m.panics > 0 {
+		m.panics--
+		panic("cleanup exploded")
+	}
+	m.Manager.CleanupInactiveSessions(timeout)
+}
+
+func TestCleanupService_RecoversFromPanic(t *testing.T) {
+	t.Run("a panicking pass does not advance LastRun", func(t *testing.T) {
+		service := NewCleanupService(&panickingManager{Manager: NewMemorySessionManager(), panics: 1}, time.Minute, time.Minute)
+
+		service.runOnce()
+		if !service.LastRun().IsZero() {
+			t.Error("expected LastRun to stay zero after a panic")
+		}
+
+		service.runOnce()
+		if service.LastRun().IsZero() {
+			t.Error("expected LastRun to be set after a successful pass")
+		}
+	})
+
+	t.Run("the loop keeps running after a panic", func(t *testing.T) {
+		service := NewCleanupService(&panickingManager{Manager: NewMemorySessionManager(), panics: 2}, time.Minute, 10*time.Millisecond)
+		service.Start()
+		defer service.Stop()
+
+		deadline := time.Now().Add(time.Second)
+		for service.LastRun().IsZero() {
+			if time.Now().After(deadline) {
+				t.Fatal("cleanup loop never completed a pass after panicking")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+}
+
+func TestCleanupService_Stalled(t *testing.T) {
+	t.Run("not stalled before Start", func(t *testing.T) {
+		service := NewCleanupService(NewMemorySessionManager(), time.Minute, time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+
+		if service.Stalled() {
+			t.Error("expected a service that was never started not to be stalled")
+		}
+	})
+
+	t.Run("stalled when no pass completes within twice the interval", func(t *testing.T) {
+		service := NewCleanupService(NewMemorySessionManager(), time.Minute, 10*time.Millisecond)
+		service.Start()
+		service.Stop()
+
+		time.Sleep(30 * time.Millisecond)
+		if !service.Stalled() {
+			t.Error("expected a stopped service to be reported as stalled")
+		}
+
+		service.runOnce()
+		if service.Stalled() {
+			t.Error("expected a fresh pass to clear the stall")
+		}
+	})
+}
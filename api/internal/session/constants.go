@@ -11,5 +11,14 @@ const (
 
 	// HeartbeatInterval is the expected interval between heartbeat calls
 	HeartbeatInterval = 30 * time.Second
-)
 
+	// DefaultArchiveTimeout bounds how long archiving an ended session may take
+	DefaultArchiveTimeout = 30 * time.Second
+
+	// DefaultCursorProcessStopTimeout is how long a persistent cursor-agent gets to
+	// exit after its stdin closes before it is killed
+	DefaultCursorProcessStopTimeout = 5 * time.Second
+
+	// TrimmedAnswerPlaceholder replaces the content of answers older than the stored answer limit
+	TrimmedAnswerPlaceholder = "[answer trimmed]"
+)
@@ -0,0 +1,13 @@
+package session
+
+// CursorAgentError is returned when cursor-agent ran but reported a failure in
+// its result (is_error set), so callers can act on its subtype, such as
+// "rate_limit" or "auth"
+type CursorAgentError struct {
+	Subtype string
+	Message string
+}
+
+func (e *CursorAgentError) Error() string {
+	return "cursor-agent returned error: " + e.Message
+}
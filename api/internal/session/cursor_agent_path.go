@@ -0,0 +1,55 @@
+package session
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sean/janus/internal/logger"
+)
+
+// CursorAgentLocator is implemented by managers that can re-resolve the
+// cursor-agent executable while running, so a reinstall at a different path
+// doesn't leave every ask failing until restart
+type CursorAgentLocator interface {
+	RediscoverCursorAgent() (path string, err error)
+}
+
+// cursorAgent returns the cursor-agent executable asks currently run
+func (m *MemorySessionManager) cursorAgent() string {
+	m.cursorAgentMu.RLock()
+	defer m.cursorAgentMu.RUnlock()
+
+	if m.resolvedCursorAgentPath != "" {
+		return m.resolvedCursorAgentPath
+	}
+	return m.cursorAgentPath
+}
+
+// RediscoverCursorAgent resolves the configured cursor-agent with
+// exec.LookPath and switches subsequent asks to the result when it changed.
+// A configured path that no longer exists falls back to searching PATH for
+// its base name. When nothing is found, the current path is kept and the
+// lookup error returned.
+func (m *MemorySessionManager) RediscoverCursorAgent() (string, error) {
+	resolved, err := exec.LookPath(m.cursorAgentPath)
+	if err != nil && strings.ContainsRune(m.cursorAgentPath, filepath.Separator) {
+		resolved, err = exec.LookPath(filepath.Base(m.cursorAgentPath))
+	}
+	if err != nil {
+		return m.cursorAgent(), err
+	}
+
+	m.cursorAgentMu.Lock()
+	previous := m.resolvedCursorAgentPath
+	m.resolvedCursorAgentPath = resolved
+	m.cursorAgentMu.Unlock()
+
+	if previous != "" && previous != resolved {
+		logger.Get().Info().
+			Str("previous_path", previous).
+			Str("cursor_agent_path", resolved).
+			Msg("cursor-agent path changed, using the new location")
+	}
+	return resolved, nil
+}
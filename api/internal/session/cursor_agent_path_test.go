@@ -0,0 +1,92 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// installFakeCursorAgent writes a cursor-agent into dir that answers with answer
+func installFakeCursorAgent(t *testing.T, dir string, answer string) string {
+	t.Helper()
+	path := filepath.Join(dir, "cursor-agent")
+	script := "#!/bin/sh\necho '{\"result\":\"" + answer + "\",\"session_id\":\"chat-1\"}'\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake cursor-agent: %v", err)
+	}
+	return path
+}
+
+func TestRediscoverCursorAgent(t *testing.T) {
+	ask := func(t *testing.T, manager Manager) string {
+		t.Helper()
+		session, _ := manager.CreateSession()
+		answer, _, _, err := manager.AskQuestion(context.Background(), session.ID, "hi", t.TempDir())
+		if err != nil {
+			t.Fatalf("ask failed: %v", err)
+		}
+		return answer
+	}
+
+	t.Run("follows a reinstall to another directory on PATH", func(t *testing.T) {
+		oldDir, newDir := t.TempDir(), t.TempDir()
+		installFakeCursorAgent(t, oldDir, "old")
+		t.Setenv("PATH", oldDir)
+		manager := NewMemorySessionManager().(*MemorySessionManager)
+
+		if path, err := manager.RediscoverCursorAgent(); err != nil || path != filepath.Join(oldDir, "cursor-agent") {
+			t.Fatalf("expected the old install, got %q (err %v)", path, err)
+		}
+		if got := ask(t, manager); got != "old" {
+			t.Fatalf("expected the old install to answer, got %q", got)
+		}
+
+		installFakeCursorAgent(t, newDir, "new")
+		os.Remove(filepath.Join(oldDir, "cursor-agent"))
+		t.Setenv("PATH", newDir)
+
+		if path, _ := manager.RediscoverCursorAgent(); path != filepath.Join(newDir, "cursor-agent") {
+			t.Errorf("expected the new install, got %q", path)
+		}
+		if got := ask(t, manager); got != "new" {
+			t.Errorf("expected asks to use the new install, got %q", got)
+		}
+	})
+
+	t.Run("a missing configured path falls back to PATH", func(t *testing.T) {
+		configured := installFakeCursorAgent(t, t.TempDir(), "configured")
+		newDir := t.TempDir()
+		installFakeCursorAgent(t, newDir, "found")
+		t.Setenv("PATH", newDir)
+		manager := NewMemorySessionManager(WithCursorAgentPath(configured)).(*MemorySessionManager)
+
+		if path, _ := manager.RediscoverCursorAgent(); path != configured {
+			t.Fatalf("expected the configured path while it exists, got %q", path)
+		}
+
+		os.Remove(configured)
+		if path, _ := manager.RediscoverCursorAgent(); path != filepath.Join(newDir, "cursor-agent") {
+			t.Errorf("expected the PATH install, got %q", path)
+		}
+		if got := ask(t, manager); got != "found" {
+			t.Errorf("expected asks to use the PATH install, got %q", got)
+		}
+	})
+
+	t.Run("keeps the current path when nothing is found", func(t *testing.T) {
+		configured := installFakeCursorAgent(t, t.TempDir(), "configured")
+		t.Setenv("PATH", t.TempDir())
+		manager := NewMemorySessionManager(WithCursorAgentPath(configured)).(*MemorySessionManager)
+		manager.RediscoverCursorAgent()
+
+		os.Remove(configured)
+		path, err := manager.RediscoverCursorAgent()
+		if err == nil {
+			t.Error("expected a lookup error")
+		}
+		if path != configured || manager.cursorAgent() != configured {
+			t.Errorf("expected the previous path to be kept, got %q", path)
+		}
+	})
+}
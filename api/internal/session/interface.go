@@ -8,12 +8,16 @@ import (
 // Manager handles session lifecycle operations
 type Manager interface {
 	CreateSession() (*Session, error)
+	CreateSessionWithOptions(opts SessionOptions) (*Session, error)
 	GetSession(id string) (*Session, error)
+	GetOrCreateByKey(externalKey string) (sess *Session, created bool, err error)
 	UpdateActivity(id string) error
 	UpdateCursorChatID(id string, cursorChatID string) error
-	AskQuestion(ctx context.Context, id string, question string, workspaceDir string) (answer string, cursorChatID string, err error)
+	// AskQuestion reports resumed when the ask continued the session's existing cursor chat
+	AskQuestion(ctx context.Context, id string, question string, workspaceDir string) (answer string, cursorChatID string, resumed bool, err error)
 	AddToConversationLog(id string, messages []Message) error
 	EndSession(id string) error
 	GetAllSessions() []*Session
+	GetAllSessionsShallow() []*Session
 	CleanupInactiveSessions(timeout time.Duration)
 }
@@ -0,0 +1,119 @@
+package session
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LockStats is a snapshot of the manager's mutex instrumentation
+type LockStats struct {
+	Enabled        bool    `json:"enabled"`
+	Acquisitions   int64   `json:"acquisitions"`
+	Contentions    int64   `json:"contentions"`
+	ContentionRate float64 `json:"contention_rate"`
+	AvgWaitMicros  float64 `json:"avg_wait_us"`
+	AvgHoldMicros  float64 `json:"avg_hold_us"`
+}
+
+// LockStatsProvider is implemented by managers that can report lock contention
+type LockStatsProvider interface {
+	LockStats() LockStats
+}
+
+// lockMetrics accumulates lock counters using atomics so that recording
+// never requires taking the lock being measured
+type lockMetrics struct {
+	enabled      bool
+	acquisitions atomic.Int64
+	contentions  atomic.Int64
+	waitNanos    atomic.Int64
+	holdNanos    atomic.Int64
+}
+
+// recordAcquire records a completed acquisition and how long it waited
+func (l *lockMetrics) recordAcquire(wait time.Duration, contended bool) {
+	l.acquisitions.Add(1)
+	l.waitNanos.Add(int64(wait))
+	if contended {
+		l.contentions.Add(1)
+	}
+}
+
+// recordRelease records how long the lock was held
+func (l *lockMetrics) recordRelease(hold time.Duration) {
+	l.holdNanos.Add(int64(hold))
+}
+
+// snapshot returns the current counters with derived averages
+func (l *lockMetrics) snapshot() LockStats {
+	stats := LockStats{
+		Enabled:      l.enabled,
+		Acquisitions: l.acquisitions.Load(),
+		Contentions:  l.contentions.Load(),
+	}
+
+	if stats.Acquisitions > 0 {
+		count := float64(stats.Acquisitions)
+		stats.ContentionRate = float64(stats.Contentions) / count
+		stats.AvgWaitMicros = float64(l.waitNanos.Load()) / count / float64(time.Microsecond)
+		stats.AvgHoldMicros = float64(l.holdNanos.Load()) / count / float64(time.Microsecond)
+	}
+
+	return stats
+}
+
+// lock acquires the shard's write lock and returns the acquisition time for unlock.
+// When metrics are disabled this is a plain Lock with no timing overhead.
+func (m *MemorySessionManager) lock(s *sessionShard) time.Time {
+	if !m.lockMetrics.enabled {
+		s.mu.Lock()
+		return time.Time{}
+	}
+
+	start := time.Now()
+	contended := !s.mu.TryLock()
+	if contended {
+		s.mu.Lock()
+	}
+	acquired := time.Now()
+	m.lockMetrics.recordAcquire(acquired.Sub(start), contended)
+	return acquired
+}
+
+// unlock releases the shard's write lock, recording the hold time if enabled
+func (m *MemorySessionManager) unlock(s *sessionShard, acquired time.Time) {
+	if m.lockMetrics.enabled {
+		m.lockMetrics.recordRelease(time.Since(acquired))
+	}
+	s.mu.Unlock()
+}
+
+// rlock acquires the shard's read lock and returns the acquisition time for runlock
+func (m *MemorySessionManager) rlock(s *sessionShard) time.Time {
+	if !m.lockMetrics.enabled {
+		s.mu.RLock()
+		return time.Time{}
+	}
+
+	start := time.Now()
+	contended := !s.mu.TryRLock()
+	if contended {
+		s.mu.RLock()
+	}
+	acquired := time.Now()
+	m.lockMetrics.recordAcquire(acquired.Sub(start), contended)
+	return acquired
+}
+
+// runlock releases the shard's read lock, recording the hold time if enabled
+func (m *MemorySessionManager) runlock(s *sessionShard, acquired time.Time) {
+	if m.lockMetrics.enabled {
+		m.lockMetrics.recordRelease(time.Since(acquired))
+	}
+	s.mu.RUnlock()
+}
+
+// LockStats returns a snapshot of the lock instrumentation counters
+func (m *MemorySessionManager) LockStats() LockStats {
+	return m.lockMetrics.snapshot()
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockMetrics(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		manager := NewMemorySessionManager().(*MemorySessionManager)
+		manager.CreateSession()
+		manager.GetAllSessions()
+
+		stats := manager.LockStats()
+		if stats.Enabled {
+			t.Error("expected lock metrics to be disabled by default")
+		}
+		if stats.Acquisitions != 0 {
+			t.Errorf("expected no recorded acquisitions, got %d", stats.Acquisitions)
+		}
+	})
+
+	t.Run("counts acquisitions under concurrent access", func(t *testing.T) {
+		manager := NewMemorySessionManager(WithLockMetrics(true)).(*MemorySessionManager)
+		session, _ := manager.CreateSession()
+
+		const goroutines = 20
+		const opsPerGoroutine = 10
+
+		var wg sync.WaitGroup
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < opsPerGoroutine; j++ {
+					manager.UpdateActivity(session.ID)
+					manager.GetSession(session.ID)
+				}
+			}()
+		}
+		wg.Wait()
+
+		stats := manager.LockStats()
+		// One acquisition for CreateSession plus two per loop iteration
+		expected := int64(1 + goroutines*opsPerGoroutine*2)
+		if stats.Acquisitions != expected {
+			t.Errorf("expected %d acquisitions, got %d", expected, stats.Acquisitions)
+		}
+		if stats.AvgHoldMicros < 0 || stats.AvgWaitMicros < 0 {
+			t.Error("expected non-negative average timings")
+		}
+	})
+
+	t.Run("records contention when lock is held", func(t *testing.T) {
+		manager := NewMemorySessionManager(WithLockMetrics(true)).(*MemorySessionManager)
+		session, _ := manager.CreateSession()
+
+		// Hold the lock so the next acquisition is forced to wait
+		manager.shards[0].mu.Lock()
+		done := make(chan struct{})
+		go func() {
+			manager.GetSession(session.ID)
+			close(done)
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		manager.shards[0].mu.Unlock()
+		<-done
+
+		stats := manager.LockStats()
+		if stats.Contentions < 1 {
+			t.Errorf("expected at least 1 contention, got %d", stats.Contentions)
+		}
+		if stats.ContentionRate <= 0 {
+			t.Errorf("expected positive contention rate, got %f", stats.ContentionRate)
+		}
+		if stats.AvgWaitMicros <= 0 {
+			t.Errorf("expected positive average wait, got %f", stats.AvgWaitMicros)
+		}
+	})
+}
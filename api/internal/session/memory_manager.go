@@ -6,54 +6,167 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sean/janus/internal/config"
+	"github.com/sean/janus/internal/logger"
+	"github.com/sean/janus/internal/metrics"
 )
 
 // MemorySessionManager implements Manager interface with in-memory storage
 // and thread-safe operations. Returns deep copies to prevent external mutations.
 type MemorySessionManager struct {
-	sessions map[string]*Session
-	mu       sync.RWMutex
+	// shards partitions sessions by ID hash, each behind its own lock
+	shards          []*sessionShard
+	shardCount      int
+	initialCapacity int
+	lockMetrics     lockMetrics
+	cursorAgentPath string
+	// resolvedCursorAgentPath is where RediscoverCursorAgent last found
+	// cursor-agent, used instead of cursorAgentPath once set
+	resolvedCursorAgentPath string
+	cursorAgentMu           sync.RWMutex
+	fallbackModels          []string
+	// externalKeys maps client-supplied keys to session IDs for GetOrCreateByKey.
+	// keysMu is taken before any shard lock, never after one.
+	externalKeys map[string]string
+	keysMu       sync.Mutex
+	// longConversationThreshold is the message count past which a session is flagged (0 disables)
+	longConversationThreshold int
+	// maxCursorOutputBytes bounds captured cursor-agent stdout (0 disables)
+	maxCursorOutputBytes int
+	// maxConversationBytes bounds total message content per session (0 disables)
+	maxConversationBytes int
+	// maxStoredAnswers is how many recent answers keep their text per session (0 disables)
+	maxStoredAnswers int
+	// cursorAgentUseStdin always sends the question on stdin instead of as an argument
+	cursorAgentUseStdin bool
+	// cursorAgentStdinThreshold is the question length at which stdin is used (0 disables)
+	cursorAgentStdinThreshold int
+	// cursorAgentModes maps mode names to the arguments prepended for AskQuestionInMode
+	cursorAgentModes map[string][]string
+	// archiver stores transcripts of ended sessions (nil disables archiving)
+	archiver Archiver
+	// persistentCursorProcess answers each session's questions with one long-lived cursor-agent
+	persistentCursorProcess bool
+	// persister saves sessions to disk so they survive restarts (nil disables persistence)
+	persister *sessionPersister
+	// activeAsks tracks in-flight questions so CancelQuestion can stop them
+	activeAsks activeAsks
+	// serializeAsks runs one question at a time per session; rejectConcurrentAsks
+	// fails overlapping questions with ErrSessionBusy instead of queueing them
+	serializeAsks        bool
+	rejectConcurrentAsks bool
+	askLocks             askLocks
+	// askSlots bounds concurrent cursor-agent runs across sessions (nil disables)
+	askSlots *fifoSemaphore
+	// usageSummaries logs a usage summary for every session that ends
+	usageSummaries bool
 }
 
 // NewMemorySessionManager creates a new in-memory session manager
-func NewMemorySessionManager() Manager {
-	return &MemorySessionManager{
-		sessions: make(map[string]*Session),
+func NewMemorySessionManager(opts ...Option) Manager {
+	m := &MemorySessionManager{
+		externalKeys:              make(map[string]string),
+		shardCount:                config.DefaultSessionShards,
+		cursorAgentPath:           config.DefaultCursorAgentPath,
+		maxCursorOutputBytes:      config.DefaultMaxCursorOutputBytes,
+		cursorAgentStdinThreshold: config.DefaultCursorAgentStdinThreshold,
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.shards = newSessionShards(m.shardCount, m.initialCapacity)
+	if m.persister != nil {
+		m.persister.snapshot = m.GetAllSessions
+		m.restoreSessions()
+	}
+	return m
 }
 
 // CreateSession creates a new session with a unique ID
 func (m *MemorySessionManager) CreateSession() (*Session, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	return m.CreateSessionWithOptions(SessionOptions{})
+}
+
+// CreateSessionWithOptions creates a new session with a unique ID and client-selected settings
+func (m *MemorySessionManager) CreateSessionWithOptions(opts SessionOptions) (*Session, error) {
+	return m.createSession(opts, ""), nil
+}
+
+// GetOrCreateByKey returns the session previously created for externalKey, or
+// atomically creates and indexes a new one. created reports which happened.
+func (m *MemorySessionManager) GetOrCreateByKey(externalKey string) (*Session, bool, error) {
+	if externalKey == "" {
+		return nil, false, fmt.Errorf("external key cannot be empty")
+	}
+
+	m.keysMu.Lock()
+	defer m.keysMu.Unlock()
 
-	sessionID := uuid.New().String()
+	if id, exists := m.externalKeys[externalKey]; exists {
+		if session, err := m.GetSession(id); err == nil {
+			return session, false, nil
+		}
+	}
+
+	session := m.createSession(SessionOptions{}, externalKey)
+	m.externalKeys[externalKey] = session.ID
+
+	return session, true, nil
+}
+
+// createSession creates and stores a new session in its shard and returns a
+// clone, preventing external mutations of internal state
+func (m *MemorySessionManager) createSession(opts SessionOptions, externalKey string) *Session {
 	now := time.Now()
 
 	session := &Session{
-		ID:              sessionID,
-		CreatedAt:       now,
-		LastActivity:    now,
-		ConversationLog: make([]Message, 0),
+		ID:               uuid.New().String(),
+		CreatedAt:        now,
+		LastActivity:     now,
+		ConversationLog:  make([]Message, 0),
+		AutoTTS:          opts.AutoTTS,
+		Branch:           opts.Branch,
+		Timeout:          opts.Timeout,
+		Ephemeral:        opts.Ephemeral,
+		AnswerWebhookURL: opts.AnswerWebhookURL,
+		ExternalKey:      externalKey,
 	}
 
-	m.sessions[sessionID] = session
+	shard := m.shardFor(session.ID)
+	acquired := m.lock(shard)
+	shard.sessions[session.ID] = session
+	clone := session.Clone()
+	m.unlock(shard, acquired)
 
-	// Return a clone to prevent external mutations of internal state
-	return session.Clone(), nil
+	m.markDirty()
+	return clone
+}
+
+// unindexSessions drops the external key index entries of removed sessions.
+// It takes keysMu, so callers must not hold a shard lock.
+func (m *MemorySessionManager) unindexSessions(sessions []*Session) {
+	m.keysMu.Lock()
+	defer m.keysMu.Unlock()
+
+	for _, session := range sessions {
+		if session.ExternalKey != "" && m.externalKeys[session.ExternalKey] == session.ID {
+			delete(m.externalKeys, session.ExternalKey)
+		}
+	}
 }
 
 // GetSession retrieves a session by ID and returns a deep copy
 // to prevent external mutations of internal state
 func (m *MemorySessionManager) GetSession(id string) (*Session, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	shard := m.shardFor(id)
+	defer m.runlock(shard, m.rlock(shard))
 
-	session, exists := m.sessions[id]
+	session, exists := shard.sessions[id]
 	if !exists {
 		return nil, fmt.Errorf("session not found: %s", id)
 	}
@@ -64,10 +177,10 @@ func (m *MemorySessionManager) GetSession(id string) (*Session, error) {
 
 // UpdateActivity updates the LastActivity timestamp for a session
 func (m *MemorySessionManager) UpdateActivity(id string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	shard := m.shardFor(id)
+	defer m.unlock(shard, m.lock(shard))
 
-	session, exists := m.sessions[id]
+	session, exists := shard.sessions[id]
 	if !exists {
 		return fmt.Errorf("session not found: %s", id)
 	}
@@ -76,17 +189,34 @@ func (m *MemorySessionManager) UpdateActivity(id string) error {
 	return nil
 }
 
-// UpdateCursorChatID updates the cursor-agent chat session ID for a session
+// UpdateCursorChatID updates the cursor-agent chat session ID for a session,
+// stopping its persistent cursor-agent if that is on another chat
 func (m *MemorySessionManager) UpdateCursorChatID(id string, cursorChatID string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	shard := m.shardFor(id)
+	acquired := m.lock(shard)
 
-	session, exists := m.sessions[id]
+	session, exists := shard.sessions[id]
 	if !exists {
+		m.unlock(shard, acquired)
 		return fmt.Errorf("session not found: %s", id)
 	}
 
-	session.CursorChatID = cursorChatID
+	if session.CursorChatID != cursorChatID {
+		session.CursorChatID = cursorChatID
+		m.markDirty()
+	}
+
+	// The next ask starts a process resuming this chat instead
+	var stale *cursorProcess
+	if session.process != nil && session.process.cursorChatID != cursorChatID {
+		stale = session.process
+		session.process = nil
+	}
+	m.unlock(shard, acquired)
+
+	if stale != nil {
+		stale.close()
+	}
 	return nil
 }
 
@@ -101,109 +231,421 @@ type CursorAgentResponse struct {
 
 // AskQuestion sends a question to cursor-agent and returns the answer
 // It runs cursor-agent as a command with --print and --resume flags
-// The context is used to cancel the command if the request times out
-func (m *MemorySessionManager) AskQuestion(ctx context.Context, id string, question string, workspaceDir string) (string, string, error) {
-	m.mu.RLock()
-	session, exists := m.sessions[id]
-	m.mu.RUnlock()
+// The context is used to cancel the command if the request times out.
+// If the default model is unavailable, configured fallback models are tried in order.
+// resumed is read when the ask starts running, after any ask it waited for.
+func (m *MemorySessionManager) AskQuestion(ctx context.Context, id string, question string, workspaceDir string) (string, string, bool, error) {
+	return m.askQuestion(ctx, id, nil, question, workspaceDir)
+}
+
+// askQuestion runs cursor-agent for a question with modeArgs placed before the
+// standard chat arguments, retrying with fallback models as AskQuestion describes.
+// The ask can be stopped with CancelQuestion, which makes it fail with ErrQuestionCancelled.
+// It waits its turn as admitAsk describes before running cursor-agent.
+func (m *MemorySessionManager) askQuestion(ctx context.Context, id string, modeArgs []string, question string, workspaceDir string) (string, string, bool, error) {
+	ctx, untrack := m.activeAsks.track(ctx, id)
+	defer untrack()
+
+	release, err := m.admitAsk(ctx, id)
+	if err != nil {
+		return "", "", false, cancelledError(ctx, err)
+	}
+	defer release()
+
+	answer, cursorChatID, resumed, err := m.runAsk(ctx, id, modeArgs, question, workspaceDir)
+	if err == nil && cursorChatID != "" {
+		// Recorded before the next ask is admitted so it sees the chat to resume
+		if updateErr := m.UpdateCursorChatID(id, cursorChatID); updateErr != nil {
+			logger.FromContext(ctx).Warn().
+				Err(updateErr).
+				Str("session_id", id).
+				Str("cursor_chat_id", cursorChatID).
+				Msg("Failed to record cursor chat ID after ask")
+		}
+	}
+	return answer, cursorChatID, resumed, cancelledError(ctx, err)
+}
+
+// runAsk answers a question for askQuestion, reporting whether it resumed the session's cursor chat
+func (m *MemorySessionManager) runAsk(ctx context.Context, id string, modeArgs []string, question string, workspaceDir string) (string, string, bool, error) {
+	if m.persistentCursorProcess && len(modeArgs) == 0 {
+		return m.askPersistent(ctx, id, question, workspaceDir)
+	}
+
+	shard := m.shardFor(id)
+	acquired := m.rlock(shard)
+	session, exists := shard.sessions[id]
+	var cursorChatID string
+	if exists {
+		cursorChatID = session.CursorChatID
+	}
+	m.runlock(shard, acquired)
 
 	if !exists {
-		return "", "", fmt.Errorf("session not found: %s", id)
+		return "", "", false, fmt.Errorf("session not found: %s", id)
+	}
+	resumed := cursorChatID != ""
+
+	// An empty model means cursor-agent's own default
+	models := append([]string{""}, m.fallbackModels...)
+
+	// Long questions go over stdin so they can't exceed the OS argument size limit
+	argQuestion, stdin := question, ""
+	if m.useStdinFor(question) {
+		argQuestion, stdin = "", question
+	}
+
+	for i, model := range models {
+		args := append(append([]string{}, modeArgs...), buildCursorAgentArgs(cursorChatID, model, argQuestion)...)
+		response, err := m.runCursorAgent(ctx, args, stdin, workspaceDir)
+		if err == nil {
+			return response.Result, response.SessionID, resumed, nil
+		}
+
+		// Only model availability failures are worth retrying with another model
+		if ctx.Err() != nil || !isModelError(err) || i == len(models)-1 {
+			return "", "", false, err
+		}
+
+		logger.FromContext(ctx).Warn().
+			Str("session_id", id).
+			Str("failed_model", displayModel(model)).
+			Str("fallback_model", models[i+1]).
+			Err(err).
+			Msg("cursor-agent model unavailable, falling back")
+	}
+
+	// Unreachable: models always contains at least the default entry
+	return "", "", false, fmt.Errorf("cursor-agent produced no response")
+}
+
+// useStdinFor reports whether question should be sent to cursor-agent on stdin
+func (m *MemorySessionManager) useStdinFor(question string) bool {
+	if m.cursorAgentUseStdin {
+		return true
 	}
+	return m.cursorAgentStdinThreshold > 0 && len(question) >= m.cursorAgentStdinThreshold
+}
 
-	// Build cursor-agent command
+// buildCursorAgentArgs builds the cursor-agent arguments for a single question.
+// An empty question is omitted so cursor-agent reads the prompt from stdin.
+func buildCursorAgentArgs(cursorChatID string, model string, question string) []string {
 	args := []string{"--print", "--output-format", "json"}
 
 	// If we have a cursor chat ID, resume that conversation
-	if session.CursorChatID != "" {
-		args = append(args, "--resume", session.CursorChatID)
+	if cursorChatID != "" {
+		args = append(args, "--resume", cursorChatID)
 	}
 
-	args = append(args, question)
+	if model != "" {
+		args = append(args, "--model", model)
+	}
+
+	if question == "" {
+		return args
+	}
+	return append(args, question)
+}
 
+// runCursorAgent executes cursor-agent once and parses its JSON response.
+// A non-empty stdin is written to the process as the prompt.
+func (m *MemorySessionManager) runCursorAgent(ctx context.Context, args []string, stdin string, workspaceDir string) (*CursorAgentResponse, error) {
 	// Use CommandContext to respect timeout/cancellation
-	cmd := exec.CommandContext(ctx, "cursor-agent", args...)
+	cmd := exec.CommandContext(ctx, m.cursorAgent(), args...)
 	cmd.Dir = workspaceDir
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
 
-	// Capture output
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
+	// Capture output, bounding stdout so a runaway response can't exhaust memory
+	stdout := newBoundedBuffer(m.maxCursorOutputBytes)
+	var stderr bytes.Buffer
+	cmd.Stdout = stdout
 	cmd.Stderr = &stderr
 
 	// Run command - will be killed if context is cancelled
-	if err := cmd.Run(); err != nil {
+	err := cmd.Run()
+	metrics.RecordProcess(metrics.ProcessCursorAgent, err)
+	if err != nil {
 		// Check if error was due to context cancellation
 		if ctx.Err() != nil {
-			return "", "", fmt.Errorf("cursor-agent command cancelled: %w", ctx.Err())
+			return nil, fmt.Errorf("cursor-agent command cancelled: %w", ctx.Err())
 		}
-		return "", "", fmt.Errorf("cursor-agent command failed: %w, stderr: %s", err, stderr.String())
+		logger.FromContext(ctx).Debug().
+			Err(err).
+			Str("stderr", stderr.String()).
+			Msg("cursor-agent command failed")
+		return nil, fmt.Errorf("cursor-agent command failed: %w, stderr: %s", err, stderr.String())
+	}
+
+	if stdout.Exceeded() {
+		return nil, fmt.Errorf("%w (limit %d bytes)", ErrCursorOutputTooLarge, m.maxCursorOutputBytes)
 	}
 
 	// Parse JSON response
 	var response CursorAgentResponse
 	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
-		return "", "", fmt.Errorf("failed to parse cursor-agent response: %w, output: %s", err, stdout.String())
+		return nil, fmt.Errorf("failed to parse cursor-agent response: %w, output: %s", err, stdout.String())
 	}
 
 	// Check for errors in response
 	if response.IsError {
-		return "", "", fmt.Errorf("cursor-agent returned error: %s", response.Result)
+		return nil, &CursorAgentError{Subtype: response.Subtype, Message: response.Result}
+	}
+
+	return &response, nil
+}
+
+// modelErrorPhrases are fragments cursor-agent uses when a model can't be used
+var modelErrorPhrases = []string{"not available", "unavailable", "not found", "unknown", "invalid", "not supported"}
+
+// isModelError reports whether a cursor-agent failure was caused by the selected model
+func isModelError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	if !strings.Contains(msg, "model") {
+		return false
+	}
+	for _, phrase := range modelErrorPhrases {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
 	}
+	return false
+}
 
-	return response.Result, response.SessionID, nil
+// displayModel returns a loggable name for a model, naming the implicit default
+func displayModel(model string) string {
+	if model == "" {
+		return "default"
+	}
+	return model
 }
 
 // AddToConversationLog appends messages to the session's conversation log
 func (m *MemorySessionManager) AddToConversationLog(id string, messages []Message) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	shard := m.shardFor(id)
+	defer m.unlock(shard, m.lock(shard))
 
-	session, exists := m.sessions[id]
+	session, exists := shard.sessions[id]
 	if !exists {
 		return fmt.Errorf("session not found: %s", id)
 	}
 
 	session.ConversationLog = append(session.ConversationLog, messages...)
+	m.trimAnswers(session)
+	m.trimConversation(session)
+	m.markDirty()
+
+	if m.longConversationThreshold > 0 && !session.LongConversation &&
+		len(session.ConversationLog) > m.longConversationThreshold {
+		session.LongConversation = true
+		logger.Get().Info().
+			Str("session_id", id).
+			Int("message_count", len(session.ConversationLog)).
+			Int("threshold", m.longConversationThreshold).
+			Msg("Session conversation exceeded long conversation threshold")
+	}
+
 	return nil
 }
 
-// EndSession removes a session from the manager
-func (m *MemorySessionManager) EndSession(id string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// trimAnswers replaces the content of all but the newest maxStoredAnswers
+// assistant messages with TrimmedAnswerPlaceholder. Questions are untouched and
+// trimmed answers keep their place and timestamp in the log.
+func (m *MemorySessionManager) trimAnswers(session *Session) {
+	if m.maxStoredAnswers <= 0 {
+		return
+	}
 
-	if _, exists := m.sessions[id]; !exists {
-		return fmt.Errorf("session not found: %s", id)
+	kept := 0
+	trimmed := 0
+	for i := len(session.ConversationLog) - 1; i >= 0; i-- {
+		msg := &session.ConversationLog[i]
+		if msg.Role != "assistant" {
+			continue
+		}
+		if kept < m.maxStoredAnswers {
+			kept++
+			continue
+		}
+		if msg.Content == TrimmedAnswerPlaceholder {
+			// Everything older was trimmed by an earlier append
+			break
+		}
+		msg.Content = TrimmedAnswerPlaceholder
+		trimmed++
+	}
+	if trimmed == 0 {
+		return
+	}
+
+	logger.Get().Debug().
+		Str("session_id", session.ID).
+		Int("trimmed_answers", trimmed).
+		Int("max_stored_answers", m.maxStoredAnswers).
+		Msg("Trimmed old answers from conversation log")
+}
+
+// trimConversation drops the oldest messages until the log's content fits in
+// maxConversationBytes. The newest message is always kept so the latest answer
+// is never lost, even if it alone exceeds the limit.
+func (m *MemorySessionManager) trimConversation(session *Session) {
+	if m.maxConversationBytes <= 0 {
+		return
+	}
+
+	total := 0
+	for _, msg := range session.ConversationLog {
+		total += len(msg.Content)
+	}
+
+	trimmed := 0
+	for total > m.maxConversationBytes && trimmed < len(session.ConversationLog)-1 {
+		total -= len(session.ConversationLog[trimmed].Content)
+		trimmed++
+	}
+	if trimmed == 0 {
+		return
+	}
+
+	// Copy into a fresh slice so the dropped messages can be garbage collected
+	session.ConversationLog = append([]Message(nil), session.ConversationLog[trimmed:]...)
+
+	logger.Get().Debug().
+		Str("session_id", session.ID).
+		Int("trimmed_messages", trimmed).
+		Int("conversation_bytes", total).
+		Msg("Trimmed conversation log to fit byte limit")
+}
+
+// EndSession removes a session from the manager, stops its persistent
+// cursor-agent, and archives its transcript
+func (m *MemorySessionManager) EndSession(id string) error {
+	session, err := m.removeSession(id)
+	if err != nil {
+		return err
+	}
+	m.logUsage(session, EndReasonEnded)
+	if session.process != nil {
+		session.process.close()
 	}
 
-	delete(m.sessions, id)
+	if m.archiver != nil {
+		m.archiveSession(session)
+	}
 	return nil
 }
 
+// removeSession deletes a session and its external key and returns it
+func (m *MemorySessionManager) removeSession(id string) (*Session, error) {
+	shard := m.shardFor(id)
+	acquired := m.lock(shard)
+	session, exists := shard.sessions[id]
+	if exists {
+		delete(shard.sessions, id)
+	}
+	m.unlock(shard, acquired)
+
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", id)
+	}
+
+	m.unindexSessions([]*Session{session})
+	m.markDirty()
+	return session, nil
+}
+
+// archiveSession hands an ended session's transcript to the archiver, skipping
+// ephemeral sessions. Failures are logged, not returned, so they never keep a
+// session from ending.
+func (m *MemorySessionManager) archiveSession(session *Session) {
+	if session.Ephemeral {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultArchiveTimeout)
+	defer cancel()
+
+	if err := m.archiver.Archive(ctx, session); err != nil {
+		logger.Get().Error().
+			Str("session_id", session.ID).
+			Err(err).
+			Msg("Failed to archive session")
+	}
+}
+
 // GetAllSessions returns all active sessions as deep copies
 // to prevent external mutations of internal state
 func (m *MemorySessionManager) GetAllSessions() []*Session {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	sessions := make([]*Session, 0)
+	for _, shard := range m.shards {
+		acquired := m.rlock(shard)
+		for _, session := range shard.sessions {
+			// Clone each session to prevent external mutations
+			sessions = append(sessions, session.Clone())
+		}
+		m.runlock(shard, acquired)
+	}
+
+	return sessions
+}
 
-	sessions := make([]*Session, 0, len(m.sessions))
-	for _, session := range m.sessions {
-		// Clone each session to prevent external mutations
-		sessions = append(sessions, session.Clone())
+// GetAllSessionsShallow returns all active sessions without their conversation
+// logs. Cheaper than GetAllSessions for counting and metadata use cases.
+func (m *MemorySessionManager) GetAllSessionsShallow() []*Session {
+	sessions := make([]*Session, 0)
+	for _, shard := range m.shards {
+		acquired := m.rlock(shard)
+		for _, session := range shard.sessions {
+			sessions = append(sessions, session.CloneShallow())
+		}
+		m.runlock(shard, acquired)
 	}
 
 	return sessions
 }
 
-// CleanupInactiveSessions removes sessions inactive for longer than timeout
+// CleanupInactiveSessions removes sessions inactive for longer than timeout, or
+// their own Timeout when set, stops their persistent cursor-agents, and archives them
 func (m *MemorySessionManager) CleanupInactiveSessions(timeout time.Duration) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	removed := m.removeInactiveSessions(timeout)
 
+	for _, session := range removed {
+		m.logUsage(session, EndReasonReaped)
+		if session.process != nil {
+			session.process.close()
+		}
+	}
+
+	if m.archiver != nil {
+		for _, session := range removed {
+			m.archiveSession(session)
+		}
+	}
+}
+
+// removeInactiveSessions deletes stale sessions one shard at a time and returns them
+func (m *MemorySessionManager) removeInactiveSessions(timeout time.Duration) []*Session {
+	var removed []*Session
 	now := time.Now()
-	for id, session := range m.sessions {
-		if now.Sub(session.LastActivity) > timeout {
-			delete(m.sessions, id)
+	for _, shard := range m.shards {
+		acquired := m.lock(shard)
+		for _, session := range shard.sessions {
+			limit := timeout
+			if session.Timeout > 0 {
+				limit = session.Timeout
+			}
+			if now.Sub(session.LastActivity) > limit {
+				delete(shard.sessions, session.ID)
+				removed = append(removed, session)
+			}
 		}
+		m.unlock(shard, acquired)
+	}
+
+	m.unindexSessions(removed)
+	if len(removed) > 0 {
+		m.markDirty()
 	}
+	return removed
 }
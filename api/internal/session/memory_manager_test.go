@@ -2,11 +2,34 @@ package session
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 )
 
+// writeFakeCursorAgent writes an executable shell script standing in for cursor-agent
+func writeFakeCursorAgent(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cursor-agent")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("failed to write fake cursor-agent: %v", err)
+	}
+	return path
+}
+
+// fakeModelArgParser extracts the --model value into $model in fake scripts
+const fakeModelArgParser = `model=""
+while [ $# -gt 0 ]; do
+  if [ "$1" = "--model" ]; then model="$2"; fi
+  shift
+done
+`
+
 func TestCreateSession(t *testing.T) {
 	manager := NewMemorySessionManager()
 
@@ -207,7 +230,7 @@ func TestAskQuestion(t *testing.T) {
 
 	t.Run("returns error for non-existent session", func(t *testing.T) {
 		ctx := context.Background()
-		_, _, err := manager.AskQuestion(ctx, "non-existent-id", "test question", "/tmp")
+		_, _, _, err := manager.AskQuestion(ctx, "non-existent-id", "test question", "/tmp")
 		if err == nil {
 			t.Error("expected error for non-existent session")
 		}
@@ -215,6 +238,140 @@ func TestAskQuestion(t *testing.T) {
 
 	// Note: Full integration test with actual cursor-agent would require cursor-agent to be installed
 	// and would be slow, so we skip it in unit tests. The method is tested via integration tests.
+
+	t.Run("falls back to next model on model error", func(t *testing.T) {
+		logFile := filepath.Join(t.TempDir(), "invocations")
+		fake := writeFakeCursorAgent(t, fakeModelArgParser+`echo "$model" >> `+logFile+`
+if [ "$model" != "sonnet-4" ]; then
+  echo "Error: model '$model' is not available for your plan" >&2
+  exit 1
+fi
+echo '{"type":"result","is_error":false,"result":"answer from sonnet-4","session_id":"chat-1"}'
+`)
+		fallbackManager := NewMemorySessionManager(
+			WithCursorAgentPath(fake),
+			WithFallbackModels([]string{"gpt-5", "sonnet-4"}),
+		)
+		session, _ := fallbackManager.CreateSession()
+
+		answer, chatID, _, err := fallbackManager.AskQuestion(context.Background(), session.ID, "hi", t.TempDir())
+		if err != nil {
+			t.Fatalf("expected fallback to succeed, got %v", err)
+		}
+		if answer != "answer from sonnet-4" {
+			t.Errorf("unexpected answer: %q", answer)
+		}
+		if chatID != "chat-1" {
+			t.Errorf("unexpected cursor chat ID: %q", chatID)
+		}
+
+		// The default attempt has an empty model, which Fields drops
+		invocations, _ := os.ReadFile(logFile)
+		if got := strings.Fields(string(invocations)); len(got) != 2 || got[0] != "gpt-5" || got[1] != "sonnet-4" {
+			t.Errorf("expected attempts [default gpt-5 sonnet-4], got %q", string(invocations))
+		}
+	})
+
+	t.Run("does not fall back on non-model errors", func(t *testing.T) {
+		logFile := filepath.Join(t.TempDir(), "invocations")
+		fake := writeFakeCursorAgent(t, `echo attempt >> `+logFile+`
+echo "network unreachable" >&2
+exit 1
+`)
+		fallbackManager := NewMemorySessionManager(
+			WithCursorAgentPath(fake),
+			WithFallbackModels([]string{"gpt-5"}),
+		)
+		session, _ := fallbackManager.CreateSession()
+
+		_, _, _, err := fallbackManager.AskQuestion(context.Background(), session.ID, "hi", t.TempDir())
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		invocations, _ := os.ReadFile(logFile)
+		if count := strings.Count(string(invocations), "attempt"); count != 1 {
+			t.Errorf("expected a single attempt, got %d", count)
+		}
+	})
+
+	t.Run("returns last error when all models fail", func(t *testing.T) {
+		fake := writeFakeCursorAgent(t, `echo "model unavailable" >&2
+exit 1
+`)
+		fallbackManager := NewMemorySessionManager(
+			WithCursorAgentPath(fake),
+			WithFallbackModels([]string{"gpt-5"}),
+		)
+		session, _ := fallbackManager.CreateSession()
+
+		_, _, _, err := fallbackManager.AskQuestion(context.Background(), session.ID, "hi", t.TempDir())
+		if err == nil || !strings.Contains(err.Error(), "model unavailable") {
+			t.Errorf("expected model error, got %v", err)
+		}
+	})
+}
+
+func TestAskQuestion_MaxCursorOutputBytes(t *testing.T) {
+	t.Run("returns bounded error when output exceeds the limit", func(t *testing.T) {
+		// A single enormous JSON line, well past the 1KB limit
+		fake := writeFakeCursorAgent(t, `printf '{"type":"result","is_error":false,"result":"'
+head -c 65536 /dev/zero | tr '\0' 'a'
+printf '","session_id":"chat-1"}'
+`)
+		manager := NewMemorySessionManager(
+			WithCursorAgentPath(fake),
+			WithMaxCursorOutputBytes(1024),
+		)
+		session, _ := manager.CreateSession()
+
+		_, _, _, err := manager.AskQuestion(context.Background(), session.ID, "hi", t.TempDir())
+		if !errors.Is(err, ErrCursorOutputTooLarge) {
+			t.Fatalf("expected ErrCursorOutputTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("accepts output within the limit", func(t *testing.T) {
+		fake := writeFakeCursorAgent(t, `echo '{"type":"result","is_error":false,"result":"short answer","session_id":"chat-1"}'
+`)
+		manager := NewMemorySessionManager(
+			WithCursorAgentPath(fake),
+			WithMaxCursorOutputBytes(1024),
+		)
+		session, _ := manager.CreateSession()
+
+		answer, _, _, err := manager.AskQuestion(context.Background(), session.ID, "hi", t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if answer != "short answer" {
+			t.Errorf("unexpected answer: %q", answer)
+		}
+	})
+}
+
+func TestBoundedBuffer(t *testing.T) {
+	t.Run("keeps output up to the limit and flags the rest", func(t *testing.T) {
+		buf := newBoundedBuffer(5)
+		n, err := buf.Write([]byte("hello world"))
+		if err != nil || n != 11 {
+			t.Fatalf("expected write to report full length without error, got %d, %v", n, err)
+		}
+		if buf.String() != "hello" {
+			t.Errorf("expected retained prefix, got %q", buf.String())
+		}
+		if !buf.Exceeded() {
+			t.Error("expected buffer to report exceeded")
+		}
+	})
+
+	t.Run("unbounded when limit is zero", func(t *testing.T) {
+		buf := newBoundedBuffer(0)
+		buf.Write([]byte("hello world"))
+		if buf.String() != "hello world" || buf.Exceeded() {
+			t.Errorf("expected unbounded write, got %q (exceeded=%v)", buf.String(), buf.Exceeded())
+		}
+	})
 }
 
 func TestAddToConversationLog(t *testing.T) {
@@ -352,6 +509,201 @@ func TestGetAllSessions(t *testing.T) {
 	})
 }
 
+func TestGetAllSessionsShallow(t *testing.T) {
+	manager := NewMemorySessionManager()
+
+	t.Run("returns empty slice when no sessions", func(t *testing.T) {
+		sessions := manager.GetAllSessionsShallow()
+		if len(sessions) != 0 {
+			t.Errorf("expected 0 sessions, got %d", len(sessions))
+		}
+	})
+
+	t.Run("omits conversation logs but keeps metadata", func(t *testing.T) {
+		created, _ := manager.CreateSession()
+		manager.UpdateCursorChatID(created.ID, "chat-1")
+		manager.AddToConversationLog(created.ID, []Message{
+			{Role: "user", Content: "question", Timestamp: time.Now()},
+			{Role: "assistant", Content: "answer", Timestamp: time.Now()},
+		})
+
+		sessions := manager.GetAllSessionsShallow()
+		if len(sessions) != 1 {
+			t.Fatalf("expected 1 session, got %d", len(sessions))
+		}
+
+		shallow := sessions[0]
+		if shallow.ID != created.ID {
+			t.Errorf("expected ID %s, got %s", created.ID, shallow.ID)
+		}
+		if shallow.CursorChatID != "chat-1" {
+			t.Errorf("expected cursor chat ID to be preserved, got %q", shallow.CursorChatID)
+		}
+		if !shallow.CreatedAt.Equal(created.CreatedAt) || shallow.LastActivity.IsZero() {
+			t.Error("expected timestamps to be preserved")
+		}
+		if len(shallow.ConversationLog) != 0 {
+			t.Errorf("expected conversation log to be omitted, got %d messages", len(shallow.ConversationLog))
+		}
+
+		// The full listing still carries the log
+		full, _ := manager.GetSession(created.ID)
+		if len(full.ConversationLog) != 2 {
+			t.Errorf("expected stored log to be intact, got %d messages", len(full.ConversationLog))
+		}
+	})
+}
+
+func TestLongConversationThreshold(t *testing.T) {
+	exchange := []Message{
+		{Role: "user", Content: "question", Timestamp: time.Now()},
+		{Role: "assistant", Content: "answer", Timestamp: time.Now()},
+	}
+
+	t.Run("flag flips once the log exceeds the threshold", func(t *testing.T) {
+		manager := NewMemorySessionManager(WithLongConversationThreshold(3))
+		created, _ := manager.CreateSession()
+
+		manager.AddToConversationLog(created.ID, exchange)
+		if sess, _ := manager.GetSession(created.ID); sess.LongConversation {
+			t.Error("expected flag to be unset at 2 messages")
+		}
+
+		manager.AddToConversationLog(created.ID, exchange)
+		if sess, _ := manager.GetSession(created.ID); !sess.LongConversation {
+			t.Error("expected flag to be set at 4 messages")
+		}
+
+		shallow := manager.GetAllSessionsShallow()
+		if len(shallow) != 1 || !shallow[0].LongConversation {
+			t.Error("expected shallow copies to carry the flag")
+		}
+	})
+
+	t.Run("exactly at the threshold is not long", func(t *testing.T) {
+		manager := NewMemorySessionManager(WithLongConversationThreshold(2))
+		created, _ := manager.CreateSession()
+
+		manager.AddToConversationLog(created.ID, exchange)
+		if sess, _ := manager.GetSession(created.ID); sess.LongConversation {
+			t.Error("expected flag to be unset at the threshold")
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		manager := NewMemorySessionManager()
+		created, _ := manager.CreateSession()
+
+		for i := 0; i < 10; i++ {
+			manager.AddToConversationLog(created.ID, exchange)
+		}
+		if sess, _ := manager.GetSession(created.ID); sess.LongConversation {
+			t.Error("expected flag to stay unset without a threshold")
+		}
+	})
+}
+
+func TestMaxConversationBytes(t *testing.T) {
+	message := func(content string) Message {
+		return Message{Role: "user", Content: content, Timestamp: time.Now()}
+	}
+
+	t.Run("appending large messages trims the oldest", func(t *testing.T) {
+		manager := NewMemorySessionManager(WithMaxConversationBytes(100))
+		created, _ := manager.CreateSession()
+
+		manager.AddToConversationLog(created.ID, []Message{message(strings.Repeat("a", 40)), message(strings.Repeat("b", 40))})
+		manager.AddToConversationLog(created.ID, []Message{message(strings.Repeat("c", 40))})
+
+		sess, _ := manager.GetSession(created.ID)
+		if len(sess.ConversationLog) != 2 {
+			t.Fatalf("expected oldest message to be trimmed, got %d messages", len(sess.ConversationLog))
+		}
+		if sess.ConversationLog[0].Content[0] != 'b' || sess.ConversationLog[1].Content[0] != 'c' {
+			t.Errorf("expected the two newest messages to remain, got %q and %q",
+				sess.ConversationLog[0].Content[:1], sess.ConversationLog[1].Content[:1])
+		}
+	})
+
+	t.Run("keeps the newest message even if it alone exceeds the limit", func(t *testing.T) {
+		manager := NewMemorySessionManager(WithMaxConversationBytes(10))
+		created, _ := manager.CreateSession()
+
+		manager.AddToConversationLog(created.ID, []Message{message("short"), message(strings.Repeat("x", 50))})
+
+		sess, _ := manager.GetSession(created.ID)
+		if len(sess.ConversationLog) != 1 || len(sess.ConversationLog[0].Content) != 50 {
+			t.Errorf("expected only the newest message to remain, got %d messages", len(sess.ConversationLog))
+		}
+	})
+
+	t.Run("does not trim within the limit", func(t *testing.T) {
+		manager := NewMemorySessionManager(WithMaxConversationBytes(100))
+		created, _ := manager.CreateSession()
+
+		manager.AddToConversationLog(created.ID, []Message{message("question"), message("answer")})
+
+		sess, _ := manager.GetSession(created.ID)
+		if len(sess.ConversationLog) != 2 {
+			t.Errorf("expected 2 messages, got %d", len(sess.ConversationLog))
+		}
+	})
+}
+
+func TestMaxStoredAnswers(t *testing.T) {
+	exchange := func(n int) []Message {
+		at := time.Date(2025, 1, 2, 9, n, 0, 0, time.UTC)
+		return []Message{
+			{Role: "user", Content: fmt.Sprintf("question %d", n), Timestamp: at},
+			{Role: "assistant", Content: fmt.Sprintf("answer %d", n), Timestamp: at.Add(time.Second)},
+		}
+	}
+
+	t.Run("placeholders old answers and keeps questions", func(t *testing.T) {
+		manager := NewMemorySessionManager(WithMaxStoredAnswers(2))
+		created, _ := manager.CreateSession()
+
+		for n := 1; n <= 4; n++ {
+			manager.AddToConversationLog(created.ID, exchange(n))
+		}
+
+		sess, _ := manager.GetSession(created.ID)
+		if len(sess.ConversationLog) != 8 {
+			t.Fatalf("expected all 8 messages to be kept, got %d", len(sess.ConversationLog))
+		}
+		for n := 1; n <= 4; n++ {
+			question, answer := sess.ConversationLog[2*n-2], sess.ConversationLog[2*n-1]
+			if question.Content != fmt.Sprintf("question %d", n) {
+				t.Errorf("expected question %d intact, got %q", n, question.Content)
+			}
+			want := fmt.Sprintf("answer %d", n)
+			if n <= 2 {
+				want = TrimmedAnswerPlaceholder
+			}
+			if answer.Content != want {
+				t.Errorf("expected answer %d to be %q, got %q", n, want, answer.Content)
+			}
+			if !answer.Timestamp.Equal(exchange(n)[1].Timestamp) {
+				t.Errorf("expected answer %d to keep its timestamp, got %v", n, answer.Timestamp)
+			}
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		manager := NewMemorySessionManager()
+		created, _ := manager.CreateSession()
+
+		for n := 1; n <= 3; n++ {
+			manager.AddToConversationLog(created.ID, exchange(n))
+		}
+
+		sess, _ := manager.GetSession(created.ID)
+		if sess.ConversationLog[1].Content != "answer 1" {
+			t.Errorf("expected answers to be kept, got %q", sess.ConversationLog[1].Content)
+		}
+	})
+}
+
 func TestCleanupInactiveSessions(t *testing.T) {
 	manager := NewMemorySessionManager()
 
@@ -395,6 +747,32 @@ func TestCleanupInactiveSessions(t *testing.T) {
 		}
 	})
 
+	t.Run("honors per-session timeouts", func(t *testing.T) {
+		manager := NewMemorySessionManager()
+		short, _ := manager.CreateSessionWithOptions(SessionOptions{Timeout: 5 * time.Millisecond})
+		long, _ := manager.CreateSessionWithOptions(SessionOptions{Timeout: time.Hour})
+		global, _ := manager.CreateSession()
+		time.Sleep(10 * time.Millisecond)
+
+		// The short session expires on its own schedule, well before the global timeout
+		manager.CleanupInactiveSessions(time.Minute)
+		if _, err := manager.GetSession(short.ID); err == nil {
+			t.Error("expected session with a short timeout to be removed")
+		}
+		if _, err := manager.GetSession(global.ID); err != nil {
+			t.Error("expected session without a timeout to follow the global timeout")
+		}
+
+		// The long session outlives a global timeout that reaps everything else
+		manager.CleanupInactiveSessions(time.Millisecond)
+		if _, err := manager.GetSession(long.ID); err != nil {
+			t.Error("expected session with a long timeout to be kept")
+		}
+		if _, err := manager.GetSession(global.ID); err == nil {
+			t.Error("expected session without a timeout to be removed")
+		}
+	})
+
 	t.Run("handles empty session map", func(t *testing.T) {
 		emptyManager := NewMemorySessionManager()
 		// Should not panic
@@ -527,3 +905,210 @@ func TestThreadSafety(t *testing.T) {
 		// If we reach here without deadlock or panic, thread safety is good
 	})
 }
+
+func TestAskQuestion_Stdin(t *testing.T) {
+	// Echoes stdin as the answer and records the arguments it was given
+	newEchoAgent := func(t *testing.T) (string, string) {
+		argsFile := filepath.Join(t.TempDir(), "args")
+		fake := writeFakeCursorAgent(t, `echo "$#:$*" > `+argsFile+`
+prompt=$(cat)
+printf '{"type":"result","is_error":false,"result":"%s","session_id":"chat-1"}' "$prompt"
+`)
+		return fake, argsFile
+	}
+
+	t.Run("delivers the question on stdin when enabled", func(t *testing.T) {
+		fake, argsFile := newEchoAgent(t)
+		manager := NewMemorySessionManager(
+			WithCursorAgentPath(fake),
+			WithCursorAgentStdin(true, 0),
+		)
+		session, _ := manager.CreateSession()
+
+		answer, _, _, err := manager.AskQuestion(context.Background(), session.ID, "what does main do", t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if answer != "what does main do" {
+			t.Errorf("expected question echoed from stdin, got %q", answer)
+		}
+		args, _ := os.ReadFile(argsFile)
+		if strings.Contains(string(args), "what does main do") {
+			t.Errorf("expected question to be left out of the arguments, got %q", string(args))
+		}
+	})
+
+	t.Run("switches to stdin once the question reaches the threshold", func(t *testing.T) {
+		fake, _ := newEchoAgent(t)
+		manager := NewMemorySessionManager(
+			WithCursorAgentPath(fake),
+			WithCursorAgentStdin(false, 16),
+		)
+		session, _ := manager.CreateSession()
+		question := strings.Repeat("q", 16)
+
+		answer, _, _, err := manager.AskQuestion(context.Background(), session.ID, question, t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if answer != question {
+			t.Errorf("expected question echoed from stdin, got %q", answer)
+		}
+	})
+
+	t.Run("passes short questions as an argument", func(t *testing.T) {
+		fake, argsFile := newEchoAgent(t)
+		manager := NewMemorySessionManager(
+			WithCursorAgentPath(fake),
+			WithCursorAgentStdin(false, 16),
+		)
+		session, _ := manager.CreateSession()
+
+		answer, _, _, err := manager.AskQuestion(context.Background(), session.ID, "short", t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if answer != "" {
+			t.Errorf("expected empty stdin, got %q", answer)
+		}
+		args, _ := os.ReadFile(argsFile)
+		if !strings.HasSuffix(strings.TrimSpace(string(args)), " short") {
+			t.Errorf("expected question as the last argument, got %q", string(args))
+		}
+	})
+}
+
+func TestGetOrCreateByKey(t *testing.T) {
+	t.Run("creates once then returns the same session", func(t *testing.T) {
+		manager := NewMemorySessionManager()
+
+		first, created, err := manager.GetOrCreateByKey("device-1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !created {
+			t.Error("expected first call to create a session")
+		}
+		if first.ExternalKey != "device-1" {
+			t.Errorf("expected external key on session, got %q", first.ExternalKey)
+		}
+
+		second, created, err := manager.GetOrCreateByKey("device-1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if created {
+			t.Error("expected second call to reuse the session")
+		}
+		if second.ID != first.ID {
+			t.Errorf("expected session %s, got %s", first.ID, second.ID)
+		}
+
+		other, created, _ := manager.GetOrCreateByKey("device-2")
+		if !created || other.ID == first.ID {
+			t.Error("expected a different key to get its own session")
+		}
+	})
+
+	t.Run("creates a new session after the keyed one ends", func(t *testing.T) {
+		manager := NewMemorySessionManager()
+		first, _, _ := manager.GetOrCreateByKey("device-1")
+		manager.EndSession(first.ID)
+
+		second, created, _ := manager.GetOrCreateByKey("device-1")
+		if !created || second.ID == first.ID {
+			t.Error("expected a fresh session once the previous one ended")
+		}
+	})
+
+	t.Run("drops the key index when sessions are cleaned up", func(t *testing.T) {
+		manager := NewMemorySessionManager().(*MemorySessionManager)
+		manager.GetOrCreateByKey("device-1")
+		manager.CleanupInactiveSessions(0)
+
+		if len(manager.externalKeys) != 0 {
+			t.Errorf("expected key index to be empty, got %v", manager.externalKeys)
+		}
+	})
+
+	t.Run("concurrent calls create a single session", func(t *testing.T) {
+		manager := NewMemorySessionManager()
+		var wg sync.WaitGroup
+		var createdCount sync.Map
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if _, created, _ := manager.GetOrCreateByKey("device-1"); created {
+					createdCount.Store(i, true)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		count := 0
+		createdCount.Range(func(_, _ any) bool { count++; return true })
+		if count != 1 {
+			t.Errorf("expected exactly one creation, got %d", count)
+		}
+		if sessions := manager.GetAllSessions(); len(sessions) != 1 {
+			t.Errorf("expected 1 session, got %d", len(sessions))
+		}
+	})
+
+	t.Run("rejects an empty key", func(t *testing.T) {
+		manager := NewMemorySessionManager()
+		if _, _, err := manager.GetOrCreateByKey(""); err == nil {
+			t.Error("expected error for empty key")
+		}
+	})
+}
+
+func TestAskQuestionInMode(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	fake := writeFakeCursorAgent(t, `echo "$*" > `+argsFile+`
+echo '{"type":"result","is_error":false,"result":"summary","session_id":"chat-1"}'
+`)
+	manager := NewMemorySessionManager(
+		WithCursorAgentPath(fake),
+		WithCursorAgentModes(map[string][]string{"summarize": {"summarize", "--brief"}}),
+	).(*MemorySessionManager)
+	session, _ := manager.CreateSession()
+
+	t.Run("configured mode prepends its arguments", func(t *testing.T) {
+		answer, chatID, _, err := manager.AskQuestionInMode(context.Background(), session.ID, "summarize", "the last hour", t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if answer != "summary" || chatID != "chat-1" {
+			t.Errorf("unexpected result: %q, %q", answer, chatID)
+		}
+		args, _ := os.ReadFile(argsFile)
+		if got := strings.TrimSpace(string(args)); got != "summarize --brief --print --output-format json the last hour" {
+			t.Errorf("unexpected cursor-agent args: %q", got)
+		}
+	})
+
+	t.Run("unknown mode is rejected", func(t *testing.T) {
+		_, _, _, err := manager.AskQuestionInMode(context.Background(), session.ID, "deploy", "the last hour", t.TempDir())
+		if !errors.Is(err, ErrUnknownMode) {
+			t.Errorf("expected ErrUnknownMode, got %v", err)
+		}
+	})
+}
+
+func TestAskQuestion_CursorAgentError(t *testing.T) {
+	script := `echo '{"type":"result","subtype":"rate_limit","is_error":true,"result":"Too many requests"}'`
+	manager := NewMemorySessionManager(WithCursorAgentPath(writeFakeCursorAgent(t, script)))
+	session, _ := manager.CreateSession()
+
+	_, _, _, err := manager.AskQuestion(context.Background(), session.ID, "hi", t.TempDir())
+
+	var agentErr *CursorAgentError
+	if !errors.As(err, &agentErr) {
+		t.Fatalf("expected a CursorAgentError, got %v", err)
+	}
+	if agentErr.Subtype != "rate_limit" || agentErr.Message != "Too many requests" {
+		t.Errorf("expected subtype and message from the result, got %+v", agentErr)
+	}
+}
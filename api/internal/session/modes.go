@@ -0,0 +1,27 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownMode is returned by AskQuestionInMode for a mode that isn't configured
+var ErrUnknownMode = errors.New("unknown cursor-agent mode")
+
+// ModeManager is implemented by managers that can run cursor-agent operations
+// other than plain chat, such as a "summarize" subcommand
+type ModeManager interface {
+	AskQuestionInMode(ctx context.Context, id string, mode string, question string, workspaceDir string) (answer string, cursorChatID string, resumed bool, err error)
+}
+
+// AskQuestionInMode asks a question like AskQuestion, but with the configured
+// arguments for mode placed before the usual chat flags. The session's cursor
+// chat is resumed and fallback models apply as for a plain ask.
+func (m *MemorySessionManager) AskQuestionInMode(ctx context.Context, id string, mode string, question string, workspaceDir string) (string, string, bool, error) {
+	modeArgs, ok := m.cursorAgentModes[mode]
+	if !ok {
+		return "", "", false, fmt.Errorf("%w: %q", ErrUnknownMode, mode)
+	}
+	return m.askQuestion(ctx, id, modeArgs, question, workspaceDir)
+}
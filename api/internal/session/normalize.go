@@ -0,0 +1,15 @@
+package session
+
+import "strings"
+
+// NormalizeQuestion returns a canonical form of a question for cache keys and
+// analytics: surrounding whitespace is trimmed and internal runs of whitespace
+// collapse to a single space. With lowercase, the result is also case-folded.
+// The original question is what gets sent to cursor-agent and logged.
+func NormalizeQuestion(question string, lowercase bool) string {
+	normalized := strings.Join(strings.Fields(question), " ")
+	if lowercase {
+		normalized = strings.ToLower(normalized)
+	}
+	return normalized
+}
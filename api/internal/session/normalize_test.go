@@ -0,0 +1,27 @@
+package session
+
+import "testing"
+
+func TestNormalizeQuestion(t *testing.T) {
+	tests := []struct {
+		name      string
+		question  string
+		lowercase bool
+		expected  string
+	}{
+		{"trims surrounding whitespace", "  what is this?  ", false, "what is this?"},
+		{"collapses internal whitespace", "what   is\tthis\n\nfile?", false, "what is this file?"},
+		{"preserves case by default", "What Is Main.go?", false, "What Is Main.go?"},
+		{"lowercases when enabled", "What Is Main.go?", true, "what is main.go?"},
+		{"empty stays empty", " \t\n ", true, ""},
+		{"unicode whitespace collapses", "hello  world", false, "hello world"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeQuestion(tt.question, tt.lowercase); got != tt.expected {
+				t.Errorf("NormalizeQuestion(%q, %v) = %q, want %q", tt.question, tt.lowercase, got, tt.expected)
+			}
+		})
+	}
+}
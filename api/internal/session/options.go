@@ -0,0 +1,156 @@
+package session
+
+import "time"
+
+// Option configures optional behavior of a MemorySessionManager
+type Option func(*MemorySessionManager)
+
+// WithLockMetrics enables instrumentation of the manager's mutex.
+// Disabled by default to avoid the timing overhead on every lock acquisition.
+func WithLockMetrics(enabled bool) Option {
+	return func(m *MemorySessionManager) {
+		m.lockMetrics.enabled = enabled
+	}
+}
+
+// WithCursorAgentPath sets the cursor-agent executable to invoke
+func WithCursorAgentPath(path string) Option {
+	return func(m *MemorySessionManager) {
+		if path != "" {
+			m.cursorAgentPath = path
+		}
+	}
+}
+
+// WithFallbackModels sets an ordered list of models to retry with when
+// cursor-agent fails because the requested model is unavailable
+func WithFallbackModels(models []string) Option {
+	return func(m *MemorySessionManager) {
+		m.fallbackModels = models
+	}
+}
+
+// WithLongConversationThreshold flags sessions whose conversation log grows
+// past the given number of messages. Zero disables the flag.
+func WithLongConversationThreshold(messages int) Option {
+	return func(m *MemorySessionManager) {
+		m.longConversationThreshold = messages
+	}
+}
+
+// WithMaxCursorOutputBytes bounds how much cursor-agent stdout is captured per
+// ask. Responses over the limit fail with ErrCursorOutputTooLarge; zero disables the bound.
+func WithMaxCursorOutputBytes(limit int) Option {
+	return func(m *MemorySessionManager) {
+		m.maxCursorOutputBytes = limit
+	}
+}
+
+// WithMaxConversationBytes bounds the total message content kept per session,
+// trimming the oldest messages once exceeded. Zero disables the bound.
+func WithMaxConversationBytes(limit int) Option {
+	return func(m *MemorySessionManager) {
+		m.maxConversationBytes = limit
+	}
+}
+
+// WithMaxStoredAnswers keeps the full text of only the newest limit assistant
+// answers per session, replacing older ones with TrimmedAnswerPlaceholder while
+// questions and timestamps are kept. Zero disables the limit.
+func WithMaxStoredAnswers(limit int) Option {
+	return func(m *MemorySessionManager) {
+		m.maxStoredAnswers = limit
+	}
+}
+
+// WithCursorAgentStdin sends questions to cursor-agent on stdin instead of as a
+// positional argument, either always or once they reach threshold bytes.
+// A zero threshold disables the length-based switch.
+func WithCursorAgentStdin(always bool, threshold int) Option {
+	return func(m *MemorySessionManager) {
+		m.cursorAgentUseStdin = always
+		m.cursorAgentStdinThreshold = threshold
+	}
+}
+
+// WithCursorAgentModes sets the named cursor-agent modes available to
+// AskQuestionInMode. Each mode's arguments are placed before the usual chat flags.
+func WithCursorAgentModes(modes map[string][]string) Option {
+	return func(m *MemorySessionManager) {
+		m.cursorAgentModes = modes
+	}
+}
+
+// WithArchiver stores each session's transcript through archiver when the session ends
+func WithArchiver(archiver Archiver) Option {
+	return func(m *MemorySessionManager) {
+		m.archiver = archiver
+	}
+}
+
+// WithPersistentCursorProcess keeps one long-lived cursor-agent per session that
+// answers successive questions over stdin/stdout instead of spawning a process
+// per ask. Mode asks still spawn a process; fallback models are not retried.
+func WithPersistentCursorProcess(enabled bool) Option {
+	return func(m *MemorySessionManager) {
+		m.persistentCursorProcess = enabled
+	}
+}
+
+// WithSessionShards splits session storage across count maps, each with its own
+// lock, so busy deployments contend less. Values below 1 mean one shard.
+func WithSessionShards(count int) Option {
+	return func(m *MemorySessionManager) {
+		m.shardCount = count
+	}
+}
+
+// WithInitialSessionCapacity pre-sizes session storage for capacity sessions so
+// bursts of session creation don't trigger map growth
+func WithInitialSessionCapacity(capacity int) Option {
+	return func(m *MemorySessionManager) {
+		m.initialCapacity = capacity
+	}
+}
+
+// WithPersistence saves sessions to path, rewriting it debounce after each
+// change, and restores them from it on startup. An empty path disables persistence.
+func WithPersistence(path string, debounce time.Duration) Option {
+	return func(m *MemorySessionManager) {
+		if path == "" {
+			m.persister = nil
+			return
+		}
+		m.persister = &sessionPersister{path: path, debounce: debounce}
+	}
+}
+
+// WithSerializedAsks runs one question at a time per session, so overlapping
+// asks can't interleave in the resumed cursor chat. A second question waits for
+// the first, or fails with ErrSessionBusy when reject is set. Questions for
+// different sessions still run in parallel.
+func WithSerializedAsks(enabled bool, reject bool) Option {
+	return func(m *MemorySessionManager) {
+		m.serializeAsks = enabled
+		m.rejectConcurrentAsks = reject
+	}
+}
+
+// WithMaxConcurrentAsks bounds how many asks run cursor-agent at once across
+// all sessions. Asks over the limit queue and are admitted in arrival order.
+// Zero disables the limit.
+func WithMaxConcurrentAsks(limit int) Option {
+	return func(m *MemorySessionManager) {
+		m.askSlots = nil
+		if limit > 0 {
+			m.askSlots = newFIFOSemaphore(limit)
+		}
+	}
+}
+
+// WithUsageSummaries logs each session's UsageSummary when it is ended or reaped
+func WithUsageSummaries(enabled bool) Option {
+	return func(m *MemorySessionManager) {
+		m.usageSummaries = enabled
+	}
+}
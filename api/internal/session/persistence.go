@@ -0,0 +1,172 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sean/janus/internal/logger"
+)
+
+const (
+	// SessionsFileName is the file sessions are persisted to inside the context directory
+	SessionsFileName = "sessions.json"
+
+	// sessionsFileVersion identifies the persisted file layout
+	sessionsFileVersion = 1
+)
+
+// sessionsFile is the on-disk layout of persisted sessions
+type sessionsFile struct {
+	Version  int        `json:"version"`
+	Sessions []*Session `json:"sessions"`
+}
+
+// sessionPersister writes the manager's sessions to a JSON file, debouncing so
+// a burst of changes produces a single write
+type sessionPersister struct {
+	path     string
+	debounce time.Duration
+	// snapshot returns the sessions to write
+	snapshot func() []*Session
+
+	mu    sync.Mutex
+	timer *time.Timer
+	// writeMu serializes writes so an older snapshot never replaces a newer one
+	writeMu sync.Mutex
+}
+
+// markDirty schedules a write debounce from now unless one is already pending
+func (p *sessionPersister) markDirty() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.timer == nil {
+		p.timer = time.AfterFunc(p.debounce, p.flushPending)
+	}
+}
+
+// flushPending writes sessions for the scheduled change
+func (p *sessionPersister) flushPending() {
+	p.mu.Lock()
+	p.timer = nil
+	p.mu.Unlock()
+
+	if err := p.write(); err != nil {
+		logger.Get().Error().Err(err).Str("path", p.path).Msg("Failed to persist sessions")
+	}
+}
+
+// flush cancels any pending write and writes sessions now
+func (p *sessionPersister) flush() error {
+	p.mu.Lock()
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	p.mu.Unlock()
+
+	return p.write()
+}
+
+// write replaces the sessions file with the current snapshot. It writes a
+// temporary file and renames it over the old one so a crash mid-write never
+// leaves a truncated file.
+func (p *sessionPersister) write() error {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	data, err := json.Marshal(sessionsFile{Version: sessionsFileVersion, Sessions: p.snapshot()})
+	if err != nil {
+		return fmt.Errorf("failed to encode sessions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write sessions: %w", err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return fmt.Errorf("failed to replace sessions file: %w", err)
+	}
+	return nil
+}
+
+// loadSessions reads sessions persisted at path. A missing file yields no
+// sessions; an unreadable or corrupt one is reported as an error.
+func loadSessions(path string) ([]*Session, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions file: %w", err)
+	}
+
+	var file sessionsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse sessions file: %w", err)
+	}
+	if file.Version != sessionsFileVersion {
+		return nil, fmt.Errorf("unsupported sessions file version %d", file.Version)
+	}
+	return file.Sessions, nil
+}
+
+// restoreSessions loads persisted sessions into the manager's shards. Restored
+// sessions start a fresh inactivity window, since heartbeats aren't persisted
+// and time spent down shouldn't count against them. A corrupt file is logged
+// and ignored so the server starts with no sessions rather than failing.
+func (m *MemorySessionManager) restoreSessions() {
+	log := logger.Get()
+
+	sessions, err := loadSessions(m.persister.path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", m.persister.path).Msg("Ignoring unreadable sessions file, starting fresh")
+		return
+	}
+
+	now := time.Now()
+	restored := 0
+	for _, session := range sessions {
+		if session == nil || session.ID == "" {
+			continue
+		}
+		session.LastActivity = now
+		if session.ConversationLog == nil {
+			session.ConversationLog = make([]Message, 0)
+		}
+
+		m.shardFor(session.ID).sessions[session.ID] = session
+		if session.ExternalKey != "" {
+			m.externalKeys[session.ExternalKey] = session.ID
+		}
+		restored++
+	}
+
+	if restored > 0 {
+		log.Info().Int("sessions", restored).Str("path", m.persister.path).Msg("Restored persisted sessions")
+	}
+}
+
+// markDirty schedules the sessions file to be rewritten when persistence is enabled
+func (m *MemorySessionManager) markDirty() {
+	if m.persister != nil {
+		m.persister.markDirty()
+	}
+}
+
+// Flush writes sessions to disk immediately when persistence is enabled,
+// skipping the debounce. Call it on shutdown so recent changes aren't lost.
+func (m *MemorySessionManager) Flush() error {
+	if m.persister == nil {
+		return nil
+	}
+	return m.persister.flush()
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPersistence_RestoresSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SessionsFileName)
+	manager := NewMemorySessionManager(WithPersistence(path, time.Hour)).(*MemorySessionManager)
+
+	kept, _ := manager.CreateSessionWithOptions(SessionOptions{Branch: "feature/x", Timeout: 30 * time.Minute})
+	manager.UpdateCursorChatID(kept.ID, "chat-123")
+	manager.AddToConversationLog(kept.ID, []Message{
+		{Role: "user", Content: "question", Timestamp: time.Now()},
+		{Role: "assistant", Content: "answer", Timestamp: time.Now()},
+	})
+	keyed, _, _ := manager.GetOrCreateByKey("device-1")
+	ended, _ := manager.CreateSession()
+	manager.EndSession(ended.ID)
+
+	if err := manager.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	restored := NewMemorySessionManager(WithPersistence(path, time.Hour))
+
+	session, err := restored.GetSession(kept.ID)
+	if err != nil {
+		t.Fatalf("expected session to be restored: %v", err)
+	}
+	if session.CursorChatID != "chat-123" {
+		t.Errorf("expected cursor chat ID chat-123, got %q", session.CursorChatID)
+	}
+	if len(session.ConversationLog) != 2 || session.ConversationLog[1].Content != "answer" {
+		t.Errorf("expected conversation log to be restored, got %+v", session.ConversationLog)
+	}
+	if session.Branch != "feature/x" || session.Timeout != 30*time.Minute {
+		t.Errorf("expected session options to be restored, got %+v", session)
+	}
+	if _, err := restored.GetSession(ended.ID); err == nil {
+		t.Error("expected an ended session not to be restored")
+	}
+
+	again, created, _ := restored.GetOrCreateByKey("device-1")
+	if created || again.ID != keyed.ID {
+		t.Errorf("expected external key to map to restored session %s, got %s (created %v)", keyed.ID, again.ID, created)
+	}
+}
+
+func TestPersistence_DebouncesWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SessionsFileName)
+	manager := NewMemorySessionManager(WithPersistence(path, 50*time.Millisecond))
+
+	session, _ := manager.CreateSession()
+	for i := 0; i < 10; i++ {
+		manager.AddToConversationLog(session.ID, []Message{{Role: "user", Content: "hi"}})
+		manager.UpdateActivity(session.ID)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected no write before the debounce elapses")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected sessions to be written after the debounce")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	restored := NewMemorySessionManager(WithPersistence(path, time.Hour))
+	if got, err := restored.GetSession(session.ID); err != nil || len(got.ConversationLog) != 10 {
+		t.Errorf("expected all batched changes in one write, got %+v (err %v)", got, err)
+	}
+}
+
+func TestPersistence_CorruptFileStartsFresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SessionsFileName)
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatalf("failed to write corrupt file: %v", err)
+	}
+
+	manager := NewMemorySessionManager(WithPersistence(path, time.Hour))
+
+	if sessions := manager.GetAllSessions(); len(sessions) != 0 {
+		t.Errorf("expected no sessions from a corrupt file, got %d", len(sessions))
+	}
+	if _, err := manager.CreateSession(); err != nil {
+		t.Errorf("expected the manager to keep working: %v", err)
+	}
+}
+
+func TestPersistence_DisabledWithoutPath(t *testing.T) {
+	manager := NewMemorySessionManager(WithPersistence("", time.Millisecond)).(*MemorySessionManager)
+	manager.CreateSession()
+
+	if manager.persister != nil {
+		t.Error("expected persistence to be disabled")
+	}
+	if err := manager.Flush(); err != nil {
+		t.Errorf("expected Flush to be a no-op, got %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/sean/janus/internal/logger"
+	"github.com/sean/janus/internal/metrics"
+)
+
+// errCursorProcessExited is returned when a persistent cursor-agent exits mid-conversation
+var errCursorProcessExited = errors.New("persistent cursor-agent process exited")
+
+// persistentInput is the line written to a persistent cursor-agent for each question
+type persistentInput struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// cursorProcess is a long-lived cursor-agent that answers one question at a
+// time over stdin/stdout. Each question is a persistentInput JSON line; the
+// answer is the next line whose type is "result", and other lines (progress
+// events) are skipped.
+type cursorProcess struct {
+	cmd          *exec.Cmd
+	stdin        io.WriteCloser
+	workspaceDir string
+	// cursorChatID is the chat the process is resuming; guarded by the session's shard lock
+	cursorChatID string
+	// lines carries stdout lines from the reader goroutine; closed when stdout ends
+	lines chan []byte
+	// readErr is set before lines is closed when reading stdout failed
+	readErr error
+	// stopping is closed by close so the reader discards output instead of forwarding it
+	stopping chan struct{}
+	// exited is closed once the process has been waited on
+	exited chan struct{}
+	// mu serializes questions, since answers are matched to questions by order
+	mu        sync.Mutex
+	closeOnce sync.Once
+}
+
+// startCursorProcess launches cursor-agent with args in workspaceDir and begins
+// reading its stdout. Lines longer than maxLineBytes fail the process with
+// ErrCursorOutputTooLarge; zero leaves lines unbounded.
+func startCursorProcess(path string, args []string, workspaceDir string, maxLineBytes int) (*cursorProcess, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Dir = workspaceDir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cursor-agent stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cursor-agent stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start persistent cursor-agent: %w", err)
+	}
+
+	p := &cursorProcess{
+		cmd:          cmd,
+		stdin:        stdin,
+		workspaceDir: workspaceDir,
+		lines:        make(chan []byte),
+		stopping:     make(chan struct{}),
+		exited:       make(chan struct{}),
+	}
+	go p.readLines(stdout, maxLineBytes)
+	return p, nil
+}
+
+// readLines forwards stdout lines until the process closes its output, then reaps it
+func (p *cursorProcess) readLines(stdout io.Reader, maxLineBytes int) {
+	scanner := bufio.NewScanner(stdout)
+	if maxLineBytes > 0 {
+		scanner.Buffer(make([]byte, 0, 64<<10), maxLineBytes)
+	} else {
+		scanner.Buffer(make([]byte, 0, 64<<10), int(^uint(0)>>1))
+	}
+
+	forwarding := true
+	for scanner.Scan() {
+		if !forwarding {
+			continue
+		}
+		line := append([]byte(nil), scanner.Bytes()...)
+		select {
+		case p.lines <- line:
+		case <-p.stopping:
+			forwarding = false
+		}
+	}
+
+	if err := scanner.Err(); errors.Is(err, bufio.ErrTooLong) {
+		p.readErr = fmt.Errorf("%w (limit %d bytes)", ErrCursorOutputTooLarge, maxLineBytes)
+		p.cmd.Process.Kill()
+	} else if err != nil {
+		p.readErr = err
+	}
+	close(p.lines)
+	p.cmd.Wait()
+	close(p.exited)
+}
+
+// ask writes question to the process and waits for its result line. A
+// cancelled context kills the process, since its reply can no longer be matched.
+func (p *cursorProcess) ask(ctx context.Context, question string) (*CursorAgentResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	input, err := json.Marshal(persistentInput{Type: "user", Message: question})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode question: %w", err)
+	}
+	if _, err := p.stdin.Write(append(input, '\n')); err != nil {
+		return nil, fmt.Errorf("%w: %v", errCursorProcessExited, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.cmd.Process.Kill()
+			p.close()
+			return nil, fmt.Errorf("cursor-agent command cancelled: %w", ctx.Err())
+		case line, ok := <-p.lines:
+			if !ok {
+				if p.readErr != nil {
+					return nil, p.readErr
+				}
+				return nil, errCursorProcessExited
+			}
+
+			var response CursorAgentResponse
+			if err := json.Unmarshal(line, &response); err != nil {
+				return nil, fmt.Errorf("failed to parse cursor-agent response: %w, output: %s", err, line)
+			}
+			if response.Type != "result" {
+				continue
+			}
+			if response.IsError {
+				return nil, &CursorAgentError{Subtype: response.Subtype, Message: response.Result}
+			}
+			return &response, nil
+		}
+	}
+}
+
+// alive reports whether the process is still running
+func (p *cursorProcess) alive() bool {
+	select {
+	case <-p.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// usable reports whether p is running in workspaceDir. A nil p is not usable.
+func (p *cursorProcess) usable(workspaceDir string) bool {
+	return p != nil && p.alive() && p.workspaceDir == workspaceDir
+}
+
+// close ends the process by closing its stdin, killing it if it hasn't exited
+// within DefaultCursorProcessStopTimeout. Safe to call more than once.
+func (p *cursorProcess) close() {
+	p.closeOnce.Do(func() {
+		close(p.stopping)
+		p.stdin.Close()
+		select {
+		case <-p.exited:
+		case <-time.After(DefaultCursorProcessStopTimeout):
+			logger.Get().Warn().
+				Int("pid", p.cmd.Process.Pid).
+				Msg("Persistent cursor-agent did not exit after stdin closed, killing it")
+			p.cmd.Process.Kill()
+			<-p.exited
+		}
+	})
+}
+
+// buildPersistentCursorAgentArgs builds the arguments for a persistent
+// cursor-agent that reads questions from stdin
+func buildPersistentCursorAgentArgs(cursorChatID string) []string {
+	args := []string{"--print", "--output-format", "json", "--input-format", "stream-json"}
+	if cursorChatID != "" {
+		args = append(args, "--resume", cursorChatID)
+	}
+	return args
+}
+
+// askPersistent answers a question through the session's long-lived
+// cursor-agent, starting one when the session has none, it has exited, or the
+// workspace changed. UpdateCursorChatID stops a process left on another chat.
+// A process that fails is discarded so the next ask starts fresh.
+func (m *MemorySessionManager) askPersistent(ctx context.Context, id string, question string, workspaceDir string) (string, string, bool, error) {
+	proc, err := m.sessionProcess(id, workspaceDir)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	shard := m.shardFor(id)
+	acquired := m.rlock(shard)
+	resumed := proc.cursorChatID != ""
+	m.runlock(shard, acquired)
+
+	response, err := proc.ask(ctx, question)
+	metrics.RecordProcess(metrics.ProcessCursorAgent, err)
+	if err != nil {
+		m.discardProcess(id, proc)
+		return "", "", false, err
+	}
+
+	// A process started without a chat is now in the one cursor-agent created
+	acquired = m.lock(shard)
+	proc.cursorChatID = response.SessionID
+	m.unlock(shard, acquired)
+
+	return response.Result, response.SessionID, resumed, nil
+}
+
+// sessionProcess returns the session's running cursor-agent for workspaceDir,
+// replacing a stale one. The new process is
+// started without holding the shard lock; if another ask installed a usable
+// process meanwhile, that one is kept and the new one stopped.
+func (m *MemorySessionManager) sessionProcess(id string, workspaceDir string) (*cursorProcess, error) {
+	shard := m.shardFor(id)
+	acquired := m.lock(shard)
+	session, exists := shard.sessions[id]
+	if !exists {
+		m.unlock(shard, acquired)
+		return nil, fmt.Errorf("session not found: %s", id)
+	}
+	if proc := session.process; proc.usable(workspaceDir) {
+		m.unlock(shard, acquired)
+		return proc, nil
+	}
+	cursorChatID := session.CursorChatID
+	m.unlock(shard, acquired)
+
+	proc, err := startCursorProcess(m.cursorAgent(), buildPersistentCursorAgentArgs(cursorChatID), workspaceDir, m.maxCursorOutputBytes)
+	if proc != nil {
+		proc.cursorChatID = cursorChatID
+	}
+
+	acquired = m.lock(shard)
+	session, exists = shard.sessions[id]
+	if !exists {
+		m.unlock(shard, acquired)
+		if proc != nil {
+			proc.close()
+		}
+		return nil, fmt.Errorf("session not found: %s", id)
+	}
+	if current := session.process; current.usable(workspaceDir) {
+		m.unlock(shard, acquired)
+		if proc != nil {
+			proc.close()
+		}
+		return current, nil
+	}
+	stale := session.process
+	session.process = proc
+	m.unlock(shard, acquired)
+
+	if stale != nil {
+		stale.close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Get().Debug().
+		Str("session_id", id).
+		Int("pid", proc.cmd.Process.Pid).
+		Str("workspace_dir", workspaceDir).
+		Msg("Started persistent cursor-agent")
+	return proc, nil
+}
+
+// discardProcess detaches proc from the session if it is still attached, then stops it
+func (m *MemorySessionManager) discardProcess(id string, proc *cursorProcess) {
+	shard := m.shardFor(id)
+	acquired := m.lock(shard)
+	if session, exists := shard.sessions[id]; exists && session.process == proc {
+		session.process = nil
+	}
+	m.unlock(shard, acquired)
+
+	proc.close()
+}
+
+// CloseProcesses stops every session's persistent cursor-agent. Call it on
+// shutdown so no cursor-agent outlives the server.
+func (m *MemorySessionManager) CloseProcesses() {
+	var procs []*cursorProcess
+	for _, shard := range m.shards {
+		acquired := m.lock(shard)
+		for _, session := range shard.sessions {
+			if session.process != nil {
+				procs = append(procs, session.process)
+				session.process = nil
+			}
+		}
+		m.unlock(shard, acquired)
+	}
+
+	for _, proc := range procs {
+		proc.close()
+	}
+}
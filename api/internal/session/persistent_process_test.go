@@ -0,0 +1,215 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePersistentAgent answers each stdin line with a numbered result carrying
+// its PID, emitting a progress event first, and writes "exited" to $MARKER on EOF
+const fakePersistentAgent = `n=0
+while IFS= read -r line; do
+  n=$((n+1))
+  echo '{"type":"progress"}'
+  echo "{\"type\":\"result\",\"result\":\"answer $n from $$\",\"session_id\":\"chat-1\"}"
+done
+echo exited > "$MARKER"
+`
+
+func TestAskQuestion_PersistentCursorProcess(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	t.Setenv("MARKER", marker)
+	path := writeFakeCursorAgent(t, fakePersistentAgent)
+	manager := NewMemorySessionManager(WithCursorAgentPath(path), WithPersistentCursorProcess(true))
+
+	session, err := manager.CreateSession()
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	workspace := t.TempDir()
+	first, chatID, _, err := manager.AskQuestion(context.Background(), session.ID, "first question", workspace)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if chatID != "chat-1" {
+		t.Errorf("expected chat ID chat-1, got %q", chatID)
+	}
+	second, _, _, err := manager.AskQuestion(context.Background(), session.ID, "second question", workspace)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	pid, ok := strings.CutPrefix(first, "answer 1 from ")
+	if !ok {
+		t.Fatalf("expected first answer from a fresh process, got %q", first)
+	}
+	if second != "answer 2 from "+pid {
+		t.Errorf("expected the same process to answer the second question, got %q", second)
+	}
+
+	// A different workspace needs a process started there
+	other, _, _, err := manager.AskQuestion(context.Background(), session.ID, "elsewhere", t.TempDir())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(other, "answer 1 from ") || other == first {
+		t.Errorf("expected a workspace change to start a new process, got %q", other)
+	}
+	os.Remove(marker) // Written by the replaced process
+
+	if err := manager.EndSession(session.ID); err != nil {
+		t.Fatalf("failed to end session: %v", err)
+	}
+	data, err := os.ReadFile(marker)
+	if err != nil || strings.TrimSpace(string(data)) != "exited" {
+		t.Errorf("expected the process to exit when the session ended, marker: %q, err: %v", data, err)
+	}
+}
+
+func TestAskQuestion_PersistentCursorProcessRestartsAfterExit(t *testing.T) {
+	// Answers a single question, then exits
+	path := writeFakeCursorAgent(t, `read -r line
+echo "{\"type\":\"result\",\"result\":\"from $$\",\"session_id\":\"chat-1\"}"
+`)
+	manager := NewMemorySessionManager(WithCursorAgentPath(path), WithPersistentCursorProcess(true))
+	session, _ := manager.CreateSession()
+	workspace := t.TempDir()
+
+	first, _, _, err := manager.AskQuestion(context.Background(), session.ID, "one", workspace)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Give the process time to exit so the next ask sees it gone
+	time.Sleep(100 * time.Millisecond)
+
+	second, _, _, err := manager.AskQuestion(context.Background(), session.ID, "two", workspace)
+	if err != nil {
+		t.Fatalf("expected a restarted process to answer, got %v", err)
+	}
+	if first == second {
+		t.Errorf("expected a new process after the first exited, both answered %q", first)
+	}
+}
+
+func TestAskQuestion_PersistentCursorProcessRestartsOnChatChange(t *testing.T) {
+	t.Setenv("MARKER", filepath.Join(t.TempDir(), "marker"))
+	path := writeFakeCursorAgent(t, fakePersistentAgent)
+	manager := NewMemorySessionManager(WithCursorAgentPath(path), WithPersistentCursorProcess(true))
+	session, _ := manager.CreateSession()
+	workspace := t.TempDir()
+
+	first, chatID, _, err := manager.AskQuestion(context.Background(), session.ID, "one", workspace)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Recording the chat the process created keeps it
+	if err := manager.UpdateCursorChatID(session.ID, chatID); err != nil {
+		t.Fatalf("failed to update chat ID: %v", err)
+	}
+	second, _, _, err := manager.AskQuestion(context.Background(), session.ID, "two", workspace)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	pid := strings.TrimPrefix(first, "answer 1 from ")
+	if second != "answer 2 from "+pid {
+		t.Errorf("expected the same process to answer after recording its chat, got %q", second)
+	}
+
+	// Switching to another chat must not be answered by the old one
+	if err := manager.UpdateCursorChatID(session.ID, "chat-other"); err != nil {
+		t.Fatalf("failed to update chat ID: %v", err)
+	}
+	third, _, _, err := manager.AskQuestion(context.Background(), session.ID, "three", workspace)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(third, "answer 1 from ") || third == first {
+		t.Errorf("expected a chat change to start a new process, got %q", third)
+	}
+}
+
+func TestSessionProcess_RacingStartsKeepOneProcess(t *testing.T) {
+	t.Setenv("MARKER", filepath.Join(t.TempDir(), "marker"))
+	path := writeFakeCursorAgent(t, fakePersistentAgent)
+	manager := NewMemorySessionManager(WithCursorAgentPath(path), WithPersistentCursorProcess(true))
+	session, _ := manager.CreateSession()
+	m := manager.(*MemorySessionManager)
+	defer m.CloseProcesses()
+	workspace := t.TempDir()
+
+	const racers = 8
+	procs := make([]*cursorProcess, racers)
+	var wg sync.WaitGroup
+	for i := range procs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			proc, err := m.sessionProcess(session.ID, workspace)
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			procs[i] = proc
+		}()
+	}
+	wg.Wait()
+
+	for _, proc := range procs {
+		if proc != procs[0] {
+			t.Fatal("expected every racing ask to get the installed process")
+		}
+	}
+	if !procs[0].alive() {
+		t.Error("expected the installed process to be running")
+	}
+}
+
+func TestAskQuestion_PersistentCursorProcessCancelled(t *testing.T) {
+	// Reads questions but never answers
+	path := writeFakeCursorAgent(t, `while IFS= read -r line; do :; done
+`)
+	manager := NewMemorySessionManager(WithCursorAgentPath(path), WithPersistentCursorProcess(true))
+	session, _ := manager.CreateSession()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, _, _, err := manager.AskQuestion(ctx, session.ID, "hello", t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+
+	m := manager.(*MemorySessionManager)
+	shard := m.shardFor(session.ID)
+	shard.mu.RLock()
+	proc := shard.sessions[session.ID].process
+	shard.mu.RUnlock()
+	if proc != nil {
+		t.Error("expected the cancelled process to be discarded")
+	}
+}
+
+func TestCloseProcesses(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	t.Setenv("MARKER", marker)
+	path := writeFakeCursorAgent(t, fakePersistentAgent)
+	manager := NewMemorySessionManager(WithCursorAgentPath(path), WithPersistentCursorProcess(true))
+	session, _ := manager.CreateSession()
+
+	if _, _, _, err := manager.AskQuestion(context.Background(), session.ID, "hello", t.TempDir()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	manager.(*MemorySessionManager).CloseProcesses()
+
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("expected the process to exit on CloseProcesses: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrReadOnly is returned by ReadOnlyManager for any method that would mutate sessions
+var ErrReadOnly = errors.New("session manager is read-only")
+
+// ReadOnlyManager wraps a Manager for consumers such as dashboards that must
+// never change session state. Reads are delegated to the underlying manager;
+// mutating methods return ErrReadOnly without touching it.
+type ReadOnlyManager struct {
+	manager Manager
+}
+
+// NewReadOnlyManager creates a read-only view over manager
+func NewReadOnlyManager(manager Manager) *ReadOnlyManager {
+	return &ReadOnlyManager{manager: manager}
+}
+
+// CreateSession is not permitted on a read-only manager
+func (r *ReadOnlyManager) CreateSession() (*Session, error) {
+	return nil, ErrReadOnly
+}
+
+// CreateSessionWithOptions is not permitted on a read-only manager
+func (r *ReadOnlyManager) CreateSessionWithOptions(opts SessionOptions) (*Session, error) {
+	return nil, ErrReadOnly
+}
+
+// GetSession delegates to the underlying manager
+func (r *ReadOnlyManager) GetSession(id string) (*Session, error) {
+	return r.manager.GetSession(id)
+}
+
+// GetOrCreateByKey is not permitted on a read-only manager since it may create a session
+func (r *ReadOnlyManager) GetOrCreateByKey(externalKey string) (*Session, bool, error) {
+	return nil, false, ErrReadOnly
+}
+
+// UpdateActivity is not permitted on a read-only manager
+func (r *ReadOnlyManager) UpdateActivity(id string) error {
+	return ErrReadOnly
+}
+
+// UpdateCursorChatID is not permitted on a read-only manager
+func (r *ReadOnlyManager) UpdateCursorChatID(id string, cursorChatID string) error {
+	return ErrReadOnly
+}
+
+// AskQuestion is not permitted on a read-only manager since it advances the cursor chat
+func (r *ReadOnlyManager) AskQuestion(ctx context.Context, id string, question string, workspaceDir string) (string, string, bool, error) {
+	return "", "", false, ErrReadOnly
+}
+
+// AddToConversationLog is not permitted on a read-only manager
+func (r *ReadOnlyManager) AddToConversationLog(id string, messages []Message) error {
+	return ErrReadOnly
+}
+
+// EndSession is not permitted on a read-only manager
+func (r *ReadOnlyManager) EndSession(id string) error {
+	return ErrReadOnly
+}
+
+// GetAllSessions delegates to the underlying manager
+func (r *ReadOnlyManager) GetAllSessions() []*Session {
+	return r.manager.GetAllSessions()
+}
+
+// GetAllSessionsShallow delegates to the underlying manager
+func (r *ReadOnlyManager) GetAllSessionsShallow() []*Session {
+	return r.manager.GetAllSessionsShallow()
+}
+
+// CleanupInactiveSessions is a no-op on a read-only manager; the owner of the
+// underlying manager is responsible for cleanup
+func (r *ReadOnlyManager) CleanupInactiveSessions(timeout time.Duration) {}
+
+// LockStats reports the underlying manager's lock metrics when it has them
+func (r *ReadOnlyManager) LockStats() LockStats {
+	if provider, ok := r.manager.(LockStatsProvider); ok {
+		return provider.LockStats()
+	}
+	return LockStats{}
+}
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadOnlyManager(t *testing.T) {
+	newFixture := func() (Manager, *Session, *ReadOnlyManager) {
+		manager := NewMemorySessionManager(WithLockMetrics(true))
+		created, _ := manager.CreateSession()
+		manager.AddToConversationLog(created.ID, []Message{
+			{Role: "user", Content: "question", Timestamp: time.Now()},
+		})
+		return manager, created, NewReadOnlyManager(manager)
+	}
+
+	t.Run("mutating calls return ErrReadOnly", func(t *testing.T) {
+		manager, created, readOnly := newFixture()
+		before, _ := manager.GetSession(created.ID)
+
+		_, err := readOnly.CreateSession()
+		checkReadOnly(t, "CreateSession", err)
+		_, err = readOnly.CreateSessionWithOptions(SessionOptions{AutoTTS: true})
+		checkReadOnly(t, "CreateSessionWithOptions", err)
+		_, _, err = readOnly.GetOrCreateByKey("device-1")
+		checkReadOnly(t, "GetOrCreateByKey", err)
+		checkReadOnly(t, "UpdateActivity", readOnly.UpdateActivity(created.ID))
+		checkReadOnly(t, "UpdateCursorChatID", readOnly.UpdateCursorChatID(created.ID, "chat-1"))
+		_, _, _, err = readOnly.AskQuestion(context.Background(), created.ID, "question", ".")
+		checkReadOnly(t, "AskQuestion", err)
+		checkReadOnly(t, "AddToConversationLog", readOnly.AddToConversationLog(created.ID, []Message{{Role: "user"}}))
+		checkReadOnly(t, "EndSession", readOnly.EndSession(created.ID))
+		readOnly.CleanupInactiveSessions(0)
+
+		after, err := manager.GetSession(created.ID)
+		if err != nil {
+			t.Fatalf("expected session to survive read-only calls: %v", err)
+		}
+		if len(manager.GetAllSessions()) != 1 {
+			t.Errorf("expected 1 session, got %d", len(manager.GetAllSessions()))
+		}
+		if after.CursorChatID != "" || len(after.ConversationLog) != 1 || !after.LastActivity.Equal(before.LastActivity) {
+			t.Error("expected underlying session to be unchanged")
+		}
+	})
+
+	t.Run("reads pass through", func(t *testing.T) {
+		_, created, readOnly := newFixture()
+
+		sess, err := readOnly.GetSession(created.ID)
+		if err != nil || sess.ID != created.ID {
+			t.Fatalf("expected GetSession to return %s, got %v (%v)", created.ID, sess, err)
+		}
+		if len(sess.ConversationLog) != 1 {
+			t.Errorf("expected conversation log, got %d messages", len(sess.ConversationLog))
+		}
+		if _, err := readOnly.GetSession("missing"); err == nil || errors.Is(err, ErrReadOnly) {
+			t.Errorf("expected not-found error for missing session, got %v", err)
+		}
+		if sessions := readOnly.GetAllSessions(); len(sessions) != 1 {
+			t.Errorf("expected 1 session, got %d", len(sessions))
+		}
+		if sessions := readOnly.GetAllSessionsShallow(); len(sessions) != 1 {
+			t.Errorf("expected 1 shallow session, got %d", len(sessions))
+		}
+		if stats := readOnly.LockStats(); !stats.Enabled || stats.Acquisitions == 0 {
+			t.Errorf("expected underlying lock stats, got %+v", stats)
+		}
+	})
+}
+
+func checkReadOnly(t *testing.T, method string, err error) {
+	t.Helper()
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("%s: expected ErrReadOnly, got %v", method, err)
+	}
+}
@@ -0,0 +1,33 @@
+package session
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// sessionShard holds a slice of the manager's sessions behind its own lock, so
+// operations on sessions in different shards never contend
+type sessionShard struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+// newSessionShards creates count shards, splitting capacity evenly between them
+func newSessionShards(count int, capacity int) []*sessionShard {
+	count = max(count, 1)
+	shards := make([]*sessionShard, count)
+	for i := range shards {
+		shards[i] = &sessionShard{sessions: make(map[string]*Session, capacity/count)}
+	}
+	return shards
+}
+
+// shardFor returns the shard that owns session id
+func (m *MemorySessionManager) shardFor(id string) *sessionShard {
+	if len(m.shards) == 1 {
+		return m.shards[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return m.shards[h.Sum32()%uint32(len(m.shards))]
+}
@@ -0,0 +1,184 @@
+package session
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSessionShards(t *testing.T) {
+	t.Run("spreads sessions across shards and finds them all", func(t *testing.T) {
+		manager := NewMemorySessionManager(WithSessionShards(8), WithInitialSessionCapacity(64)).(*MemorySessionManager)
+
+		ids := make([]string, 0, 100)
+		for i := 0; i < 100; i++ {
+			session, _ := manager.CreateSession()
+			ids = append(ids, session.ID)
+		}
+
+		used := 0
+		for _, shard := range manager.shards {
+			if len(shard.sessions) > 0 {
+				used++
+			}
+		}
+		if used < 2 {
+			t.Errorf("expected sessions in several shards, got %d", used)
+		}
+
+		for _, id := range ids {
+			if _, err := manager.GetSession(id); err != nil {
+				t.Errorf("expected session %s to be found: %v", id, err)
+			}
+		}
+		if n := len(manager.GetAllSessions()); n != 100 {
+			t.Errorf("expected 100 sessions, got %d", n)
+		}
+	})
+
+	t.Run("cleanup and external keys work across shards", func(t *testing.T) {
+		manager := NewMemorySessionManager(WithSessionShards(4)).(*MemorySessionManager)
+
+		for i := 0; i < 20; i++ {
+			manager.GetOrCreateByKey(fmt.Sprintf("device-%d", i))
+		}
+		again, created, _ := manager.GetOrCreateByKey("device-3")
+		if created {
+			t.Error("expected the existing keyed session to be returned")
+		}
+
+		for _, shard := range manager.shards {
+			for _, session := range shard.sessions {
+				session.LastActivity = time.Now().Add(-time.Hour)
+			}
+		}
+		manager.CleanupInactiveSessions(time.Minute)
+
+		if n := len(manager.GetAllSessionsShallow()); n != 0 {
+			t.Errorf("expected all sessions cleaned up, got %d", n)
+		}
+		if len(manager.externalKeys) != 0 {
+			t.Errorf("expected key index to be empty, got %v", manager.externalKeys)
+		}
+		if _, err := manager.GetSession(again.ID); err == nil {
+			t.Error("expected the keyed session to be gone")
+		}
+	})
+
+	t.Run("values below one mean a single shard", func(t *testing.T) {
+		manager := NewMemorySessionManager(WithSessionShards(0)).(*MemorySessionManager)
+
+		if len(manager.shards) != 1 {
+			t.Errorf("expected 1 shard, got %d", len(manager.shards))
+		}
+	})
+
+	t.Run("a held shard does not block sessions in other shards", func(t *testing.T) {
+		manager := NewMemorySessionManager(WithSessionShards(16), WithLockMetrics(true)).(*MemorySessionManager)
+
+		// Find two sessions that landed in different shards
+		first, _ := manager.CreateSession()
+		var second *Session
+		for second == nil {
+			candidate, _ := manager.CreateSession()
+			if manager.shardFor(candidate.ID) != manager.shardFor(first.ID) {
+				second = candidate
+			}
+		}
+
+		held := manager.shardFor(first.ID)
+		held.mu.Lock()
+		defer held.mu.Unlock()
+
+		done := make(chan struct{})
+		go func() {
+			manager.GetSession(second.ID)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("expected a session in another shard to be readable while one shard is locked")
+		}
+		if stats := manager.LockStats(); stats.Contentions != 0 {
+			t.Errorf("expected no contention, got %d", stats.Contentions)
+		}
+	})
+}
+
+// TestSessionShards_ReduceContention compares lock contention for the same
+// concurrent workload with one shard and with many
+func TestSessionShards_ReduceContention(t *testing.T) {
+	if runtime.GOMAXPROCS(0) < 2 {
+		t.Skip("lock contention needs more than one CPU")
+	}
+
+	contention := func(shards int) int64 {
+		manager := NewMemorySessionManager(WithSessionShards(shards), WithLockMetrics(true)).(*MemorySessionManager)
+
+		const workers = 16
+		ids := make([]string, workers)
+		for i := range ids {
+			session, _ := manager.CreateSession()
+			ids[i] = session.ID
+		}
+
+		var wg sync.WaitGroup
+		for _, id := range ids {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				for j := 0; j < 500; j++ {
+					manager.UpdateActivity(id)
+					manager.AddToConversationLog(id, []Message{{Role: "user", Content: "hi"}})
+				}
+			}(id)
+		}
+		wg.Wait()
+
+		return manager.LockStats().Contentions
+	}
+
+	single := contention(1)
+	sharded := contention(64)
+	t.Logf("contentions: 1 shard = %d, 64 shards = %d", single, sharded)
+
+	// Scheduling makes exact counts vary, so only a clear regression fails
+	if sharded > 2*single+10 {
+		t.Errorf("expected sharding not to increase contention: 1 shard = %d, 64 shards = %d", single, sharded)
+	}
+}
+
+func benchmarkSessionShards(b *testing.B, shards int) {
+	manager := NewMemorySessionManager(WithSessionShards(shards), WithInitialSessionCapacity(1024))
+	ids := make([]string, 1024)
+	for i := range ids {
+		session, _ := manager.CreateSession()
+		ids[i] = session.ID
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			id := ids[i%len(ids)]
+			if i%4 == 0 {
+				manager.UpdateActivity(id)
+			} else {
+				manager.GetSession(id)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkSessionManager_OneShard(b *testing.B) {
+	benchmarkSessionShards(b, 1)
+}
+
+func BenchmarkSessionManager_SixteenShards(b *testing.B) {
+	benchmarkSessionShards(b, 16)
+}
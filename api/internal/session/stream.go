@@ -0,0 +1,270 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sean/janus/internal/logger"
+	"github.com/sean/janus/internal/metrics"
+)
+
+// streamWaitDelay bounds how long a cancelled cursor-agent may hold its output
+// pipe open (e.g. via orphaned children) before it is closed forcibly
+const streamWaitDelay = 2 * time.Second
+
+// StreamResult is the outcome of a streamed ask
+type StreamResult struct {
+	Answer       string
+	CursorChatID string
+	// Truncated is set when the request deadline cut the answer off; Answer then
+	// holds only the text streamed before the deadline
+	Truncated bool
+}
+
+// StreamingManager is implemented by managers that can deliver an answer as
+// cursor-agent produces it
+type StreamingManager interface {
+	AskQuestionStream(ctx context.Context, id string, question string, workspaceDir string, onChunk func(chunk string)) (*StreamResult, error)
+}
+
+// ModeStreamingManager is implemented by managers that can stream the answer
+// of a cursor-agent mode (see ModeManager)
+type ModeStreamingManager interface {
+	AskQuestionStreamInMode(ctx context.Context, id string, mode string, question string, workspaceDir string, onChunk func(chunk string)) (*StreamResult, error)
+}
+
+// cursorStreamEvent is one line of cursor-agent --output-format stream-json output
+type cursorStreamEvent struct {
+	Type      string `json:"type"`
+	Subtype   string `json:"subtype"`
+	IsError   bool   `json:"is_error"`
+	Result    string `json:"result"`
+	SessionID string `json:"session_id"`
+	Message   struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"message"`
+}
+
+// AskQuestionStream asks a question like AskQuestion but reads cursor-agent's
+// stream-json output, passing each text chunk to onChunk as it arrives.
+// onChunk is called from the output-reading goroutine and must not block for long.
+// If the context deadline passes after some text has streamed, the partial
+// accumulation is returned with Truncated set instead of an error; the same
+// applies when the ask is stopped with CancelQuestion.
+func (m *MemorySessionManager) AskQuestionStream(ctx context.Context, id string, question string, workspaceDir string, onChunk func(chunk string)) (*StreamResult, error) {
+	return m.askQuestionStream(ctx, id, nil, question, workspaceDir, onChunk)
+}
+
+// AskQuestionStreamInMode streams an answer like AskQuestionStream, with the
+// configured arguments for mode placed before the streaming chat flags
+func (m *MemorySessionManager) AskQuestionStreamInMode(ctx context.Context, id string, mode string, question string, workspaceDir string, onChunk func(chunk string)) (*StreamResult, error) {
+	modeArgs, ok := m.cursorAgentModes[mode]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMode, mode)
+	}
+	return m.askQuestionStream(ctx, id, modeArgs, question, workspaceDir, onChunk)
+}
+
+// askQuestionStream streams an answer with modeArgs placed before the standard streaming flags
+func (m *MemorySessionManager) askQuestionStream(ctx context.Context, id string, modeArgs []string, question string, workspaceDir string, onChunk func(chunk string)) (*StreamResult, error) {
+	ctx, untrack := m.activeAsks.track(ctx, id)
+	defer untrack()
+
+	release, err := m.admitAsk(ctx, id)
+	if err != nil {
+		return nil, cancelledError(ctx, err)
+	}
+	defer release()
+
+	shard := m.shardFor(id)
+	acquired := m.rlock(shard)
+	session, exists := shard.sessions[id]
+	var cursorChatID string
+	if exists {
+		cursorChatID = session.CursorChatID
+	}
+	m.runlock(shard, acquired)
+
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", id)
+	}
+
+	// Long questions go over stdin so they can't exceed the OS argument size limit
+	argQuestion := question
+	if m.useStdinFor(question) {
+		argQuestion = ""
+	}
+
+	cmd := exec.CommandContext(ctx, m.cursorAgent(), append(append([]string{}, modeArgs...), buildCursorAgentStreamArgs(cursorChatID, argQuestion)...)...)
+	cmd.Dir = workspaceDir
+	if argQuestion == "" {
+		cmd.Stdin = strings.NewReader(question)
+	}
+	cmd.WaitDelay = streamWaitDelay
+
+	parser := newStreamParser(m.maxCursorOutputBytes, onChunk)
+	var stderr bytes.Buffer
+	cmd.Stdout = parser
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	metrics.RecordProcess(metrics.ProcessCursorAgent, runErr)
+	parser.flush()
+
+	chatID := parser.sessionID
+	if chatID == "" {
+		chatID = cursorChatID
+	}
+	answer := parser.answer.String()
+
+	if ctx.Err() != nil {
+		if answer == "" {
+			return nil, cancelledError(ctx, fmt.Errorf("cursor-agent command cancelled: %w", ctx.Err()))
+		}
+		logger.FromContext(ctx).Warn().
+			Str("session_id", id).
+			Int("partial_length", len(answer)).
+			Msg("cursor-agent stream cut off, returning partial answer")
+		return &StreamResult{Answer: answer, CursorChatID: chatID, Truncated: true}, nil
+	}
+	if runErr != nil {
+		logger.FromContext(ctx).Debug().
+			Err(runErr).
+			Str("stderr", stderr.String()).
+			Msg("cursor-agent command failed")
+		return nil, fmt.Errorf("cursor-agent command failed: %w, stderr: %s", runErr, stderr.String())
+	}
+	if parser.exceeded {
+		return nil, fmt.Errorf("%w (limit %d bytes)", ErrCursorOutputTooLarge, m.maxCursorOutputBytes)
+	}
+
+	if result := parser.result; result != nil {
+		if result.IsError {
+			return nil, &CursorAgentError{Subtype: result.Subtype, Message: result.Result}
+		}
+		// The final result carries the complete answer; prefer it over the accumulation
+		if result.Result != "" {
+			answer = result.Result
+		}
+	}
+	if answer == "" {
+		return nil, fmt.Errorf("cursor-agent stream produced no answer")
+	}
+
+	return &StreamResult{Answer: answer, CursorChatID: chatID}, nil
+}
+
+// buildCursorAgentStreamArgs builds the cursor-agent arguments for a streamed question.
+// An empty question is omitted so cursor-agent reads the prompt from stdin.
+func buildCursorAgentStreamArgs(cursorChatID string, question string) []string {
+	args := []string{"--print", "--output-format", "stream-json", "--stream-partial-output"}
+
+	if cursorChatID != "" {
+		args = append(args, "--resume", cursorChatID)
+	}
+
+	if question == "" {
+		return args
+	}
+	return append(args, question)
+}
+
+// streamParser is an io.Writer that splits cursor-agent stream-json output into
+// events, accumulating assistant text. Lines longer than maxLine are discarded
+// and flagged as exceeded; a maxLine of 0 or less means unbounded.
+type streamParser struct {
+	maxLine   int
+	onChunk   func(string)
+	pending   []byte
+	dropping  bool
+	exceeded  bool
+	answer    strings.Builder
+	sessionID string
+	result    *cursorStreamEvent
+}
+
+// newStreamParser creates a parser that reports text chunks to onChunk (may be nil)
+func newStreamParser(maxLine int, onChunk func(string)) *streamParser {
+	return &streamParser{maxLine: maxLine, onChunk: onChunk}
+}
+
+// Write consumes output, handling every complete line
+func (p *streamParser) Write(data []byte) (int, error) {
+	written := len(data)
+	for len(data) > 0 {
+		newline := bytes.IndexByte(data, '\n')
+		if newline < 0 {
+			p.appendPending(data)
+			break
+		}
+		p.appendPending(data[:newline])
+		p.endLine()
+		data = data[newline+1:]
+	}
+	return written, nil
+}
+
+// flush handles a final line that was not newline-terminated
+func (p *streamParser) flush() {
+	if len(p.pending) > 0 || p.dropping {
+		p.endLine()
+	}
+}
+
+// appendPending buffers part of the current line, dropping it once it exceeds maxLine
+func (p *streamParser) appendPending(data []byte) {
+	if p.dropping {
+		return
+	}
+	if p.maxLine > 0 && len(p.pending)+len(data) > p.maxLine {
+		p.dropping = true
+		p.exceeded = true
+		p.pending = nil
+		return
+	}
+	p.pending = append(p.pending, data...)
+}
+
+// endLine parses the buffered line and resets for the next
+func (p *streamParser) endLine() {
+	line := bytes.TrimSpace(p.pending)
+	dropped := p.dropping
+	p.pending = p.pending[:0]
+	p.dropping = false
+	if dropped || len(line) == 0 {
+		return
+	}
+
+	var event cursorStreamEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		logger.Get().Debug().Err(err).Msg("Skipping unparseable cursor-agent stream line")
+		return
+	}
+
+	if event.SessionID != "" {
+		p.sessionID = event.SessionID
+	}
+
+	switch event.Type {
+	case "assistant":
+		for _, content := range event.Message.Content {
+			if content.Type != "text" || content.Text == "" {
+				continue
+			}
+			p.answer.WriteString(content.Text)
+			if p.onChunk != nil {
+				p.onChunk(content.Text)
+			}
+		}
+	case "result":
+		p.result = &event
+	}
+}
@@ -0,0 +1,159 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeStreamPrelude is the init event cursor-agent emits before any text
+const fakeStreamPrelude = `echo '{"type":"system","subtype":"init","session_id":"chat-stream"}'
+`
+
+func TestAskQuestionStream(t *testing.T) {
+	t.Run("streams chunks and returns the final result", func(t *testing.T) {
+		fake := writeFakeCursorAgent(t, fakeStreamPrelude+`echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Hello"}]}}'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":" world"}]}}'
+echo '{"type":"result","subtype":"success","is_error":false,"result":"Hello world","session_id":"chat-stream"}'
+`)
+		manager := NewMemorySessionManager(WithCursorAgentPath(fake)).(*MemorySessionManager)
+		session, _ := manager.CreateSession()
+
+		var chunks []string
+		result, err := manager.AskQuestionStream(context.Background(), session.ID, "hi", t.TempDir(), func(chunk string) {
+			chunks = append(chunks, chunk)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Answer != "Hello world" || result.Truncated {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if result.CursorChatID != "chat-stream" {
+			t.Errorf("expected cursor chat ID from stream, got %q", result.CursorChatID)
+		}
+		if strings.Join(chunks, "|") != "Hello| world" {
+			t.Errorf("unexpected chunks: %q", chunks)
+		}
+	})
+
+	t.Run("returns partial answer when cut off by timeout", func(t *testing.T) {
+		fake := writeFakeCursorAgent(t, fakeStreamPrelude+`echo '{"type":"assistant","message":{"content":[{"type":"text","text":"The answer is"}]}}'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":" partly"}]}}'
+exec sleep 10
+`)
+		manager := NewMemorySessionManager(WithCursorAgentPath(fake)).(*MemorySessionManager)
+		session, _ := manager.CreateSession()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		result, err := manager.AskQuestionStream(ctx, session.ID, "hi", t.TempDir(), nil)
+		if err != nil {
+			t.Fatalf("expected partial result, got error: %v", err)
+		}
+		if !result.Truncated {
+			t.Error("expected result to be marked truncated")
+		}
+		if result.Answer != "The answer is partly" {
+			t.Errorf("unexpected partial answer: %q", result.Answer)
+		}
+		if result.CursorChatID != "chat-stream" {
+			t.Errorf("expected cursor chat ID, got %q", result.CursorChatID)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected the deadline to stop the stream promptly, took %v", elapsed)
+		}
+	})
+
+	t.Run("returns error when timeout hits before any text", func(t *testing.T) {
+		fake := writeFakeCursorAgent(t, fakeStreamPrelude+`exec sleep 10
+`)
+		manager := NewMemorySessionManager(WithCursorAgentPath(fake)).(*MemorySessionManager)
+		session, _ := manager.CreateSession()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		if _, err := manager.AskQuestionStream(ctx, session.ID, "hi", t.TempDir(), nil); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline error, got %v", err)
+		}
+	})
+
+	t.Run("bounds oversized stream lines", func(t *testing.T) {
+		fake := writeFakeCursorAgent(t, `printf '{"type":"assistant","message":{"content":[{"type":"text","text":"'
+head -c 65536 /dev/zero | tr '\0' 'a'
+printf '"}]}}\n'
+`)
+		manager := NewMemorySessionManager(
+			WithCursorAgentPath(fake),
+			WithMaxCursorOutputBytes(1024),
+		).(*MemorySessionManager)
+		session, _ := manager.CreateSession()
+
+		if _, err := manager.AskQuestionStream(context.Background(), session.ID, "hi", t.TempDir(), nil); !errors.Is(err, ErrCursorOutputTooLarge) {
+			t.Errorf("expected ErrCursorOutputTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("sends questions over the stdin threshold on stdin", func(t *testing.T) {
+		argsFile := filepath.Join(t.TempDir(), "args")
+		fake := writeFakeCursorAgent(t, `echo "$*" > `+argsFile+`
+prompt=$(cat)
+printf '{"type":"result","subtype":"success","is_error":false,"result":"%s","session_id":"chat-stream"}\n' "$prompt"
+`)
+		manager := NewMemorySessionManager(
+			WithCursorAgentPath(fake),
+			WithCursorAgentStdin(false, 16),
+		).(*MemorySessionManager)
+		session, _ := manager.CreateSession()
+		question := strings.Repeat("q", 32)
+
+		result, err := manager.AskQuestionStream(context.Background(), session.ID, question, t.TempDir(), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Answer != question {
+			t.Errorf("expected question echoed from stdin, got %q", result.Answer)
+		}
+		args, _ := os.ReadFile(argsFile)
+		if strings.Contains(string(args), question) {
+			t.Errorf("expected question to be left out of the arguments, got %q", string(args))
+		}
+	})
+
+	t.Run("returns error for unknown session", func(t *testing.T) {
+		manager := NewMemorySessionManager().(*MemorySessionManager)
+		if _, err := manager.AskQuestionStream(context.Background(), "missing", "hi", t.TempDir(), nil); err == nil {
+			t.Error("expected error for unknown session")
+		}
+	})
+}
+
+func TestAskQuestionStreamInMode(t *testing.T) {
+	// Echoes its first argument so the test can see the mode's arguments lead
+	fake := writeFakeCursorAgent(t, fakeStreamPrelude+`echo "{\"type\":\"result\",\"subtype\":\"success\",\"is_error\":false,\"result\":\"$1\"}"
+`)
+	manager := NewMemorySessionManager(
+		WithCursorAgentPath(fake),
+		WithCursorAgentModes(map[string][]string{"summarize": {"summarize"}}),
+	).(*MemorySessionManager)
+	session, _ := manager.CreateSession()
+
+	result, err := manager.AskQuestionStreamInMode(context.Background(), session.ID, "summarize", "hi", t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Answer != "summarize" {
+		t.Errorf("expected the mode's arguments first, got %q", result.Answer)
+	}
+
+	if _, err := manager.AskQuestionStreamInMode(context.Background(), session.ID, "unknown", "hi", t.TempDir(), nil); !errors.Is(err, ErrUnknownMode) {
+		t.Errorf("expected ErrUnknownMode, got %v", err)
+	}
+}
@@ -13,11 +13,30 @@ type Message struct {
 
 // Session represents an active cursor-agent chat session
 type Session struct {
-	ID              string
-	CursorChatID    string // Cursor-agent's internal chat session ID for --resume
-	CreatedAt       time.Time
-	LastActivity    time.Time
-	ConversationLog []Message
+	ID               string
+	CursorChatID     string // Cursor-agent's internal chat session ID for --resume
+	CreatedAt        time.Time
+	LastActivity     time.Time
+	ConversationLog  []Message
+	AutoTTS          bool          // Whether answers should be offered as synthesized audio
+	LongConversation bool          // Set once the conversation log exceeds the manager's threshold
+	Branch           string        // Git branch the workspace is switched to before each ask (optional)
+	ExternalKey      string        // Client-supplied stable identifier (e.g. device ID) from GetOrCreateByKey
+	Timeout          time.Duration // Inactivity timeout overriding the cleanup timeout (zero uses the cleanup timeout)
+	Ephemeral        bool          // Exchanges are not logged and the session is never archived
+	AnswerWebhookURL string        // Receives each answer, overriding the global AnswerWebhookURL (optional)
+
+	// process is the session's long-lived cursor-agent in persistent mode; never cloned
+	process *cursorProcess
+}
+
+// SessionOptions holds client-selected settings applied when a session is created
+type SessionOptions struct {
+	AutoTTS          bool
+	Branch           string
+	Timeout          time.Duration
+	Ephemeral        bool
+	AnswerWebhookURL string
 }
 
 // Clone creates a deep copy of the Session
@@ -31,10 +50,39 @@ func (s *Session) Clone() *Session {
 	copy(conversationCopy, s.ConversationLog)
 
 	return &Session{
-		ID:              s.ID,
-		CursorChatID:    s.CursorChatID,
-		CreatedAt:       s.CreatedAt,
-		LastActivity:    s.LastActivity,
-		ConversationLog: conversationCopy,
+		ID:               s.ID,
+		CursorChatID:     s.CursorChatID,
+		CreatedAt:        s.CreatedAt,
+		LastActivity:     s.LastActivity,
+		ConversationLog:  conversationCopy,
+		AutoTTS:          s.AutoTTS,
+		LongConversation: s.LongConversation,
+		Branch:           s.Branch,
+		ExternalKey:      s.ExternalKey,
+		Timeout:          s.Timeout,
+		Ephemeral:        s.Ephemeral,
+		AnswerWebhookURL: s.AnswerWebhookURL,
+	}
+}
+
+// CloneShallow creates a copy of the Session without its conversation log,
+// for callers that only need identity and timestamps
+func (s *Session) CloneShallow() *Session {
+	if s == nil {
+		return nil
+	}
+
+	return &Session{
+		ID:               s.ID,
+		CursorChatID:     s.CursorChatID,
+		CreatedAt:        s.CreatedAt,
+		LastActivity:     s.LastActivity,
+		AutoTTS:          s.AutoTTS,
+		LongConversation: s.LongConversation,
+		Branch:           s.Branch,
+		ExternalKey:      s.ExternalKey,
+		Timeout:          s.Timeout,
+		Ephemeral:        s.Ephemeral,
+		AnswerWebhookURL: s.AnswerWebhookURL,
 	}
 }
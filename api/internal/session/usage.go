@@ -0,0 +1,60 @@
+package session
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/sean/janus/internal/logger"
+)
+
+// Why a session ended, as reported in its UsageSummary
+const (
+	// EndReasonEnded means the client ended the session explicitly
+	EndReasonEnded = "ended"
+	// EndReasonReaped means cleanup removed the session after it went inactive
+	EndReasonReaped = "reaped"
+)
+
+// UsageSummary describes what a session was used for over its lifetime
+type UsageSummary struct {
+	Questions int `json:"questions"`
+	// AnswerCharacters counts the characters of every stored answer
+	AnswerCharacters int     `json:"answer_characters"`
+	DurationSeconds  float64 `json:"duration_seconds"`
+	EndReason        string  `json:"end_reason"`
+}
+
+// Usage summarizes a session ending at endedAt for reason. It reflects the
+// conversation log as stored, so ephemeral sessions and trimmed history count
+// only what was kept.
+func Usage(session *Session, endedAt time.Time, reason string) UsageSummary {
+	summary := UsageSummary{
+		DurationSeconds: endedAt.Sub(session.CreatedAt).Seconds(),
+		EndReason:       reason,
+	}
+	for _, msg := range session.ConversationLog {
+		switch msg.Role {
+		case "user":
+			summary.Questions++
+		case "assistant":
+			summary.AnswerCharacters += utf8.RuneCountInString(msg.Content)
+		}
+	}
+	return summary
+}
+
+// logUsage logs the usage summary of a session that just ended when enabled
+func (m *MemorySessionManager) logUsage(session *Session, reason string) {
+	if !m.usageSummaries {
+		return
+	}
+
+	summary := Usage(session, time.Now(), reason)
+	logger.Get().Info().
+		Str("session_id", session.ID).
+		Int("questions", summary.Questions).
+		Int("answer_characters", summary.AnswerCharacters).
+		Float64("duration_seconds", summary.DurationSeconds).
+		Str("end_reason", summary.EndReason).
+		Msg("Session usage summary")
+}
@@ -0,0 +1,45 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsage(t *testing.T) {
+	manager := NewMemorySessionManager(WithUsageSummaries(true))
+	created, _ := manager.CreateSession()
+
+	for _, answer := range []string{"four", "twelve chars", "héllo"} {
+		manager.AddToConversationLog(created.ID, []Message{
+			{Role: "user", Content: "question", Timestamp: time.Now()},
+			{Role: "assistant", Content: answer, Timestamp: time.Now()},
+		})
+	}
+	sess, _ := manager.GetSession(created.ID)
+
+	summary := Usage(sess, sess.CreatedAt.Add(90*time.Second), EndReasonEnded)
+
+	if summary.Questions != 3 {
+		t.Errorf("expected 3 questions, got %d", summary.Questions)
+	}
+	if summary.AnswerCharacters != 4+12+5 {
+		t.Errorf("expected 21 answer characters, got %d", summary.AnswerCharacters)
+	}
+	if summary.DurationSeconds != 90 {
+		t.Errorf("expected a 90s duration, got %v", summary.DurationSeconds)
+	}
+	if summary.EndReason != EndReasonEnded {
+		t.Errorf("expected end reason %q, got %q", EndReasonEnded, summary.EndReason)
+	}
+
+	// Summaries are logged on both paths; ending and reaping must still work
+	if err := manager.EndSession(created.ID); err != nil {
+		t.Errorf("failed to end session: %v", err)
+	}
+	reaped, _ := manager.CreateSession()
+	manager.(*MemorySessionManager).shardFor(reaped.ID).sessions[reaped.ID].LastActivity = time.Now().Add(-time.Hour)
+	manager.CleanupInactiveSessions(time.Minute)
+	if _, err := manager.GetSession(reaped.ID); err == nil {
+		t.Error("expected the inactive session to be reaped")
+	}
+}
@@ -0,0 +1,60 @@
+// Package version reports the running server's version from Go build info.
+package version
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+const (
+	// Unknown is reported when the build carries no module version or VCS revision
+	Unknown = "dev"
+	// revisionLength is how much of a VCS revision is kept for display
+	revisionLength = 12
+)
+
+var (
+	once    sync.Once
+	current string
+)
+
+// Get returns the module version for tagged builds (e.g. "v1.4.0"), otherwise
+// the VCS revision the binary was built from, otherwise Unknown
+func Get() string {
+	once.Do(func() {
+		info, ok := debug.ReadBuildInfo()
+		current = fromBuildInfo(info, ok)
+	})
+	return current
+}
+
+// fromBuildInfo picks the most specific version available in info
+func fromBuildInfo(info *debug.BuildInfo, ok bool) string {
+	if !ok || info == nil {
+		return Unknown
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		return Unknown
+	}
+	if len(revision) > revisionLength {
+		revision = revision[:revisionLength]
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return revision
+}
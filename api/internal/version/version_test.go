@@ -0,0 +1,62 @@
+package version
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestFromBuildInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		info *debug.BuildInfo
+		ok   bool
+		want string
+	}{
+		{
+			name: "tagged module version",
+			info: &debug.BuildInfo{Main: debug.Module{Version: "v1.4.0"}},
+			ok:   true,
+			want: "v1.4.0",
+		},
+		{
+			name: "development build falls back to the VCS revision",
+			info: &debug.BuildInfo{
+				Main:     debug.Module{Version: "(devel)"},
+				Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "0123456789abcdef0123"}},
+			},
+			ok:   true,
+			want: "0123456789ab",
+		},
+		{
+			name: "modified working tree is marked dirty",
+			info: &debug.BuildInfo{
+				Main: debug.Module{Version: "(devel)"},
+				Settings: []debug.BuildSetting{
+					{Key: "vcs.revision", Value: "abc123"},
+					{Key: "vcs.modified", Value: "true"},
+				},
+			},
+			ok:   true,
+			want: "abc123-dirty",
+		},
+		{
+			name: "no version information",
+			info: &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}},
+			ok:   true,
+			want: Unknown,
+		},
+		{
+			name: "build info unavailable",
+			ok:   false,
+			want: Unknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fromBuildInfo(tt.info, tt.ok); got != tt.want {
+				t.Errorf("fromBuildInfo() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}